@@ -0,0 +1,468 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+)
+
+type (
+	//agent forwards requests from CLI invocations to the Sia API, keeping a pool of open
+	//connections for each target so repeated calls skip connection and TLS setup
+	agent struct {
+		started  time.Time
+		requests uint64
+
+		mu         sync.Mutex
+		transports map[string]*http.Transport
+		//pingers check the targets are reachable every keepalive interval, by transport key
+		pingers   map[string]*livenessPinger
+		keepalive time.Duration
+		//cmd the agent command, status lines are written with its settings
+		cmd Command
+
+		stop chan struct{}
+		once sync.Once
+	}
+
+	//agentStatus the response of the agent's status endpoint
+	agentStatus struct {
+		PID        int       `json:"pid"`
+		Started    time.Time `json:"started"`
+		Requests   uint64    `json:"requests"`
+		Targets    int       `json:"targets"`
+		SocketPath string    `json:"socketpath"`
+		//Availability whether each target was reachable at its last keepalive check
+		Availability []livenessStatus `json:"availability,omitempty"`
+	}
+
+	//agentTransport sends requests through the agent's socket
+	agentTransport struct {
+		cmd       Command
+		transport *http.Transport
+	}
+)
+
+const (
+	//agentTargetHeader carries the base URL of the Sia API the agent should forward to
+	agentTargetHeader = "X-Sia-Json-Target"
+	//agentCACertHeader, agentInsecureHeader and agentServerNameHeader carry the TLS settings
+	agentCACertHeader     = "X-Sia-Json-Ca-Cert"
+	agentInsecureHeader   = "X-Sia-Json-Insecure"
+	agentServerNameHeader = "X-Sia-Json-Server-Name"
+	//agentConnectTimeoutHeader carries the --connect-timeout of the command
+	agentConnectTimeoutHeader = "X-Sia-Json-Connect-Timeout"
+	//agentDialErrorHeader marks a response of the agent that could not connect to the target. It
+	//carries the dial error, which the CLI returns like a failed direct connection
+	agentDialErrorHeader = "X-Sia-Json-Dial-Error"
+
+	//agentDialTimeout how long the CLI waits to connect to the agent before sending directly
+	agentDialTimeout = 100 * time.Millisecond
+	//agentStartTimeout how long agent start waits for the agent to accept connections
+	agentStartTimeout = 5 * time.Second
+)
+
+//agentSocketPath returns the path of the agent's unix socket
+func agentSocketPath() string {
+	return filepath.Join(DefaultConfigDir(), "agent.sock")
+}
+
+//agentRunning returns true if an agent is accepting connections on the socket
+func agentRunning() bool {
+	conn, err := net.DialTimeout("unix", agentSocketPath(), agentDialTimeout)
+
+	if err != nil {
+		return false
+	}
+
+	conn.Close()
+
+	return true
+}
+
+//agentClient returns an HTTP client that sends requests to the agent's control endpoints
+func agentClient() *http.Client {
+	return &http.Client{
+		Transport: &http.Transport{
+			DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+				var d net.Dialer
+				return d.DialContext(ctx, "unix", agentSocketPath())
+			},
+		},
+		Timeout: agentStartTimeout,
+	}
+}
+
+//useAgent returns true if the command's requests should be delegated to a running agent
+func useAgent(cmd Command) bool {
+	if len(os.Getenv("SIA_JSON_NO_AGENT")) > 0 || len(cmd.SSHTarget) > 0 {
+		return false
+	}
+
+	return agentRunning()
+}
+
+//RoundTrip rewrites the request to the agent's socket, moving the real target and TLS settings
+//into headers
+func (t *agentTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	forwarded := req.WithContext(req.Context())
+	forwarded.Header = make(http.Header)
+
+	for k, v := range req.Header {
+		forwarded.Header[k] = v
+	}
+
+	u := *req.URL
+	forwarded.Header.Set(agentTargetHeader, u.Scheme+"://"+u.Host)
+	u.Scheme = "http"
+	u.Host = "sia-json-agent"
+	forwarded.URL = &u
+	forwarded.Host = ""
+
+	if len(t.cmd.TLSCACert) > 0 {
+		if path, err := filepath.Abs(t.cmd.TLSCACert); err == nil {
+			forwarded.Header.Set(agentCACertHeader, path)
+		}
+	}
+
+	if t.cmd.TLSInsecure {
+		forwarded.Header.Set(agentInsecureHeader, "true")
+	}
+
+	if len(t.cmd.TLSServerName) > 0 {
+		forwarded.Header.Set(agentServerNameHeader, t.cmd.TLSServerName)
+	}
+
+	forwarded.Header.Set(agentConnectTimeoutHeader, t.cmd.ConnectTimeout.String())
+
+	resp, err := t.transport.RoundTrip(forwarded)
+
+	if err != nil {
+		return nil, err
+	}
+
+	// the target was unreachable, so the request can be retried and exits as a connection error
+	if dialErr := resp.Header.Get(agentDialErrorHeader); len(dialErr) > 0 {
+		resp.Body.Close()
+		return nil, &net.OpError{Op: "dial", Net: "tcp", Addr: agentTargetAddr(req.URL.Host), Err: errors.New(dialErr)}
+	}
+
+	return resp, nil
+}
+
+//agentTargetAddr the address of an agent's target in dial errors
+type agentTargetAddr string
+
+//Network returns the network of the address
+func (a agentTargetAddr) Network() string {
+	return "tcp"
+}
+
+//String returns the host:port of the address
+func (a agentTargetAddr) String() string {
+	return string(a)
+}
+
+//newAgentTransport returns a transport that delegates the command's requests to the agent
+func newAgentTransport(cmd Command) http.RoundTripper {
+	transport := newTransport()
+	transport.DialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+		var d net.Dialer
+		return d.DialContext(ctx, "unix", agentSocketPath())
+	}
+
+	return &agentTransport{cmd: cmd, transport: transport}
+}
+
+//transport returns the pooled transport for the target and TLS settings, creating it on first use
+func (a *agent) transport(target string, cmd Command) (transport *http.Transport, err error) {
+	key := fmt.Sprintf("%s|%s|%t|%s|%s", target, cmd.TLSCACert, cmd.TLSInsecure, cmd.TLSServerName, cmd.ConnectTimeout)
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if transport, ok := a.transports[key]; ok {
+		return transport, nil
+	}
+
+	tls, err := tlsConfig(cmd)
+
+	if err != nil {
+		return
+	}
+
+	transport = newTransport()
+	transport.DialContext = (&net.Dialer{
+		Timeout:   cmd.ConnectTimeout,
+		KeepAlive: 30 * time.Second,
+	}).DialContext
+	transport.TLSClientConfig = tls
+	transport.MaxIdleConnsPerHost = 16
+	a.transports[key] = transport
+
+	if a.keepalive > 0 {
+		a.pingers[key] = transportPinger(target, a.keepalive, DefaultConnectTimeout, transport, func(status livenessStatus) {
+			notifyLiveness(a.cmd, status)
+		})
+	}
+
+	return
+}
+
+//ServeHTTP forwards requests carrying a target header and serves the control endpoints for
+//everything else
+func (a *agent) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	target := r.Header.Get(agentTargetHeader)
+
+	if len(target) == 0 {
+		a.serveControl(w, r)
+		return
+	}
+
+	targetURL, err := url.Parse(target)
+
+	if err != nil || len(targetURL.Host) == 0 {
+		http.Error(w, fmt.Sprintf("invalid target %q", target), http.StatusBadRequest)
+		return
+	}
+
+	// a missing or invalid timeout connects without one, like --connect-timeout 0
+	connectTimeout, _ := time.ParseDuration(r.Header.Get(agentConnectTimeoutHeader))
+	transport, err := a.transport(target, Command{
+		TLSCACert:      r.Header.Get(agentCACertHeader),
+		TLSInsecure:    r.Header.Get(agentInsecureHeader) == "true",
+		TLSServerName:  r.Header.Get(agentServerNameHeader),
+		ConnectTimeout: connectTimeout,
+	})
+
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+
+	atomic.AddUint64(&a.requests, 1)
+
+	proxy := &httputil.ReverseProxy{
+		Director: func(req *http.Request) {
+			req.URL.Scheme = targetURL.Scheme
+			req.URL.Host = targetURL.Host
+			req.Host = targetURL.Host
+
+			for _, header := range []string{agentTargetHeader, agentCACertHeader, agentInsecureHeader, agentServerNameHeader, agentConnectTimeoutHeader} {
+				req.Header.Del(header)
+			}
+
+			// the agent is not a proxy the API should know about
+			req.Header["X-Forwarded-For"] = nil
+		},
+		Transport:     transport,
+		FlushInterval: 100 * time.Millisecond,
+		ErrorHandler: func(w http.ResponseWriter, r *http.Request, err error) {
+			if opErr, ok := err.(*net.OpError); ok && isConnectionError(err) {
+				w.Header().Set(agentDialErrorHeader, opErr.Err.Error())
+			}
+
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusBadGateway)
+			json.NewEncoder(w).Encode(APIError{Message: err.Error()})
+		},
+	}
+
+	proxy.ServeHTTP(w, r)
+}
+
+//serveControl serves the agent's status and stop endpoints
+func (a *agent) serveControl(w http.ResponseWriter, r *http.Request) {
+	switch r.URL.Path {
+	case "/status":
+		a.mu.Lock()
+		status := agentStatus{
+			PID:        os.Getpid(),
+			Started:    a.started,
+			Requests:   atomic.LoadUint64(&a.requests),
+			Targets:    len(a.transports),
+			SocketPath: agentSocketPath(),
+		}
+
+		for _, pinger := range a.pingers {
+			status.Availability = append(status.Availability, pinger.Status())
+		}
+		a.mu.Unlock()
+
+		sort.Slice(status.Availability, func(i, j int) bool {
+			return status.Availability[i].Address < status.Availability[j].Address
+		})
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(status)
+	case "/stop":
+		if r.Method != "POST" {
+			http.Error(w, "stop must be a POST", http.StatusMethodNotAllowed)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+		a.once.Do(func() { close(a.stop) })
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+//agentCommand runs the agent in the foreground until it is interrupted or stopped
+func agentCommand(cmd Command) (err error) {
+	if agentRunning() {
+		return fmt.Errorf("an agent is already running on %s", agentSocketPath())
+	}
+
+	socket := agentSocketPath()
+
+	if err = os.MkdirAll(filepath.Dir(socket), 0700); err != nil {
+		return
+	}
+
+	// a socket left behind by an agent that did not shut down cleanly
+	os.Remove(socket)
+
+	l, err := net.Listen("unix", socket)
+
+	if err != nil {
+		return
+	}
+
+	defer os.Remove(socket)
+
+	if err = os.Chmod(socket, 0600); err != nil {
+		l.Close()
+		return
+	}
+
+	a := &agent{
+		started:    time.Now(),
+		transports: make(map[string]*http.Transport),
+		pingers:    make(map[string]*livenessPinger),
+		keepalive:  cmd.Keepalive,
+		cmd:        cmd,
+		stop:       make(chan struct{}),
+	}
+	server := &http.Server{Handler: a}
+
+	defer func() {
+		a.mu.Lock()
+		defer a.mu.Unlock()
+
+		for _, pinger := range a.pingers {
+			pinger.Stop()
+		}
+	}()
+
+	signal.Ignore(syscall.SIGHUP)
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, os.Interrupt, syscall.SIGTERM)
+
+	go func() {
+		select {
+		case <-sigs:
+		case <-a.stop:
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		server.Shutdown(ctx)
+	}()
+
+	notify(cmd, "agent listening on %s", socket)
+
+	if err = server.Serve(l); err == http.ErrServerClosed {
+		err = nil
+	}
+
+	return
+}
+
+//agentStartCommand starts the agent as a background process and waits for it to accept
+//connections
+func agentStartCommand(cmd Command) (err error) {
+	if agentRunning() {
+		notify(cmd, "agent already running on %s", agentSocketPath())
+		return
+	}
+
+	exe, err := os.Executable()
+
+	if err != nil {
+		return
+	}
+
+	c := exec.Command(exe, "agent", "--quiet", "--keepalive", cmd.Keepalive.String())
+
+	if err = c.Start(); err != nil {
+		return fmt.Errorf("unable to start agent: %s", err)
+	}
+
+	c.Process.Release()
+
+	for deadline := time.Now().Add(agentStartTimeout); time.Now().Before(deadline); time.Sleep(50 * time.Millisecond) {
+		if agentRunning() {
+			notify(cmd, "agent started on %s", agentSocketPath())
+			return
+		}
+	}
+
+	return fmt.Errorf("agent did not start within %s", agentStartTimeout)
+}
+
+//agentStopCommand asks the running agent to shut down
+func agentStopCommand(cmd Command) (err error) {
+	if !agentRunning() {
+		notify(cmd, "agent is not running")
+		return
+	}
+
+	resp, err := agentClient().Post("http://sia-json-agent/stop", "", nil)
+
+	if err != nil {
+		return
+	}
+
+	resp.Body.Close()
+
+	for deadline := time.Now().Add(agentStartTimeout); time.Now().Before(deadline); time.Sleep(50 * time.Millisecond) {
+		if !agentRunning() {
+			notify(cmd, "agent stopped")
+			return
+		}
+	}
+
+	return fmt.Errorf("agent did not stop within %s", agentStartTimeout)
+}
+
+//agentStatusCommand writes the running agent's status
+func agentStatusCommand(cmd Command) (err error) {
+	if !agentRunning() {
+		return ExitCodeError{Code: ExitConnectionError, Err: fmt.Errorf("agent is not running")}
+	}
+
+	resp, err := agentClient().Get("http://sia-json-agent/status")
+
+	if err != nil {
+		return
+	}
+
+	defer resp.Body.Close()
+
+	return writeOutput(cmd, resp)
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+type (
+	//argToken a flag and its value, or a positional argument, of the command line
+	argToken struct {
+		//Flag the lower case name of the flag without dashes, empty for a positional argument
+		Flag  string
+		Value string
+		//Raw the args the token was read from
+		Raw []string
+		//Param the token was given with --param key value and is sent as a param even if its key
+		//is the name of a flag
+		Param bool
+	}
+)
+
+//shortFlags the single dash aliases of flags
+var shortFlags = map[string]string{
+	"a": "addr",
+	"m": "method",
+	"p": "apipassword",
+}
+
+//tokenizeArgs splits the args into flags and positional arguments. Flags are given as --key value,
+//--key=value or a short alias such as -m GET. The next arg is the value of a flag even if it starts
+//with a single dash, like -100, but a flag followed by another flag or nothing is an error rather
+//than an empty value. Use --key=value for values starting with --, and --param key value to send a
+//param named like a flag. Every arg after a -- is positional
+func tokenizeArgs(args []string) (tokens []argToken, err error) {
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+
+		if strings.ToLower(arg) == "--param" {
+			if i+2 >= len(args) {
+				return nil, fmt.Errorf("--param needs a key and a value, such as --param format json")
+			}
+
+			tokens = append(tokens, argToken{Flag: strings.ToLower(args[i+1]), Value: args[i+2], Raw: args[i : i+3], Param: true})
+			i += 2
+
+			continue
+		}
+
+		if arg == "--" {
+			for _, positional := range args[i+1:] {
+				tokens = append(tokens, argToken{Value: positional, Raw: []string{"--", positional}})
+			}
+
+			return
+		}
+
+		var key string
+
+		if strings.HasPrefix(arg, "--") {
+			key = arg[2:]
+		} else if strings.HasPrefix(arg, "-") && len(arg) > 1 {
+			name := strings.SplitN(arg[1:], "=", 2)[0]
+
+			if flag, ok := shortFlags[name]; ok {
+				key = flag + strings.TrimPrefix(arg[1:], name)
+			}
+		}
+
+		if len(key) == 0 {
+			tokens = append(tokens, argToken{Value: arg, Raw: []string{arg}})
+			continue
+		}
+
+		token := argToken{Flag: strings.ToLower(key), Raw: []string{arg}}
+
+		if n := strings.Index(key, "="); n >= 0 {
+			token.Flag, token.Value = strings.ToLower(key[:n]), key[n+1:]
+
+			if boolFlags[token.Flag] {
+				set, parseErr := strconv.ParseBool(token.Value)
+
+				if parseErr != nil {
+					return nil, fmt.Errorf("invalid --%s value %q, expected true or false", token.Flag, token.Value)
+				} else if !set {
+					continue
+				}
+
+				token.Value = ""
+			}
+		} else if !boolFlags[token.Flag] {
+			if i+1 >= len(args) || strings.HasPrefix(args[i+1], "--") {
+				return nil, fmt.Errorf("--%s needs a value, use --%s=value for a value starting with --", token.Flag, token.Flag)
+			}
+
+			token.Value = args[i+1]
+			token.Raw = append(token.Raw, args[i+1])
+			i++
+		}
+
+		tokens = append(tokens, token)
+	}
+
+	return
+}
@@ -0,0 +1,601 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/n8maninger/siac-json/format"
+)
+
+type (
+	//backupFile a file of a snapshot and the chunks its contents are split into
+	backupFile struct {
+		Path    string      `json:"path"`
+		Size    int64       `json:"size"`
+		Mode    os.FileMode `json:"mode"`
+		ModTime time.Time   `json:"modtime"`
+		Chunks  []string    `json:"chunks"`
+	}
+
+	//backupSnapshot the manifest uploaded by each backup run
+	backupSnapshot struct {
+		ID      string       `json:"id"`
+		Created time.Time    `json:"created"`
+		Source  string       `json:"source"`
+		Size    int64        `json:"size"`
+		Files   []backupFile `json:"files"`
+	}
+
+	//backupState the chunks known to be in a repository and the files of the last snapshot of
+	//each source directory, so unchanged files are not read again
+	backupState struct {
+		Chunks  map[string]int64                 `json:"chunks"`
+		Sources map[string]map[string]backupFile `json:"sources"`
+
+		path string
+	}
+
+	//backupResult the result of backup run
+	backupResult struct {
+		Snapshot     string `json:"snapshot"`
+		Repo         string `json:"repo"`
+		Files        int    `json:"files"`
+		Size         int64  `json:"size"`
+		Chunks       int    `json:"chunks"`
+		NewChunks    int    `json:"newchunks"`
+		Uploaded     int64  `json:"uploaded"`
+		Deduplicated int64  `json:"deduplicated"`
+	}
+
+	//restoreResult the result of backup restore
+	restoreResult struct {
+		Snapshot string `json:"snapshot"`
+		Path     string `json:"path"`
+		Files    int    `json:"files"`
+		Size     int64  `json:"size"`
+	}
+
+	//backupSnapshotInfo a snapshot listed by backup list
+	backupSnapshotInfo struct {
+		ID   string `json:"id"`
+		Size int64  `json:"size"`
+	}
+
+	//renterDirFiles the files of a /renter/dir response
+	renterDirFiles struct {
+		Files []struct {
+			SiaPath    string    `json:"siapath"`
+			FileSize   int64     `json:"filesize"`
+			CreateTime time.Time `json:"createtime"`
+		} `json:"files"`
+	}
+
+	//chunker splits a reader into content defined chunks, so an insert or delete only changes
+	//the chunks around it
+	chunker struct {
+		r   *bufio.Reader
+		buf []byte
+	}
+)
+
+const (
+	//defaultBackupRepo the siapath backups are stored below
+	defaultBackupRepo = "sia-json-backup"
+
+	//backupMinChunk the smallest chunk a file is split into, unless the file is smaller
+	backupMinChunk = 1 << 20
+
+	//backupMaxChunk the largest chunk a file is split into
+	backupMaxChunk = 32 << 20
+
+	//backupChunkMask the rolling hash bits that must be zero to end a chunk, 8 MiB on average
+	backupChunkMask = 1<<23 - 1
+
+	//backupSnapshotFormat the format of snapshot ids
+	backupSnapshotFormat = "20060102T150405Z"
+)
+
+//gearTable the random values of the gear rolling hash. The table is generated from a fixed
+//seed since changing it would change every chunk boundary
+var gearTable = func() (table [256]uint64) {
+	x := uint64(0x5349414a534f4e31)
+
+	for i := range table {
+		// splitmix64
+		x += 0x9e3779b97f4a7c15
+		z := x
+		z = (z ^ z>>30) * 0xbf58476d1ce4e5b9
+		z = (z ^ z>>27) * 0x94d049bb133111eb
+		table[i] = z ^ z>>31
+	}
+
+	return
+}()
+
+//newChunker returns a chunker of r
+func newChunker(r io.Reader) *chunker {
+	return &chunker{
+		r:   bufio.NewReaderSize(r, 1<<20),
+		buf: make([]byte, 0, backupMaxChunk),
+	}
+}
+
+//next returns the next chunk. The chunk is only valid until the next call
+func (c *chunker) next() ([]byte, error) {
+	var h uint64
+	c.buf = c.buf[:0]
+
+	for len(c.buf) < backupMaxChunk {
+		b, err := c.r.ReadByte()
+
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+
+		c.buf = append(c.buf, b)
+		h = h<<1 + gearTable[b]
+
+		if len(c.buf) >= backupMinChunk && h&backupChunkMask == 0 {
+			break
+		}
+	}
+
+	if len(c.buf) == 0 {
+		return nil, io.EOF
+	}
+
+	return c.buf, nil
+}
+
+//backupRepo returns the --repo siapath, default sia-json-backup
+func backupRepo(cmd Command) string {
+	if values := cmd.Params["repo"]; len(values) > 0 && len(strings.Trim(values[0], "/")) > 0 {
+		return strings.Trim(values[0], "/")
+	}
+
+	return defaultBackupRepo
+}
+
+//loadBackupState loads the local state of the repository
+func loadBackupState(cmd Command, repo string) (state backupState, err error) {
+	sum := sha256.Sum256([]byte(cmd.APIAddress + "\x00" + repo))
+	state = backupState{
+		Chunks:  make(map[string]int64),
+		Sources: make(map[string]map[string]backupFile),
+		path:    filepath.Join(DefaultConfigDir(), "backup", hex.EncodeToString(sum[:8])+".json"),
+	}
+
+	buf, err := ioutil.ReadFile(state.path)
+
+	if os.IsNotExist(err) {
+		return state, nil
+	} else if err != nil {
+		return
+	}
+
+	if err = json.Unmarshal(buf, &state); err != nil {
+		return
+	}
+
+	if state.Chunks == nil {
+		state.Chunks = make(map[string]int64)
+	}
+
+	if state.Sources == nil {
+		state.Sources = make(map[string]map[string]backupFile)
+	}
+
+	return
+}
+
+//save atomically writes the state
+func (s backupState) save() (err error) {
+	if err = os.MkdirAll(filepath.Dir(s.path), 0700); err != nil {
+		return
+	}
+
+	buf, err := json.Marshal(s)
+
+	if err != nil {
+		return
+	}
+
+	tmp := s.path + ".tmp"
+
+	if err = ioutil.WriteFile(tmp, buf, 0600); err != nil {
+		return
+	}
+
+	return os.Rename(tmp, s.path)
+}
+
+//listRenterDir lists the files in the renter directory. A missing directory has no files
+func listRenterDir(cmd Command, dir string) (files map[string]int64, err error) {
+	var resp renterDirFiles
+
+	files = make(map[string]int64)
+
+	if err = callAPI(cmd, "GET", "/renter/dir/"+dir, nil, &resp); err != nil {
+		if _, ok := err.(APIError); ok {
+			return files, nil
+		}
+
+		return
+	}
+
+	for _, file := range resp.Files {
+		files[path.Base(file.SiaPath)] = file.FileSize
+	}
+
+	return
+}
+
+//uploadBackupObject uploads the buffer to the siapath
+func uploadBackupObject(cmd Command, siapath string, buf []byte) error {
+	upload := cmd
+	upload.Method = "POST"
+	upload.RequestPath = "/renter/uploadstream/" + siapath
+	upload.Params = nil
+	upload.ContentType = "application/octet-stream"
+	upload.BodyLength = int64(len(buf))
+
+	return streamAPI(upload, bytes.NewReader(buf), nil)
+}
+
+//downloadBackupObject streams the file at the siapath to read
+func downloadBackupObject(cmd Command, siapath string, read func(io.Reader) error) error {
+	download := cmd
+	download.Method = "GET"
+	download.RequestPath = "/renter/download/" + siapath
+	download.Params = url.Values{"httpresp": {"true"}}
+
+	return streamAPI(download, nil, read)
+}
+
+//backupChunks splits the file into chunks and uploads the chunks the repository does not have
+func backupChunks(cmd Command, repo, name string, state backupState, result *backupResult) (chunks []string, err error) {
+	f, err := os.Open(name)
+
+	if err != nil {
+		return
+	}
+
+	defer f.Close()
+
+	chunks = []string{}
+	c := newChunker(f)
+
+	for {
+		chunk, err := c.next()
+
+		if err == io.EOF {
+			return chunks, nil
+		} else if err != nil {
+			return nil, err
+		}
+
+		sum := sha256.Sum256(chunk)
+		id := hex.EncodeToString(sum[:])
+		chunks = append(chunks, id)
+
+		if _, ok := state.Chunks[id]; ok {
+			result.Deduplicated += int64(len(chunk))
+			continue
+		}
+
+		if err = uploadBackupObject(cmd, repo+"/chunks/"+id, chunk); err != nil {
+			return nil, fmt.Errorf("unable to upload chunk %s: %s", id, err)
+		}
+
+		state.Chunks[id] = int64(len(chunk))
+		result.NewChunks++
+		result.Uploaded += int64(len(chunk))
+
+		if err = state.save(); err != nil {
+			return nil, err
+		}
+	}
+}
+
+//backupRunCommand backs up a directory into the --repo siapath, default sia-json-backup:
+//backup run <dir>. Files are split into content defined chunks stored by their hash, so only
+//chunks the repository does not have yet are uploaded, and a manifest of the snapshot is
+//uploaded to <repo>/snapshots. Files unchanged since the last snapshot of the directory are
+//not read again
+func backupRunCommand(cmd Command) (err error) {
+	if len(cmd.Args) != 3 {
+		return fmt.Errorf("usage: backup run <dir>")
+	}
+
+	source, err := filepath.Abs(cmd.Args[2])
+
+	if err != nil {
+		return
+	}
+
+	if info, err := os.Stat(source); err != nil {
+		return err
+	} else if !info.IsDir() {
+		return fmt.Errorf("%s is not a directory", cmd.Args[2])
+	}
+
+	repo := backupRepo(cmd)
+	state, err := loadBackupState(cmd, repo)
+
+	if err != nil {
+		return
+	}
+
+	// a new machine or a lost state learns the chunks from the repository
+	if len(state.Chunks) == 0 {
+		if state.Chunks, err = listRenterDir(cmd, repo+"/chunks"); err != nil {
+			return
+		}
+	}
+
+	previous := state.Sources[source]
+	current := make(map[string]backupFile)
+	snapshot := backupSnapshot{
+		ID:      time.Now().UTC().Format(backupSnapshotFormat),
+		Created: time.Now().UTC(),
+		Source:  source,
+		Files:   []backupFile{},
+	}
+	result := backupResult{
+		Snapshot: snapshot.ID,
+		Repo:     repo,
+	}
+
+	err = filepath.Walk(source, func(name string, info os.FileInfo, err error) error {
+		if err != nil || !info.Mode().IsRegular() {
+			return err
+		}
+
+		rel, err := filepath.Rel(source, name)
+
+		if err != nil {
+			return err
+		}
+
+		file := backupFile{
+			Path:    filepath.ToSlash(rel),
+			Size:    info.Size(),
+			Mode:    info.Mode().Perm(),
+			ModTime: info.ModTime().UTC(),
+		}
+
+		if last, ok := previous[file.Path]; ok && last.Size == file.Size && last.ModTime.Equal(file.ModTime) && hasChunks(state, last.Chunks) {
+			file.Chunks = last.Chunks
+			result.Deduplicated += file.Size
+		} else {
+			notify(cmd, "backing up %s", file.Path)
+
+			if file.Chunks, err = backupChunks(cmd, repo, name, state, &result); err != nil {
+				return fmt.Errorf("%s: %s", file.Path, err)
+			}
+		}
+
+		current[file.Path] = file
+		snapshot.Files = append(snapshot.Files, file)
+		snapshot.Size += file.Size
+		result.Chunks += len(file.Chunks)
+
+		return nil
+	})
+
+	if err != nil {
+		return
+	}
+
+	buf, err := json.MarshalIndent(snapshot, "", "\t")
+
+	if err != nil {
+		return
+	}
+
+	if err = uploadBackupObject(cmd, repo+"/snapshots/"+snapshot.ID+".json", buf); err != nil {
+		return fmt.Errorf("unable to upload the snapshot manifest: %s", err)
+	}
+
+	state.Sources[source] = current
+
+	if err = state.save(); err != nil {
+		return
+	}
+
+	result.Files = len(snapshot.Files)
+	result.Size = snapshot.Size
+
+	notify(cmd, "snapshot %s: %d files, %s uploaded, %s deduplicated", snapshot.ID, result.Files, format.FormatBytes(result.Uploaded), format.FormatBytes(result.Deduplicated))
+
+	return writeJSON(result)
+}
+
+//hasChunks returns true if every chunk is known to be in the repository
+func hasChunks(state backupState, chunks []string) bool {
+	for _, id := range chunks {
+		if _, ok := state.Chunks[id]; !ok {
+			return false
+		}
+	}
+
+	return true
+}
+
+//listBackupSnapshots returns the snapshots in the repository, oldest first
+func listBackupSnapshots(cmd Command, repo string) (snapshots []backupSnapshotInfo, err error) {
+	files, err := listRenterDir(cmd, repo+"/snapshots")
+
+	if err != nil {
+		return
+	}
+
+	snapshots = []backupSnapshotInfo{}
+
+	for name, size := range files {
+		if strings.HasSuffix(name, ".json") {
+			snapshots = append(snapshots, backupSnapshotInfo{
+				ID:   strings.TrimSuffix(name, ".json"),
+				Size: size,
+			})
+		}
+	}
+
+	sort.Slice(snapshots, func(i, j int) bool {
+		return snapshots[i].ID < snapshots[j].ID
+	})
+
+	return
+}
+
+//backupListCommand lists the snapshots in the --repo siapath
+func backupListCommand(cmd Command) (err error) {
+	snapshots, err := listBackupSnapshots(cmd, backupRepo(cmd))
+
+	if err != nil {
+		return
+	}
+
+	return writeJSON(snapshots)
+}
+
+//loadBackupSnapshot downloads the snapshot's manifest. latest loads the newest snapshot
+func loadBackupSnapshot(cmd Command, repo, id string) (snapshot backupSnapshot, err error) {
+	if id == "latest" {
+		snapshots, err := listBackupSnapshots(cmd, repo)
+
+		if err != nil {
+			return snapshot, err
+		} else if len(snapshots) == 0 {
+			return snapshot, fmt.Errorf("no snapshots in %s", repo)
+		}
+
+		id = snapshots[len(snapshots)-1].ID
+	}
+
+	err = downloadBackupObject(cmd, repo+"/snapshots/"+id+".json", func(r io.Reader) error {
+		return json.NewDecoder(r).Decode(&snapshot)
+	})
+
+	if err != nil {
+		return snapshot, fmt.Errorf("unable to load snapshot %s: %s", id, err)
+	}
+
+	return
+}
+
+//restoreBackupFile downloads the file's chunks into the target, checking each chunk's hash
+func restoreBackupFile(cmd Command, repo string, file backupFile, target string) (err error) {
+	if err = os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return
+	}
+
+	f, err := os.OpenFile(target, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, file.Mode)
+
+	if err != nil {
+		return
+	}
+
+	for _, id := range file.Chunks {
+		h := sha256.New()
+
+		err = downloadBackupObject(cmd, repo+"/chunks/"+id, func(r io.Reader) error {
+			_, err := io.Copy(io.MultiWriter(f, h), r)
+			return err
+		})
+
+		if err == nil && hex.EncodeToString(h.Sum(nil)) != id {
+			err = fmt.Errorf("chunk %s is corrupted", id)
+		}
+
+		if err != nil {
+			break
+		}
+	}
+
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+
+	if err != nil {
+		return
+	}
+
+	return os.Chtimes(target, file.ModTime, file.ModTime)
+}
+
+//backupTarget returns the path the file is restored to in the directory. Paths outside of the
+//directory are refused
+func backupTarget(dir string, file backupFile) (string, error) {
+	name := path.Clean(file.Path)
+
+	if path.IsAbs(name) || name == ".." || strings.HasPrefix(name, "../") {
+		return "", fmt.Errorf("refusing to restore %s outside of %s", file.Path, dir)
+	}
+
+	return filepath.Join(dir, filepath.FromSlash(name)), nil
+}
+
+//backupRestoreCommand restores a snapshot, or latest, into a directory: backup restore
+//<snapshot> <dir>. --path restores only the matching files and directories
+func backupRestoreCommand(cmd Command) (err error) {
+	if len(cmd.Args) != 4 {
+		return fmt.Errorf("usage: backup restore <snapshot|latest> <dir>")
+	}
+
+	repo := backupRepo(cmd)
+	snapshot, err := loadBackupSnapshot(cmd, repo, cmd.Args[2])
+
+	if err != nil {
+		return
+	}
+
+	dir := cmd.Args[3]
+	paths := cmd.Params["path"]
+	result := restoreResult{
+		Snapshot: snapshot.ID,
+		Path:     dir,
+	}
+
+	for _, file := range snapshot.Files {
+		if len(paths) > 0 && !bundleFile(paths, file.Path) {
+			continue
+		}
+
+		target, err := backupTarget(dir, file)
+
+		if err != nil {
+			return err
+		}
+
+		notify(cmd, "restoring %s", file.Path)
+
+		if err = restoreBackupFile(cmd, repo, file, target); err != nil {
+			return fmt.Errorf("unable to restore %s: %s", file.Path, err)
+		}
+
+		result.Files++
+		result.Size += file.Size
+	}
+
+	if result.Files == 0 && len(paths) > 0 {
+		return fmt.Errorf("no files in snapshot %s match %s", snapshot.ID, strings.Join(paths, ", "))
+	}
+
+	return writeJSON(result)
+}
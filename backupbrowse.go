@@ -0,0 +1,580 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/n8maninger/siac-json/format"
+)
+
+type (
+	//backupVersion a version of a file in the snapshots of a repository
+	backupVersion struct {
+		Snapshot string    `json:"snapshot"`
+		Size     int64     `json:"size"`
+		ModTime  time.Time `json:"modtime"`
+		//Changed the file's contents differ from the previous snapshot containing it
+		Changed bool `json:"changed"`
+
+		file backupFile
+	}
+
+	//restoredFile a file restored by backup browse
+	restoredFile struct {
+		Path     string `json:"path"`
+		Snapshot string `json:"snapshot"`
+		Size     int64  `json:"size"`
+	}
+
+	//browseNode a file or directory of the snapshot tree shown by backup browse
+	browseNode struct {
+		name     string
+		path     string
+		file     *backupFile
+		children []*browseNode
+		expanded bool
+		//files the paths of the files at or below the node
+		files []string
+	}
+
+	//browseRow a visible row of the tree
+	browseRow struct {
+		node  *browseNode
+		depth int
+	}
+
+	//backupBrowser the state of backup browse
+	backupBrowser struct {
+		cmd      Command
+		repo     string
+		snapshot backupSnapshot
+		root     *browseNode
+		rows     []browseRow
+		cursor   int
+		top      int
+		//selected the version of each selected file to restore
+		selected map[string]backupVersion
+		//snapshots the manifests loaded for file histories by id
+		snapshots map[string]backupSnapshot
+		status    string
+	}
+)
+
+//browseHelp the keys of backup browse
+const browseHelp = "up/down move  right/enter open  left close  space select  h history  r restore  q quit"
+
+//buildBrowseTree returns the directory tree of the snapshot's files
+func buildBrowseTree(files []backupFile) *browseNode {
+	root := &browseNode{expanded: true}
+	dirs := map[string]*browseNode{"": root}
+
+	for i := range files {
+		file := &files[i]
+		parts := strings.Split(strings.Trim(file.Path, "/"), "/")
+		parent := root
+
+		for j, part := range parts {
+			nodePath := strings.Join(parts[:j+1], "/")
+			parent.files = append(parent.files, file.Path)
+
+			if j == len(parts)-1 {
+				parent.children = append(parent.children, &browseNode{
+					name:  part,
+					path:  nodePath,
+					file:  file,
+					files: []string{file.Path},
+				})
+				break
+			}
+
+			dir, ok := dirs[nodePath]
+
+			if !ok {
+				dir = &browseNode{name: part, path: nodePath}
+				dirs[nodePath] = dir
+				parent.children = append(parent.children, dir)
+			}
+
+			parent = dir
+		}
+	}
+
+	for _, dir := range dirs {
+		children := dir.children
+
+		sort.Slice(children, func(i, j int) bool {
+			if (children[i].file == nil) != (children[j].file == nil) {
+				return children[i].file == nil
+			}
+
+			return children[i].name < children[j].name
+		})
+	}
+
+	return root
+}
+
+//fileHistory returns the versions of the file in every snapshot of the repository containing
+//it, oldest first. Manifests are cached in snapshots
+func fileHistory(cmd Command, repo, filePath string, snapshots map[string]backupSnapshot) (versions []backupVersion, err error) {
+	infos, err := listBackupSnapshots(cmd, repo)
+
+	if err != nil {
+		return
+	}
+
+	versions = []backupVersion{}
+	var previous []string
+
+	for _, info := range infos {
+		snapshot, ok := snapshots[info.ID]
+
+		if !ok {
+			if snapshot, err = loadBackupSnapshot(cmd, repo, info.ID); err != nil {
+				return
+			}
+
+			snapshots[info.ID] = snapshot
+		}
+
+		for _, file := range snapshot.Files {
+			if file.Path != filePath {
+				continue
+			}
+
+			versions = append(versions, backupVersion{
+				Snapshot: snapshot.ID,
+				Size:     file.Size,
+				ModTime:  file.ModTime,
+				Changed:  previous == nil || strings.Join(file.Chunks, ",") != strings.Join(previous, ","),
+				file:     file,
+			})
+			previous = file.Chunks
+			break
+		}
+	}
+
+	return
+}
+
+//terminalHeight returns the number of rows of the terminal, 24 if it cannot be read
+func terminalHeight() int {
+	stty := exec.Command("stty", "size")
+	stty.Stdin = os.Stdin
+	out, err := stty.Output()
+
+	if err != nil {
+		return 24
+	}
+
+	fields := strings.Fields(string(out))
+
+	if len(fields) != 2 {
+		return 24
+	}
+
+	rows, err := strconv.Atoi(fields[0])
+
+	if err != nil || rows < 5 {
+		return 24
+	}
+
+	return rows
+}
+
+//refresh recomputes the visible rows of the tree
+func (b *backupBrowser) refresh() {
+	b.rows = b.rows[:0]
+
+	var walk func(node *browseNode, depth int)
+	walk = func(node *browseNode, depth int) {
+		for _, child := range node.children {
+			b.rows = append(b.rows, browseRow{node: child, depth: depth})
+
+			if child.expanded {
+				walk(child, depth+1)
+			}
+		}
+	}
+
+	walk(b.root, 0)
+
+	if b.cursor >= len(b.rows) {
+		b.cursor = len(b.rows) - 1
+	}
+
+	if b.cursor < 0 {
+		b.cursor = 0
+	}
+}
+
+//marker returns the selection marker of the node: [x] if all its files are selected, [-] if
+//some are
+func (b *backupBrowser) marker(node *browseNode) string {
+	selected := 0
+
+	for _, p := range node.files {
+		if _, ok := b.selected[p]; ok {
+			selected++
+		}
+	}
+
+	switch {
+	case selected == 0:
+		return "[ ]"
+	case selected == len(node.files):
+		return "[x]"
+	default:
+		return "[-]"
+	}
+}
+
+//draw writes the visible part of the tree to the terminal
+func (b *backupBrowser) draw() {
+	height := terminalHeight() - 3
+
+	if b.cursor < b.top {
+		b.top = b.cursor
+	} else if b.cursor >= b.top+height {
+		b.top = b.cursor - height + 1
+	}
+
+	var sb strings.Builder
+
+	sb.WriteString("\033[H\033[2J")
+	fmt.Fprintf(&sb, "snapshot %s of %s, %d files, %d selected\r\n", b.snapshot.ID, b.snapshot.Source, len(b.snapshot.Files), len(b.selected))
+
+	for i := b.top; i < len(b.rows) && i < b.top+height; i++ {
+		row := b.rows[i]
+		cursor := "  "
+
+		if i == b.cursor {
+			cursor = "> "
+		}
+
+		line := cursor + strings.Repeat("  ", row.depth) + b.marker(row.node) + " "
+
+		if row.node.file == nil {
+			line += row.node.name + "/"
+		} else {
+			line += fmt.Sprintf("%s  %s", row.node.name, format.FormatBytes(row.node.file.Size))
+
+			if version, ok := b.selected[row.node.path]; ok && version.Snapshot != b.snapshot.ID {
+				line += "  (from " + version.Snapshot + ")"
+			}
+		}
+
+		sb.WriteString(line + "\r\n")
+	}
+
+	if len(b.status) > 0 {
+		sb.WriteString(b.status + "\r\n")
+	}
+
+	sb.WriteString(browseHelp)
+	os.Stderr.WriteString(sb.String())
+}
+
+//toggle selects the files at or below the node from the browsed snapshot, or deselects them if
+//they are all selected
+func (b *backupBrowser) toggle(node *browseNode) {
+	if b.marker(node) == "[x]" {
+		for _, p := range node.files {
+			delete(b.selected, p)
+		}
+
+		return
+	}
+
+	files := make(map[string]bool)
+
+	for _, p := range node.files {
+		files[p] = true
+	}
+
+	for _, file := range b.snapshot.Files {
+		if _, ok := b.selected[file.Path]; ok || !files[file.Path] {
+			continue
+		}
+
+		b.selected[file.Path] = backupVersion{
+			Snapshot: b.snapshot.ID,
+			Size:     file.Size,
+			ModTime:  file.ModTime,
+			file:     file,
+		}
+	}
+}
+
+//readKey reads a key press. Arrow keys are returned as up, down, left and right
+func readKey() (key string, err error) {
+	r, _, err := stdinReader.ReadRune()
+
+	if err != nil {
+		return
+	}
+
+	if r != 27 {
+		return string(r), nil
+	}
+
+	// arrow keys are sent as ESC [ A-D
+	if next, _, _ := stdinReader.ReadRune(); next != '[' {
+		return "esc", nil
+	}
+
+	arrow, _, _ := stdinReader.ReadRune()
+
+	switch arrow {
+	case 'A':
+		return "up", nil
+	case 'B':
+		return "down", nil
+	case 'C':
+		return "right", nil
+	case 'D':
+		return "left", nil
+	}
+
+	return "esc", nil
+}
+
+//history shows the versions of the file under the cursor. Selecting a version marks it to be
+//restored instead of the browsed snapshot's
+func (b *backupBrowser) history(node *browseNode) (err error) {
+	b.status = "loading history of " + node.path
+	b.draw()
+
+	versions, err := fileHistory(b.cmd, b.repo, node.path, b.snapshots)
+
+	if err != nil {
+		return
+	}
+
+	cursor := len(versions) - 1
+
+	for {
+		var sb strings.Builder
+
+		sb.WriteString("\033[H\033[2J")
+		fmt.Fprintf(&sb, "history of %s\r\n", node.path)
+
+		for i, version := range versions {
+			prefix := "  "
+
+			if i == cursor {
+				prefix = "> "
+			}
+
+			changed := ""
+
+			if version.Changed {
+				changed = "  changed"
+			}
+
+			fmt.Fprintf(&sb, "%s%s  %s  %s%s\r\n", prefix, version.Snapshot, format.FormatBytes(version.Size), version.ModTime.Local().Format("2006-01-02 15:04:05"), changed)
+		}
+
+		sb.WriteString("up/down move  space/enter select version  left/q back")
+		os.Stderr.WriteString(sb.String())
+
+		key, err := readKey()
+
+		if err != nil {
+			return err
+		}
+
+		switch key {
+		case "up", "k":
+			if cursor > 0 {
+				cursor--
+			}
+		case "down", "j":
+			if cursor < len(versions)-1 {
+				cursor++
+			}
+		case " ", "\r", "\n":
+			b.selected[node.path] = versions[cursor]
+			b.status = fmt.Sprintf("selected %s from %s", node.path, versions[cursor].Snapshot)
+			return nil
+		case "left", "q", "esc", "h":
+			b.status = ""
+			return nil
+		}
+	}
+}
+
+//browse runs the tree until the selection is restored or browsing is quit. Returns true if the
+//selection should be restored
+func (b *backupBrowser) browse() (restore bool, err error) {
+	rawRestore, err := setRawMode()
+
+	if err != nil {
+		return
+	}
+
+	defer func() {
+		rawRestore()
+		os.Stderr.WriteString("\033[H\033[2J")
+	}()
+
+	b.refresh()
+
+	for {
+		b.draw()
+
+		key, err := readKey()
+
+		if err != nil {
+			return false, err
+		}
+
+		if len(b.rows) == 0 {
+			return false, nil
+		}
+
+		node := b.rows[b.cursor].node
+
+		switch key {
+		case "up", "k":
+			if b.cursor > 0 {
+				b.cursor--
+			}
+		case "down", "j":
+			if b.cursor < len(b.rows)-1 {
+				b.cursor++
+			}
+		case "right", "l", "\r", "\n":
+			if node.file == nil {
+				node.expanded = true
+			}
+		case "left":
+			if node.file == nil && node.expanded {
+				node.expanded = false
+				break
+			}
+
+			// move to the parent directory
+			for i := b.cursor - 1; i >= 0; i-- {
+				if b.rows[i].depth < b.rows[b.cursor].depth {
+					b.cursor = i
+					break
+				}
+			}
+		case " ":
+			b.toggle(node)
+		case "h":
+			if node.file == nil {
+				b.status = "history is shown for files"
+			} else if err = b.history(node); err != nil {
+				return false, err
+			}
+		case "r":
+			if len(b.selected) == 0 {
+				b.status = "nothing is selected"
+				break
+			}
+
+			return true, nil
+		case "q", "esc", "\x03":
+			return false, nil
+		}
+
+		b.refresh()
+	}
+}
+
+//backupBrowseCommand opens an interactive tree of a snapshot's files to select files and
+//directories and restore only those into a directory: backup browse <snapshot|latest> <dir>.
+//The history of a file across snapshots is shown with h, where an older version can be
+//selected instead
+func backupBrowseCommand(cmd Command) (err error) {
+	if len(cmd.Args) != 4 {
+		return fmt.Errorf("usage: backup browse <snapshot|latest> <dir>")
+	}
+
+	if !isTerminal(os.Stdin) || !isTerminal(os.Stderr) || runtime.GOOS == "windows" {
+		return fmt.Errorf("backup browse needs a terminal, use backup restore --path instead")
+	}
+
+	repo := backupRepo(cmd)
+	snapshot, err := loadBackupSnapshot(cmd, repo, cmd.Args[2])
+
+	if err != nil {
+		return
+	}
+
+	browser := &backupBrowser{
+		cmd:       cmd,
+		repo:      repo,
+		snapshot:  snapshot,
+		root:      buildBrowseTree(snapshot.Files),
+		selected:  make(map[string]backupVersion),
+		snapshots: map[string]backupSnapshot{snapshot.ID: snapshot},
+	}
+
+	restore, err := browser.browse()
+
+	if err != nil || !restore {
+		return
+	}
+
+	paths := make([]string, 0, len(browser.selected))
+
+	for p := range browser.selected {
+		paths = append(paths, p)
+	}
+
+	sort.Strings(paths)
+
+	dir := cmd.Args[3]
+	restored := []restoredFile{}
+
+	for _, p := range paths {
+		version := browser.selected[p]
+		target, err := backupTarget(dir, version.file)
+
+		if err != nil {
+			return err
+		}
+
+		notify(cmd, "restoring %s from %s", p, version.Snapshot)
+
+		if err = restoreBackupFile(cmd, repo, version.file, target); err != nil {
+			return fmt.Errorf("unable to restore %s: %s", p, err)
+		}
+
+		restored = append(restored, restoredFile{
+			Path:     p,
+			Snapshot: version.Snapshot,
+			Size:     version.Size,
+		})
+	}
+
+	return writeJSON(restored)
+}
+
+//backupHistoryCommand lists the versions of a file in the snapshots of the repository:
+//backup history <path>
+func backupHistoryCommand(cmd Command) (err error) {
+	if len(cmd.Args) < 3 {
+		return fmt.Errorf("usage: backup history <path>")
+	}
+
+	filePath := strings.Trim(strings.Join(cmd.Args[2:], "/"), "/")
+	versions, err := fileHistory(cmd, backupRepo(cmd), filePath, make(map[string]backupSnapshot))
+
+	if err != nil {
+		return
+	} else if len(versions) == 0 {
+		return fmt.Errorf("%s is not in any snapshot", filePath)
+	}
+
+	return writeJSON(versions)
+}
@@ -0,0 +1,275 @@
+package main
+
+import (
+	"fmt"
+	"math/big"
+	"path"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/n8maninger/siac-json/format"
+)
+
+type (
+	//backupRetention the number of daily, weekly and monthly snapshots kept by backup prune
+	backupRetention struct {
+		Daily   int `json:"daily"`
+		Weekly  int `json:"weekly"`
+		Monthly int `json:"monthly"`
+	}
+
+	//backupPruneReport the result of backup prune
+	backupPruneReport struct {
+		DryRun    bool            `json:"dryrun"`
+		Repo      string          `json:"repo"`
+		Retention backupRetention `json:"retention"`
+		Kept      []string        `json:"kept"`
+		Removed   []string        `json:"removed"`
+		Chunks    int             `json:"chunks"`
+		Reclaimed int64           `json:"reclaimed"`
+		//MonthlySavings the storage cost of the reclaimed data per month at the current prices
+		MonthlySavings string `json:"monthlysavings,omitempty"`
+	}
+)
+
+//defaultBackupRetention the snapshots kept by backup prune unless the config or flags change it
+var defaultBackupRetention = backupRetention{
+	Daily:   7,
+	Weekly:  4,
+	Monthly: 12,
+}
+
+//parseBackupRetention reads keep_daily, keep_weekly and keep_monthly from the [backup] table of
+//the config file. Missing keys use the defaults
+func parseBackupRetention(table map[string]interface{}) (retention *backupRetention, err error) {
+	retention = &backupRetention{}
+
+	if retention.Daily, err = configInt(table, "keep_daily", defaultBackupRetention.Daily); err != nil {
+		return
+	}
+
+	if retention.Weekly, err = configInt(table, "keep_weekly", defaultBackupRetention.Weekly); err != nil {
+		return
+	}
+
+	retention.Monthly, err = configInt(table, "keep_monthly", defaultBackupRetention.Monthly)
+
+	return
+}
+
+//commandRetention returns the retention of the config file overridden by --keep-daily,
+//--keep-weekly and --keep-monthly
+func commandRetention(cmd Command) (retention backupRetention, err error) {
+	retention = defaultBackupRetention
+
+	if DefaultConfig.BackupRetention != nil {
+		retention = *DefaultConfig.BackupRetention
+	}
+
+	for flag, keep := range map[string]*int{"keep-daily": &retention.Daily, "keep-weekly": &retention.Weekly, "keep-monthly": &retention.Monthly} {
+		values := cmd.Params[flag]
+
+		if len(values) == 0 {
+			continue
+		}
+
+		if *keep, err = strconv.Atoi(values[0]); err != nil || *keep < 0 {
+			return retention, fmt.Errorf("invalid --%s value %q", flag, values[0])
+		}
+	}
+
+	return
+}
+
+//retainedSnapshots returns the ids of the snapshots kept by the retention: the newest snapshot
+//of each of the last Daily days, Weekly weeks and Monthly months that have snapshots. The
+//newest snapshot and snapshots with unknown ids are always kept. ids are sorted oldest first
+func retainedSnapshots(ids []string, retention backupRetention) map[string]bool {
+	kept := make(map[string]bool)
+
+	if len(ids) > 0 {
+		kept[ids[len(ids)-1]] = true
+	}
+
+	policies := []struct {
+		keep   int
+		bucket func(time.Time) string
+	}{
+		{retention.Daily, func(t time.Time) string { return t.Format("2006-01-02") }},
+		{retention.Weekly, func(t time.Time) string {
+			year, week := t.ISOWeek()
+			return fmt.Sprintf("%d-%d", year, week)
+		}},
+		{retention.Monthly, func(t time.Time) string { return t.Format("2006-01") }},
+	}
+
+	for _, policy := range policies {
+		last := ""
+		count := 0
+
+		for i := len(ids) - 1; i >= 0 && count < policy.keep; i-- {
+			created, err := time.Parse(backupSnapshotFormat, ids[i])
+
+			if err != nil {
+				continue
+			}
+
+			if bucket := policy.bucket(created); bucket != last {
+				kept[ids[i]] = true
+				last = bucket
+				count++
+			}
+		}
+	}
+
+	for _, id := range ids {
+		if _, err := time.Parse(backupSnapshotFormat, id); err != nil {
+			kept[id] = true
+		}
+	}
+
+	return kept
+}
+
+//monthlySavings returns the cost of storing the bytes for a month at the renter's current
+//prices
+func monthlySavings(cmd Command, bytes int64) (string, error) {
+	var prices renterPricesResponse
+
+	if err := callAPI(cmd, "GET", "/renter/prices", nil, &prices); err != nil {
+		return "", err
+	}
+
+	cost := new(big.Int).Mul(parseHastings(prices.StorageTerabyteMonth), big.NewInt(bytes))
+	cost.Div(cost, big.NewInt(1e12))
+
+	return format.FormatCurrency(cost), nil
+}
+
+//backupPruneCommand applies the retention policy to the --repo siapath: backup prune. The
+//snapshots the policy does not keep are deleted, then the chunks no kept snapshot references.
+//Chunks created after the newest snapshot may belong to a backup in progress and are kept.
+//--dry-run reports what would be deleted and the space reclaimed without deleting anything
+func backupPruneCommand(cmd Command) (err error) {
+	retention, err := commandRetention(cmd)
+
+	if err != nil {
+		return
+	}
+
+	repo := backupRepo(cmd)
+	snapshots, err := listBackupSnapshots(cmd, repo)
+
+	if err != nil {
+		return
+	} else if len(snapshots) == 0 {
+		return fmt.Errorf("no snapshots in %s", repo)
+	}
+
+	ids := make([]string, len(snapshots))
+
+	for i, snapshot := range snapshots {
+		ids[i] = snapshot.ID
+	}
+
+	kept := retainedSnapshots(ids, retention)
+	report := backupPruneReport{
+		DryRun:    cmd.DryRun,
+		Repo:      repo,
+		Retention: retention,
+		Kept:      []string{},
+		Removed:   []string{},
+	}
+
+	// every kept manifest must load, a chunk it references could otherwise be deleted
+	referenced := make(map[string]bool)
+	var newest time.Time
+
+	for _, id := range ids {
+		if !kept[id] {
+			report.Removed = append(report.Removed, id)
+			continue
+		}
+
+		snapshot, err := loadBackupSnapshot(cmd, repo, id)
+
+		if err != nil {
+			return err
+		}
+
+		for _, file := range snapshot.Files {
+			for _, chunk := range file.Chunks {
+				referenced[chunk] = true
+			}
+		}
+
+		if snapshot.Created.After(newest) {
+			newest = snapshot.Created
+		}
+
+		report.Kept = append(report.Kept, id)
+	}
+
+	var chunks renterDirFiles
+
+	if err = callAPI(cmd, "GET", "/renter/dir/"+repo+"/chunks", nil, &chunks); err != nil {
+		if _, ok := err.(APIError); !ok {
+			return
+		}
+	}
+
+	var unreferenced []string
+
+	for _, file := range chunks.Files {
+		if id := path.Base(file.SiaPath); !referenced[id] && !file.CreateTime.After(newest) {
+			unreferenced = append(unreferenced, id)
+			report.Reclaimed += file.FileSize
+		}
+	}
+
+	sort.Strings(unreferenced)
+	report.Chunks = len(unreferenced)
+
+	if report.Reclaimed > 0 {
+		if report.MonthlySavings, err = monthlySavings(cmd, report.Reclaimed); err != nil {
+			notify(cmd, "unable to estimate the savings: %s", err)
+			err = nil
+		}
+	}
+
+	if !cmd.DryRun {
+		// manifests are deleted first so an interrupted prune leaves unreferenced chunks behind
+		// rather than snapshots missing chunks
+		for _, id := range report.Removed {
+			notify(cmd, "removing snapshot %s", id)
+
+			if err = callAPI(cmd, "POST", "/renter/delete/"+repo+"/snapshots/"+id+".json", nil, nil); err != nil {
+				return fmt.Errorf("unable to remove snapshot %s: %s", id, err)
+			}
+		}
+
+		state, err := loadBackupState(cmd, repo)
+
+		if err != nil {
+			return err
+		}
+
+		for _, id := range unreferenced {
+			if err = callAPI(cmd, "POST", "/renter/delete/"+repo+"/chunks/"+id, nil, nil); err != nil {
+				return fmt.Errorf("unable to delete chunk %s: %s", id, err)
+			}
+
+			// the next backup run uploads the chunk again if a file still contains it
+			delete(state.Chunks, id)
+		}
+
+		if err = state.save(); err != nil {
+			return err
+		}
+	}
+
+	notify(cmd, "%d snapshots kept, %d removed, %d chunks of %s unreferenced", len(report.Kept), len(report.Removed), report.Chunks, format.FormatBytes(report.Reclaimed))
+
+	return writeJSON(report)
+}
@@ -0,0 +1,218 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+type (
+	//batchFile the commands of a batch file run with sia-json run <file>
+	batchFile struct {
+		Commands []string
+		//StopOnError stops the batch at the first command that fails, nil if the file does not
+		//set it
+		StopOnError *bool
+	}
+
+	//batchResult the result of a command in a batch
+	batchResult struct {
+		Command  string      `json:"command,omitempty"`
+		ExitCode int         `json:"exitcode"`
+		Output   interface{} `json:"output,omitempty"`
+		Error    string      `json:"error,omitempty"`
+		//TimedOut the command was killed or skipped by parallel's --target-timeout
+		TimedOut bool `json:"timedout,omitempty"`
+	}
+)
+
+//yamlScalar returns the value of a YAML scalar with its quotes removed
+func yamlScalar(value string) (string, error) {
+	value = strings.TrimSpace(value)
+
+	switch {
+	case strings.HasPrefix(value, `"`):
+		return strconv.Unquote(value)
+	case strings.HasPrefix(value, "'") && strings.HasSuffix(value, "'") && len(value) > 1:
+		return strings.Replace(value[1:len(value)-1], "''", "'", -1), nil
+	}
+
+	return value, nil
+}
+
+//parseBatchYAML parses the small YAML format of batch files: a commands list of command lines
+//and an optional stop-on-error boolean
+//
+//	stop-on-error: false
+//	commands:
+//	  - consensus
+//	  - renter contracts --limit 5
+func parseBatchYAML(buf []byte) (batch batchFile, err error) {
+	inCommands := false
+
+	for i, line := range strings.Split(string(buf), "\n") {
+		trimmed := strings.TrimSpace(line)
+
+		if len(trimmed) == 0 || strings.HasPrefix(trimmed, "#") || trimmed == "---" {
+			continue
+		}
+
+		if inCommands && strings.HasPrefix(trimmed, "- ") && line != trimmed {
+			command, err := yamlScalar(trimmed[2:])
+
+			if err != nil {
+				return batch, fmt.Errorf("line %d: %s", i+1, err)
+			}
+
+			batch.Commands = append(batch.Commands, command)
+			continue
+		}
+
+		sep := strings.Index(trimmed, ":")
+
+		if sep == -1 || line != trimmed {
+			return batch, fmt.Errorf("line %d: expected key: value or a commands list item", i+1)
+		}
+
+		key, value := strings.TrimSpace(trimmed[:sep]), strings.TrimSpace(trimmed[sep+1:])
+		inCommands = false
+
+		switch key {
+		case "commands":
+			if len(value) > 0 {
+				return batch, fmt.Errorf("line %d: commands must be a list", i+1)
+			}
+
+			inCommands = true
+		case "stop-on-error":
+			stop, err := strconv.ParseBool(value)
+
+			if err != nil {
+				return batch, fmt.Errorf("line %d: stop-on-error must be true or false", i+1)
+			}
+
+			batch.StopOnError = &stop
+		default:
+			return batch, fmt.Errorf("line %d: unknown key %q", i+1, key)
+		}
+	}
+
+	return
+}
+
+//loadBatchFile loads a batch file. .yaml and .yml files use the YAML format, other files have a
+//command per line with # comments
+func loadBatchFile(path string) (batch batchFile, err error) {
+	buf, err := ioutil.ReadFile(path)
+
+	if err != nil {
+		return
+	}
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		batch, err = parseBatchYAML(buf)
+	default:
+		for _, line := range strings.Split(string(buf), "\n") {
+			if line = strings.TrimSpace(line); len(line) > 0 && !strings.HasPrefix(line, "#") {
+				batch.Commands = append(batch.Commands, line)
+			}
+		}
+	}
+
+	if err == nil && len(batch.Commands) == 0 {
+		err = fmt.Errorf("no commands in %s", path)
+	}
+
+	return
+}
+
+//runBatchLine runs a line of a batch file with the batch's flags and returns its result
+func runBatchLine(base []string, line string) (result batchResult) {
+	result.Command = line
+
+	words, err := splitShellLine(line)
+
+	if err == nil {
+		var command Command
+
+		if command, err = parseInputs(append(append([]string{}, base...), words...)); err == nil {
+			var output bytes.Buffer
+
+			err = redirectStdout(&output, func() error {
+				return execute(command)
+			})
+
+			if out := bytes.TrimSpace(output.Bytes()); json.Valid(out) {
+				result.Output = json.RawMessage(out)
+			} else if len(out) > 0 {
+				result.Output = string(out)
+			}
+		}
+	}
+
+	// API errors are written to stdout and kept in the output
+	if err != nil && len(err.Error()) > 0 {
+		result.Error = err.Error()
+	}
+
+	result.ExitCode = exitCode(err)
+
+	return
+}
+
+//runBatchCommand runs the commands of a batch file in order and writes their results as a JSON
+//array: run <file>. The flags given to run apply to every command. The batch stops at the
+//first failed command unless --stop-on-error false is given or set in the file
+func runBatchCommand(cmd Command, path string) (err error) {
+	batch, err := loadBatchFile(path)
+
+	if err != nil {
+		return
+	}
+
+	stop := true
+
+	if batch.StopOnError != nil {
+		stop = *batch.StopOnError
+	}
+
+	if values := cmd.Params["stop-on-error"]; len(values) > 0 {
+		if stop, err = strconv.ParseBool(values[0]); err != nil {
+			return fmt.Errorf("invalid --stop-on-error value %q", values[0])
+		}
+	}
+
+	base := flagArgs(cmd.Flags, "stop-on-error")
+	results := []batchResult{}
+	failed := 0
+
+	for _, line := range batch.Commands {
+		notify(cmd, "running %s", line)
+
+		result := runBatchLine(base, line)
+		results = append(results, result)
+
+		if result.ExitCode != ExitSuccess {
+			failed++
+
+			if stop {
+				break
+			}
+		}
+	}
+
+	if err = writeJSON(results); err != nil {
+		return
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d of %d commands failed", failed, len(batch.Commands))
+	}
+
+	return
+}
@@ -0,0 +1,453 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type (
+	//readCloser combines a wrapped reader with the closer of the underlying file
+	readCloser struct {
+		io.Reader
+		io.Closer
+	}
+)
+
+//openUploadFile opens the command's upload file and sets the body length and content type
+func openUploadFile(cmd *Command) (f *os.File, err error) {
+	if !strings.HasPrefix(cmd.Endpoint.Path, "/renter/uploadstream/") {
+		err = fmt.Errorf("--file can only be used with renter uploadstream")
+		return
+	}
+
+	f, err = os.Open(cmd.UploadFile)
+
+	if err != nil {
+		return
+	}
+
+	info, err := f.Stat()
+
+	if err != nil {
+		f.Close()
+		return
+	}
+
+	if info.IsDir() {
+		f.Close()
+		err = fmt.Errorf("%s is a directory", cmd.UploadFile)
+		return
+	}
+
+	cmd.BodyLength = info.Size()
+	cmd.ContentType = "application/octet-stream"
+
+	return
+}
+
+//versionExistingFile renames the remote file an upload would replace by adding a timestamp suffix
+func versionExistingFile(cmd Command) (err error) {
+	if !strings.HasPrefix(cmd.Endpoint.Path, "/renter/upload") {
+		return fmt.Errorf("--version-on-conflict can only be used with renter upload and uploadstream")
+	}
+
+	_, siapath, ok := splitSiaPath(cmd)
+
+	if !ok {
+		return fmt.Errorf("missing siapath")
+	}
+
+	siapath = strings.TrimPrefix(siapath, "/")
+
+	// siad returns an error status if the file does not exist
+	if err = callAPI(cmd, "GET", "/renter/file/"+siapath, nil, nil); err != nil {
+		if _, ok := err.(APIError); ok {
+			return nil
+		}
+
+		return
+	}
+
+	versioned := siapath + "." + time.Now().UTC().Format("20060102T150405Z")
+
+	if err = callAPI(cmd, "POST", "/renter/rename/"+siapath, url.Values{"newsiapath": {versioned}}, nil); err != nil {
+		return fmt.Errorf("unable to version %s: %s", siapath, err)
+	}
+
+	notify(cmd, "renamed existing %s to %s", siapath, versioned)
+
+	return
+}
+
+//loadJSONBody loads the command's JSON body from the flag value or @file and checks that it is valid
+func loadJSONBody(cmd *Command) (buf []byte, err error) {
+	if strings.HasPrefix(cmd.JSONBody, "@") {
+		if buf, err = ioutil.ReadFile(cmd.JSONBody[1:]); err != nil {
+			return
+		}
+	} else {
+		buf = []byte(cmd.JSONBody)
+	}
+
+	if !json.Valid(buf) {
+		err = fmt.Errorf("--json value is not valid JSON")
+		return
+	}
+
+	cmd.BodyLength = int64(len(buf))
+	cmd.ContentType = "application/json"
+
+	return
+}
+
+//isPiped returns true if the file is a pipe or a regular file redirected into the process
+func isPiped(f *os.File) bool {
+	info, err := f.Stat()
+
+	if err != nil {
+		return false
+	}
+
+	return info.Mode()&os.ModeNamedPipe != 0 || info.Mode().IsRegular()
+}
+
+//interactiveStdin set while the shell reads its commands from stdin so a piped script is not
+//sent as a request body
+var interactiveStdin bool
+
+//stdinPiped returns true if a request body was piped into stdin
+func stdinPiped() bool {
+	return !interactiveStdin && isPiped(os.Stdin)
+}
+
+//rawContentType returns the content type of a raw body from its first bytes: JSON if it starts
+//with an object or array and raw bytes otherwise
+func rawContentType(peek []byte) string {
+	if trimmed := bytes.TrimSpace(peek); len(trimmed) > 0 && (trimmed[0] == '{' || trimmed[0] == '[') {
+		return "application/json"
+	}
+
+	return "application/octet-stream"
+}
+
+//stdinBody returns stdin as the request body. The content type is JSON if the body starts with
+//an object or array and raw bytes otherwise
+func stdinBody(cmd *Command) io.ReadCloser {
+	r := bufio.NewReader(os.Stdin)
+	peek, _ := r.Peek(512)
+	cmd.ContentType = rawContentType(peek)
+
+	return readCloser{
+		Reader: r,
+		Closer: os.Stdin,
+	}
+}
+
+//rawBody returns the --data-raw or --data-binary body. --data-binary @file streams the file and
+//@- reads stdin, the body is sent exactly as given either way
+func rawBody(cmd *Command) (body io.ReadCloser, err error) {
+	if len(cmd.RawBodyFile) == 0 {
+		cmd.BodyLength = int64(len(cmd.RawBody))
+		cmd.ContentType = rawContentType([]byte(cmd.RawBody))
+
+		return ioutil.NopCloser(strings.NewReader(cmd.RawBody)), nil
+	}
+
+	if cmd.RawBodyFile == "-" {
+		return stdinBody(cmd), nil
+	}
+
+	f, err := os.Open(cmd.RawBodyFile)
+
+	if err != nil {
+		return
+	}
+
+	info, err := f.Stat()
+
+	if err != nil {
+		f.Close()
+		return
+	} else if info.IsDir() {
+		f.Close()
+		return nil, fmt.Errorf("%s is a directory", cmd.RawBodyFile)
+	}
+
+	r := bufio.NewReader(f)
+	peek, _ := r.Peek(512)
+	cmd.BodyLength = info.Size()
+	cmd.ContentType = rawContentType(peek)
+
+	return readCloser{
+		Reader: r,
+		Closer: f,
+	}, nil
+}
+
+//rawBodyFlags returns the flags of the command that set the request body
+func rawBodyFlags(cmd Command) (flags []string) {
+	if len(cmd.UploadFile) > 0 {
+		flags = append(flags, "--file")
+	}
+
+	if len(cmd.JSONBody) > 0 {
+		flags = append(flags, "--json")
+	}
+
+	if cmd.RawBodySet {
+		flags = append(flags, "--data-raw or --data-binary")
+	}
+
+	return
+}
+
+//openRequestBody opens the raw request body for the command. The body is nil if the params
+//should be form encoded instead. A POST without params reads its body from stdin when it is piped
+func openRequestBody(cmd *Command) (body io.ReadCloser, err error) {
+	flags := rawBodyFlags(*cmd)
+
+	if len(flags) > 1 {
+		err = fmt.Errorf("%s cannot be used together", strings.Join(flags, " and "))
+		return
+	}
+
+	if len(flags) > 0 && cmd.Method != "POST" {
+		err = fmt.Errorf("a request body can only be sent with POST")
+		return
+	}
+
+	switch {
+	case len(cmd.UploadFile) > 0:
+		f, err := openUploadFile(cmd)
+
+		if err != nil {
+			return nil, err
+		}
+
+		var r io.Reader = f
+
+		// an encrypted upload cannot be resumed since its ciphertext differs every time, and
+		// requests that are not sent do not start a journal
+		if len(cmd.EncryptKey) == 0 && !cmd.DryRun && !cmd.AsCurl {
+			if cmd.UploadJournal, err = openUploadJournal(cmd, f); err != nil {
+				f.Close()
+				return nil, err
+			}
+		}
+
+		if cmd.UploadJournal != nil {
+			r = newJournalReader(f, cmd.UploadJournal)
+		}
+
+		// the progress is of the file since the compressed size is not known until it is sent
+		r = withProgress(*cmd, r, cmd.BodyLength, "uploading")
+
+		if len(cmd.Compress) > 0 {
+			r = newGzipReader(r)
+			cmd.BodyLength = 0
+		}
+
+		if len(cmd.EncryptKey) > 0 {
+			if r, err = encryptedUpload(cmd, r); err != nil {
+				f.Close()
+				return nil, err
+			}
+		}
+
+		// the checksums are of the data stored by siad, after it is compressed and encrypted
+		if checksums, ok := newChecksumReader(*cmd, r); ok && !cmd.DryRun && !cmd.AsCurl {
+			cmd.UploadChecksums = checksums.record
+			r = checksums
+		}
+
+		body = readCloser{
+			Reader: r,
+			Closer: f,
+		}
+	case len(cmd.JSONBody) > 0:
+		buf, err := loadJSONBody(cmd)
+
+		if err != nil {
+			return nil, err
+		}
+
+		body = ioutil.NopCloser(bytes.NewReader(buf))
+	case cmd.RawBodySet:
+		return rawBody(cmd)
+	case cmd.Method == "POST" && len(cmd.Params) == 0 && stdinPiped():
+		body = stdinBody(cmd)
+	}
+
+	return
+}
+
+//bodyFieldPrefix the prefix of params composed into a JSON body, such as --body.settings.maxduration
+const bodyFieldPrefix = "body."
+
+//bodyValue returns the JSON value of a --body. flag. Numbers, true, false, null, objects and
+//arrays are decoded, anything else is a string. A quoted value such as '"100"' is a string
+func bodyValue(s string) interface{} {
+	dec := json.NewDecoder(strings.NewReader(s))
+	// numbers are kept as written so large values such as hastings are sent exactly
+	dec.UseNumber()
+
+	var v interface{}
+
+	if err := dec.Decode(&v); err != nil || dec.More() {
+		return s
+	}
+
+	return v
+}
+
+//compareBodyFields orders dotted fields by segment, comparing array indices as numbers so
+//hosts.2 is set before hosts.10
+func compareBodyFields(a, b string) bool {
+	as, bs := strings.Split(a, "."), strings.Split(b, ".")
+
+	for i := 0; i < len(as) && i < len(bs); i++ {
+		if as[i] == bs[i] {
+			continue
+		}
+
+		ai, aErr := strconv.Atoi(as[i])
+		bi, bErr := strconv.Atoi(bs[i])
+
+		if aErr == nil && bErr == nil {
+			return ai < bi
+		}
+
+		return as[i] < bs[i]
+	}
+
+	return len(as) < len(bs)
+}
+
+//bodyParent returns the field above the remaining parts of the dotted field, for errors
+func bodyParent(field string, parts []string) string {
+	all := strings.Split(field, ".")
+
+	if parent := strings.Join(all[:len(all)-len(parts)], "."); len(parent) > 0 {
+		return parent
+	}
+
+	return "the body"
+}
+
+//setBodyField sets the dotted field below the node, creating objects, and arrays for numeric
+//segments. Array elements must be set in order, an index can only be one past the last
+func setBodyField(node interface{}, parts []string, value interface{}, field string) (interface{}, error) {
+	if len(parts) == 0 {
+		return value, nil
+	}
+
+	part := parts[0]
+
+	if i, err := strconv.Atoi(part); err == nil && i >= 0 {
+		arr, ok := node.([]interface{})
+
+		if node != nil && !ok {
+			return nil, fmt.Errorf("--%s%s: %s is not an array", bodyFieldPrefix, field, bodyParent(field, parts))
+		} else if i > len(arr) {
+			return nil, fmt.Errorf("--%s%s: index %d skips index %d", bodyFieldPrefix, field, i, len(arr))
+		}
+
+		if i == len(arr) {
+			arr = append(arr, nil)
+		}
+
+		arr[i], err = setBodyField(arr[i], parts[1:], value, field)
+
+		return arr, err
+	}
+
+	obj, ok := node.(map[string]interface{})
+
+	if node == nil {
+		obj = make(map[string]interface{})
+	} else if !ok {
+		return nil, fmt.Errorf("--%s%s: %s is not an object", bodyFieldPrefix, field, bodyParent(field, parts))
+	}
+
+	child, err := setBodyField(obj[part], parts[1:], value, field)
+	obj[part] = child
+
+	return obj, err
+}
+
+//composeJSONBody builds the JSON body from the dotted --body. flags, such as
+//--body.settings.maxduration 8640 --body.hosts.0 ed25519:..., so structured bodies can be sent
+//without writing JSON. The fields are set on the --json object if both are given
+func composeJSONBody(cmd *Command) (err error) {
+	var fields []string
+
+	for key := range cmd.Params {
+		if strings.HasPrefix(key, bodyFieldPrefix) {
+			fields = append(fields, strings.TrimPrefix(key, bodyFieldPrefix))
+		}
+	}
+
+	if len(fields) == 0 {
+		return
+	}
+
+	sort.Slice(fields, func(i, j int) bool {
+		return compareBodyFields(fields[i], fields[j])
+	})
+
+	var body interface{}
+
+	if len(cmd.JSONBody) > 0 {
+		buf, err := loadJSONBody(cmd)
+
+		if err != nil {
+			return err
+		}
+
+		dec := json.NewDecoder(bytes.NewReader(buf))
+		dec.UseNumber()
+
+		if err = dec.Decode(&body); err != nil {
+			return err
+		}
+	}
+
+	for _, field := range fields {
+		values := cmd.Params[bodyFieldPrefix+field]
+		delete(cmd.Params, bodyFieldPrefix+field)
+
+		if len(values) > 1 {
+			return fmt.Errorf("--%s%s was given more than once", bodyFieldPrefix, field)
+		}
+
+		for _, part := range strings.Split(field, ".") {
+			if len(part) == 0 {
+				return fmt.Errorf("--%s%s has an empty field name", bodyFieldPrefix, field)
+			}
+		}
+
+		if body, err = setBodyField(body, strings.Split(field, "."), bodyValue(values[0]), field); err != nil {
+			return
+		}
+	}
+
+	buf, err := json.Marshal(body)
+
+	if err != nil {
+		return
+	}
+
+	cmd.JSONBody = string(buf)
+
+	return
+}
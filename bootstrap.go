@@ -0,0 +1,257 @@
+package main
+
+import (
+	"fmt"
+	"math/big"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/n8maninger/siac-json/format"
+)
+
+type (
+	//walletResponse the fields of /wallet used by sia-json
+	walletResponse struct {
+		Encrypted               bool   `json:"encrypted"`
+		Unlocked                bool   `json:"unlocked"`
+		Rescanning              bool   `json:"rescanning"`
+		ConfirmedSiacoinBalance string `json:"confirmedsiacoinbalance"`
+		SiafundBalance          string `json:"siafundbalance"`
+	}
+
+	//renterPricesResponse the fields of /renter/prices used by sia-json
+	renterPricesResponse struct {
+		FormContracts        string `json:"formcontracts"`
+		DownloadTerabyte     string `json:"downloadterabyte"`
+		StorageTerabyteMonth string `json:"storageterabytemonth"`
+		UploadTerabyte       string `json:"uploadterabyte"`
+	}
+
+	//renterContractsResponse the fields of /renter/contracts used by sia-json
+	renterContractsResponse struct {
+		ActiveContracts []map[string]interface{} `json:"activecontracts"`
+	}
+
+	//allowanceProposal the allowance renter bootstrap proposes to apply
+	allowanceProposal struct {
+		Funds              string `json:"funds"`
+		Hosts              uint64 `json:"hosts"`
+		Period             uint64 `json:"period"`
+		RenewWindow        uint64 `json:"renewwindow"`
+		ExpectedStorage    uint64 `json:"expectedstorage"`
+		ExpectedUpload     uint64 `json:"expectedupload"`
+		ExpectedDownload   uint64 `json:"expecteddownload"`
+		ExpectedRedundancy string `json:"expectedredundancy"`
+	}
+)
+
+const (
+	//blocksPerMonth the number of 10 minute blocks in a 30 day month
+	blocksPerMonth = 4320
+
+	//defaultAllowancePeriod 12 weeks
+	defaultAllowancePeriod = 12096
+
+	//defaultRenewWindow 4 weeks
+	defaultRenewWindow = 4032
+
+	//defaultAllowanceHosts the number of hosts siac defaults to
+	defaultAllowanceHosts = 50
+)
+
+//paramUint returns the param's value as an unsigned integer or the default if it was not supplied
+func paramUint(cmd Command, key string, def uint64) (uint64, error) {
+	values := cmd.Params[key]
+
+	if len(values) == 0 {
+		return def, nil
+	}
+
+	n, err := strconv.ParseUint(values[0], 10, 64)
+
+	if err != nil {
+		return 0, fmt.Errorf("invalid --%s value %q", key, values[0])
+	}
+
+	return n, nil
+}
+
+//parseHastings parses a hastings string returned by the Sia API
+func parseHastings(s string) *big.Int {
+	h, ok := new(big.Int).SetString(s, 10)
+
+	if !ok {
+		return new(big.Int)
+	}
+
+	return h
+}
+
+//proposeAllowance estimates how much data the budget can store for the period at the current
+//prices and builds an allowance from it
+func proposeAllowance(budget *big.Int, redundancy float64, hosts, period, renewWindow uint64, prices renterPricesResponse) allowanceProposal {
+	proposal := allowanceProposal{
+		Funds:              budget.String(),
+		Hosts:              hosts,
+		Period:             period,
+		RenewWindow:        renewWindow,
+		ExpectedRedundancy: strconv.FormatFloat(redundancy, 'f', -1, 64),
+	}
+
+	// the cost of storing and uploading one terabyte for the period at the target redundancy
+	months := new(big.Float).SetFloat64(float64(period) / blocksPerMonth)
+	perTB := new(big.Float).Mul(new(big.Float).SetInt(parseHastings(prices.StorageTerabyteMonth)), months)
+	perTB.Add(perTB, new(big.Float).SetInt(parseHastings(prices.UploadTerabyte)))
+	perTB.Mul(perTB, big.NewFloat(redundancy))
+
+	spendable := new(big.Int).Sub(budget, parseHastings(prices.FormContracts))
+
+	if spendable.Sign() <= 0 || perTB.Sign() <= 0 {
+		return proposal
+	}
+
+	tb := new(big.Float).Quo(new(big.Float).SetInt(spendable), perTB)
+	storage, _ := new(big.Float).Mul(tb, big.NewFloat(1e12)).Uint64()
+
+	proposal.ExpectedStorage = storage
+	proposal.ExpectedUpload = storage / period
+	proposal.ExpectedDownload = storage / period
+
+	return proposal
+}
+
+//waitForContracts polls /renter/contracts until the renter has formed at least min active contracts
+func waitForContracts(cmd Command, min int, interval, timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+
+	for {
+		var contracts renterContractsResponse
+
+		if err := callAPI(cmd, "GET", "/renter/contracts", nil, &contracts); err != nil {
+			return err
+		}
+
+		notify(cmd, "%d/%d contracts formed", len(contracts.ActiveContracts), min)
+
+		if len(contracts.ActiveContracts) >= min {
+			return nil
+		}
+
+		if time.Now().Add(interval).After(deadline) {
+			return fmt.Errorf("timed out waiting for %d contracts", min)
+		}
+
+		time.Sleep(interval)
+	}
+}
+
+//renterBootstrapCommand proposes and applies an allowance for a new renter, then waits for contracts
+//to form: renter bootstrap --budget 200SC --target-redundancy 3
+func renterBootstrapCommand(cmd Command) (err error) {
+	if len(cmd.Params["budget"]) == 0 {
+		return fmt.Errorf("--budget is required")
+	}
+
+	budget, err := format.ParseCurrency(cmd.Params["budget"][0])
+
+	if err != nil {
+		return
+	}
+
+	redundancy := 3.0
+
+	if values := cmd.Params["target-redundancy"]; len(values) > 0 {
+		if redundancy, err = strconv.ParseFloat(values[0], 64); err != nil || redundancy <= 0 {
+			return fmt.Errorf("invalid --target-redundancy value %q", values[0])
+		}
+	}
+
+	hosts, err := paramUint(cmd, "hosts", defaultAllowanceHosts)
+
+	if err != nil {
+		return
+	}
+
+	minContracts, err := paramUint(cmd, "min-contracts", hosts/2)
+
+	if err != nil {
+		return
+	}
+
+	var wallet walletResponse
+
+	if err = callAPI(cmd, "GET", "/wallet", nil, &wallet); err != nil {
+		return
+	}
+
+	if !wallet.Unlocked {
+		return fmt.Errorf("the wallet must be unlocked to form contracts")
+	}
+
+	if balance := parseHastings(wallet.ConfirmedSiacoinBalance); balance.Cmp(budget) < 0 {
+		return fmt.Errorf("insufficient funds: the budget is %s but the confirmed balance is %s", format.FormatCurrency(budget), format.FormatCurrency(balance))
+	}
+
+	var prices renterPricesResponse
+
+	if err = callAPI(cmd, "GET", "/renter/prices", nil, &prices); err != nil {
+		return
+	}
+
+	proposal := proposeAllowance(budget, redundancy, hosts, defaultAllowancePeriod, defaultRenewWindow, prices)
+
+	if proposal.ExpectedStorage == 0 {
+		return fmt.Errorf("a budget of %s does not cover the %s estimated contract fees", format.FormatCurrency(budget), format.FormatCurrency(parseHastings(prices.FormContracts)))
+	}
+
+	notify(cmd, "a budget of %s stores about %s at %gx redundancy for %d blocks",
+		format.FormatCurrency(budget), format.FormatBytes(int64(proposal.ExpectedStorage)), redundancy, proposal.Period)
+
+	allowance := url.Values{
+		"funds":              {proposal.Funds},
+		"hosts":              {strconv.FormatUint(proposal.Hosts, 10)},
+		"period":             {strconv.FormatUint(proposal.Period, 10)},
+		"renewwindow":        {strconv.FormatUint(proposal.RenewWindow, 10)},
+		"expectedstorage":    {strconv.FormatUint(proposal.ExpectedStorage, 10)},
+		"expectedupload":     {strconv.FormatUint(proposal.ExpectedUpload, 10)},
+		"expecteddownload":   {strconv.FormatUint(proposal.ExpectedDownload, 10)},
+		"expectedredundancy": {proposal.ExpectedRedundancy},
+	}
+
+	if cmd.Plan {
+		return planParams(cmd, "POST", "/renter", allowance)
+	}
+
+	if err = writeJSON(proposal); err != nil {
+		return
+	}
+
+	if ok, err := confirm(cmd, "Apply this allowance?"); err != nil || !ok {
+		if err == nil {
+			err = fmt.Errorf("allowance not applied")
+		}
+
+		return err
+	}
+
+	if err = callAPI(cmd, "POST", "/renter", allowance, nil); err != nil {
+		return
+	}
+
+	interval, timeout := 30*time.Second, 6*time.Hour
+
+	if values := cmd.Params["poll-interval"]; len(values) > 0 {
+		if interval, err = format.ParseDuration(values[0]); err != nil {
+			return
+		}
+	}
+
+	if values := cmd.Params["wait-timeout"]; len(values) > 0 {
+		if timeout, err = format.ParseDuration(values[0]); err != nil {
+			return
+		}
+	}
+
+	return waitForContracts(cmd, int(minContracts), interval, timeout)
+}
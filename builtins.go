@@ -0,0 +1,307 @@
+package main
+
+//BuiltinCommands commands handled by sia-json itself. These are matched before endpoints.All
+var BuiltinCommands = []BuiltinCommand{
+	BuiltinCommand{
+		Path:     "/validate/id/:value",
+		HelpText: "validates a 64 character hex identifier. --type txid|contractid|merkleroot|blockid",
+		Run:      validateIDCommand,
+	},
+	BuiltinCommand{
+		Path:     "/cd",
+		HelpText: "changes the remote working directory to the root",
+		Run:      cdCommand,
+	},
+	BuiltinCommand{
+		Path:     "/cd/*siapath",
+		HelpText: "changes the remote working directory relative siapaths are resolved against",
+		Run:      cdCommand,
+	},
+	BuiltinCommand{
+		Path:     "/pwd",
+		HelpText: "prints the remote working directory",
+		Run:      pwdCommand,
+	},
+	BuiltinCommand{
+		Path:     "/renter/rm/*siapath",
+		HelpText: "deletes a renter file. --trash moves it to the trash instead, --retention sets how long trashed files are kept (default 7d)",
+		Run:      renterRmCommand,
+	},
+	BuiltinCommand{
+		Path:     "/renter/trash",
+		HelpText: "lists the files in the trash",
+		Run:      trashListCommand,
+	},
+	BuiltinCommand{
+		Path:     "/renter/trash/restore/*siapath",
+		HelpText: "restores the most recently trashed copy of a file",
+		Run:      trashRestoreCommand,
+	},
+	BuiltinCommand{
+		Path:     "/renter/trash/purge",
+		HelpText: "permanently deletes the files in the trash. --older-than only purges files trashed before the duration",
+		Run:      trashPurgeCommand,
+	},
+	BuiltinCommand{
+		Path:     "/profiles/list",
+		HelpText: "lists the connection profiles in the config file",
+		Run:      profilesListCommand,
+	},
+	BuiltinCommand{
+		Path:     "/profiles/add/:name",
+		HelpText: "adds a connection profile. --address --user-agent --api-password --api-password-file",
+		Run:      profilesAddCommand,
+	},
+	BuiltinCommand{
+		Path:     "/profiles/remove/:name",
+		HelpText: "removes a connection profile from the config file",
+		Run:      profilesRemoveCommand,
+	},
+	BuiltinCommand{
+		Path:     "/renter/bootstrap",
+		HelpText: "proposes and applies an allowance for a new renter and waits for contracts to form. --budget --target-redundancy --hosts --min-contracts --yes --plan",
+		Run:      renterBootstrapCommand,
+		Plan:     true,
+	},
+	BuiltinCommand{
+		Path:     "/host/maintenance/:mode",
+		HelpText: "on stops the host accepting contracts and off restores the previous setting. --wait waits for negotiations to settle. --plan",
+		Run:      hostMaintenanceCommand,
+		Plan:     true,
+	},
+	BuiltinCommand{
+		Path:     "/host/safe-to-stop",
+		HelpText: "checks no storage proofs are due within --margin blocks (default 144) and storage folders are healthy. Exits 4 if it is not safe to stop",
+		Run:      hostSafeToStopCommand,
+	},
+	BuiltinCommand{
+		Path:     "/host/forecast",
+		HelpText: "projects the host's payouts week by week from its unresolved obligations, discounted by the rate of missed proofs or --miss-rate",
+		Run:      hostForecastCommand,
+	},
+	BuiltinCommand{
+		Path:     "/host/risk",
+		HelpText: "reports the collateral risked by contract and proof window day and the outages of --downtime (default 24h) that would lose the most. --top",
+		Run:      hostRiskCommand,
+	},
+	BuiltinCommand{
+		Path:     "/rotate-apipassword",
+		HelpText: "writes a new random apipassword file and updates profiles that used the old one. --apipassword-file --restart-hook",
+		Run:      rotateAPIPasswordCommand,
+	},
+	BuiltinCommand{
+		Path:     "/agent",
+		HelpText: "runs the agent in the foreground. While an agent is running requests are sent through it, reusing its open connections",
+		Run:      agentCommand,
+		Stream:   true,
+	},
+	BuiltinCommand{
+		Path:     "/agent/start",
+		HelpText: "starts the agent in the background",
+		Run:      agentStartCommand,
+	},
+	BuiltinCommand{
+		Path:     "/agent/stop",
+		HelpText: "stops the running agent",
+		Run:      agentStopCommand,
+	},
+	BuiltinCommand{
+		Path:     "/agent/status",
+		HelpText: "writes the running agent's pid, uptime and request count",
+		Run:      agentStatusCommand,
+	},
+	BuiltinCommand{
+		Path:     "/hostdb/scan",
+		HelpText: "checks whether hosts not scanned within --stale (default 7d) are reachable and reports hosts that came online or went offline. --workers --dial-timeout",
+		Run:      hostDBScanCommand,
+	},
+	BuiltinCommand{
+		Path:     "/wallet/setup",
+		HelpText: "initializes a new wallet, confirms the seed was written down and checks the wallet unlocks. --password-file --dictionary --backup",
+		Run:      walletSetupCommand,
+	},
+	BuiltinCommand{
+		Path:     "/wallet/import/legacy",
+		HelpText: "imports a v0.3.3.x wallet file or comma separated siag key files and waits for the balance to update. --file --password-file --poll-interval --wait-timeout",
+		Run:      walletImportLegacyCommand,
+	},
+	BuiltinCommand{
+		Path:     "/wallet/analyze",
+		HelpText: "reports the distribution of the wallet's unspent outputs, its dust and the most one transaction can send, recommending consolidation when needed",
+		Run:      walletAnalyzeCommand,
+	},
+	BuiltinCommand{
+		Path:     "/wallet/bump/:id",
+		HelpText: "respends the wallet's change output of an unconfirmed transaction with a higher fee so both confirm sooner. --fee --yes --dry-run",
+		Run:      walletBumpCommand,
+		DryRun:   true,
+	},
+	BuiltinCommand{
+		Path:     "/monitor/mempool",
+		HelpText: "writes a JSON line when an unconfirmed transaction touching an --address appears, confirms, is evicted or is double spent, until interrupted. --interval",
+		Run:      monitorMempoolCommand,
+		Stream:   true,
+	},
+	BuiltinCommand{
+		Path:     "/gateway/stats",
+		HelpText: "summarizes the gateway peer counts and bandwidth recorded within --since (default 24h), including periods with no peers",
+		Run:      gatewayStatsCommand,
+	},
+	BuiltinCommand{
+		Path:     "/gateway/stats/record",
+		HelpText: "records the gateway's peer counts and bandwidth counters every --interval (default 1m) until interrupted",
+		Run:      gatewayStatsRecordCommand,
+		Stream:   true,
+	},
+	BuiltinCommand{
+		Path:     "/gateway/prune",
+		HelpText: "disconnects peers older than --min-version or slower to connect to than --max-latency. --dry-run lists them without disconnecting",
+		Run:      gatewayPruneCommand,
+		DryRun:   true,
+	},
+	BuiltinCommand{
+		Path:     "/status/storage",
+		HelpText: "reports the size of each module directory under the Sia dir, its growth since earlier runs and when the disk is projected to fill. --sia-dir",
+		Run:      statusStorageCommand,
+	},
+	BuiltinCommand{
+		Path:     "/renter/downloads/tail",
+		HelpText: "writes a JSON line for each progress update of the downloads in progress until they finish, failing if any fail. --interval",
+		Run:      downloadsTailCommand,
+		Stream:   true,
+	},
+	BuiltinCommand{
+		Path:     "/history",
+		HelpText: "writes the last --limit (default 20) commands run with their endpoint, params, exit code and duration, sensitive values redacted",
+		Run:      historyCommand,
+	},
+	BuiltinCommand{
+		Path:     "/completion/:shell",
+		HelpText: "writes a completion script for bash, zsh or fish generated from the endpoint table",
+		Run:      completionCommand,
+	},
+	BuiltinCommand{
+		Path:     "/search/*term",
+		HelpText: "searches renter files, contracts, wallet transactions and hosts for siapaths, IDs, addresses and public keys containing the term",
+		Run:      searchCommand,
+	},
+	BuiltinCommand{
+		Path:     "/at/consensus/block",
+		HelpText: "writes the block at --height or the last block before --date",
+		Run:      atBlockCommand,
+	},
+	BuiltinCommand{
+		Path:     "/at/wallet/balance-estimate",
+		HelpText: "estimates the wallet's balances at --height or --date from its confirmed transactions",
+		Run:      atBalanceCommand,
+	},
+	BuiltinCommand{
+		Path:     "/renter/watch-dir/*paths",
+		HelpText: "uploads new and modified files in a local directory to a remote directory until interrupted: renter watch-dir <local dir> <remote dir>. --include --exclude --exclude-from",
+		Run:      watchDirCommand,
+		Stream:   true,
+	},
+	BuiltinCommand{
+		Path:     "/wallet/transactions/all",
+		HelpText: "returns the wallet's transactions from --startheight to --endheight as one array, requesting --window blocks (default 10000) at a time",
+		Run:      walletTransactionsAllCommand,
+	},
+	BuiltinCommand{
+		Path:     "/keys/list",
+		HelpText: "lists the keys uploads can be encrypted with using --encrypt-key",
+		Run:      keysListCommand,
+	},
+	BuiltinCommand{
+		Path:     "/keys/generate/:name",
+		HelpText: "generates a random key for --encrypt-key in the config directory. Back it up, encrypted files cannot be restored without it",
+		Run:      keysGenerateCommand,
+	},
+	BuiltinCommand{
+		Path:     "/parallel",
+		HelpText: "runs the commands of a --from batch file concurrently and writes their responses as one object keyed by command. --workers",
+		Run:      parallelCommand,
+	},
+	BuiltinCommand{
+		Path:     "/parallel/*commands",
+		HelpText: "runs the quoted commands concurrently and writes their responses as one object keyed by command. --workers --from <batch file>",
+		Run:      parallelCommand,
+	},
+	BuiltinCommand{
+		Path:     "/renter/scrub",
+		HelpText: "downloads a random --sample of the blocks of files uploaded with --file and verifies them against the checksums recorded at upload",
+		Run:      renterScrubCommand,
+	},
+	BuiltinCommand{
+		Path:     "/renter/scrub/*siapath",
+		HelpText: "scrubs the files below the siapath. --sample",
+		Run:      renterScrubCommand,
+	},
+	BuiltinCommand{
+		Path:     "/backup/run/*dir",
+		HelpText: "backs up a directory as a deduplicated snapshot, uploading only new chunks: backup run <dir>. --repo",
+		Run:      backupRunCommand,
+	},
+	BuiltinCommand{
+		Path:     "/backup/list",
+		HelpText: "lists the snapshots in the backup repository. --repo",
+		Run:      backupListCommand,
+	},
+	BuiltinCommand{
+		Path:     "/backup/restore/*args",
+		HelpText: "restores a snapshot, or latest, into a directory: backup restore <snapshot> <dir>. --path --repo",
+		Run:      backupRestoreCommand,
+	},
+	BuiltinCommand{
+		Path:     "/backup/prune",
+		HelpText: "deletes the snapshots the retention policy does not keep and the chunks only they reference. --keep-daily --keep-weekly --keep-monthly --repo. --dry-run reports the space reclaimed without deleting",
+		Run:      backupPruneCommand,
+		DryRun:   true,
+	},
+	BuiltinCommand{
+		Path:     "/backup/browse/*args",
+		HelpText: "opens an interactive tree of a snapshot to select files, or older versions of them, and restore them into a directory: backup browse <snapshot> <dir>. --repo",
+		Run:      backupBrowseCommand,
+	},
+	BuiltinCommand{
+		Path:     "/backup/history/*path",
+		HelpText: "lists the versions of a file in the snapshots of the backup repository. --repo",
+		Run:      backupHistoryCommand,
+	},
+	BuiltinCommand{
+		Path:     "/detect",
+		HelpText: "detects whether the daemon is siad, renterd or hostd, its version and how many endpoints of the table it has",
+		Run:      detectCommand,
+	},
+	BuiltinCommand{
+		Path:     "/fixtures/capture",
+		HelpText: "captures the responses of GET endpoints as fixtures with seeds, passwords and addresses redacted. --endpoints wallet,consensus --output <dir>",
+		Run:      fixturesCaptureCommand,
+	},
+	BuiltinCommand{
+		Path:     "/conformance",
+		HelpText: "compares the structure of GET responses with the baselines in --baseline, exiting 4 if any differ. --endpoints --update writes new baselines",
+		Run:      conformanceCommand,
+	},
+	BuiltinCommand{
+		Path:     "/tail/host-contracts",
+		HelpText: "writes a JSON line for each new storage obligation, successful or missed storage proof, rejected obligation and expired contract of the host until interrupted, recording them as metrics. --interval",
+		Run:      tailHostContractsCommand,
+		Stream:   true,
+	},
+	BuiltinCommand{
+		Path:     "/plugins",
+		HelpText: "lists the sia-json-<name> command plugins and sia-json-format-<name> format plugins on PATH and the endpoints they add",
+		Run:      pluginsCommand,
+	},
+	BuiltinCommand{
+		Path:     "/hooks/serve",
+		HelpText: "serves the hooks of the config file, running a hook's recipe when POST /hooks/<name> is called with its token. --listen --audit-log --tls-cert --tls-key",
+		Run:      hooksServeCommand,
+		Stream:   true,
+	},
+	BuiltinCommand{
+		Path:     "/openapi",
+		HelpText: "writes an OpenAPI 3 document of the endpoint table, including endpoints loaded with --endpoints, with the --addr as its server",
+		Run:      openAPICommand,
+	},
+}
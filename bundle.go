@@ -0,0 +1,420 @@
+package main
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type (
+	//bundleEntry a file in a bundle. Each file is written as its own gzip member so it can be
+	//restored by downloading only the member's byte range
+	bundleEntry struct {
+		Name    string      `json:"name"`
+		Size    int64       `json:"size"`
+		Mode    os.FileMode `json:"mode"`
+		ModTime time.Time   `json:"modtime"`
+		Offset  int64       `json:"offset"`
+		Length  int64       `json:"length"`
+	}
+
+	//bundleIndex the index uploaded alongside a bundle
+	bundleIndex struct {
+		Created time.Time     `json:"created"`
+		Size    int64         `json:"size"`
+		Files   []bundleEntry `json:"files"`
+	}
+
+	//bundleResult the result of a bundle upload or download
+	bundleResult struct {
+		SiaPath string `json:"siapath"`
+		Index   string `json:"index"`
+		Path    string `json:"path"`
+		Files   int    `json:"files"`
+		Size    int64  `json:"size"`
+	}
+
+	//countingWriter counts the bytes written to the wrapped writer
+	countingWriter struct {
+		w io.Writer
+		n int64
+	}
+)
+
+//bundleIndexSuffix the suffix of the siapath the bundle's index is uploaded to
+const bundleIndexSuffix = ".index.json"
+
+//Write writes p to the wrapped writer and counts the bytes written
+func (cw *countingWriter) Write(p []byte) (n int, err error) {
+	n, err = cw.w.Write(p)
+	cw.n += int64(n)
+
+	return
+}
+
+//writeBundle writes the directory to w as a tar.gz and returns its index. Every file and
+//directory is a separate gzip member, so the bundle is still a single valid tar.gz
+func writeBundle(w io.Writer, dir string) (index bundleIndex, err error) {
+	cw := &countingWriter{w: w}
+	index.Files = []bundleEntry{}
+
+	err = filepath.Walk(dir, func(name string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(dir, name)
+
+		if err != nil || rel == "." {
+			return err
+		}
+
+		// links and special files are not bundled
+		if !info.IsDir() && !info.Mode().IsRegular() {
+			return nil
+		}
+
+		hdr, err := tar.FileInfoHeader(info, "")
+
+		if err != nil {
+			return err
+		}
+
+		hdr.Name = filepath.ToSlash(rel)
+
+		if info.IsDir() {
+			hdr.Name += "/"
+		}
+
+		offset := cw.n
+		gz := gzip.NewWriter(cw)
+		tw := tar.NewWriter(gz)
+
+		if err = tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+
+		if info.Mode().IsRegular() {
+			f, err := os.Open(name)
+
+			if err != nil {
+				return err
+			}
+
+			_, err = io.Copy(tw, f)
+			f.Close()
+
+			if err != nil {
+				return err
+			}
+		}
+
+		// the end of the archive is only written after the last file
+		if err = tw.Flush(); err != nil {
+			return err
+		}
+
+		if err = gz.Close(); err != nil {
+			return err
+		}
+
+		if info.Mode().IsRegular() {
+			index.Files = append(index.Files, bundleEntry{
+				Name:    hdr.Name,
+				Size:    info.Size(),
+				Mode:    info.Mode().Perm(),
+				ModTime: info.ModTime().UTC(),
+				Offset:  offset,
+				Length:  cw.n - offset,
+			})
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return
+	}
+
+	gz := gzip.NewWriter(cw)
+
+	if err = tar.NewWriter(gz).Close(); err != nil {
+		return
+	}
+
+	if err = gz.Close(); err != nil {
+		return
+	}
+
+	index.Created = time.Now().UTC()
+	index.Size = cw.n
+
+	return
+}
+
+//extractBundle extracts the tar entries into the directory and returns the number of files and
+//bytes extracted. Entries outside of the directory are refused
+func extractBundle(tr *tar.Reader, dir string) (files int, size int64, err error) {
+	for {
+		hdr, err := tr.Next()
+
+		if err == io.EOF {
+			return files, size, nil
+		} else if err != nil {
+			return files, size, err
+		}
+
+		name := path.Clean(hdr.Name)
+
+		if path.IsAbs(name) || name == ".." || strings.HasPrefix(name, "../") {
+			return files, size, fmt.Errorf("refusing to extract %s outside of %s", hdr.Name, dir)
+		}
+
+		target := filepath.Join(dir, filepath.FromSlash(name))
+
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			if err = os.MkdirAll(target, 0755); err != nil {
+				return files, size, err
+			}
+		case tar.TypeReg:
+			if err = os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+				return files, size, err
+			}
+
+			f, err := os.OpenFile(target, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, hdr.FileInfo().Mode().Perm())
+
+			if err != nil {
+				return files, size, err
+			}
+
+			n, err := io.Copy(f, tr)
+			size += n
+
+			if closeErr := f.Close(); err == nil {
+				err = closeErr
+			}
+
+			if err != nil {
+				return files, size, err
+			}
+
+			os.Chtimes(target, hdr.ModTime, hdr.ModTime)
+			files++
+		}
+	}
+}
+
+//bundleSiaPaths returns the request path of the bundle and of its index
+func bundleSiaPaths(cmd Command) (bundle, index string, err error) {
+	prefix, siapath, ok := splitSiaPath(cmd)
+
+	if !ok || len(strings.Trim(siapath, "/")) == 0 {
+		return "", "", fmt.Errorf("missing siapath")
+	}
+
+	siapath = strings.Trim(siapath, "/")
+
+	return siapath, prefix + "/" + siapath + bundleIndexSuffix, nil
+}
+
+//bundleUpload streams the directory given with --file to the siapath as a tar.gz, then uploads
+//its index to the siapath with .index.json added
+func bundleUpload(cmd Command) (err error) {
+	info, err := os.Stat(cmd.UploadFile)
+
+	if err != nil {
+		return
+	} else if !info.IsDir() {
+		return fmt.Errorf("--bundle uploads a directory, %s is a file", cmd.UploadFile)
+	}
+
+	siapath, indexPath, err := bundleSiaPaths(cmd)
+
+	if err != nil {
+		return
+	}
+
+	type bundled struct {
+		index bundleIndex
+		err   error
+	}
+
+	pr, pw := io.Pipe()
+	done := make(chan bundled, 1)
+
+	go func() {
+		index, err := writeBundle(pw, cmd.UploadFile)
+		pw.CloseWithError(err)
+		done <- bundled{index, err}
+	}()
+
+	upload := cmd
+	upload.ContentType = "application/octet-stream"
+	upload.BodyLength = 0
+
+	err = streamAPI(upload, withProgress(cmd, pr, -1, "uploading"), nil)
+	// stops the bundle from being written if the upload failed
+	pr.CloseWithError(io.ErrClosedPipe)
+	result := <-done
+
+	if err != nil {
+		return
+	} else if result.err != nil {
+		return result.err
+	}
+
+	buf, err := json.MarshalIndent(result.index, "", "\t")
+
+	if err != nil {
+		return
+	}
+
+	upload.RequestPath = indexPath
+	upload.BodyLength = int64(len(buf))
+
+	if err = streamAPI(upload, bytes.NewReader(buf), nil); err != nil {
+		return fmt.Errorf("the bundle was uploaded but its index was not: %s", err)
+	}
+
+	return writeJSON(bundleResult{
+		SiaPath: siapath,
+		Index:   siapath + bundleIndexSuffix,
+		Path:    cmd.UploadFile,
+		Files:   len(result.index.Files),
+		Size:    result.index.Size,
+	})
+}
+
+//bundleFile returns true if the bundled file is one of the paths or below one of them
+func bundleFile(paths []string, name string) bool {
+	for _, p := range paths {
+		p = strings.Trim(filepath.ToSlash(p), "/")
+
+		if name == p || strings.HasPrefix(name, p+"/") {
+			return true
+		}
+	}
+
+	return false
+}
+
+//bundleDownload downloads the bundle at the siapath and extracts it into the --output
+//directory. With --bundle-path only the matching files are restored, each downloading just its
+//byte range found in the bundle's index
+func bundleDownload(cmd Command) (err error) {
+	if len(cmd.OutputFile) == 0 {
+		return fmt.Errorf("--bundle downloads need --output <dir> to extract into")
+	}
+
+	siapath, indexPath, err := bundleSiaPaths(cmd)
+
+	if err != nil {
+		return
+	}
+
+	if err = os.MkdirAll(cmd.OutputFile, 0755); err != nil {
+		return
+	}
+
+	download := cmd
+	download.Params = map[string][]string{"httpresp": {"true"}}
+	result := bundleResult{
+		SiaPath: siapath,
+		Index:   siapath + bundleIndexSuffix,
+		Path:    cmd.OutputFile,
+	}
+
+	if len(cmd.BundlePaths) == 0 {
+		err = streamAPI(download, nil, func(r io.Reader) error {
+			gz, err := gzip.NewReader(withProgress(cmd, r, -1, "downloading"))
+
+			if err != nil {
+				return err
+			}
+
+			result.Files, result.Size, err = extractBundle(tar.NewReader(gz), cmd.OutputFile)
+
+			return err
+		})
+
+		if err != nil {
+			return
+		}
+
+		return writeJSON(result)
+	}
+
+	var index bundleIndex
+	indexCmd := download
+	indexCmd.RequestPath = indexPath
+
+	err = streamAPI(indexCmd, nil, func(r io.Reader) error {
+		return json.NewDecoder(r).Decode(&index)
+	})
+
+	if err != nil {
+		return fmt.Errorf("unable to load the bundle's index: %s", err)
+	}
+
+	for _, entry := range index.Files {
+		if !bundleFile(cmd.BundlePaths, entry.Name) {
+			continue
+		}
+
+		download.Params["offset"] = []string{strconv.FormatInt(entry.Offset, 10)}
+		download.Params["length"] = []string{strconv.FormatInt(entry.Length, 10)}
+
+		err = streamAPI(download, nil, func(r io.Reader) error {
+			gz, err := gzip.NewReader(r)
+
+			if err != nil {
+				return err
+			}
+
+			// only the file's member was downloaded
+			gz.Multistream(false)
+
+			files, size, err := extractBundle(tar.NewReader(gz), cmd.OutputFile)
+			result.Files += files
+			result.Size += size
+
+			return err
+		})
+
+		if err != nil {
+			return fmt.Errorf("unable to restore %s: %s", entry.Name, err)
+		}
+	}
+
+	if result.Files == 0 {
+		return fmt.Errorf("no files in the bundle match %s", strings.Join(cmd.BundlePaths, ", "))
+	}
+
+	return writeJSON(result)
+}
+
+//bundleSender returns the function sending a --bundle command
+func bundleSender(cmd Command) (send func(Command) error, err error) {
+	switch {
+	case cmd.DryRun || cmd.AsCurl:
+		err = fmt.Errorf("--dry-run and --as-curl cannot be used with --bundle")
+	case strings.HasPrefix(cmd.Endpoint.Path, "/renter/uploadstream/") && len(cmd.UploadFile) > 0:
+		send = bundleUpload
+	case cmd.Endpoint.Path == "/renter/download/*siapath":
+		send = bundleDownload
+	default:
+		err = fmt.Errorf("--bundle can only be used with renter uploadstream --file <dir> and renter download --output <dir>")
+	}
+
+	return
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+)
+
+//cacheHeader the response header set on responses read from the cache, with their age in seconds
+const cacheHeader = "X-Sia-Json-Cache-Age"
+
+//cacheDir returns the directory cached responses are stored in
+func cacheDir() string {
+	return filepath.Join(DefaultConfigDir(), "cache")
+}
+
+//cachePath returns the file the command's response is cached in, or an empty string if its
+//response is not cached. Only JSON responses of GET requests to endpoints without secrets are
+//cached, keyed by the address, path, params, API password and headers so different nodes,
+//queries and credentials do not share entries
+func cachePath(cmd Command) string {
+	if cmd.Cache <= 0 || cmd.Method != "GET" || cmd.Endpoint.Binary || cmd.Endpoint.Sensitive {
+		return ""
+	}
+
+	// the password and headers are hashed into the key, they are never stored
+	sum := sha256.Sum256([]byte(cmd.APIAddress + "\x00" + cmd.RequestPath + "\x00" + url.Values(cmd.Params).Encode() +
+		"\x00" + cmd.APIPassword + "\x00" + url.Values(cmd.Header).Encode()))
+
+	return filepath.Join(cacheDir(), hex.EncodeToString(sum[:])+".json")
+}
+
+//cachedResponse returns the command's cached response if it was cached within --cache. --no-cache
+//and --watch skip the cache, the fresh response still replaces the cached one
+func cachedResponse(cmd Command) (resp *http.Response, ok bool) {
+	path := cachePath(cmd)
+
+	// each --watch iteration has to see the current response
+	if len(path) == 0 || cmd.NoCache || cmd.Watch > 0 {
+		return nil, false
+	}
+
+	info, err := os.Stat(path)
+
+	if err != nil || time.Since(info.ModTime()) > cmd.Cache {
+		return nil, false
+	}
+
+	buf, err := ioutil.ReadFile(path)
+
+	if err != nil {
+		return nil, false
+	}
+
+	resp = &http.Response{
+		Status:        "200 OK",
+		StatusCode:    http.StatusOK,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        make(http.Header),
+		Body:          ioutil.NopCloser(bytes.NewReader(buf)),
+		ContentLength: int64(len(buf)),
+	}
+	resp.Header.Set("Content-Type", "application/json")
+	resp.Header.Set(cacheHeader, strconv.Itoa(int(time.Since(info.ModTime()).Seconds())))
+
+	return resp, true
+}
+
+//cacheResponse stores a successful response of the command in the cache. The body is read and
+//replaced so it can still be written. A failure to store it is only warned about, the response
+//itself is not affected
+func cacheResponse(cmd Command, resp *http.Response) error {
+	path := cachePath(cmd)
+
+	if len(path) == 0 || resp.StatusCode != http.StatusOK {
+		return nil
+	}
+
+	buf, err := ioutil.ReadAll(resp.Body)
+
+	if err != nil {
+		return err
+	}
+
+	resp.Body = ioutil.NopCloser(bytes.NewReader(buf))
+
+	if err := os.MkdirAll(cacheDir(), 0700); err != nil {
+		notify(cmd, "warning: unable to cache the response: %s", err)
+		return nil
+	}
+
+	// written to a temporary file first so a concurrent read never sees a partial response
+	tmp := path + ".tmp" + strconv.Itoa(os.Getpid())
+
+	if err := ioutil.WriteFile(tmp, buf, 0600); err != nil {
+		notify(cmd, "warning: unable to cache the response: %s", err)
+		return nil
+	}
+
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		notify(cmd, "warning: unable to cache the response: %s", err)
+	}
+
+	return nil
+}
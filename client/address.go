@@ -0,0 +1,101 @@
+package client
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+//DefaultPort the port of the Sia API used for addresses without one
+const DefaultPort = "9980"
+
+//ParseAddress returns the base URL of a Sia API address: a host:port such as localhost:9980 or
+//[::1]:9980, a host or IP address without a port, which uses DefaultPort, or a URL including the
+//scheme. IPv6 addresses are bracketed in the URL's host
+func ParseAddress(address string) (u *url.URL, err error) {
+	address = strings.TrimSpace(address)
+
+	if len(address) == 0 {
+		return nil, fmt.Errorf("empty address")
+	}
+
+	if strings.Contains(address, "://") {
+		if u, err = url.Parse(address); err != nil {
+			return nil, fmt.Errorf("invalid address %q: %s", address, err)
+		}
+
+		if u.Scheme != "http" && u.Scheme != "https" {
+			return nil, fmt.Errorf("invalid address %q: unsupported scheme %s", address, u.Scheme)
+		} else if len(u.Hostname()) == 0 {
+			return nil, fmt.Errorf("invalid address %q: missing host", address)
+		} else if port := u.Port(); len(port) > 0 {
+			if err = checkPort(address, port); err != nil {
+				return nil, err
+			}
+		}
+
+		u.Path = strings.TrimRight(u.Path, "/")
+		u.RawQuery, u.Fragment = "", ""
+
+		return
+	}
+
+	host, port, err := net.SplitHostPort(address)
+
+	if err != nil {
+		// a host without a port, including bare and bracketed IPv6 addresses such as ::1
+		host, port = address, DefaultPort
+
+		if strings.HasPrefix(host, "[") && strings.HasSuffix(host, "]") {
+			host = host[1 : len(host)-1]
+		}
+
+		if strings.Contains(host, ":") && net.ParseIP(strings.SplitN(host, "%", 2)[0]) == nil {
+			return nil, fmt.Errorf("invalid address %q, IPv6 addresses with a port must be bracketed such as [::1]:9980", address)
+		} else if strings.ContainsAny(host, "[]/ ") {
+			return nil, fmt.Errorf("invalid address %q", address)
+		}
+	}
+
+	if len(host) == 0 {
+		return nil, fmt.Errorf("invalid address %q: missing host", address)
+	}
+
+	if err = checkPort(address, port); err != nil {
+		return
+	}
+
+	return &url.URL{Scheme: "http", Host: net.JoinHostPort(host, port)}, nil
+}
+
+//HostPort returns the host:port of a Sia API address, with IPv6 addresses bracketed
+func HostPort(address string) (hostport string, err error) {
+	u, err := ParseAddress(address)
+
+	if err != nil {
+		return
+	}
+
+	if len(u.Port()) > 0 {
+		return u.Host, nil
+	}
+
+	port := "80"
+
+	if u.Scheme == "https" {
+		port = "443"
+	}
+
+	return net.JoinHostPort(u.Hostname(), port), nil
+}
+
+//checkPort returns an error if the port of the address is not a TCP port
+func checkPort(address, port string) error {
+	if n, err := strconv.Atoi(port); err != nil || n < 1 || n > 65535 {
+		return fmt.Errorf("invalid address %q: invalid port %s", address, port)
+	}
+
+	return nil
+}
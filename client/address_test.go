@@ -0,0 +1,63 @@
+package client
+
+import "testing"
+
+func TestParseAddress(t *testing.T) {
+	tests := []struct {
+		address  string
+		url      string
+		hostport string
+		err      bool
+	}{
+		{address: "localhost:9980", url: "http://localhost:9980", hostport: "localhost:9980"},
+		{address: "localhost", url: "http://localhost:9980", hostport: "localhost:9980"},
+		{address: " 10.0.0.2 ", url: "http://10.0.0.2:9980", hostport: "10.0.0.2:9980"},
+		{address: "sia.example.com:443", url: "http://sia.example.com:443", hostport: "sia.example.com:443"},
+		{address: "[::1]:9980", url: "http://[::1]:9980", hostport: "[::1]:9980"},
+		{address: "[::1]", url: "http://[::1]:9980", hostport: "[::1]:9980"},
+		{address: "::1", url: "http://[::1]:9980", hostport: "[::1]:9980"},
+		{address: "fe80::1%eth0", url: "http://[fe80::1%25eth0]:9980", hostport: "[fe80::1%eth0]:9980"},
+		{address: "http://localhost:9980", url: "http://localhost:9980", hostport: "localhost:9980"},
+		{address: "http://localhost", url: "http://localhost", hostport: "localhost:80"},
+		{address: "https://sia.example.com", url: "https://sia.example.com", hostport: "sia.example.com:443"},
+		{address: "https://sia.example.com/api/?q=1", url: "https://sia.example.com/api", hostport: "sia.example.com:443"},
+		{address: "https://[::1]:8443", url: "https://[::1]:8443", hostport: "[::1]:8443"},
+		{address: "", err: true},
+		{address: "localhost:0", err: true},
+		{address: "localhost:65536", err: true},
+		{address: "localhost:port", err: true},
+		{address: "http://localhost:99999", err: true},
+		{address: "::1:9980:x", err: true},
+		{address: "ftp://localhost:9980", err: true},
+		{address: "http://:9980", err: true},
+		{address: ":9980", err: true},
+		{address: "local host", err: true},
+	}
+
+	for _, tt := range tests {
+		u, err := ParseAddress(tt.address)
+
+		if tt.err {
+			if err == nil {
+				t.Errorf("ParseAddress(%q) = %s, expected an error", tt.address, u)
+			}
+
+			continue
+		} else if err != nil {
+			t.Errorf("ParseAddress(%q) returned error %s", tt.address, err)
+			continue
+		}
+
+		if u.String() != tt.url {
+			t.Errorf("ParseAddress(%q) = %s, expected %s", tt.address, u, tt.url)
+		}
+
+		hostport, err := HostPort(tt.address)
+
+		if err != nil {
+			t.Errorf("HostPort(%q) returned error %s", tt.address, err)
+		} else if hostport != tt.hostport {
+			t.Errorf("HostPort(%q) = %s, expected %s", tt.address, hostport, tt.hostport)
+		}
+	}
+}
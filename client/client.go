@@ -0,0 +1,229 @@
+//Package client sends requests to the Sia API. It is the client sia-json is built on, for Go
+//programs that want to call siad with sia-json's endpoint knowledge instead of running it
+//
+//	c := client.New("localhost:9980", password)
+//	var consensus struct {
+//		Height uint64 `json:"height"`
+//	}
+//	err := c.Call(client.Command{Path: "/consensus"}, &consensus)
+package client
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/n8maninger/siac-json/endpoints"
+)
+
+type (
+	//Client the connection settings of a Sia API
+	Client struct {
+		//Address the host:port of the Sia API, or a URL including the scheme. See ParseAddress
+		Address string
+		//Password the API password sent with every request
+		Password string
+		//UserAgent the user agent sent with every request. siad rejects requests without Sia-Agent
+		UserAgent string
+		//Header extra headers sent with every request. A header replaces the generated header of
+		//the same name, such as User-Agent
+		Header http.Header
+		//HTTPClient the client requests are sent with, http.DefaultClient if nil
+		HTTPClient *http.Client
+	}
+
+	//Command a request to the Sia API
+	Command struct {
+		//Method the request method. If empty the method of the single endpoint matching the path
+		//is used
+		Method string
+		//Path the request path, including any siapath
+		Path string
+		//Params sent in the query string of GET requests and requests with a body, and form
+		//encoded in the body of other POST requests
+		Params url.Values
+		//Body a raw request body
+		Body io.Reader
+		//ContentType the content type of the raw body, form encoded if empty
+		ContentType string
+		//ContentLength the length of the raw body, 0 if unknown
+		ContentLength int64
+	}
+
+	//APIError an error response returned by the Sia API
+	APIError struct {
+		StatusCode int    `json:"-"`
+		Message    string `json:"message"`
+	}
+)
+
+//DefaultUserAgent the user agent siad expects
+const DefaultUserAgent = "Sia-Agent"
+
+func (e APIError) Error() string {
+	if len(e.Message) == 0 {
+		return http.StatusText(e.StatusCode)
+	}
+
+	return e.Message
+}
+
+//New returns a client of the Sia API at the address
+func New(address, password string) *Client {
+	return &Client{
+		Address:   address,
+		Password:  password,
+		UserAgent: DefaultUserAgent,
+	}
+}
+
+//BaseURL returns the scheme and host of the Sia API. Addresses without a scheme use http. An
+//invalid address is returned as is, NewRequest reports the error
+func (c *Client) BaseURL() string {
+	u, err := ParseAddress(c.Address)
+
+	if err != nil {
+		return c.Address
+	}
+
+	return u.String()
+}
+
+//commandMethod returns the command's method, or the method of the endpoint matching its path
+func commandMethod(cmd Command) (string, error) {
+	if len(cmd.Method) > 0 {
+		return cmd.Method, nil
+	}
+
+	switch matched := endpoints.Match(cmd.Path, ""); len(matched) {
+	case 0:
+		return "", fmt.Errorf("no endpoint matches %s", cmd.Path)
+	case 1:
+		return matched[0].Method, nil
+	default:
+		return "", fmt.Errorf("more than one endpoint matches %s, set the method", cmd.Path)
+	}
+}
+
+//NewRequest returns the HTTP request of the command
+func (c *Client) NewRequest(cmd Command) (req *http.Request, err error) {
+	method, err := commandMethod(cmd)
+
+	if err != nil {
+		return
+	}
+
+	base, err := ParseAddress(c.Address)
+
+	if err != nil {
+		return
+	}
+
+	urlStr := base.String() + cmd.Path
+	body := cmd.Body
+	contentType := "application/x-www-form-urlencoded"
+
+	// params are sent in the query string when the body is used for raw data
+	if (method == "GET" || body != nil) && len(cmd.Params) > 0 {
+		urlStr += "?" + cmd.Params.Encode()
+	} else if method == "POST" && body == nil && len(cmd.Params) > 0 {
+		body = strings.NewReader(cmd.Params.Encode())
+	}
+
+	if body != nil && len(cmd.ContentType) > 0 {
+		contentType = cmd.ContentType
+	}
+
+	req, err = http.NewRequest(method, urlStr, body)
+
+	if err != nil {
+		return
+	}
+
+	// a known length avoids chunked transfer encoding for raw bodies
+	if cmd.ContentLength > 0 {
+		req.ContentLength = cmd.ContentLength
+	}
+
+	req.SetBasicAuth("", c.Password)
+	req.Header.Add("User-Agent", c.UserAgent)
+
+	if method == "POST" {
+		req.Header.Add("Content-Type", contentType)
+	}
+
+	for name, values := range c.Header {
+		req.Header[name] = values
+	}
+
+	return
+}
+
+//ReadError returns the APIError of an error response and closes its body
+func ReadError(resp *http.Response) error {
+	defer resp.Body.Close()
+
+	buf, _ := ioutil.ReadAll(resp.Body)
+	apiErr := APIError{StatusCode: resp.StatusCode}
+
+	if json.Unmarshal(buf, &apiErr) != nil {
+		apiErr.Message = string(bytes.TrimSpace(buf))
+	}
+
+	return apiErr
+}
+
+//Send sends the request. Error responses are returned as an APIError. The caller must close the
+//body of a successful response
+func (c *Client) Send(req *http.Request) (resp *http.Response, err error) {
+	httpClient := c.HTTPClient
+
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+
+	if resp, err = httpClient.Do(req); err != nil {
+		return
+	}
+
+	if resp.StatusCode >= 300 {
+		return nil, ReadError(resp)
+	}
+
+	return
+}
+
+//Do sends the command. Error responses are returned as an APIError. The caller must close the
+//body of a successful response
+func (c *Client) Do(cmd Command) (*http.Response, error) {
+	req, err := c.NewRequest(cmd)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return c.Send(req)
+}
+
+//Call sends the command and decodes its JSON response into obj. obj may be nil if the response
+//should be discarded
+func (c *Client) Call(cmd Command, obj interface{}) error {
+	resp, err := c.Do(cmd)
+
+	if err != nil {
+		return err
+	}
+
+	defer resp.Body.Close()
+
+	if obj == nil {
+		return nil
+	}
+
+	return json.NewDecoder(resp.Body).Decode(obj)
+}
@@ -0,0 +1,328 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type (
+	//historyEntry a command recorded in the history
+	historyEntry struct {
+		ID   int       `json:"id"`
+		Time time.Time `json:"time"`
+		//Args the command line with the values of sensitive flags redacted
+		Args    []string            `json:"args"`
+		Address string              `json:"address"`
+		Method  string              `json:"method,omitempty"`
+		Path    string              `json:"path"`
+		Params  map[string][]string `json:"params,omitempty"`
+		//Status ok or failed
+		Status   string `json:"status"`
+		Code     int    `json:"code"`
+		Error    string `json:"error,omitempty"`
+		Duration string `json:"duration"`
+		//Redacted the flags whose values were redacted, they must be given again to replay
+		Redacted []string `json:"redacted,omitempty"`
+	}
+)
+
+//defaultHistoryLimit the number of entries history writes
+const defaultHistoryLimit = 20
+
+//historySensitiveFlags flags redacted from the history in addition to the sensitive params.
+//Headers and bodies may carry credentials or seeds anywhere in their values, so they are redacted
+//whole
+var historySensitiveFlags = map[string]bool{
+	"data-raw":    true,
+	"encrypt-key": true,
+	"header":      true,
+	"json":        true,
+}
+
+//historySensitive returns true if the value of the flag or param is redacted from the history.
+//--body. fields are redacted by the name of their last key, such as --body.wallet.seed, and
+//--data-binary only when it is not a @file path
+func historySensitive(flag, value string) bool {
+	switch {
+	case sensitiveParams[flag] || historySensitiveFlags[flag]:
+		return true
+	case flag == "data-binary":
+		return !strings.HasPrefix(value, "@")
+	case strings.HasPrefix(flag, bodyFieldPrefix):
+		keys := strings.Split(flag, ".")
+		return sensitiveParams[keys[len(keys)-1]]
+	}
+
+	return false
+}
+
+//historyParams returns a copy of the params with the sensitive values redacted
+func historyParams(params map[string][]string) map[string][]string {
+	redactedParams := make(map[string][]string, len(params))
+
+	for key, values := range params {
+		if historySensitive(strings.ToLower(key), "") {
+			values = []string{redacted}
+		}
+
+		redactedParams[key] = values
+	}
+
+	return redactedParams
+}
+
+//replay is registered at init because the commands it runs look up BuiltinCommands
+func init() {
+	BuiltinCommands = append(BuiltinCommands, BuiltinCommand{
+		Path:     "/replay/:id",
+		HelpText: "runs a command of the history again. Flags given replace the recorded flags, redacted flags must be given again",
+		Run:      replayCommand,
+		Stream:   true,
+	})
+}
+
+//historyPath returns the file commands are recorded in, one JSON line per command
+func historyPath() string {
+	return filepath.Join(DefaultConfigDir(), "history.jsonl")
+}
+
+//historyArgs returns the args with the values of sensitive flags redacted and the flags that
+//were redacted
+func historyArgs(args []string) (redactedArgs, flags []string) {
+	// args that do not parse were never executed, they are recorded as given
+	tokens, err := tokenizeArgs(args)
+
+	if err != nil {
+		return args, nil
+	}
+
+	seen := make(map[string]bool)
+
+	for _, token := range tokens {
+		raw := token.Raw
+
+		if len(token.Flag) > 0 && historySensitive(token.Flag, token.Value) {
+			raw = append([]string{}, raw...)
+
+			if len(raw) == 1 {
+				raw[0] = raw[0][:strings.Index(raw[0], "=")+1] + redacted
+			} else {
+				raw[len(raw)-1] = redacted
+			}
+
+			// a repeated flag such as --header is given again once per value
+			if !seen[token.Flag] {
+				seen[token.Flag] = true
+				flags = append(flags, token.Flag)
+			}
+		}
+
+		redactedArgs = append(redactedArgs, raw...)
+	}
+
+	return
+}
+
+//lastHistoryID returns the id of the last entry of the history, 0 if it is empty. Only the end
+//of the file is read
+func lastHistoryID(path string) int {
+	f, err := os.Open(path)
+
+	if err != nil {
+		return 0
+	}
+
+	defer f.Close()
+
+	info, err := f.Stat()
+
+	if err != nil {
+		return 0
+	}
+
+	offset := info.Size() - 64<<10
+
+	if offset < 0 {
+		offset = 0
+	}
+
+	if _, err = f.Seek(offset, io.SeekStart); err != nil {
+		return 0
+	}
+
+	var last historyEntry
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64<<10), 1<<20)
+
+	for scanner.Scan() {
+		var entry historyEntry
+
+		// the first line may be cut off by the seek
+		if json.Unmarshal(scanner.Bytes(), &entry) == nil {
+			last = entry
+		}
+	}
+
+	return last.ID
+}
+
+//recordHistory appends the executed command to the history. The history and replay commands
+//themselves are not recorded, a replayed command is recorded as it runs
+func recordHistory(args []string, cmd Command, started time.Time, err error) {
+	if cmd.RequestPath == "/history" || strings.HasPrefix(cmd.RequestPath, "/replay/") {
+		return
+	}
+
+	entry := historyEntry{
+		Time:     started.UTC().Truncate(time.Millisecond),
+		Address:  cmd.APIAddress,
+		Method:   cmd.Method,
+		Path:     cmd.RequestPath,
+		Params:   historyParams(cmd.Params),
+		Status:   "ok",
+		Code:     exitCode(err),
+		Duration: time.Since(started).Round(time.Millisecond).String(),
+	}
+	entry.Args, entry.Redacted = historyArgs(args)
+
+	if err != nil {
+		entry.Status = "failed"
+
+		if exitErr, ok := err.(ExitCodeError); ok {
+			err = exitErr.Err
+		}
+
+		if reported, ok := err.(reportedError); ok {
+			err = reported.error
+		}
+
+		entry.Error = strings.TrimSpace(err.Error())
+	}
+
+	path := historyPath()
+	entry.ID = lastHistoryID(path) + 1
+
+	// the history must never fail the command it records
+	if err := appendMetric(path, entry); err != nil {
+		notify(cmd, "warning: unable to record the command in the history: %s", err)
+	}
+}
+
+//readHistory returns the entries of the history, oldest first
+func readHistory() (entries []historyEntry, err error) {
+	err = readMetrics(historyPath(), func(line []byte) {
+		var entry historyEntry
+
+		if json.Unmarshal(line, &entry) == nil {
+			entries = append(entries, entry)
+		}
+	})
+
+	return
+}
+
+//historyCommand writes the last --limit (default 20) commands of the history, oldest first:
+//history. Every executed command is recorded with its endpoint, params, exit code and duration,
+//sensitive values redacted
+func historyCommand(cmd Command) (err error) {
+	limit, err := paramUint(cmd, "limit", defaultHistoryLimit)
+
+	if err != nil {
+		return
+	}
+
+	entries, err := readHistory()
+
+	if err != nil {
+		return
+	}
+
+	if uint64(len(entries)) > limit {
+		entries = entries[uint64(len(entries))-limit:]
+	}
+
+	if entries == nil {
+		entries = []historyEntry{}
+	}
+
+	return writeJSON(entries)
+}
+
+//replayCommand runs a command of the history again with the same args: replay <id>. Flags given
+//to replay replace the recorded flags of the same name, redacted flags must be given again, such
+//as replay 12 --seed "..."
+func replayCommand(cmd Command) (err error) {
+	id, err := strconv.Atoi(cmd.Args[1])
+
+	if err != nil {
+		return fmt.Errorf("invalid history id %q", cmd.Args[1])
+	}
+
+	entries, err := readHistory()
+
+	if err != nil {
+		return
+	}
+
+	var entry *historyEntry
+
+	for i := range entries {
+		if entries[i].ID == id {
+			entry = &entries[i]
+		}
+	}
+
+	if entry == nil {
+		return fmt.Errorf("no command %d in the history", id)
+	}
+
+	extra, err := tokenizeArgs(cmd.Flags)
+
+	if err != nil {
+		return
+	}
+
+	given := make(map[string]bool)
+
+	for _, token := range extra {
+		given[token.Flag] = true
+	}
+
+	for _, flag := range entry.Redacted {
+		if !given[flag] {
+			return fmt.Errorf("--%s was redacted from the history, give it again such as replay %d --%s <value>", flag, id, flag)
+		}
+	}
+
+	// every value of a repeated flag is replaced, so each redacted --header must be given again
+
+	recorded, err := tokenizeArgs(entry.Args)
+
+	if err != nil {
+		return
+	}
+
+	var args []string
+
+	for _, token := range recorded {
+		if len(token.Flag) == 0 || !given[token.Flag] {
+			args = append(args, token.Raw...)
+		}
+	}
+
+	args = append(args, cmd.Flags...)
+	notify(cmd, "replaying %d: %s", id, strings.Join(args, " "))
+
+	if code := run(args); code != ExitSuccess {
+		return ExitCodeError{Code: code, Err: reportedError{fmt.Errorf("replay of %d exited with %d", id, code)}}
+	}
+
+	return
+}
@@ -0,0 +1,220 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/n8maninger/siac-json/endpoints"
+)
+
+//completeCommandName the hidden first argument the completion scripts call sia-json with to get
+//the completions of the words typed so far. It is handled before the args are parsed as flags
+const completeCommandName = "__complete"
+
+//valueFlags tool flags that take a value. Flags that do not take a value are in boolFlags
+var valueFlags = []string{
+	"addr", "apipassword", "bundle-path", "cache", "cacert", "compress", "compute",
+	"connect-timeout", "data-binary", "data-raw", "encrypt-key", "endpoints", "fail-on", "file",
+	"format", "header", "json", "keepalive", "limit", "max-interval", "method", "offset", "output",
+	"password-file", "profile", "relock-after", "retry", "retry-delay", "sample", "servername",
+	"ssh", "timeout", "useragent", "watch",
+}
+
+//completionScripts the shell completion scripts. %[1]s is the command name and %[2]s the
+//command name as a shell function name
+var completionScripts = map[string]string{
+	"bash": `_%[2]s_complete() {
+	local IFS=$'\n'
+	COMPREPLY=($(%[1]s ` + completeCommandName + ` "${COMP_WORDS[@]:1:COMP_CWORD}" 2>/dev/null))
+}
+
+complete -o default -F _%[2]s_complete %[1]s
+`,
+	"zsh": `#compdef %[1]s
+
+_%[2]s() {
+	local -a completions
+	completions=(${(f)"$(%[1]s ` + completeCommandName + ` "${(@)words[2,CURRENT]}" 2>/dev/null)"})
+
+	if (( ${#completions} )); then
+		compadd -- $completions
+	else
+		_files
+	fi
+}
+
+compdef _%[2]s %[1]s
+`,
+	"fish": `function __%[2]s_complete
+	set -l tokens (commandline -opc)
+	set -l current (commandline -ct)
+	%[1]s ` + completeCommandName + ` $tokens[2..-1] "$current" 2>/dev/null
+end
+
+complete -c %[1]s -f -a '(__%[2]s_complete)'
+`,
+}
+
+//completionTemplates returns the path templates of the endpoints and builtin commands
+func completionTemplates() (templates []string) {
+	for _, endpoint := range endpoints.All {
+		templates = append(templates, endpoint.Path)
+		templates = append(templates, endpoint.AlternativeMatches...)
+	}
+
+	for _, builtin := range BuiltinCommands {
+		templates = append(templates, builtin.Path)
+	}
+
+	return
+}
+
+//nextSegments returns the literal path segments that can follow the typed segments
+func nextSegments(typed []string) (segments []string) {
+	for _, template := range completionTemplates() {
+		templateSegs := strings.Split(strings.Trim(template, "/"), "/")
+
+		if len(templateSegs) <= len(typed) {
+			continue
+		}
+
+		matched := true
+
+		for i, seg := range typed {
+			if strings.HasPrefix(templateSegs[i], "*") || !strings.HasPrefix(templateSegs[i], ":") && templateSegs[i] != seg {
+				matched = false
+				break
+			}
+		}
+
+		if next := templateSegs[len(typed)]; matched && !strings.HasPrefix(next, ":") && !strings.HasPrefix(next, "*") {
+			segments = append(segments, next)
+		}
+	}
+
+	return
+}
+
+//completeWords returns the completions of the last word given the words before it
+func completeWords(words []string) (completions []string) {
+	if len(words) == 0 {
+		words = []string{""}
+	}
+
+	current, prev := words[len(words)-1], ""
+	var typed []string
+
+	for i := 0; i < len(words)-1; i++ {
+		word := words[i]
+
+		if strings.HasPrefix(word, "--") {
+			prev = strings.ToLower(word[2:])
+
+			// the value of the flag is not part of the path
+			if !boolFlags[prev] && i+1 < len(words)-1 {
+				i++
+				prev = ""
+			}
+
+			continue
+		}
+
+		prev = ""
+
+		for _, seg := range strings.Split(word, "/") {
+			if len(seg) > 0 {
+				typed = append(typed, seg)
+			}
+		}
+	}
+
+	candidates := make(map[string]bool)
+
+	switch {
+	case prev == "method":
+		for _, endpoint := range endpoints.All {
+			candidates[endpoint.Method] = true
+		}
+	case len(prev) > 0 && !boolFlags[prev]:
+		// other flag values such as file names are completed by the shell
+		return
+	case strings.HasPrefix(current, "-"):
+		for flag := range boolFlags {
+			candidates["--"+flag] = true
+		}
+
+		for _, flag := range valueFlags {
+			candidates["--"+flag] = true
+		}
+
+		path := "/" + strings.Join(typed, "/")
+
+		for _, endpoint := range endpoints.All {
+			if len(typed) == 0 || !endpoints.MatchPath(path, endpoint.Path) {
+				continue
+			}
+
+			for _, param := range endpoint.Params {
+				if param.Location != endpoints.URLParam {
+					candidates["--"+param.Key] = true
+				}
+			}
+		}
+	default:
+		// siapaths may be typed with slashes instead of spaces
+		prefix := ""
+
+		if i := strings.LastIndex(current, "/"); i != -1 {
+			prefix = current[:i+1]
+
+			for _, seg := range strings.Split(current[:i], "/") {
+				if len(seg) > 0 {
+					typed = append(typed, seg)
+				}
+			}
+		}
+
+		for _, seg := range nextSegments(typed) {
+			candidates[prefix+seg] = true
+		}
+	}
+
+	for candidate := range candidates {
+		if strings.HasPrefix(candidate, current) {
+			completions = append(completions, candidate)
+		}
+	}
+
+	sort.Strings(completions)
+
+	return
+}
+
+//completeCommand writes the completions of the words typed so far, one per line. Called by the
+//completion scripts
+func completeCommand(words []string) int {
+	for _, completion := range completeWords(words) {
+		os.Stdout.WriteString(completion + "\n")
+	}
+
+	return ExitSuccess
+}
+
+//completionCommand writes the completion script for the shell: completion bash|zsh|fish
+func completionCommand(cmd Command) (err error) {
+	shell := strings.ToLower(cmd.Args[1])
+	script, ok := completionScripts[shell]
+
+	if !ok {
+		return fmt.Errorf("unsupported shell %q, expected bash, zsh or fish", shell)
+	}
+
+	name := strings.TrimSuffix(filepath.Base(os.Args[0]), ".exe")
+
+	_, err = fmt.Fprintf(os.Stdout, script, name, strings.Replace(name, "-", "_", -1))
+
+	return
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"strings"
+)
+
+type (
+	//gzipReader compresses the wrapped reader as it is read
+	gzipReader struct {
+		src   io.Reader
+		gz    *gzip.Writer
+		buf   bytes.Buffer
+		chunk []byte
+		done  bool
+	}
+)
+
+//compressedSuffix the suffix added to the siapath of compressed uploads so downloads know to
+//decompress them
+const compressedSuffix = ".gz"
+
+//newGzipReader returns a reader of r compressed with gzip
+func newGzipReader(r io.Reader) *gzipReader {
+	gr := &gzipReader{
+		src:   r,
+		chunk: make([]byte, 32<<10),
+	}
+	gr.gz = gzip.NewWriter(&gr.buf)
+
+	return gr
+}
+
+//Read reads the compressed data
+func (gr *gzipReader) Read(p []byte) (n int, err error) {
+	for gr.buf.Len() == 0 && !gr.done {
+		read, err := gr.src.Read(gr.chunk)
+
+		if read > 0 {
+			if _, err := gr.gz.Write(gr.chunk[:read]); err != nil {
+				return 0, err
+			}
+		}
+
+		if err == io.EOF {
+			if err = gr.gz.Close(); err != nil {
+				return 0, err
+			}
+
+			gr.done = true
+		} else if err != nil {
+			return 0, err
+		}
+	}
+
+	if gr.buf.Len() == 0 {
+		return 0, io.EOF
+	}
+
+	return gr.buf.Read(p)
+}
+
+//checkCompress checks that --compress is used with a request it can compress or decompress and
+//adds .gz to the siapath, so a file is uploaded and downloaded with the same siapath
+func checkCompress(cmd *Command) (err error) {
+	switch cmd.Compress {
+	case "":
+		return
+	case "gzip":
+	case "zstd":
+		return fmt.Errorf("zstd is not supported, use --compress gzip")
+	default:
+		return fmt.Errorf("invalid --compress value %q, use gzip", cmd.Compress)
+	}
+
+	switch {
+	case cmd.Bundle:
+		return fmt.Errorf("--compress cannot be used with --bundle, bundles are already compressed")
+	case strings.HasPrefix(cmd.Endpoint.Path, "/renter/uploadstream/") && len(cmd.UploadFile) > 0:
+	case cmd.Endpoint.Path == "/renter/download/*siapath":
+		if values := cmd.Params["httpresp"]; len(values) == 0 || values[0] != "true" {
+			return fmt.Errorf("compressed downloads are decompressed locally and need --httpresp true")
+		}
+
+		if len(cmd.Params["offset"]) > 0 || len(cmd.Params["length"]) > 0 {
+			return fmt.Errorf("--offset and --length cannot be used with --compress")
+		}
+	default:
+		return fmt.Errorf("--compress can only be used with renter uploadstream --file and renter download --httpresp true")
+	}
+
+	cmd.RequestPath = compressedPath(cmd.RequestPath)
+
+	return
+}
+
+//compressedPath returns the request path with .gz added to the siapath if it is missing
+func compressedPath(path string) string {
+	if strings.HasSuffix(path, compressedSuffix) {
+		return path
+	}
+
+	return path + compressedSuffix
+}
+
+//decompressedBody returns the download's body decompressed
+func decompressedBody(body io.ReadCloser) (io.ReadCloser, error) {
+	gz, err := gzip.NewReader(body)
+
+	if err != nil {
+		return nil, fmt.Errorf("the download is not compressed: %s", err)
+	}
+
+	return readCloser{
+		Reader: gz,
+		Closer: body,
+	}, nil
+}
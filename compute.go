@@ -0,0 +1,307 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+type (
+	//computedField a parsed --compute expression in the format "name = expression". Names may be
+	//prefixed with the path of the objects they are added to, e.g. "activecontracts.spent"
+	computedField struct {
+		Expression string
+		Path       []string
+		Name       string
+		tokens     []string
+	}
+
+	//exprParser a recursive descent parser evaluating an arithmetic expression over an object
+	exprParser struct {
+		field  computedField
+		tokens []string
+		pos    int
+		obj    interface{}
+		//check only the syntax is checked, field references are not resolved
+		check bool
+	}
+)
+
+//tokenizeExpr splits an expression into numbers, field references, operators and parentheses
+func tokenizeExpr(expr string) (tokens []string, err error) {
+	for i := 0; i < len(expr); {
+		c := rune(expr[i])
+
+		switch {
+		case unicode.IsSpace(c):
+			i++
+		case strings.ContainsRune("+-*/()", c):
+			tokens = append(tokens, string(c))
+			i++
+		case c == '.' || c == '_' || unicode.IsLetter(c) || unicode.IsDigit(c):
+			j := i
+
+			for j < len(expr) && (expr[j] == '.' || expr[j] == '_' || unicode.IsLetter(rune(expr[j])) || unicode.IsDigit(rune(expr[j]))) {
+				// exponents such as 1e-3 belong to the number
+				if (expr[j] == 'e' || expr[j] == 'E') && j+1 < len(expr) && (expr[j+1] == '-' || expr[j+1] == '+') && unicode.IsDigit(c) {
+					j++
+				}
+
+				j++
+			}
+
+			tokens = append(tokens, expr[i:j])
+			i = j
+		default:
+			return nil, fmt.Errorf("unexpected %q", c)
+		}
+	}
+
+	return
+}
+
+//parseComputedField parses a --compute expression such as "spent_sc = totalallocated / 1e24"
+func parseComputedField(expr string) (field computedField, err error) {
+	i := strings.Index(expr, "=")
+
+	if i == -1 {
+		err = fmt.Errorf("invalid --compute expression %q: expected name = expression", expr)
+		return
+	}
+
+	path := strings.Split(strings.TrimSpace(expr[:i]), ".")
+	field = computedField{
+		Expression: expr,
+		Path:       path[:len(path)-1],
+		Name:       path[len(path)-1],
+	}
+
+	if len(field.Name) == 0 {
+		err = fmt.Errorf("invalid --compute expression %q: missing name", expr)
+		return
+	}
+
+	if field.tokens, err = tokenizeExpr(expr[i+1:]); err != nil {
+		err = fmt.Errorf("invalid --compute expression %q: %s", expr, err)
+	} else if len(field.tokens) == 0 {
+		err = fmt.Errorf("invalid --compute expression %q: missing expression", expr)
+	} else if _, err = (&exprParser{field: field, tokens: field.tokens, check: true}).parse(); err != nil {
+		err = fmt.Errorf("invalid --compute expression %s", err)
+	}
+
+	return
+}
+
+//next returns the next token without consuming it
+func (p *exprParser) next() string {
+	if p.pos < len(p.tokens) {
+		return p.tokens[p.pos]
+	}
+
+	return ""
+}
+
+//expr parses terms separated by + and -
+func (p *exprParser) expr() (v *big.Float, err error) {
+	if v, err = p.term(); err != nil {
+		return
+	}
+
+	for op := p.next(); op == "+" || op == "-"; op = p.next() {
+		p.pos++
+		rhs, err := p.term()
+
+		if err != nil {
+			return nil, err
+		}
+
+		if op == "+" {
+			v.Add(v, rhs)
+		} else {
+			v.Sub(v, rhs)
+		}
+	}
+
+	return
+}
+
+//term parses factors separated by * and /
+func (p *exprParser) term() (v *big.Float, err error) {
+	if v, err = p.factor(); err != nil {
+		return
+	}
+
+	for op := p.next(); op == "*" || op == "/"; op = p.next() {
+		p.pos++
+		rhs, err := p.factor()
+
+		if err != nil {
+			return nil, err
+		}
+
+		if op == "*" {
+			v.Mul(v, rhs)
+		} else if p.check {
+			continue
+		} else if rhs.Sign() == 0 {
+			return nil, fmt.Errorf("%s: division by zero", p.field.Expression)
+		} else {
+			v.Quo(v, rhs)
+		}
+	}
+
+	return
+}
+
+//factor parses a number, a field reference, a negation or a parenthesized expression
+func (p *exprParser) factor() (v *big.Float, err error) {
+	tok := p.next()
+	p.pos++
+
+	switch {
+	case tok == "":
+		return nil, fmt.Errorf("%s: unexpected end of expression", p.field.Expression)
+	case tok == "-":
+		if v, err = p.factor(); err != nil {
+			return
+		}
+
+		return v.Neg(v), nil
+	case tok == "(":
+		if v, err = p.expr(); err != nil {
+			return
+		}
+
+		if p.next() != ")" {
+			return nil, fmt.Errorf("%s: missing )", p.field.Expression)
+		}
+
+		p.pos++
+
+		return
+	case unicode.IsDigit(rune(tok[0])) || tok[0] == '.':
+		if f, ok := parseNumber(tok); ok {
+			return f, nil
+		}
+
+		return nil, fmt.Errorf("%s: invalid number %s", p.field.Expression, tok)
+	case strings.ContainsRune("+*/)", rune(tok[0])):
+		return nil, fmt.Errorf("%s: unexpected %s", p.field.Expression, tok)
+	}
+
+	if p.check {
+		return new(big.Float), nil
+	}
+
+	value, ok := walkField(p.obj, strings.Split(tok, "."))
+
+	if !ok {
+		return nil, fmt.Errorf("%s: %s", tok, errFieldNotFound)
+	}
+
+	// hastings values are numeric strings
+	switch value.(type) {
+	case json.Number, string:
+		if f, ok := parseNumber(fmt.Sprint(value)); ok {
+			return f, nil
+		}
+	}
+
+	return nil, fmt.Errorf("%s: %s is not a number", p.field.Expression, tok)
+}
+
+//parse parses the whole expression, tokens left after it are an error
+func (p *exprParser) parse() (v *big.Float, err error) {
+	if v, err = p.expr(); err != nil {
+		return
+	}
+
+	if p.pos < len(p.tokens) {
+		return nil, fmt.Errorf("%s: unexpected %s", p.field.Expression, p.next())
+	}
+
+	return
+}
+
+//evaluate evaluates the field's expression with references resolved against obj
+func (field computedField) evaluate(obj interface{}) (json.Number, error) {
+	v, err := (&exprParser{field: field, tokens: field.tokens, obj: obj}).parse()
+
+	if err != nil {
+		return "", err
+	}
+
+	// whole numbers such as hastings are kept exact
+	if v.IsInt() {
+		return json.Number(v.Text('f', 0)), nil
+	}
+
+	f, _ := v.Float64()
+
+	return json.Number(strconv.FormatFloat(f, 'g', -1, 64)), nil
+}
+
+//apply adds the computed field to each object at the field's path. Arrays of objects have the
+//field added to each element
+func (field computedField) apply(root interface{}) error {
+	target, ok := walkField(root, field.Path)
+
+	if !ok {
+		return fmt.Errorf("%s: %s", strings.Join(field.Path, "."), errFieldNotFound)
+	}
+
+	objects := []interface{}{target}
+
+	if arr, ok := target.([]interface{}); ok {
+		objects = arr
+	}
+
+	for _, elem := range objects {
+		obj, ok := elem.(map[string]interface{})
+
+		if !ok {
+			continue
+		}
+
+		value, err := field.evaluate(obj)
+
+		if err != nil {
+			return err
+		}
+
+		obj[field.Name] = value
+	}
+
+	return nil
+}
+
+//computeFields adds the command's --compute fields to the response body. Fields are computed in
+//order so later expressions can reference earlier computed fields
+func computeFields(cmd Command, body []byte) (computed []byte, err error) {
+	var obj interface{}
+
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.UseNumber()
+
+	if err = dec.Decode(&obj); err != nil {
+		return nil, fmt.Errorf("unable to evaluate --compute, response is not JSON: %s", err)
+	}
+
+	for _, expr := range cmd.Compute {
+		field, err := parseComputedField(expr)
+
+		if err != nil {
+			return nil, err
+		}
+
+		if err = field.apply(obj); err != nil {
+			return nil, err
+		}
+	}
+
+	return json.Marshal(obj)
+}
@@ -0,0 +1,297 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/n8maninger/siac-json/client"
+	"github.com/n8maninger/siac-json/format"
+)
+
+type (
+	//Config persistent settings loaded from config.toml in the config dir
+	Config struct {
+		Address string
+		//Addresses the addresses of equivalent daemons, --select-fastest chooses one
+		Addresses       []string
+		UserAgent       string
+		APIPassword     string
+		APIPasswordFile string
+		Format          string
+		Timeout         time.Duration
+		Cache           time.Duration
+		CACert          string
+		Insecure        bool
+		ServerName      string
+		//Headers "Name: value" headers sent with every request
+		Headers []string
+		//Detect detects the daemon on every command to select the endpoints of its version
+		Detect bool
+		//RestartHook a shell command that restarts siad, run by rotate-apipassword
+		RestartHook string
+		//Endpoints JSON files of endpoint definitions merged into the endpoint table
+		Endpoints []string
+		//BackupRetention the snapshots kept by backup prune, from the [backup] table
+		BackupRetention *backupRetention
+		//Hooks the recipes hooks serve exposes over HTTP by name
+		Hooks map[string]Hook
+		//Profiles named connection settings selected with --profile
+		Profiles map[string]Config
+		//Recipes named sequences of API calls run with sia-json run
+		Recipes map[string]Recipe
+	}
+)
+
+var (
+	//DefaultConfig the config loaded from the config file
+	DefaultConfig Config
+)
+
+//ConfigPath returns the path of the config file
+func ConfigPath() string {
+	return filepath.Join(DefaultConfigDir(), "config.toml")
+}
+
+//configString returns the string value of the key or an error if it is not a string
+func configString(table map[string]interface{}, key string) (value string, err error) {
+	v, ok := table[key]
+
+	if !ok {
+		return
+	}
+
+	if value, ok = v.(string); !ok {
+		err = fmt.Errorf("%s must be a string", key)
+	}
+
+	return
+}
+
+//configBool returns the boolean value of the key or an error if it is not a boolean
+func configBool(table map[string]interface{}, key string) (value bool, err error) {
+	v, ok := table[key]
+
+	if !ok {
+		return
+	}
+
+	if value, ok = v.(bool); !ok {
+		err = fmt.Errorf("%s must be true or false", key)
+	}
+
+	return
+}
+
+//configInt returns the integer value of the key, or def if it is not set
+func configInt(table map[string]interface{}, key string, def int) (value int, err error) {
+	v, ok := table[key]
+
+	if !ok {
+		return def, nil
+	}
+
+	i, ok := v.(int64)
+
+	if !ok || i < 0 {
+		return 0, fmt.Errorf("%s must be a positive integer", key)
+	}
+
+	return int(i), nil
+}
+
+//configDuration returns the duration value of the key. Durations are written as strings "30s"
+func configDuration(table map[string]interface{}, key string) (value time.Duration, err error) {
+	str, err := configString(table, key)
+
+	if err != nil || len(str) == 0 {
+		return
+	}
+
+	if value, err = format.ParseDuration(str); err != nil {
+		err = fmt.Errorf("%s: %s", key, err)
+	}
+
+	return
+}
+
+//parseConfigTable reads the connection settings from a config table
+func parseConfigTable(table map[string]interface{}) (config Config, err error) {
+	if config.Address, err = configString(table, "address"); err != nil {
+		return
+	}
+
+	if config.UserAgent, err = configString(table, "user_agent"); err != nil {
+		return
+	}
+
+	if config.APIPassword, err = configString(table, "api_password"); err != nil {
+		return
+	}
+
+	if config.APIPasswordFile, err = configString(table, "api_password_file"); err != nil {
+		return
+	}
+
+	if config.Format, err = configString(table, "format"); err != nil {
+		return
+	}
+
+	if config.Timeout, err = configDuration(table, "timeout"); err != nil {
+		return
+	}
+
+	if config.Cache, err = configDuration(table, "cache"); err != nil {
+		return
+	}
+
+	if config.CACert, err = configString(table, "ca_cert"); err != nil {
+		return
+	}
+
+	if config.Insecure, err = configBool(table, "insecure"); err != nil {
+		return
+	}
+
+	if config.ServerName, err = configString(table, "server_name"); err != nil {
+		return
+	}
+
+	if config.Addresses, err = configStrings(table, "addresses"); err != nil {
+		return
+	}
+
+	if config.Headers, err = configStrings(table, "headers"); err != nil {
+		return
+	}
+
+	for _, header := range config.Headers {
+		if _, _, err = parseHeader(header); err != nil {
+			return
+		}
+	}
+
+	for _, address := range append([]string{config.Address}, config.Addresses...) {
+		if len(address) == 0 || isDiscoveryAddress(address) {
+			continue
+		}
+
+		if _, err = client.ParseAddress(address); err != nil {
+			return
+		}
+	}
+
+	if config.Detect, err = configBool(table, "detect"); err != nil {
+		return
+	}
+
+	if config.RestartHook, err = configString(table, "restart_hook"); err != nil {
+		return
+	}
+
+	if config.Endpoints, err = configStrings(table, "endpoints"); err != nil {
+		return
+	}
+
+	if config.Recipes, err = parseRecipes(table); err != nil {
+		return
+	}
+
+	if config.Hooks, err = parseHooks(table); err != nil {
+		return
+	}
+
+	if backup, ok := table["backup"].(map[string]interface{}); ok {
+		if config.BackupRetention, err = parseBackupRetention(backup); err != nil {
+			return config, fmt.Errorf("backup: %s", err)
+		}
+	}
+
+	profiles, ok := table["profiles"].(map[string]interface{})
+
+	if !ok {
+		return
+	}
+
+	config.Profiles = make(map[string]Config)
+
+	for name, v := range profiles {
+		profileTable, ok := v.(map[string]interface{})
+
+		if !ok {
+			return config, fmt.Errorf("profile %s must be a table", name)
+		}
+
+		profile, err := parseConfigTable(profileTable)
+
+		if err != nil {
+			return config, fmt.Errorf("profile %s: %s", name, err)
+		}
+
+		config.Profiles[name] = profile
+	}
+
+	return
+}
+
+//LoadConfig loads the config file. A missing config file returns an empty config
+func LoadConfig() (config Config, err error) {
+	f, err := os.Open(ConfigPath())
+
+	if os.IsNotExist(err) {
+		err = nil
+		return
+	} else if err != nil {
+		return
+	}
+
+	defer f.Close()
+
+	doc, err := decodeTOML(f)
+
+	if err != nil {
+		err = fmt.Errorf("%s: %s", ConfigPath(), err)
+		return
+	}
+
+	if config, err = parseConfigTable(doc); err != nil {
+		err = fmt.Errorf("%s: %s", ConfigPath(), err)
+	}
+
+	return
+}
+
+//applyTLSConfig applies the config's TLS settings to the command
+func applyTLSConfig(cmd *Command, config Config) {
+	if len(config.CACert) > 0 {
+		cmd.TLSCACert = config.CACert
+	}
+
+	if config.Insecure {
+		cmd.TLSInsecure = true
+	}
+
+	if len(config.ServerName) > 0 {
+		cmd.TLSServerName = config.ServerName
+	}
+}
+
+//Password returns the API password configured directly or through a password file
+func (c Config) Password() (password string, err error) {
+	if len(c.APIPassword) > 0 || len(c.APIPasswordFile) == 0 {
+		return c.APIPassword, nil
+	}
+
+	buf, err := ioutil.ReadFile(c.APIPasswordFile)
+
+	if err != nil {
+		return
+	}
+
+	password = strings.TrimSpace(string(buf))
+
+	return
+}
@@ -0,0 +1,233 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+)
+
+type (
+	//conformanceDifference a structural difference between a baseline and a live response
+	conformanceDifference struct {
+		//Change added, removed or type
+		Change   string `json:"change"`
+		Field    string `json:"field"`
+		Baseline string `json:"baseline,omitempty"`
+		Live     string `json:"live,omitempty"`
+	}
+
+	//conformanceResult the result of comparing an endpoint with its baseline
+	conformanceResult struct {
+		Path string `json:"path"`
+		//Status pass, fail, error or updated
+		Status      string                  `json:"status"`
+		Error       string                  `json:"error,omitempty"`
+		Differences []conformanceDifference `json:"differences,omitempty"`
+	}
+
+	//conformanceReport the result of conformance
+	conformanceReport struct {
+		Baseline string              `json:"baseline"`
+		Version  string              `json:"version,omitempty"`
+		Passed   int                 `json:"passed"`
+		Failed   int                 `json:"failed"`
+		Results  []conformanceResult `json:"results"`
+	}
+)
+
+//jsonType returns the JSON type of a decoded value
+func jsonType(v interface{}) string {
+	switch v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case float64:
+		return "number"
+	case string:
+		return "string"
+	case []interface{}:
+		return "array"
+	}
+
+	return "object"
+}
+
+//compareStructure appends the structural differences between the baseline and live values:
+//fields added or removed and fields whose type changed. Values are not compared. null is
+//compatible with every type and array elements are compared with the first baseline element
+func compareStructure(baseline, live interface{}, field string, diffs *[]conformanceDifference) {
+	if baseline == nil || live == nil {
+		return
+	}
+
+	baseType, liveType := jsonType(baseline), jsonType(live)
+
+	if baseType != liveType {
+		*diffs = append(*diffs, conformanceDifference{Change: "type", Field: field, Baseline: baseType, Live: liveType})
+		return
+	}
+
+	join := func(key string) string {
+		if len(field) == 0 {
+			return key
+		}
+
+		return field + "." + key
+	}
+
+	switch b := baseline.(type) {
+	case map[string]interface{}:
+		l := live.(map[string]interface{})
+		keys := make([]string, 0, len(b)+len(l))
+
+		for key := range b {
+			keys = append(keys, key)
+		}
+
+		for key := range l {
+			if _, ok := b[key]; !ok {
+				keys = append(keys, key)
+			}
+		}
+
+		sort.Strings(keys)
+
+		for _, key := range keys {
+			bv, inBase := b[key]
+			lv, inLive := l[key]
+
+			switch {
+			case !inLive:
+				*diffs = append(*diffs, conformanceDifference{Change: "removed", Field: join(key), Baseline: jsonType(bv)})
+			case !inBase:
+				*diffs = append(*diffs, conformanceDifference{Change: "added", Field: join(key), Live: jsonType(lv)})
+			default:
+				compareStructure(bv, lv, join(key), diffs)
+			}
+		}
+	case []interface{}:
+		l := live.([]interface{})
+
+		// an empty array has no structure to compare
+		if len(b) == 0 {
+			return
+		}
+
+		for i, lv := range l {
+			compareStructure(b[0], lv, join(strconv.Itoa(i)), diffs)
+		}
+	}
+}
+
+//conformanceCommand compares the structure of the responses of GET endpoints with the
+//baselines in the --baseline directory: conformance --baseline dir/. Baselines are fixture files
+//written by fixtures capture or by --update. Every baseline in the directory is checked unless
+//--endpoints names the endpoints to check. Exits 4 if a response no longer matches its baseline,
+//so it can gate a release in CI
+func conformanceCommand(cmd Command) (err error) {
+	var dir string
+
+	if values := cmd.Params["baseline"]; len(values) > 0 {
+		dir = values[0]
+	}
+
+	if len(dir) == 0 {
+		return fmt.Errorf("--baseline is required")
+	}
+
+	paths, err := fixtureEndpoints(cmd)
+
+	if err != nil {
+		return
+	}
+
+	_, update := cmd.Params["update"]
+
+	if len(paths) == 0 && update {
+		return fmt.Errorf("--update requires --endpoints")
+	} else if len(paths) == 0 {
+		names, err := filepath.Glob(filepath.Join(dir, "GET_*.json"))
+
+		if err != nil {
+			return err
+		}
+
+		for _, name := range names {
+			f, err := readFixture(name)
+
+			if err != nil {
+				return err
+			}
+
+			paths = append(paths, f.Path)
+		}
+
+		if len(paths) == 0 {
+			return fmt.Errorf("no baselines in %s", dir)
+		}
+	}
+
+	if update {
+		if err = os.MkdirAll(dir, 0700); err != nil {
+			return
+		}
+	}
+
+	var version daemonVersionResponse
+
+	// the version is informational, daemons without /daemon/version can still be checked
+	callAPI(cmd, "GET", "/daemon/version", nil, &version)
+
+	report := conformanceReport{
+		Baseline: dir,
+		Version:  version.Version,
+		Results:  []conformanceResult{},
+	}
+
+	for _, path := range paths {
+		result := conformanceResult{Path: path}
+		var live interface{}
+
+		if err := callAPI(cmd, "GET", path, nil, &live); err != nil {
+			result.Status, result.Error = "error", err.Error()
+		} else if update {
+			if _, _, err := writeFixture(dir, path, version.Version, live); err != nil {
+				return err
+			}
+
+			result.Status = "updated"
+		} else if baseline, err := readFixture(filepath.Join(dir, fixtureName("GET", path))); err != nil {
+			result.Status, result.Error = "error", err.Error()
+		} else {
+			compareStructure(baseline.Response, live, "", &result.Differences)
+			result.Status = "pass"
+
+			if len(result.Differences) > 0 {
+				result.Status = "fail"
+			}
+		}
+
+		switch result.Status {
+		case "pass", "updated":
+			report.Passed++
+		default:
+			report.Failed++
+		}
+
+		notify(cmd, "%s %s, %d differences", path, result.Status, len(result.Differences))
+		report.Results = append(report.Results, result)
+	}
+
+	if err = writeJSON(report); err != nil {
+		return
+	}
+
+	if report.Failed > 0 {
+		return ExitCodeError{Code: ExitAssertionFailed, Err: reportedError{fmt.Errorf("%d of %d endpoints do not conform to %s", report.Failed, len(paths), dir)}}
+	}
+
+	return
+}
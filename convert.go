@@ -0,0 +1,77 @@
+package main
+
+import (
+	"fmt"
+	"math/big"
+	"regexp"
+	"strconv"
+
+	"github.com/n8maninger/siac-json/endpoints"
+	"github.com/n8maninger/siac-json/format"
+)
+
+//hastingsRe matches a price already in hastings, which is sent as is
+var hastingsRe = regexp.MustCompile(`^[0-9]+$`)
+
+//paramConverters convert the friendly values of formatted params to the values siad expects
+var paramConverters = map[endpoints.ParamFormat]func(string) (string, error){
+	endpoints.DataFormat: func(value string) (string, error) {
+		n, err := format.ParseBytes(value)
+
+		return strconv.FormatUint(n, 10), err
+	},
+	endpoints.BlockTimeFormat: func(value string) (string, error) {
+		n, err := format.ParseBlocks(value)
+
+		return strconv.FormatUint(n, 10), err
+	},
+	endpoints.PriceFormat: func(value string) (string, error) {
+		return convertPrice(value, format.ParsePrice)
+	},
+	endpoints.MonthlyPriceFormat: func(value string) (string, error) {
+		return convertPrice(value, format.ParseMonthlyPrice)
+	},
+}
+
+//convertPrice converts a price with a unit to hastings. Prices without a unit are already in
+//hastings
+func convertPrice(value string, parse func(string) (*big.Int, error)) (string, error) {
+	if hastingsRe.MatchString(value) {
+		return value, nil
+	}
+
+	h, err := parse(value)
+
+	if err != nil {
+		return "", err
+	}
+
+	return h.String(), nil
+}
+
+//convertParams converts the values of the command's formatted params, such as 10TB to bytes and
+//100SC to hastings, before the request is sent. Values already in siad's format are left as they
+//are
+func convertParams(cmd *Command) error {
+	for _, param := range cmd.Endpoint.Params {
+		convert, ok := paramConverters[param.Formatter]
+
+		if !ok || param.Location == endpoints.URLParam {
+			continue
+		}
+
+		values := cmd.Params[param.Key]
+
+		for i, value := range values {
+			converted, err := convert(value)
+
+			if err != nil {
+				return fmt.Errorf("invalid --%s value: %s", param.Key, err)
+			}
+
+			values[i] = converted
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+)
+
+//shellQuote quotes s for a POSIX shell
+func shellQuote(s string) string {
+	if len(s) > 0 && strings.IndexFunc(s, func(r rune) bool {
+		return !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || strings.ContainsRune("-_./:@=%+,", r))
+	}) == -1 {
+		return s
+	}
+
+	return "'" + strings.Replace(s, "'", `'\''`, -1) + "'"
+}
+
+//curlData returns the curl arguments that send the request body
+func curlData(cmd Command, req *http.Request) (args []string, err error) {
+	switch {
+	case len(cmd.UploadFile) > 0:
+		return []string{"--data-binary", "@" + cmd.UploadFile}, nil
+	case strings.HasPrefix(cmd.JSONBody, "@"):
+		return []string{"--data-binary", cmd.JSONBody}, nil
+	case len(cmd.JSONBody) > 0:
+		return []string{"--data-binary", cmd.JSONBody}, nil
+	case len(cmd.RawBodyFile) > 0:
+		return []string{"--data-binary", "@" + cmd.RawBodyFile}, nil
+	case cmd.RawBodySet:
+		return []string{"--data-binary", cmd.RawBody}, nil
+	case req.Body == nil || req.Body == http.NoBody:
+		return
+	case req.GetBody == nil:
+		// a body piped to sia-json is piped to curl instead
+		return []string{"--data-binary", "@-"}, nil
+	}
+
+	body, err := req.GetBody()
+
+	if err != nil {
+		return
+	}
+
+	defer body.Close()
+
+	buf, err := ioutil.ReadAll(body)
+
+	if err != nil {
+		return
+	}
+
+	return []string{"--data", string(buf)}, nil
+}
+
+//writeCurlCommand writes a curl command line that sends the same request
+func writeCurlCommand(cmd Command, req *http.Request) (err error) {
+	args := []string{"curl", "-X", req.Method}
+
+	if _, password, ok := req.BasicAuth(); ok {
+		args = append(args, "--user", ":"+password)
+	}
+
+	args = append(args, "-A", req.UserAgent())
+
+	if contentType := req.Header.Get("Content-Type"); len(contentType) > 0 && contentType != "application/x-www-form-urlencoded" {
+		args = append(args, "-H", "Content-Type: "+contentType)
+	}
+
+	names := make([]string, 0, len(cmd.Header))
+
+	for name := range cmd.Header {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	for _, name := range names {
+		// the user agent and content type were written above
+		if name == "User-Agent" || name == "Content-Type" {
+			continue
+		}
+
+		for _, value := range cmd.Header[name] {
+			args = append(args, "-H", name+": "+value)
+		}
+	}
+
+	if cmd.TLSInsecure {
+		args = append(args, "--insecure")
+	}
+
+	if len(cmd.TLSCACert) > 0 {
+		args = append(args, "--cacert", cmd.TLSCACert)
+	}
+
+	if cmd.ConnectTimeout > 0 {
+		args = append(args, "--connect-timeout", fmt.Sprintf("%g", cmd.ConnectTimeout.Seconds()))
+	}
+
+	if timeout := requestTimeout(cmd); timeout > 0 {
+		args = append(args, "--max-time", fmt.Sprintf("%g", timeout.Seconds()))
+	}
+
+	data, err := curlData(cmd, req)
+
+	if err != nil {
+		return
+	}
+
+	args = append(args, data...)
+
+	if len(cmd.OutputFile) > 0 {
+		args = append(args, "--output", cmd.OutputFile)
+	}
+
+	args = append(args, req.URL.String())
+
+	for i := range args {
+		args[i] = shellQuote(args[i])
+	}
+
+	_, err = os.Stdout.WriteString(strings.Join(args, " ") + "\n")
+
+	return
+}
@@ -0,0 +1,176 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/n8maninger/siac-json/endpoints"
+)
+
+type (
+	//daemonInfo the kind and version of the daemon at an address
+	daemonInfo struct {
+		Address string `json:"address"`
+		//Kind siad, renterd or hostd
+		Kind    string `json:"kind"`
+		Version string `json:"version"`
+		//Endpoints the number of endpoints of the table available in the daemon
+		Endpoints int `json:"endpoints"`
+	}
+)
+
+const (
+	daemonSiad    = "siad"
+	daemonRenterd = "renterd"
+	daemonHostd   = "hostd"
+)
+
+//detectedDaemons the daemons detected by address, so each is only asked once per invocation
+var detectedDaemons = make(map[string]daemonInfo)
+
+//detectDaemon returns the kind and version of the command's daemon. The version endpoint of
+//siad is tried first, then the state endpoints of renterd and hostd
+func detectDaemon(cmd Command) (info daemonInfo, err error) {
+	if info, ok := detectedDaemons[cmd.APIAddress]; ok {
+		return info, nil
+	}
+
+	probes := []struct {
+		kind, path string
+	}{
+		{daemonSiad, "/daemon/version"},
+		{daemonRenterd, "/api/bus/state"},
+		{daemonHostd, "/api/state"},
+	}
+
+	for _, probe := range probes {
+		var version daemonVersionResponse
+
+		if err = callAPI(cmd, "GET", probe.path, nil, &version); err == nil {
+			info = daemonInfo{
+				Address: cmd.APIAddress,
+				Kind:    probe.kind,
+				Version: version.Version,
+			}
+			detectedDaemons[cmd.APIAddress] = info
+
+			return
+		} else if _, ok := err.(APIError); !ok {
+			// the daemon could not be reached, the other probes would fail the same way
+			return
+		}
+	}
+
+	return info, fmt.Errorf("unable to detect the daemon at %s: %s", cmd.APIAddress, err)
+}
+
+//availableIn reports whether the endpoint exists in the daemon. Only siad implements the
+//endpoint table, endpoints added in a later siad version are not available
+func availableIn(endpoint endpoints.CommandEndpoint, info daemonInfo) bool {
+	if info.Kind != daemonSiad {
+		return false
+	}
+
+	return len(endpoint.AddedIn) == 0 || compareVersions(info.Version, endpoint.AddedIn) >= 0
+}
+
+//selectEndpoints returns the matched endpoints of the detected daemon's table: the siad
+//endpoints available in its version. siad endpoints are not used for renterd and hostd, their
+//paths must be given with --method. Endpoints the daemon does not have are only kept when
+//nothing else matched, so checkAvailable can warn about them
+func selectEndpoints(matched []endpoints.CommandEndpoint, info daemonInfo) (selected []endpoints.CommandEndpoint) {
+	if info.Kind != daemonSiad {
+		return nil
+	}
+
+	for _, endpoint := range matched {
+		if availableIn(endpoint, info) {
+			selected = append(selected, endpoint)
+		}
+	}
+
+	if len(selected) == 0 {
+		return matched
+	}
+
+	return
+}
+
+//applyDetection detects the daemon when --detect is given or detect is set in the config file,
+//warning when it is renterd or hostd. A daemon that cannot be reached is not an error here, the
+//request itself reports it
+func applyDetection(cmd *Command) (info daemonInfo, ok bool) {
+	if !cmd.Detect {
+		return
+	}
+
+	info, err := detectDaemon(*cmd)
+
+	if err != nil {
+		notify(*cmd, "warning: %s", err)
+		return
+	}
+
+	if info.Kind != daemonSiad {
+		warning := endpointWarning{
+			Type:    "daemon",
+			Message: fmt.Sprintf("%s is %s %s, the endpoint table only describes siad. Give the request method to send the path as is", cmd.APIAddress, info.Kind, info.Version),
+		}
+
+		if cmd.Machine {
+			cmd.Warnings = append(cmd.Warnings, warning)
+		} else {
+			notify(*cmd, "warning: %s", warning.Message)
+		}
+	}
+
+	return info, true
+}
+
+//checkAvailable warns when the endpoint was added in a later siad version than the connected
+//daemon's. The daemon is only asked for its version for endpoints with a known version
+func checkAvailable(cmd *Command) {
+	endpoint := cmd.Endpoint
+
+	if len(endpoint.AddedIn) == 0 {
+		return
+	}
+
+	info, err := detectDaemon(*cmd)
+
+	// without a version the warning could be wrong, the request itself will report the error
+	if err != nil || info.Kind != daemonSiad || availableIn(endpoint, info) {
+		return
+	}
+
+	warning := endpointWarning{
+		Type:     "unavailable",
+		Endpoint: endpoint.Path,
+		Method:   endpoint.Method,
+		Since:    endpoint.AddedIn,
+		Message:  fmt.Sprintf("%s %s was added in siad %s but %s runs %s", endpoint.Method, endpoint.Path, endpoint.AddedIn, cmd.APIAddress, info.Version),
+	}
+
+	if cmd.Machine {
+		cmd.Warnings = append(cmd.Warnings, warning)
+	} else {
+		notify(*cmd, "warning: %s", warning.Message)
+	}
+}
+
+//detectCommand writes the kind and version of the daemon and how many endpoints of the table it
+//has: detect
+func detectCommand(cmd Command) (err error) {
+	info, err := detectDaemon(cmd)
+
+	if err != nil {
+		return
+	}
+
+	for _, endpoint := range endpoints.All {
+		if availableIn(endpoint, info) {
+			info.Endpoints++
+		}
+	}
+
+	return writeJSON(info)
+}
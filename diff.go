@@ -0,0 +1,91 @@
+package main
+
+import (
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+type (
+	//patchOp a JSON patch (RFC 6902) operation describing a change between two responses
+	patchOp struct {
+		Op    string      `json:"op"`
+		Path  string      `json:"path"`
+		Value interface{} `json:"value,omitempty"`
+	}
+)
+
+//jsonPointerEscaper escapes a key for use in a JSON pointer
+var jsonPointerEscaper = strings.NewReplacer("~", "~0", "/", "~1")
+
+//diffJSON returns the patch operations that turn prev into cur. Objects are compared key by key
+//and arrays element by element, so only the fields that changed are reported
+func diffJSON(prev, cur interface{}, path string) (ops []patchOp) {
+	switch p := prev.(type) {
+	case map[string]interface{}:
+		c, ok := cur.(map[string]interface{})
+
+		if !ok {
+			break
+		}
+
+		keys := make([]string, 0, len(p)+len(c))
+
+		for key := range p {
+			keys = append(keys, key)
+		}
+
+		for key := range c {
+			if _, ok := p[key]; !ok {
+				keys = append(keys, key)
+			}
+		}
+
+		sort.Strings(keys)
+
+		for _, key := range keys {
+			child := path + "/" + jsonPointerEscaper.Replace(key)
+			pv, inPrev := p[key]
+			cv, inCur := c[key]
+
+			switch {
+			case !inCur:
+				ops = append(ops, patchOp{Op: "remove", Path: child})
+			case !inPrev:
+				ops = append(ops, patchOp{Op: "add", Path: child, Value: cv})
+			default:
+				ops = append(ops, diffJSON(pv, cv, child)...)
+			}
+		}
+
+		return
+	case []interface{}:
+		c, ok := cur.([]interface{})
+
+		if !ok {
+			break
+		}
+
+		for i := 0; i < len(p) && i < len(c); i++ {
+			ops = append(ops, diffJSON(p[i], c[i], path+"/"+strconv.Itoa(i))...)
+		}
+
+		for i := len(p); i < len(c); i++ {
+			ops = append(ops, patchOp{Op: "add", Path: path + "/" + strconv.Itoa(i), Value: c[i]})
+		}
+
+		// removed from the end so the indexes of the remaining elements do not shift
+		for i := len(p) - 1; i >= len(c); i-- {
+			ops = append(ops, patchOp{Op: "remove", Path: path + "/" + strconv.Itoa(i)})
+		}
+
+		return
+	}
+
+	if !reflect.DeepEqual(prev, cur) {
+		ops = append(ops, patchOp{Op: "replace", Path: path, Value: cur})
+	}
+
+	return
+}
@@ -0,0 +1,172 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/n8maninger/siac-json/client"
+)
+
+//the prefixes of addresses resolved to the daemons of a service when the command runs
+const (
+	srvPrefix    = "srv:"
+	consulPrefix = "consul:"
+)
+
+//defaultConsulAddress the address of the local Consul agent, unless CONSUL_HTTP_ADDR is set
+const defaultConsulAddress = "127.0.0.1:8500"
+
+//isDiscoveryAddress reports whether the address names a service instead of a daemon, such as
+//srv:_sia._tcp.example.com or consul:siad
+func isDiscoveryAddress(address string) bool {
+	return strings.HasPrefix(address, srvPrefix) || strings.HasPrefix(address, consulPrefix)
+}
+
+//lookupSRV returns the targets of a DNS SRV record in the order they should be tried: by
+//priority, randomized by weight
+func lookupSRV(name string) (addresses []string, err error) {
+	_, records, err := net.LookupSRV("", "", name)
+
+	if err != nil {
+		return nil, fmt.Errorf("unable to look up %s: %s", name, err)
+	}
+
+	for _, record := range records {
+		addresses = append(addresses, net.JoinHostPort(strings.TrimSuffix(record.Target, "."), strconv.Itoa(int(record.Port))))
+	}
+
+	return
+}
+
+//lookupConsul returns the addresses of the passing instances of a service registered with the
+//Consul agent at CONSUL_HTTP_ADDR, authenticated with CONSUL_HTTP_TOKEN if set
+func lookupConsul(cmd Command, service string) (addresses []string, err error) {
+	agent := os.Getenv("CONSUL_HTTP_ADDR")
+
+	if len(agent) == 0 {
+		agent = defaultConsulAddress
+	}
+
+	base, err := client.ParseAddress(agent)
+
+	if err != nil {
+		return nil, fmt.Errorf("invalid CONSUL_HTTP_ADDR: %s", err)
+	}
+
+	req, err := http.NewRequest("GET", base.String()+"/v1/health/service/"+url.PathEscape(service)+"?passing=true", nil)
+
+	if err != nil {
+		return
+	}
+
+	if token := os.Getenv("CONSUL_HTTP_TOKEN"); len(token) > 0 {
+		req.Header.Set("X-Consul-Token", token)
+	}
+
+	// the agent is local infrastructure rather than a daemon, the API's TLS settings do not apply
+	resp, err := (&http.Client{Timeout: cmd.ConnectTimeout}).Do(req)
+
+	if err != nil {
+		return nil, fmt.Errorf("unable to query consul for %s: %s", service, err)
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unable to query consul for %s: %s", service, resp.Status)
+	}
+
+	var entries []struct {
+		Node struct {
+			Address string
+		}
+		Service struct {
+			Address string
+			Port    int
+		}
+	}
+
+	if err = json.NewDecoder(resp.Body).Decode(&entries); err != nil {
+		return nil, fmt.Errorf("unable to decode consul response: %s", err)
+	}
+
+	for _, entry := range entries {
+		host := entry.Service.Address
+
+		// services registered without an address are reached at their node's address
+		if len(host) == 0 {
+			host = entry.Node.Address
+		}
+
+		addresses = append(addresses, net.JoinHostPort(host, strconv.Itoa(entry.Service.Port)))
+	}
+
+	return
+}
+
+//resolveAddress returns the daemons of a srv: or consul: address, or the address itself
+func resolveAddress(cmd Command, address string) (addresses []string, err error) {
+	switch {
+	case strings.HasPrefix(address, srvPrefix):
+		addresses, err = lookupSRV(strings.TrimPrefix(address, srvPrefix))
+	case strings.HasPrefix(address, consulPrefix):
+		addresses, err = lookupConsul(cmd, strings.TrimPrefix(address, consulPrefix))
+	default:
+		return []string{address}, nil
+	}
+
+	if err == nil && len(addresses) == 0 {
+		err = fmt.Errorf("%s has no daemons", address)
+	}
+
+	return
+}
+
+//resolveDiscovery expands the srv: and consul: addresses of the command to the daemons they
+//name. The first daemon of an address is sent the request, and --select-fastest chooses among
+//all of them
+func resolveDiscovery(cmd *Command) (err error) {
+	if !isDiscoveryAddress(cmd.APIAddress) {
+		discovery := false
+
+		for _, address := range cmd.Addresses {
+			discovery = discovery || isDiscoveryAddress(address)
+		}
+
+		if !discovery {
+			return
+		}
+	}
+
+	addresses := cmd.Addresses
+
+	if len(addresses) == 0 {
+		addresses = []string{cmd.APIAddress}
+	}
+
+	var resolved []string
+
+	for _, address := range addresses {
+		daemons, err := resolveAddress(*cmd, address)
+
+		if err != nil {
+			return ExitCodeError{Code: ExitConnectionError, Err: err}
+		}
+
+		resolved = append(resolved, daemons...)
+	}
+
+	if isDiscoveryAddress(cmd.APIAddress) {
+		cmd.APIAddress = resolved[0]
+	}
+
+	cmd.Addresses = resolved
+
+	return
+}
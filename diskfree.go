@@ -0,0 +1,16 @@
+// +build linux darwin freebsd
+
+package main
+
+import "syscall"
+
+//diskFree returns the bytes available to unprivileged users on the filesystem containing path
+func diskFree(path string) (free uint64, err error) {
+	var stat syscall.Statfs_t
+
+	if err = syscall.Statfs(path, &stat); err != nil {
+		return
+	}
+
+	return uint64(stat.Bavail) * uint64(stat.Bsize), nil
+}
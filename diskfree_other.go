@@ -0,0 +1,13 @@
+// +build !linux,!darwin,!freebsd,!windows
+
+package main
+
+import (
+	"fmt"
+	"runtime"
+)
+
+//diskFree is not supported on this platform
+func diskFree(path string) (uint64, error) {
+	return 0, fmt.Errorf("free disk space is not supported on %s", runtime.GOOS)
+}
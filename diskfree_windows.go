@@ -0,0 +1,25 @@
+package main
+
+import (
+	"syscall"
+	"unsafe"
+)
+
+var getDiskFreeSpaceEx = syscall.NewLazyDLL("kernel32.dll").NewProc("GetDiskFreeSpaceExW")
+
+//diskFree returns the bytes available to the current user on the volume containing path
+func diskFree(path string) (free uint64, err error) {
+	p, err := syscall.UTF16PtrFromString(path)
+
+	if err != nil {
+		return
+	}
+
+	ok, _, err := getDiskFreeSpaceEx.Call(uintptr(unsafe.Pointer(p)), uintptr(unsafe.Pointer(&free)), 0, 0)
+
+	if ok == 0 {
+		return 0, err
+	}
+
+	return free, nil
+}
@@ -0,0 +1,192 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/n8maninger/siac-json/format"
+)
+
+type (
+	//renterDownload the fields of a /renter/downloads entry used by sia-json
+	renterDownload struct {
+		SiaPath     string `json:"siapath"`
+		Destination string `json:"destination"`
+		Length      uint64 `json:"length"`
+		Received    uint64 `json:"received"`
+		Completed   bool   `json:"completed"`
+		Error       string `json:"error"`
+		StartTime   string `json:"starttime"`
+	}
+
+	//renterDownloadsResponse the response of /renter/downloads
+	renterDownloadsResponse struct {
+		Downloads []renterDownload `json:"downloads"`
+	}
+
+	//downloadUpdate a progress line written by downloads tail
+	downloadUpdate struct {
+		Time        time.Time `json:"time"`
+		SiaPath     string    `json:"siapath"`
+		Destination string    `json:"destination"`
+		Status      string    `json:"status"`
+		Received    uint64    `json:"received"`
+		Length      uint64    `json:"length"`
+		Percent     float64   `json:"percent"`
+		Error       string    `json:"error,omitempty"`
+	}
+)
+
+//defaultDownloadsInterval how often downloads tail polls /renter/downloads
+const defaultDownloadsInterval = time.Second
+
+//key identifies a download. The same file can be downloaded more than once so the start time is
+//included
+func (d renterDownload) key() string {
+	return d.SiaPath + "\x00" + d.Destination + "\x00" + d.StartTime
+}
+
+//status returns the status of the download shown by downloads tail
+func (d renterDownload) status() string {
+	switch {
+	case len(d.Error) > 0:
+		return "failed"
+	case d.Completed:
+		return "completed"
+	}
+
+	return "downloading"
+}
+
+//downloadsTailCommand polls /renter/downloads every --interval and writes a JSON line for each
+//progress update of the downloads in progress, including downloads started while tailing, until
+//they have all finished. Fails if any of the downloads failed
+func downloadsTailCommand(cmd Command) (err error) {
+	interval := defaultDownloadsInterval
+
+	if values := cmd.Params["interval"]; len(values) > 0 {
+		if interval, err = format.ParseDuration(values[0]); err != nil {
+			return
+		}
+	}
+
+	var downloads renterDownloadsResponse
+
+	if err = callAPI(cmd, "GET", "/renter/downloads", nil, &downloads); err != nil {
+		return
+	}
+
+	// downloads that had already finished are ignored, later ones are tracked as they appear
+	seen := make(map[string]bool)
+	last := make(map[string]uint64)
+	active, failed := 0, 0
+
+	for _, download := range downloads.Downloads {
+		seen[download.key()] = true
+
+		if !download.Completed && len(download.Error) == 0 {
+			last[download.key()] = download.Received
+			active++
+		}
+	}
+
+	if active == 0 {
+		notify(cmd, "no downloads in progress")
+		return
+	}
+
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, os.Interrupt)
+	defer signal.Stop(sigs)
+
+	scheduler, err := commandScheduler(cmd, interval)
+
+	if err != nil {
+		return
+	}
+
+	enc := json.NewEncoder(os.Stdout)
+	changed := false
+
+	for active > 0 {
+		select {
+		case <-time.After(scheduler.Next(changed)):
+		case <-sigs:
+			return
+		}
+
+		changed = false
+
+		downloads = renterDownloadsResponse{}
+
+		if err := callAPI(cmd, "GET", "/renter/downloads", nil, &downloads); err != nil {
+			notify(cmd, "unable to get downloads: %s", err)
+			continue
+		}
+
+		current := make(map[string]bool)
+
+		for _, download := range downloads.Downloads {
+			key := download.key()
+			received, tracked := last[key]
+			current[key] = true
+
+			if !seen[key] {
+				seen[key] = true
+				active++
+			} else if !tracked || received == download.Received && download.status() == "downloading" {
+				continue
+			}
+
+			update := downloadUpdate{
+				Time:        time.Now().UTC(),
+				SiaPath:     download.SiaPath,
+				Destination: download.Destination,
+				Status:      download.status(),
+				Received:    download.Received,
+				Length:      download.Length,
+				Error:       download.Error,
+			}
+
+			if download.Length > 0 {
+				update.Percent = float64(download.Received) / float64(download.Length) * 100
+			}
+
+			if err = enc.Encode(update); err != nil {
+				return
+			}
+
+			changed = true
+
+			if update.Status == "downloading" {
+				last[key] = download.Received
+				continue
+			}
+
+			delete(last, key)
+			active--
+
+			if update.Status == "failed" {
+				failed++
+			}
+		}
+
+		// downloads cleared from the queue while in progress are no longer waited for
+		for key := range last {
+			if !current[key] {
+				notify(cmd, "download removed from the queue before finishing")
+				delete(last, key)
+				active--
+			}
+		}
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d downloads failed", failed)
+	}
+
+	return
+}
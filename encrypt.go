@@ -0,0 +1,452 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+type (
+	//encryptionKey a key uploads are encrypted with before they are sent to siad
+	encryptionKey struct {
+		Name string `json:"name"`
+		ID   string `json:"id"`
+		Path string `json:"path"`
+
+		key []byte
+	}
+
+	//encryptReader encrypts the wrapped reader in chunks of encryptedChunkSize. Every chunk is
+	//sealed with AES-256-GCM using the file's random nonce prefix and the chunk's counter. The
+	//last chunk is marked in its additional data so a truncated file does not decrypt
+	encryptReader struct {
+		src     *bufio.Reader
+		aead    cipher.AEAD
+		prefix  []byte
+		counter uint32
+		chunk   []byte
+		out     []byte
+		done    bool
+	}
+
+	//decryptReader decrypts a file written by encryptReader
+	decryptReader struct {
+		src     *bufio.Reader
+		key     encryptionKey
+		aead    cipher.AEAD
+		prefix  []byte
+		counter uint32
+		chunk   []byte
+		out     []byte
+		started bool
+		done    bool
+	}
+)
+
+const (
+	//encryptedChunkSize the size of the plaintext of each encrypted chunk
+	encryptedChunkSize = 64 << 10
+
+	//encryptedMagic the start of every encrypted file, followed by the key id and nonce prefix
+	encryptedMagic = "SJE1"
+
+	//encryptedHeaderSize the size of the magic, the 8 byte key id and the 8 byte nonce prefix
+	encryptedHeaderSize = len(encryptedMagic) + 16
+)
+
+//errDecrypt the error returned when a chunk fails to authenticate
+var errDecrypt = fmt.Errorf("unable to decrypt the download, the file is corrupted or was not encrypted with this key")
+
+//keysDir the directory encryption keys are stored in
+func keysDir() string {
+	return filepath.Join(DefaultConfigDir(), "keys")
+}
+
+//keyID identifies a key without revealing it
+func keyID(key []byte) []byte {
+	sum := sha256.Sum256(append([]byte("sia-json key id\x00"), key...))
+
+	return sum[:8]
+}
+
+//loadEncryptionKey loads the named key from the keys directory
+func loadEncryptionKey(name string) (key encryptionKey, err error) {
+	if !profileNameRe.MatchString(name) {
+		return key, fmt.Errorf("invalid key name %q, use letters, numbers, - and _", name)
+	}
+
+	key = encryptionKey{
+		Name: name,
+		Path: filepath.Join(keysDir(), name+".key"),
+	}
+
+	buf, err := ioutil.ReadFile(key.Path)
+
+	if os.IsNotExist(err) {
+		return key, fmt.Errorf("unknown key %s. Create it with keys generate %s", name, name)
+	} else if err != nil {
+		return
+	}
+
+	if key.key, err = hex.DecodeString(strings.TrimSpace(string(buf))); err != nil || len(key.key) != 32 {
+		return key, fmt.Errorf("%s is not a valid key file", key.Path)
+	}
+
+	key.ID = hex.EncodeToString(keyID(key.key))
+
+	return
+}
+
+//newAEAD returns the AES-256-GCM cipher of the key
+func (k encryptionKey) newAEAD() (cipher.AEAD, error) {
+	block, err := aes.NewCipher(k.key)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return cipher.NewGCM(block)
+}
+
+//encryptedSize returns the size of a file of n bytes once encrypted
+func encryptedSize(n int64) int64 {
+	chunks := n / encryptedChunkSize
+
+	if n%encryptedChunkSize != 0 || n == 0 {
+		chunks++
+	}
+
+	return int64(encryptedHeaderSize) + n + chunks*16
+}
+
+//chunkNonce returns the nonce of the chunk
+func chunkNonce(prefix []byte, counter uint32) []byte {
+	nonce := make([]byte, 12)
+	copy(nonce, prefix)
+	binary.BigEndian.PutUint32(nonce[8:], counter)
+
+	return nonce
+}
+
+//chunkData returns the additional data of a chunk, which marks the last chunk
+func chunkData(final bool) []byte {
+	if final {
+		return []byte{1}
+	}
+
+	return []byte{0}
+}
+
+//newEncryptReader returns a reader encrypting r with the key
+func newEncryptReader(r io.Reader, key encryptionKey) (*encryptReader, error) {
+	aead, err := key.newAEAD()
+
+	if err != nil {
+		return nil, err
+	}
+
+	prefix := make([]byte, 8)
+
+	if _, err = io.ReadFull(rand.Reader, prefix); err != nil {
+		return nil, err
+	}
+
+	header := append([]byte(encryptedMagic), keyID(key.key)...)
+
+	return &encryptReader{
+		src:    bufio.NewReader(r),
+		aead:   aead,
+		prefix: prefix,
+		chunk:  make([]byte, encryptedChunkSize),
+		out:    append(header, prefix...),
+	}, nil
+}
+
+//Read reads the encrypted file
+func (er *encryptReader) Read(p []byte) (n int, err error) {
+	for len(er.out) == 0 {
+		if er.done {
+			return 0, io.EOF
+		}
+
+		read, err := io.ReadFull(er.src, er.chunk)
+		final := err == io.EOF || err == io.ErrUnexpectedEOF
+
+		if err != nil && !final {
+			return 0, err
+		} else if !final {
+			// a full chunk is the last one if nothing follows it
+			if _, err = er.src.Peek(1); err == io.EOF {
+				final = true
+			} else if err != nil {
+				return 0, err
+			}
+		}
+
+		if er.counter == ^uint32(0) {
+			return 0, fmt.Errorf("file is too large to encrypt")
+		}
+
+		er.out = er.aead.Seal(er.out[:0], chunkNonce(er.prefix, er.counter), er.chunk[:read], chunkData(final))
+		er.counter++
+		er.done = final
+	}
+
+	n = copy(p, er.out)
+	er.out = er.out[n:]
+
+	return
+}
+
+//newDecryptReader returns a reader decrypting r with the key
+func newDecryptReader(r io.Reader, key encryptionKey) (*decryptReader, error) {
+	aead, err := key.newAEAD()
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &decryptReader{
+		src:   bufio.NewReader(r),
+		key:   key,
+		aead:  aead,
+		chunk: make([]byte, encryptedChunkSize+aead.Overhead()),
+	}, nil
+}
+
+//readHeader reads and checks the header of the encrypted file
+func (dr *decryptReader) readHeader() error {
+	header := make([]byte, encryptedHeaderSize)
+
+	if _, err := io.ReadFull(dr.src, header); err == io.EOF || err == io.ErrUnexpectedEOF {
+		return fmt.Errorf("the download is not an encrypted file")
+	} else if err != nil {
+		return err
+	}
+
+	if string(header[:len(encryptedMagic)]) != encryptedMagic {
+		return fmt.Errorf("the download is not an encrypted file")
+	}
+
+	if id := header[len(encryptedMagic) : len(encryptedMagic)+8]; !bytes.Equal(id, keyID(dr.key.key)) {
+		return fmt.Errorf("the file was encrypted with key %s, not with %s (%s)", hex.EncodeToString(id), dr.key.Name, dr.key.ID)
+	}
+
+	dr.prefix = header[len(encryptedMagic)+8:]
+	dr.started = true
+
+	return nil
+}
+
+//Read reads the decrypted file
+func (dr *decryptReader) Read(p []byte) (n int, err error) {
+	if !dr.started {
+		if err = dr.readHeader(); err != nil {
+			return
+		}
+	}
+
+	for len(dr.out) == 0 {
+		if dr.done {
+			if _, err = dr.src.Peek(1); err != io.EOF {
+				return 0, errDecrypt
+			}
+
+			return 0, io.EOF
+		}
+
+		read, err := io.ReadFull(dr.src, dr.chunk)
+
+		if err == io.EOF {
+			return 0, fmt.Errorf("the download is truncated")
+		}
+
+		final := err == io.ErrUnexpectedEOF
+
+		if err != nil && !final {
+			return 0, err
+		} else if !final {
+			if _, err = dr.src.Peek(1); err == io.EOF {
+				final = true
+			} else if err != nil {
+				return 0, err
+			}
+		}
+
+		if dr.out, err = dr.aead.Open(dr.chunk[:0], chunkNonce(dr.prefix, dr.counter), dr.chunk[:read], chunkData(final)); err != nil {
+			return 0, errDecrypt
+		}
+
+		dr.counter++
+		dr.done = final
+	}
+
+	n = copy(p, dr.out)
+	dr.out = dr.out[n:]
+
+	return
+}
+
+//checkEncryptKey checks that --encrypt-key is used with a request it can encrypt or decrypt.
+//Encrypted downloads must be streamed whole since a range of the file cannot be decrypted
+func checkEncryptKey(cmd Command) (err error) {
+	if len(cmd.EncryptKey) == 0 {
+		return
+	}
+
+	if _, err = loadEncryptionKey(cmd.EncryptKey); err != nil {
+		return
+	}
+
+	switch {
+	case cmd.Bundle:
+		return fmt.Errorf("--encrypt-key cannot be used with --bundle")
+	case strings.HasPrefix(cmd.Endpoint.Path, "/renter/uploadstream/") && len(cmd.UploadFile) > 0:
+		return
+	case cmd.Endpoint.Path == "/renter/download/*siapath":
+		if values := cmd.Params["httpresp"]; len(values) == 0 || values[0] != "true" {
+			return fmt.Errorf("encrypted downloads are decrypted locally and need --httpresp true")
+		}
+
+		if len(cmd.Params["offset"]) > 0 || len(cmd.Params["length"]) > 0 {
+			return fmt.Errorf("--offset and --length cannot be used with --encrypt-key")
+		}
+
+		return
+	}
+
+	return fmt.Errorf("--encrypt-key can only be used with renter uploadstream --file and renter download --httpresp true")
+}
+
+//keysListCommand lists the encryption keys
+func keysListCommand(cmd Command) (err error) {
+	entries, err := ioutil.ReadDir(keysDir())
+
+	if err != nil && !os.IsNotExist(err) {
+		return
+	}
+
+	var names []string
+
+	for _, entry := range entries {
+		if name := entry.Name(); strings.HasSuffix(name, ".key") && !entry.IsDir() {
+			names = append(names, strings.TrimSuffix(name, ".key"))
+		}
+	}
+
+	sort.Strings(names)
+
+	keys := []encryptionKey{}
+
+	for _, name := range names {
+		key, err := loadEncryptionKey(name)
+
+		if err != nil {
+			return err
+		}
+
+		keys = append(keys, key)
+	}
+
+	return writeJSON(keys)
+}
+
+//keysGenerateCommand generates a random encryption key: keys generate <name>. Existing keys are
+//never replaced, since files encrypted with them could no longer be decrypted
+func keysGenerateCommand(cmd Command) (err error) {
+	name := cmd.Args[2]
+
+	if !profileNameRe.MatchString(name) {
+		return fmt.Errorf("invalid key name %q, use letters, numbers, - and _", name)
+	}
+
+	if err = os.MkdirAll(keysDir(), 0700); err != nil {
+		return
+	}
+
+	buf := make([]byte, 32)
+
+	if _, err = io.ReadFull(rand.Reader, buf); err != nil {
+		return
+	}
+
+	key := encryptionKey{
+		Name: name,
+		ID:   hex.EncodeToString(keyID(buf)),
+		Path: filepath.Join(keysDir(), name+".key"),
+	}
+
+	f, err := os.OpenFile(key.Path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+
+	if os.IsExist(err) {
+		return fmt.Errorf("key %s already exists", name)
+	} else if err != nil {
+		return
+	}
+
+	_, err = f.WriteString(hex.EncodeToString(buf) + "\n")
+
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+
+	if err != nil {
+		return
+	}
+
+	notify(cmd, "back up %s, files encrypted with it cannot be restored without it", key.Path)
+
+	return writeJSON(key)
+}
+
+//encryptedUpload returns the upload body encrypted with the command's key and sets the body
+//length to the encrypted size if it is known
+func encryptedUpload(cmd *Command, body io.Reader) (r io.Reader, err error) {
+	key, err := loadEncryptionKey(cmd.EncryptKey)
+
+	if err != nil {
+		return
+	}
+
+	if r, err = newEncryptReader(body, key); err != nil {
+		return
+	}
+
+	if cmd.BodyLength > 0 {
+		cmd.BodyLength = encryptedSize(cmd.BodyLength)
+	}
+
+	return
+}
+
+//decryptedBody returns the download's body decrypted with the command's key
+func decryptedBody(cmd Command, body io.ReadCloser) (io.ReadCloser, error) {
+	key, err := loadEncryptionKey(cmd.EncryptKey)
+
+	if err != nil {
+		return nil, err
+	}
+
+	dr, err := newDecryptReader(body, key)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return readCloser{
+		Reader: dr,
+		Closer: body,
+	}, nil
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	"github.com/n8maninger/siac-json/endpoints"
+)
+
+//loadedEndpointFiles the endpoint files already merged into endpoints.All
+var loadedEndpointFiles = make(map[string]bool)
+
+//knownParamFormats the param formats an endpoint definition may use
+var knownParamFormats = map[endpoints.ParamFormat]bool{
+	endpoints.DefaultFormat:       true,
+	endpoints.DataFormat:          true,
+	endpoints.PriceFormat:         true,
+	endpoints.MonthlyPriceFormat:  true,
+	endpoints.BlockTimeFormat:     true,
+	endpoints.TransactionIDFormat: true,
+	endpoints.ContractIDFormat:    true,
+	endpoints.MerkleRootFormat:    true,
+	endpoints.BlockIDFormat:       true,
+}
+
+//knownParamTypes the param types an endpoint definition may use
+var knownParamTypes = map[endpoints.ParamType]bool{
+	endpoints.StringType:   true,
+	endpoints.IntType:      true,
+	endpoints.BoolType:     true,
+	endpoints.CurrencyType: true,
+	endpoints.HashType:     true,
+	endpoints.AddressType:  true,
+}
+
+//decodeEndpoints decodes a JSON array of endpoint definitions. Field names match the endpoint
+//table's, ignoring case
+func decodeEndpoints(buf []byte) (decoded []endpoints.CommandEndpoint, err error) {
+	if err = json.Unmarshal(buf, &decoded); err != nil {
+		return
+	}
+
+	for i, endpoint := range decoded {
+		if !strings.HasPrefix(endpoint.Path, "/") || len(endpoint.Method) == 0 {
+			return nil, fmt.Errorf("endpoint %d needs a path starting with / and a method", i+1)
+		}
+
+		decoded[i].Method = strings.ToUpper(endpoint.Method)
+
+		for _, param := range endpoint.Params {
+			switch {
+			case len(param.Key) == 0:
+				return nil, fmt.Errorf("%s: params need a key", endpoint.Path)
+			case param.Location != endpoints.URLParam && param.Location != endpoints.QueryParam && param.Location != endpoints.BodyParam:
+				return nil, fmt.Errorf("%s: param %s has location %q, expected url, query or body", endpoint.Path, param.Key, param.Location)
+			case !knownParamFormats[param.Formatter]:
+				return nil, fmt.Errorf("%s: param %s has unknown format %q", endpoint.Path, param.Key, param.Formatter)
+			case !knownParamTypes[param.Type]:
+				return nil, fmt.Errorf("%s: param %s has unknown type %q", endpoint.Path, param.Key, param.Type)
+			}
+		}
+	}
+
+	return
+}
+
+//mergeEndpoints adds the endpoints to endpoints.All. An endpoint with the path and method of a
+//known endpoint replaces it, so a custom siad build can describe the changed params of a route
+func mergeEndpoints(merged []endpoints.CommandEndpoint) {
+	for _, endpoint := range merged {
+		replaced := false
+
+		for i, known := range endpoints.All {
+			if known.Path == endpoint.Path && known.Method == endpoint.Method {
+				endpoints.All[i], replaced = endpoint, true
+				break
+			}
+		}
+
+		if !replaced {
+			endpoints.All = append(endpoints.All, endpoint)
+		}
+	}
+}
+
+//loadEndpointFiles merges the endpoint definitions of the files, from the endpoints config
+//option and --endpoints, into the endpoint table. Each file is only merged once
+func loadEndpointFiles(paths []string) (err error) {
+	for _, path := range paths {
+		if loadedEndpointFiles[path] {
+			continue
+		}
+
+		buf, err := ioutil.ReadFile(path)
+
+		if err != nil {
+			return err
+		}
+
+		decoded, err := decodeEndpoints(buf)
+
+		if err != nil {
+			return fmt.Errorf("%s: %s", path, err)
+		}
+
+		mergeEndpoints(decoded)
+		loadedEndpointFiles[path] = true
+	}
+
+	return
+}
@@ -0,0 +1,246 @@
+//Package endpoints describes the endpoints of the Sia API: their paths, methods, params and help
+//text. It is the endpoint knowledge sia-json uses to build and validate requests
+package endpoints
+
+import "strings"
+
+type (
+	//ParamLocation the location of the param in the request
+	ParamLocation string
+
+	//ParamType the type of value siad expects for the param
+	ParamType string
+
+	//ParamFormat the format of the param will be used to get the friendly strings from siac "10TB" "100SC"
+	ParamFormat string
+
+	//CommandParam a known parameter of a command. Listed by help and used to validate and format the parameter
+	CommandParam struct {
+		Key       string
+		HelpText  string
+		Location  ParamLocation
+		Formatter ParamFormat
+		//Type the type of value siad expects, implied by the format if it is not set. See TypeOf
+		Type ParamType
+		//Required the request fails without the parameter
+		Required bool
+	}
+
+	//CommandEndpoint a known Sia API endpoint. Describes how the endpoint should be accessed, any help text and any parameters that are required
+	CommandEndpoint struct {
+		Path               string
+		AlternativeMatches []string
+		Method             string
+		HelpText           string
+		Params             []CommandParam
+		//Binary the endpoint responds with raw file data instead of JSON
+		Binary bool
+		//Sensitive the response contains secrets such as the wallet's seeds and is never cached
+		Sensitive bool
+		//AddedIn the siad version the endpoint was added in, empty if it is older than 1.4
+		AddedIn string
+		//DeprecatedIn the siad version the endpoint was deprecated in
+		DeprecatedIn string
+		//Replacement the endpoint to use instead of a deprecated endpoint
+		Replacement string
+	}
+)
+
+const (
+	//URLParam the parameter should go in the url as part of the path
+	URLParam ParamLocation = "url"
+
+	//QueryParam the parameter should go in the query
+	QueryParam ParamLocation = "query"
+
+	//BodyParam the parameter should go in the body
+	BodyParam ParamLocation = "body"
+
+	//DefaultFormat an unformatted parameter
+	DefaultFormat ParamFormat = ""
+
+	//DataFormat a parameter formatted in the friendly data size format "10TB"
+	DataFormat ParamFormat = "data"
+
+	//PriceFormat a parameter formatted in the Siacoin price format "100SC"
+	PriceFormat ParamFormat = "price"
+
+	//MonthlyPriceFormat a parameter formatted in the Siacoin monthly price format "100SC"
+	MonthlyPriceFormat ParamFormat = "monthlyprice"
+
+	//BlockTimeFormat a parameter formatted in the 10 minutes per block format "10w"
+	BlockTimeFormat ParamFormat = "blocktime"
+
+	//TransactionIDFormat a parameter formatted as a 64 character hex transaction ID
+	TransactionIDFormat ParamFormat = "txid"
+
+	//ContractIDFormat a parameter formatted as a 64 character hex file contract ID
+	ContractIDFormat ParamFormat = "contractid"
+
+	//MerkleRootFormat a parameter formatted as a 64 character hex sector merkle root
+	MerkleRootFormat ParamFormat = "merkleroot"
+
+	//BlockIDFormat a parameter formatted as a 64 character hex block ID
+	BlockIDFormat ParamFormat = "blockid"
+)
+
+const (
+	//StringType a param of any string, the default
+	StringType ParamType = ""
+
+	//IntType a param that is an unsigned integer
+	IntType ParamType = "int"
+
+	//BoolType a param that is true or false
+	BoolType ParamType = "bool"
+
+	//CurrencyType a param that is an amount of hastings
+	CurrencyType ParamType = "currency"
+
+	//HashType a param that is a 64 character hex hash
+	HashType ParamType = "hash"
+
+	//AddressType a param that is a 76 character hex unlock hash
+	AddressType ParamType = "address"
+)
+
+//TypeOf returns the type of the param: its Type, or the type its format is converted to
+func TypeOf(param CommandParam) ParamType {
+	if param.Type != StringType {
+		return param.Type
+	}
+
+	switch param.Formatter {
+	case DataFormat, BlockTimeFormat:
+		return IntType
+	case PriceFormat, MonthlyPriceFormat:
+		return CurrencyType
+	case TransactionIDFormat, ContractIDFormat, MerkleRootFormat, BlockIDFormat:
+		return HashType
+	}
+
+	return StringType
+}
+
+//MatchPath returns true if the request path matches the endpoint's path template. :name
+//segments match any single segment and a *name segment matches the rest of the path
+func MatchPath(path, template string) bool {
+	pathSegments := strings.Split(path, "/")
+	segments := strings.Split(template, "/")
+
+	if len(segments) == 0 || len(pathSegments) == 0 {
+		return false
+	}
+
+	if len(pathSegments) < len(segments) {
+		return false
+	}
+
+	for i, pathSeg := range pathSegments {
+		if len(segments) <= i {
+			return false
+		}
+
+		seg := segments[i]
+
+		if strings.HasPrefix(seg, ":") {
+			continue
+		}
+
+		if strings.HasPrefix(seg, "*") {
+			return true
+		}
+
+		if seg != pathSeg {
+			return false
+		}
+	}
+
+	return true
+}
+
+//Matches returns true if the request path matches the endpoint's path or one of its
+//AlternativeMatches
+func (endpoint CommandEndpoint) Matches(path string) bool {
+	if MatchPath(path, endpoint.Path) {
+		return true
+	}
+
+	for _, alt := range endpoint.AlternativeMatches {
+		if MatchPath(path, alt) {
+			return true
+		}
+	}
+
+	return false
+}
+
+//CanonicalPath returns the request path rewritten to the endpoint's path if it matched one of
+//the endpoint's AlternativeMatches, such as /gateway/blacklist to /gateway/blocklist. :name and
+//*name segments are carried over by name
+func (endpoint CommandEndpoint) CanonicalPath(path string) string {
+	if MatchPath(path, endpoint.Path) {
+		return path
+	}
+
+	for _, alt := range endpoint.AlternativeMatches {
+		if !MatchPath(path, alt) {
+			continue
+		}
+
+		pathSegments := strings.Split(path, "/")
+		values := make(map[string]string)
+
+		for i, seg := range strings.Split(alt, "/") {
+			if strings.HasPrefix(seg, ":") {
+				values[seg] = pathSegments[i]
+			} else if strings.HasPrefix(seg, "*") {
+				values[seg] = strings.Join(pathSegments[i:], "/")
+				break
+			}
+		}
+
+		var canonical []string
+
+		for _, seg := range strings.Split(endpoint.Path, "/") {
+			if value, ok := values[seg]; ok {
+				seg = value
+			}
+
+			canonical = append(canonical, seg)
+		}
+
+		return strings.Join(canonical, "/")
+	}
+
+	return path
+}
+
+//Match returns the endpoints whose path or AlternativeMatches match the request path. An empty
+//method matches any method
+func Match(path, method string) (matched []CommandEndpoint) {
+	for _, endpoint := range All {
+		if !endpoint.Matches(path) {
+			continue
+		}
+
+		if len(method) > 0 && method != endpoint.Method {
+			continue
+		}
+
+		matched = append(matched, endpoint)
+	}
+
+	return
+}
+
+//Find returns the endpoint with the path template and method
+func Find(template, method string) (endpoint CommandEndpoint, ok bool) {
+	for _, endpoint = range All {
+		if endpoint.Path == template && endpoint.Method == method {
+			return endpoint, true
+		}
+	}
+
+	return
+}
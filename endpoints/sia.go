@@ -0,0 +1,865 @@
+package endpoints
+
+//All all current endpoints listed in https://sia.tech/docs as of v1.5.4
+var All = []CommandEndpoint{
+	CommandEndpoint{
+		Path:     "/consensus",
+		Method:   "GET",
+		HelpText: "returns the current state of the consensus set, including the block height and whether it is synced",
+	},
+	CommandEndpoint{
+		Path:     "/consensus/blocks",
+		Method:   "GET",
+		HelpText: "returns a block by its ID or height",
+		Params: []CommandParam{
+			CommandParam{Key: "id", HelpText: "ID of the block. Either id or height is required", Location: QueryParam, Formatter: BlockIDFormat},
+			CommandParam{Key: "height", HelpText: "height of the block. Either id or height is required", Location: QueryParam, Type: IntType},
+		},
+	},
+	CommandEndpoint{
+		Path:     "/consensus/validate/transactionset",
+		Method:   "POST",
+		HelpText: "validates a JSON encoded transaction set sent as the request body against the current consensus set",
+	},
+	CommandEndpoint{
+		Path:     "/daemon/alerts",
+		Method:   "GET",
+		AddedIn:  "1.4.2",
+		HelpText: "returns the daemon's active alerts, grouped by severity",
+	},
+	CommandEndpoint{
+		Path:     "/daemon/constants",
+		Method:   "GET",
+		HelpText: "returns the constants of the Sia network the daemon is running on",
+	},
+	CommandEndpoint{
+		Path:     "/daemon/settings",
+		Method:   "GET",
+		HelpText: "returns the daemon's bandwidth limits",
+	},
+	CommandEndpoint{
+		Path:     "/daemon/settings",
+		Method:   "POST",
+		HelpText: "changes the daemon's bandwidth limits",
+		Params: []CommandParam{
+			CommandParam{Key: "maxdownloadspeed", HelpText: "maximum download speed in bytes per second, 0 for unlimited", Location: QueryParam, Formatter: DataFormat},
+			CommandParam{Key: "maxuploadspeed", HelpText: "maximum upload speed in bytes per second, 0 for unlimited", Location: QueryParam, Formatter: DataFormat},
+		},
+	},
+	CommandEndpoint{
+		Path:     "/daemon/stack",
+		Method:   "GET",
+		AddedIn:  "1.4.8",
+		HelpText: "returns the stack traces of the daemon's goroutines",
+	},
+	CommandEndpoint{
+		Path:     "/daemon/stop",
+		Method:   "GET",
+		HelpText: "cleanly shuts down the daemon",
+	},
+	CommandEndpoint{
+		Path:     "/daemon/update",
+		Method:   "GET",
+		HelpText: "checks for an available update",
+	},
+	CommandEndpoint{
+		Path:     "/daemon/update",
+		Method:   "POST",
+		HelpText: "downloads and installs the available update",
+	},
+	CommandEndpoint{
+		Path:     "/daemon/version",
+		Method:   "GET",
+		HelpText: "returns the version of the daemon",
+	},
+	CommandEndpoint{
+		Path:     "/feemanager",
+		Method:   "GET",
+		AddedIn:  "1.5.0",
+		HelpText: "returns the fee manager's settings and the time of the next fee payout",
+	},
+	CommandEndpoint{
+		Path:     "/feemanager/add",
+		Method:   "POST",
+		AddedIn:  "1.5.0",
+		HelpText: "adds a fee charged by an application",
+		Params: []CommandParam{
+			CommandParam{Key: "address", HelpText: "address the fee is paid to", Location: QueryParam, Type: AddressType, Required: true},
+			CommandParam{Key: "amount", HelpText: "amount of the fee", Location: QueryParam, Formatter: PriceFormat, Required: true},
+			CommandParam{Key: "appuid", HelpText: "unique ID of the application charging the fee", Location: QueryParam, Required: true},
+			CommandParam{Key: "recurring", HelpText: "true to charge the fee every period", Location: QueryParam, Type: BoolType},
+		},
+	},
+	CommandEndpoint{
+		Path:     "/feemanager/cancel",
+		Method:   "POST",
+		AddedIn:  "1.5.0",
+		HelpText: "cancels a pending fee",
+		Params: []CommandParam{
+			CommandParam{Key: "feeuid", HelpText: "unique ID of the fee", Location: QueryParam, Required: true},
+		},
+	},
+	CommandEndpoint{
+		Path:     "/feemanager/paidfees",
+		Method:   "GET",
+		AddedIn:  "1.5.0",
+		HelpText: "returns the fees that have been paid",
+	},
+	CommandEndpoint{
+		Path:     "/feemanager/pendingfees",
+		Method:   "GET",
+		AddedIn:  "1.5.0",
+		HelpText: "returns the fees that have not been paid yet",
+	},
+	CommandEndpoint{
+		Path:     "/gateway",
+		Method:   "GET",
+		HelpText: "returns the gateway's address and connected peers",
+	},
+	CommandEndpoint{
+		Path:     "/gateway",
+		Method:   "POST",
+		HelpText: "changes the gateway's bandwidth limits",
+		Params: []CommandParam{
+			CommandParam{Key: "maxdownloadspeed", HelpText: "maximum download speed in bytes per second, 0 for unlimited", Location: QueryParam, Formatter: DataFormat},
+			CommandParam{Key: "maxuploadspeed", HelpText: "maximum upload speed in bytes per second, 0 for unlimited", Location: QueryParam, Formatter: DataFormat},
+		},
+	},
+	CommandEndpoint{
+		Path:     "/gateway/blocklist",
+		Method:   "GET",
+		AddedIn:  "1.5.4",
+		HelpText: "returns the IP addresses the gateway refuses to connect to",
+		AlternativeMatches: []string{
+			"/gateway/blacklist",
+		},
+	},
+	CommandEndpoint{
+		Path:     "/gateway/blocklist",
+		Method:   "POST",
+		AddedIn:  "1.5.4",
+		HelpText: "adds, removes or replaces the IP addresses the gateway refuses to connect to",
+		Params: []CommandParam{
+			CommandParam{Key: "action", HelpText: "append, remove or set", Location: BodyParam, Required: true},
+			CommandParam{Key: "addresses", HelpText: "IP addresses or hostnames, not required if action is set", Location: BodyParam},
+		},
+		AlternativeMatches: []string{
+			"/gateway/blacklist",
+		},
+	},
+	CommandEndpoint{
+		Path:     "/gateway/connect/:netaddress",
+		Method:   "POST",
+		HelpText: "connects the gateway to a peer",
+		Params: []CommandParam{
+			CommandParam{Key: "netaddress", HelpText: "address of the peer, host:port", Location: URLParam, Required: true},
+		},
+	},
+	CommandEndpoint{
+		Path:     "/gateway/disconnect/:netaddress",
+		Method:   "POST",
+		HelpText: "disconnects the gateway from a peer",
+		Params: []CommandParam{
+			CommandParam{Key: "netaddress", HelpText: "address of the peer, host:port", Location: URLParam, Required: true},
+		},
+	},
+	CommandEndpoint{
+		Path:     "/host",
+		Method:   "GET",
+		HelpText: "returns the host's settings, financial metrics and status",
+	},
+	CommandEndpoint{
+		Path:     "/host",
+		Method:   "POST",
+		HelpText: "changes the host's settings",
+		Params: []CommandParam{
+			CommandParam{Key: "acceptingcontracts", HelpText: "true to accept new contracts", Location: QueryParam, Type: BoolType},
+			CommandParam{Key: "maxdownloadbatchsize", HelpText: "maximum size of a single download batch", Location: QueryParam, Formatter: DataFormat},
+			CommandParam{Key: "maxduration", HelpText: "maximum duration of a contract", Location: QueryParam, Formatter: BlockTimeFormat},
+			CommandParam{Key: "maxrevisebatchsize", HelpText: "maximum size of a single upload batch", Location: QueryParam, Formatter: DataFormat},
+			CommandParam{Key: "netaddress", HelpText: "address renters should connect to", Location: QueryParam},
+			CommandParam{Key: "windowsize", HelpText: "blocks the host has to submit a storage proof", Location: QueryParam, Formatter: BlockTimeFormat},
+			CommandParam{Key: "collateral", HelpText: "collateral put up per byte of storage", Location: QueryParam, Formatter: MonthlyPriceFormat},
+			CommandParam{Key: "collateralbudget", HelpText: "total collateral the host may lock in contracts", Location: QueryParam, Formatter: PriceFormat},
+			CommandParam{Key: "maxcollateral", HelpText: "maximum collateral per contract", Location: QueryParam, Formatter: PriceFormat},
+			CommandParam{Key: "minbaserpcprice", HelpText: "minimum price of an RPC call", Location: QueryParam, Formatter: PriceFormat},
+			CommandParam{Key: "mincontractprice", HelpText: "minimum price of forming a contract", Location: QueryParam, Formatter: PriceFormat},
+			CommandParam{Key: "mindownloadbandwidthprice", HelpText: "minimum price per byte downloaded by renters", Location: QueryParam, Formatter: PriceFormat},
+			CommandParam{Key: "minsectoraccessprice", HelpText: "minimum price of reading a sector", Location: QueryParam, Formatter: PriceFormat},
+			CommandParam{Key: "minstorageprice", HelpText: "minimum price per byte of storage", Location: QueryParam, Formatter: MonthlyPriceFormat},
+			CommandParam{Key: "minuploadbandwidthprice", HelpText: "minimum price per byte uploaded by renters", Location: QueryParam, Formatter: PriceFormat},
+		},
+	},
+	CommandEndpoint{
+		Path:     "/host/announce",
+		Method:   "POST",
+		HelpText: "announces the host to the network",
+		Params: []CommandParam{
+			CommandParam{Key: "netaddress", HelpText: "address to announce instead of the host's current address", Location: QueryParam},
+		},
+	},
+	CommandEndpoint{
+		Path:     "/host/bandwidth",
+		Method:   "GET",
+		AddedIn:  "1.4.4",
+		HelpText: "returns the host's total upload and download bandwidth since it started",
+	},
+	CommandEndpoint{
+		Path:     "/host/contracts",
+		Method:   "GET",
+		HelpText: "returns the host's storage obligations",
+	},
+	CommandEndpoint{
+		Path:     "/host/storage",
+		Method:   "GET",
+		HelpText: "returns the host's storage folders",
+		AlternativeMatches: []string{
+			"/host/folders",
+		},
+	},
+	CommandEndpoint{
+		Path:     "/host/storage/folders/add",
+		Method:   "POST",
+		HelpText: "adds a storage folder to the host",
+		Params: []CommandParam{
+			CommandParam{Key: "path", HelpText: "absolute path of the folder on the host", Location: QueryParam, Required: true},
+			CommandParam{Key: "size", HelpText: "amount of storage to use in the folder", Location: QueryParam, Formatter: DataFormat, Required: true},
+		},
+	},
+	CommandEndpoint{
+		Path:     "/host/storage/folders/remove",
+		Method:   "POST",
+		HelpText: "removes a storage folder from the host, moving its sectors to the other folders",
+		Params: []CommandParam{
+			CommandParam{Key: "path", HelpText: "absolute path of the folder on the host", Location: QueryParam, Required: true},
+			CommandParam{Key: "force", HelpText: "true to remove the folder even if data would be lost", Location: QueryParam, Type: BoolType},
+		},
+	},
+	CommandEndpoint{
+		Path:     "/host/storage/folders/resize",
+		Method:   "POST",
+		HelpText: "changes the amount of storage used in a storage folder",
+		Params: []CommandParam{
+			CommandParam{Key: "path", HelpText: "absolute path of the folder on the host", Location: QueryParam, Required: true},
+			CommandParam{Key: "newsize", HelpText: "new amount of storage to use in the folder", Location: QueryParam, Formatter: DataFormat, Required: true},
+		},
+	},
+	CommandEndpoint{
+		Path:     "/host/storage/sectors/delete/:merkleroot",
+		Method:   "POST",
+		HelpText: "deletes a sector from the host",
+		Params: []CommandParam{
+			CommandParam{Key: "merkleroot", HelpText: "merkle root of the sector", Location: URLParam, Formatter: MerkleRootFormat, Required: true},
+		},
+	},
+	CommandEndpoint{
+		Path:     "/host/estimatescore",
+		Method:   "GET",
+		HelpText: "estimates the host's score in the renters' hostdb with the given settings",
+		Params: []CommandParam{
+			CommandParam{Key: "acceptingcontracts", HelpText: "true to accept new contracts", Location: QueryParam, Type: BoolType},
+			CommandParam{Key: "maxdownloadbatchsize", HelpText: "maximum size of a single download batch", Location: QueryParam, Formatter: DataFormat},
+			CommandParam{Key: "maxduration", HelpText: "maximum duration of a contract", Location: QueryParam, Formatter: BlockTimeFormat},
+			CommandParam{Key: "maxrevisebatchsize", HelpText: "maximum size of a single upload batch", Location: QueryParam, Formatter: DataFormat},
+			CommandParam{Key: "netaddress", HelpText: "address renters should connect to", Location: QueryParam},
+			CommandParam{Key: "windowsize", HelpText: "blocks the host has to submit a storage proof", Location: QueryParam, Formatter: BlockTimeFormat},
+			CommandParam{Key: "collateral", HelpText: "collateral put up per byte of storage", Location: QueryParam, Formatter: MonthlyPriceFormat},
+			CommandParam{Key: "collateralbudget", HelpText: "total collateral the host may lock in contracts", Location: QueryParam, Formatter: PriceFormat},
+			CommandParam{Key: "maxcollateral", HelpText: "maximum collateral per contract", Location: QueryParam, Formatter: PriceFormat},
+			CommandParam{Key: "minbaserpcprice", HelpText: "minimum price of an RPC call", Location: QueryParam, Formatter: PriceFormat},
+			CommandParam{Key: "mincontractprice", HelpText: "minimum price of forming a contract", Location: QueryParam, Formatter: PriceFormat},
+			CommandParam{Key: "mindownloadbandwidthprice", HelpText: "minimum price per byte downloaded by renters", Location: QueryParam, Formatter: PriceFormat},
+			CommandParam{Key: "minsectoraccessprice", HelpText: "minimum price of reading a sector", Location: QueryParam, Formatter: PriceFormat},
+			CommandParam{Key: "minstorageprice", HelpText: "minimum price per byte of storage", Location: QueryParam, Formatter: MonthlyPriceFormat},
+			CommandParam{Key: "minuploadbandwidthprice", HelpText: "minimum price per byte uploaded by renters", Location: QueryParam, Formatter: PriceFormat},
+		},
+	},
+	CommandEndpoint{
+		Path:     "/hostdb",
+		Method:   "GET",
+		HelpText: "returns whether the hostdb has completed its initial scan",
+	},
+	CommandEndpoint{
+		Path:     "/hostdb/active",
+		Method:   "GET",
+		HelpText: "returns the active hosts in the hostdb",
+		Params: []CommandParam{
+			CommandParam{Key: "numhosts", HelpText: "maximum number of hosts to return", Location: QueryParam},
+		},
+	},
+	CommandEndpoint{
+		Path:     "/hostdb/all",
+		Method:   "GET",
+		HelpText: "returns all known hosts in the hostdb",
+	},
+	CommandEndpoint{
+		Path:     "/hostdb/hosts/:pubkey",
+		Method:   "GET",
+		HelpText: "returns a host's details and score breakdown",
+		Params: []CommandParam{
+			CommandParam{Key: "pubkey", HelpText: "public key of the host, ed25519:<hex>", Location: URLParam, Required: true},
+		},
+	},
+	CommandEndpoint{
+		Path:     "/hostdb/filtermode",
+		Method:   "GET",
+		HelpText: "returns the hostdb's filter mode and filtered hosts",
+	},
+	CommandEndpoint{
+		Path:     "/hostdb/filtermode",
+		Method:   "POST",
+		HelpText: "changes the hostdb's filter mode",
+		Params: []CommandParam{
+			CommandParam{Key: "filtermode", HelpText: "whitelist, blacklist or disable", Location: BodyParam, Required: true},
+			CommandParam{Key: "hosts", HelpText: "public keys of the hosts to filter", Location: BodyParam},
+		},
+	},
+	CommandEndpoint{
+		Path:     "/miner",
+		Method:   "GET",
+		HelpText: "returns the miner's status",
+	},
+	CommandEndpoint{
+		Path:     "/miner/start",
+		Method:   "GET",
+		HelpText: "starts the CPU miner",
+	},
+	CommandEndpoint{
+		Path:     "/miner/stop",
+		Method:   "GET",
+		HelpText: "stops the CPU miner",
+	},
+	CommandEndpoint{
+		Path:     "/miner/header",
+		Method:   "GET",
+		HelpText: "returns a block header to mine",
+	},
+	CommandEndpoint{
+		Path:     "/miner/header",
+		Method:   "POST",
+		HelpText: "submits a solved block header sent as the request body",
+	},
+	CommandEndpoint{
+		Path:     "/renter",
+		Method:   "GET",
+		HelpText: "returns the renter's settings, allowance and financial metrics",
+	},
+	CommandEndpoint{
+		Path:     "/renter",
+		Method:   "POST",
+		HelpText: "changes the renter's allowance and settings",
+		Params: []CommandParam{
+			CommandParam{Key: "funds", HelpText: "siacoins to spend on contracts each period", Location: QueryParam, Formatter: PriceFormat},
+			CommandParam{Key: "hosts", HelpText: "number of hosts to form contracts with", Location: QueryParam, Type: IntType},
+			CommandParam{Key: "period", HelpText: "duration of each contract period", Location: QueryParam, Formatter: BlockTimeFormat},
+			CommandParam{Key: "renewwindow", HelpText: "blocks before the end of the period to renew contracts", Location: QueryParam, Formatter: BlockTimeFormat},
+			CommandParam{Key: "expectedstorage", HelpText: "expected amount of data stored", Location: QueryParam, Formatter: DataFormat},
+			CommandParam{Key: "expectedupload", HelpText: "expected data uploaded per period", Location: QueryParam, Formatter: DataFormat},
+			CommandParam{Key: "expecteddownload", HelpText: "expected data downloaded per period", Location: QueryParam, Formatter: DataFormat},
+			CommandParam{Key: "expectedredundancy", HelpText: "expected redundancy of uploaded files", Location: QueryParam},
+			CommandParam{Key: "maxrpcprice", HelpText: "maximum price of an RPC call", Location: QueryParam, Formatter: PriceFormat},
+			CommandParam{Key: "maxcontractprice", HelpText: "maximum price of forming a contract", Location: QueryParam, Formatter: PriceFormat},
+			CommandParam{Key: "maxdownloadbandwidthprice", HelpText: "maximum price per byte downloaded", Location: QueryParam, Formatter: PriceFormat},
+			CommandParam{Key: "maxsectoraccessprice", HelpText: "maximum price of reading a sector", Location: QueryParam, Formatter: PriceFormat},
+			CommandParam{Key: "maxstorageprice", HelpText: "maximum price per byte of storage", Location: QueryParam, Formatter: MonthlyPriceFormat},
+			CommandParam{Key: "maxuploadbandwidthprice", HelpText: "maximum price per byte uploaded", Location: QueryParam, Formatter: PriceFormat},
+			CommandParam{Key: "maxdownloadspeed", HelpText: "maximum download speed in bytes per second, 0 for unlimited", Location: QueryParam, Formatter: DataFormat},
+			CommandParam{Key: "maxuploadspeed", HelpText: "maximum upload speed in bytes per second, 0 for unlimited", Location: QueryParam, Formatter: DataFormat},
+			CommandParam{Key: "checkforipviolation", HelpText: "true to avoid hosts in the same IP subnet", Location: QueryParam, Type: BoolType},
+		},
+	},
+	CommandEndpoint{
+		Path:     "/renter/contract/cancel",
+		Method:   "POST",
+		HelpText: "cancels a renter contract",
+		Params: []CommandParam{
+			CommandParam{Key: "id", HelpText: "ID of the contract", Location: BodyParam, Formatter: ContractIDFormat, Required: true},
+		},
+	},
+	CommandEndpoint{
+		Path:     "/renter/backup",
+		Method:   "POST",
+		HelpText: "creates a backup of the renter's metadata",
+		Params: []CommandParam{
+			CommandParam{Key: "name", HelpText: "name of the backup", Location: QueryParam, Required: true},
+			CommandParam{Key: "remote", HelpText: "true to upload the backup to the renter's hosts", Location: QueryParam, Type: BoolType},
+		},
+	},
+	CommandEndpoint{
+		Path:     "/renter/recoverbackup",
+		Method:   "POST",
+		HelpText: "restores the renter's metadata from a backup",
+		Params: []CommandParam{
+			CommandParam{Key: "name", HelpText: "name of the backup", Location: QueryParam, Required: true},
+			CommandParam{Key: "remote", HelpText: "true to download the backup from the renter's hosts", Location: QueryParam, Type: BoolType},
+		},
+	},
+	CommandEndpoint{
+		Path:     "/renter/uploadedbackups",
+		Method:   "POST",
+		HelpText: "returns the backups uploaded to the renter's hosts",
+	},
+	CommandEndpoint{
+		Path:     "/renter/contracts",
+		Method:   "GET",
+		HelpText: "returns the renter's contracts",
+		Params: []CommandParam{
+			CommandParam{Key: "disabled", HelpText: "true to include disabled contracts", Location: QueryParam, Type: BoolType},
+			CommandParam{Key: "expired", HelpText: "true to include expired contracts", Location: QueryParam, Type: BoolType},
+			CommandParam{Key: "recoverable", HelpText: "true to include recoverable contracts", Location: QueryParam, Type: BoolType},
+		},
+	},
+	CommandEndpoint{
+		Path:     "/renter/dir/*siapath",
+		Method:   "GET",
+		HelpText: "returns the files and folders in a renter folder",
+		Params: []CommandParam{
+			CommandParam{Key: "siapath", HelpText: "path of the file or folder in the renter", Location: URLParam, Required: true},
+		},
+	},
+	CommandEndpoint{
+		Path:     "/renter/dir/*siapath",
+		Method:   "POST",
+		HelpText: "creates, deletes or renames a renter folder",
+		Params: []CommandParam{
+			CommandParam{Key: "siapath", HelpText: "path of the file or folder in the renter", Location: URLParam, Required: true},
+			CommandParam{Key: "action", HelpText: "create, delete or rename", Location: QueryParam, Required: true},
+			CommandParam{Key: "newsiapath", HelpText: "new path of the folder when renaming", Location: QueryParam},
+		},
+	},
+	CommandEndpoint{
+		Path:     "/renter/downloads",
+		Method:   "GET",
+		HelpText: "returns the renter's download queue",
+	},
+	CommandEndpoint{
+		Path:     "/renter/downloads/clear",
+		Method:   "POST",
+		HelpText: "clears completed downloads from the download queue",
+		Params: []CommandParam{
+			CommandParam{Key: "before", HelpText: "unix timestamp to clear downloads started before", Location: QueryParam, Type: IntType},
+			CommandParam{Key: "after", HelpText: "unix timestamp to clear downloads started after", Location: QueryParam, Type: IntType},
+		},
+	},
+	CommandEndpoint{
+		Path:     "/renter/prices",
+		Method:   "GET",
+		HelpText: "estimates the cost of storage with the current allowance or the given allowance",
+		Params: []CommandParam{
+			CommandParam{Key: "funds", HelpText: "siacoins to spend on contracts each period", Location: QueryParam, Formatter: PriceFormat},
+			CommandParam{Key: "hosts", HelpText: "number of hosts to form contracts with", Location: QueryParam, Type: IntType},
+			CommandParam{Key: "period", HelpText: "duration of each contract period", Location: QueryParam, Formatter: BlockTimeFormat},
+			CommandParam{Key: "renewwindow", HelpText: "blocks before the end of the period to renew contracts", Location: QueryParam, Formatter: BlockTimeFormat},
+			CommandParam{Key: "expectedstorage", HelpText: "expected amount of data stored", Location: QueryParam, Formatter: DataFormat},
+			CommandParam{Key: "expectedupload", HelpText: "expected data uploaded per period", Location: QueryParam, Formatter: DataFormat},
+			CommandParam{Key: "expecteddownload", HelpText: "expected data downloaded per period", Location: QueryParam, Formatter: DataFormat},
+			CommandParam{Key: "expectedredundancy", HelpText: "expected redundancy of uploaded files", Location: QueryParam},
+		},
+	},
+	CommandEndpoint{
+		Path:     "/renter/files",
+		Method:   "GET",
+		HelpText: "returns all files uploaded by the renter",
+		Params: []CommandParam{
+			CommandParam{Key: "cached", HelpText: "true to return cached file health", Location: QueryParam, Type: BoolType},
+		},
+	},
+	CommandEndpoint{
+		Path:     "/renter/file/*siapath",
+		Method:   "GET",
+		HelpText: "returns the details of a renter file",
+		Params: []CommandParam{
+			CommandParam{Key: "siapath", HelpText: "path of the file or folder in the renter", Location: URLParam, Required: true},
+		},
+	},
+	CommandEndpoint{
+		Path:     "/renter/file/*siapath",
+		Method:   "POST",
+		HelpText: "changes a renter file's tracking path or stuck status",
+		Params: []CommandParam{
+			CommandParam{Key: "siapath", HelpText: "path of the file or folder in the renter", Location: URLParam, Required: true},
+			CommandParam{Key: "trackingpath", HelpText: "new local path of the file", Location: QueryParam},
+			CommandParam{Key: "stuck", HelpText: "true to mark the file as stuck", Location: QueryParam, Type: BoolType},
+		},
+	},
+	CommandEndpoint{
+		Path:     "/renter/delete/*siapath",
+		Method:   "POST",
+		HelpText: "deletes a renter file",
+		Params: []CommandParam{
+			CommandParam{Key: "siapath", HelpText: "path of the file or folder in the renter", Location: URLParam, Required: true},
+		},
+	},
+	CommandEndpoint{
+		Path:     "/renter/download/*siapath",
+		Method:   "GET",
+		HelpText: "downloads a renter file",
+		Params: []CommandParam{
+			CommandParam{Key: "siapath", HelpText: "path of the file or folder in the renter", Location: URLParam, Required: true},
+			CommandParam{Key: "destination", HelpText: "absolute path on the daemon's machine to download to", Location: QueryParam},
+			CommandParam{Key: "httpresp", HelpText: "true to return the file in the response", Location: QueryParam, Type: BoolType},
+			CommandParam{Key: "async", HelpText: "true to return before the download finishes", Location: QueryParam, Type: BoolType},
+			CommandParam{Key: "offset", HelpText: "byte offset to start downloading from", Location: QueryParam, Type: IntType},
+			CommandParam{Key: "length", HelpText: "number of bytes to download", Location: QueryParam, Formatter: DataFormat},
+		},
+		Binary: true,
+	},
+	CommandEndpoint{
+		Path:     "/renter/download/cancel",
+		Method:   "POST",
+		HelpText: "cancels a running download",
+		Params: []CommandParam{
+			CommandParam{Key: "id", HelpText: "ID of the download from /renter/downloads", Location: QueryParam, Required: true},
+		},
+	},
+	CommandEndpoint{
+		Path:     "/renter/downloadsync/*siapath",
+		Method:   "GET",
+		HelpText: "downloads a renter file and waits for the download to finish",
+		Params: []CommandParam{
+			CommandParam{Key: "siapath", HelpText: "path of the file or folder in the renter", Location: URLParam, Required: true},
+			CommandParam{Key: "destination", HelpText: "absolute path on the daemon's machine to download to", Location: QueryParam},
+			CommandParam{Key: "httpresp", HelpText: "true to return the file in the response", Location: QueryParam, Type: BoolType},
+			CommandParam{Key: "offset", HelpText: "byte offset to start downloading from", Location: QueryParam, Type: IntType},
+			CommandParam{Key: "length", HelpText: "number of bytes to download", Location: QueryParam, Formatter: DataFormat},
+		},
+		Binary:       true,
+		DeprecatedIn: "1.4.0",
+		Replacement:  "/renter/download/*siapath",
+	},
+	CommandEndpoint{
+		Path:     "/renter/fuse",
+		Method:   "GET",
+		AddedIn:  "1.4.4",
+		HelpText: "returns the siapaths mounted with FUSE and where they are mounted",
+	},
+	CommandEndpoint{
+		Path:     "/renter/fuse/mount",
+		Method:   "POST",
+		AddedIn:  "1.4.4",
+		HelpText: "mounts a folder of the renter as a read only FUSE filesystem",
+		Params: []CommandParam{
+			CommandParam{Key: "mount", HelpText: "absolute path on the daemon's machine to mount at", Location: QueryParam, Required: true},
+			CommandParam{Key: "siapath", HelpText: "folder to mount, the root folder by default", Location: QueryParam},
+			CommandParam{Key: "readonly", HelpText: "must be true, writable mounts are not supported", Location: QueryParam, Type: BoolType, Required: true},
+			CommandParam{Key: "allowother", HelpText: "true to let other users access the mount", Location: QueryParam, Type: BoolType},
+		},
+	},
+	CommandEndpoint{
+		Path:     "/renter/fuse/unmount",
+		Method:   "POST",
+		AddedIn:  "1.4.4",
+		HelpText: "unmounts a FUSE mount",
+		Params: []CommandParam{
+			CommandParam{Key: "mount", HelpText: "absolute path of the mount on the daemon's machine", Location: QueryParam, Required: true},
+		},
+	},
+	CommandEndpoint{
+		Path:     "/renter/recoveryscan",
+		Method:   "POST",
+		HelpText: "starts a scan of the blockchain for contracts that can be recovered",
+	},
+	CommandEndpoint{
+		Path:     "/renter/recoveryscan",
+		Method:   "GET",
+		HelpText: "returns the progress of the recovery scan",
+	},
+	CommandEndpoint{
+		Path:     "/renter/rename/*siapath",
+		Method:   "POST",
+		HelpText: "renames a renter file",
+		Params: []CommandParam{
+			CommandParam{Key: "siapath", HelpText: "path of the file or folder in the renter", Location: URLParam, Required: true},
+			CommandParam{Key: "newsiapath", HelpText: "new path of the file", Location: QueryParam, Required: true},
+		},
+	},
+	CommandEndpoint{
+		Path:     "/renter/stream/*siapath",
+		Method:   "GET",
+		HelpText: "streams a renter file, supporting range requests",
+		Params: []CommandParam{
+			CommandParam{Key: "siapath", HelpText: "path of the file or folder in the renter", Location: URLParam, Required: true},
+		},
+		Binary: true,
+	},
+	CommandEndpoint{
+		Path:     "/renter/upload/*siapath",
+		Method:   "POST",
+		HelpText: "uploads a file from the daemon's machine",
+		Params: []CommandParam{
+			CommandParam{Key: "siapath", HelpText: "path of the file or folder in the renter", Location: URLParam, Required: true},
+			CommandParam{Key: "source", HelpText: "absolute path of the file on the daemon's machine", Location: QueryParam, Required: true},
+			CommandParam{Key: "datapieces", HelpText: "number of data pieces per chunk", Location: QueryParam, Type: IntType},
+			CommandParam{Key: "paritypieces", HelpText: "number of parity pieces per chunk", Location: QueryParam, Type: IntType},
+			CommandParam{Key: "force", HelpText: "true to replace an existing file", Location: QueryParam, Type: BoolType},
+		},
+	},
+	CommandEndpoint{
+		Path:     "/renter/uploadstream/*siapath",
+		Method:   "POST",
+		HelpText: "uploads the request body as a renter file",
+		Params: []CommandParam{
+			CommandParam{Key: "siapath", HelpText: "path of the file or folder in the renter", Location: URLParam, Required: true},
+			CommandParam{Key: "datapieces", HelpText: "number of data pieces per chunk", Location: QueryParam, Type: IntType},
+			CommandParam{Key: "paritypieces", HelpText: "number of parity pieces per chunk", Location: QueryParam, Type: IntType},
+			CommandParam{Key: "force", HelpText: "true to replace an existing file", Location: QueryParam, Type: BoolType},
+			CommandParam{Key: "repair", HelpText: "true to repair an existing file from the uploaded data", Location: QueryParam, Type: BoolType},
+		},
+	},
+	CommandEndpoint{
+		Path:     "/renter/validate/*siapath",
+		Method:   "POST",
+		HelpText: "validates a siapath",
+		Params: []CommandParam{
+			CommandParam{Key: "siapath", HelpText: "path of the file or folder in the renter", Location: URLParam, Required: true},
+		},
+	},
+	CommandEndpoint{
+		Path:     "/renter/workers",
+		Method:   "GET",
+		AddedIn:  "1.4.4",
+		HelpText: "returns the status of the renter's workers, one per host with a contract",
+	},
+	CommandEndpoint{
+		Path:     "/tpool/confirmed/:id",
+		Method:   "GET",
+		HelpText: "returns whether a transaction has been confirmed",
+		Params: []CommandParam{
+			CommandParam{Key: "id", HelpText: "ID of the transaction", Location: URLParam, Formatter: TransactionIDFormat, Required: true},
+		},
+	},
+	CommandEndpoint{
+		Path:     "/tpool/fee",
+		Method:   "GET",
+		HelpText: "returns the minimum and maximum estimated transaction fees",
+	},
+	CommandEndpoint{
+		Path:     "/tpool/raw/:id",
+		Method:   "GET",
+		HelpText: "returns a transaction in the transaction pool and its parents",
+		Params: []CommandParam{
+			CommandParam{Key: "id", HelpText: "ID of the transaction", Location: URLParam, Formatter: TransactionIDFormat, Required: true},
+		},
+	},
+	CommandEndpoint{
+		Path:     "/tpool/raw",
+		Method:   "POST",
+		HelpText: "broadcasts a transaction and its parents",
+		Params: []CommandParam{
+			CommandParam{Key: "parents", HelpText: "encoded parent transactions", Location: BodyParam},
+			CommandParam{Key: "transaction", HelpText: "encoded transaction", Location: BodyParam, Required: true},
+		},
+	},
+	CommandEndpoint{
+		Path:     "/wallet",
+		Method:   "GET",
+		HelpText: "returns the wallet's status and balances",
+	},
+	CommandEndpoint{
+		Path:     "/wallet/033x",
+		Method:   "POST",
+		HelpText: "loads a v0.3.3.x wallet file into the wallet",
+		Params: []CommandParam{
+			CommandParam{Key: "source", HelpText: "absolute path of the wallet file on the daemon's machine", Location: QueryParam, Required: true},
+			CommandParam{Key: "encryptionpassword", HelpText: "password the wallet is encrypted with", Location: QueryParam, Required: true},
+		},
+	},
+	CommandEndpoint{
+		Path:     "/wallet/address",
+		Method:   "GET",
+		HelpText: "returns a new address from the wallet",
+	},
+	CommandEndpoint{
+		Path:     "/wallet/addresses",
+		Method:   "GET",
+		HelpText: "returns the wallet's addresses",
+	},
+	CommandEndpoint{
+		Path:     "/wallet/seedaddrs",
+		Method:   "GET",
+		HelpText: "returns the addresses generated from the wallet's primary seed",
+		Params: []CommandParam{
+			CommandParam{Key: "count", HelpText: "number of addresses to return", Location: QueryParam, Type: IntType},
+		},
+	},
+	CommandEndpoint{
+		Path:     "/wallet/backup",
+		Method:   "GET",
+		HelpText: "creates a backup of the wallet settings file on the daemon's machine",
+		Params: []CommandParam{
+			CommandParam{Key: "destination", HelpText: "absolute path of the backup on the daemon's machine", Location: QueryParam, Required: true},
+		},
+		Binary:    true,
+		Sensitive: true,
+	},
+	CommandEndpoint{
+		Path:     "/wallet/changepassword",
+		Method:   "POST",
+		HelpText: "changes the wallet's encryption password",
+		Params: []CommandParam{
+			CommandParam{Key: "encryptionpassword", HelpText: "password the wallet is encrypted with", Location: QueryParam, Required: true},
+			CommandParam{Key: "newpassword", HelpText: "new password to encrypt the wallet with", Location: QueryParam, Required: true},
+		},
+	},
+	CommandEndpoint{
+		Path:     "/wallet/init",
+		Method:   "POST",
+		HelpText: "creates a new wallet and returns its seed",
+		Params: []CommandParam{
+			CommandParam{Key: "encryptionpassword", HelpText: "password to encrypt the wallet with, the seed is used if empty", Location: QueryParam},
+			CommandParam{Key: "dictionary", HelpText: "language of the seed, english by default", Location: QueryParam},
+			CommandParam{Key: "force", HelpText: "true to replace an existing wallet", Location: QueryParam, Type: BoolType},
+		},
+	},
+	CommandEndpoint{
+		Path:     "/wallet/init/seed",
+		Method:   "POST",
+		HelpText: "creates a wallet from an existing seed",
+		Params: []CommandParam{
+			CommandParam{Key: "encryptionpassword", HelpText: "password to encrypt the wallet with, the seed is used if empty", Location: QueryParam},
+			CommandParam{Key: "dictionary", HelpText: "language of the seed, english by default", Location: QueryParam},
+			CommandParam{Key: "seed", HelpText: "seed to create the wallet from", Location: QueryParam, Required: true},
+			CommandParam{Key: "force", HelpText: "true to replace an existing wallet", Location: QueryParam, Type: BoolType},
+		},
+	},
+	CommandEndpoint{
+		Path:     "/wallet/seed",
+		Method:   "POST",
+		HelpText: "adds a seed to the wallet",
+		Params: []CommandParam{
+			CommandParam{Key: "encryptionpassword", HelpText: "password the wallet is encrypted with", Location: QueryParam, Required: true},
+			CommandParam{Key: "dictionary", HelpText: "language of the seed, english by default", Location: QueryParam},
+			CommandParam{Key: "seed", HelpText: "seed to add", Location: QueryParam, Required: true},
+		},
+	},
+	CommandEndpoint{
+		Path:     "/wallet/seeds",
+		Method:   "GET",
+		HelpText: "returns the wallet's seeds",
+		Params: []CommandParam{
+			CommandParam{Key: "dictionary", HelpText: "language of the seeds, english by default", Location: QueryParam},
+		},
+		Sensitive: true,
+	},
+	CommandEndpoint{
+		Path:     "/wallet/siacoins",
+		Method:   "POST",
+		HelpText: "sends siacoins to an address or a set of outputs",
+		Params: []CommandParam{
+			CommandParam{Key: "amount", HelpText: "siacoins to send. Required with destination", Location: QueryParam, Formatter: PriceFormat},
+			CommandParam{Key: "destination", HelpText: "address to send to. Either destination or outputs is required", Location: QueryParam, Type: AddressType},
+			CommandParam{Key: "outputs", HelpText: "JSON array of outputs with unlockhash and value", Location: QueryParam},
+			CommandParam{Key: "feeincluded", HelpText: "true to take the fee from the amount sent", Location: QueryParam, Type: BoolType},
+		},
+	},
+	CommandEndpoint{
+		Path:     "/wallet/siafunds",
+		Method:   "POST",
+		HelpText: "sends siafunds to an address",
+		Params: []CommandParam{
+			CommandParam{Key: "amount", HelpText: "number of siafunds to send", Location: QueryParam, Type: IntType, Required: true},
+			CommandParam{Key: "destination", HelpText: "address to send to", Location: QueryParam, Type: AddressType, Required: true},
+		},
+	},
+	CommandEndpoint{
+		Path:     "/wallet/siagkey",
+		Method:   "POST",
+		HelpText: "loads siag key files into the wallet",
+		Params: []CommandParam{
+			CommandParam{Key: "encryptionpassword", HelpText: "password the wallet is encrypted with", Location: QueryParam, Required: true},
+			CommandParam{Key: "keyfiles", HelpText: "comma separated absolute paths of the key files on the daemon's machine", Location: QueryParam, Required: true},
+		},
+	},
+	CommandEndpoint{
+		Path:     "/wallet/sign",
+		Method:   "POST",
+		HelpText: "signs a transaction with the wallet's keys",
+		Params: []CommandParam{
+			CommandParam{Key: "transaction", HelpText: "transaction to sign", Location: BodyParam, Required: true},
+			CommandParam{Key: "tosign", HelpText: "IDs of the inputs to sign", Location: BodyParam},
+		},
+	},
+	CommandEndpoint{
+		Path:     "/wallet/sweep/seed",
+		Method:   "POST",
+		HelpText: "sends all funds of a seed to the wallet",
+		Params: []CommandParam{
+			CommandParam{Key: "dictionary", HelpText: "language of the seed, english by default", Location: QueryParam},
+			CommandParam{Key: "seed", HelpText: "seed to sweep", Location: QueryParam, Required: true},
+		},
+	},
+	CommandEndpoint{
+		Path:     "/wallet/lock",
+		Method:   "POST",
+		HelpText: "locks the wallet",
+	},
+	CommandEndpoint{
+		Path:     "/wallet/transaction/:id",
+		Method:   "GET",
+		HelpText: "returns a wallet transaction",
+		Params: []CommandParam{
+			CommandParam{Key: "id", HelpText: "ID of the transaction", Location: URLParam, Formatter: TransactionIDFormat, Required: true},
+		},
+	},
+	CommandEndpoint{
+		Path:     "/wallet/transactions",
+		Method:   "GET",
+		HelpText: "returns the wallet's transactions in a range of blocks",
+		Params: []CommandParam{
+			CommandParam{Key: "startheight", HelpText: "height of the first block", Location: QueryParam, Type: IntType, Required: true},
+			CommandParam{Key: "endheight", HelpText: "height of the last block", Location: QueryParam, Type: IntType, Required: true},
+		},
+	},
+	CommandEndpoint{
+		Path:     "/wallet/transactions/:addr",
+		Method:   "GET",
+		HelpText: "returns the wallet's transactions involving an address",
+		Params: []CommandParam{
+			CommandParam{Key: "addr", HelpText: "address of the transactions", Location: URLParam, Type: AddressType, Required: true},
+		},
+	},
+	CommandEndpoint{
+		Path:     "/wallet/unlock",
+		Method:   "POST",
+		HelpText: "unlocks the wallet",
+		Params: []CommandParam{
+			CommandParam{Key: "encryptionpassword", HelpText: "password the wallet is encrypted with", Location: QueryParam, Required: true},
+		},
+	},
+	CommandEndpoint{
+		Path:     "/wallet/unlockconditions/:addr",
+		Method:   "GET",
+		HelpText: "returns the unlock conditions of an address",
+		Params: []CommandParam{
+			CommandParam{Key: "addr", HelpText: "address owned by the wallet", Location: URLParam, Type: AddressType, Required: true},
+		},
+	},
+	CommandEndpoint{
+		Path:     "/wallet/unspent",
+		Method:   "GET",
+		HelpText: "returns the wallet's unspent outputs",
+	},
+	CommandEndpoint{
+		Path:     "/wallet/verify/address/:addr",
+		Method:   "GET",
+		HelpText: "checks that an address is valid",
+		Params: []CommandParam{
+			CommandParam{Key: "addr", HelpText: "address to verify", Location: URLParam, Required: true},
+		},
+	},
+	CommandEndpoint{
+		Path:     "/wallet/watch",
+		Method:   "GET",
+		HelpText: "returns the addresses watched by the wallet",
+	},
+	CommandEndpoint{
+		Path:     "/wallet/watch",
+		Method:   "POST",
+		HelpText: "adds or removes watched addresses",
+		Params: []CommandParam{
+			CommandParam{Key: "addresses", HelpText: "addresses to watch or remove", Location: BodyParam, Required: true},
+			CommandParam{Key: "remove", HelpText: "true to remove the addresses", Location: BodyParam, Type: BoolType},
+			CommandParam{Key: "unused", HelpText: "true if the addresses have not been used, skipping the rescan", Location: BodyParam, Type: BoolType},
+		},
+	},
+}
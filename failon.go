@@ -0,0 +1,229 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+type (
+	//failCondition a parsed --fail-on expression in the format "field op value"
+	failCondition struct {
+		Expression string
+		Field      string
+		Operator   string
+		Value      string
+	}
+)
+
+//failOperators the supported comparison operators. Two character operators must come first
+var failOperators = []string{"==", "!=", "<=", ">=", "<", ">"}
+
+//errFieldNotFound returned when a field cannot be found in the response
+var errFieldNotFound = errors.New("field not found")
+
+//parseFailCondition parses a --fail-on expression such as "peers < 4"
+func parseFailCondition(expr string) (cond failCondition, err error) {
+	for _, op := range failOperators {
+		i := strings.Index(expr, op)
+
+		if i == -1 {
+			continue
+		}
+
+		cond = failCondition{
+			Expression: expr,
+			Field:      strings.TrimSpace(expr[:i]),
+			Operator:   op,
+			Value:      strings.Trim(strings.TrimSpace(expr[i+len(op):]), `"'`),
+		}
+
+		// a quoted empty value such as status == "" is allowed, a missing one is not
+		if len(cond.Field) == 0 {
+			err = fmt.Errorf("invalid --fail-on expression %q: missing field", expr)
+		} else if len(strings.TrimSpace(expr[i+len(op):])) == 0 {
+			err = fmt.Errorf("invalid --fail-on expression %q: missing value", expr)
+		}
+
+		return
+	}
+
+	err = fmt.Errorf("invalid --fail-on expression %q: expected one of %s", expr, strings.Join(failOperators, " "))
+	return
+}
+
+//walkField follows the dotted path through the object. Numeric segments index into arrays
+func walkField(obj interface{}, path []string) (interface{}, bool) {
+	for _, seg := range path {
+		switch v := obj.(type) {
+		case map[string]interface{}:
+			next, ok := v[seg]
+
+			if !ok {
+				return nil, false
+			}
+
+			obj = next
+		case []interface{}:
+			i, err := strconv.Atoi(seg)
+
+			if err != nil || i < 0 || i >= len(v) {
+				return nil, false
+			}
+
+			obj = v[i]
+		default:
+			return nil, false
+		}
+	}
+
+	return obj, true
+}
+
+//findField finds the dotted field in the response. If the path does not match from the root
+//the first object containing the first segment is used, so "acceptingcontracts" matches
+//"internalsettings.acceptingcontracts". Keys are searched in sorted order
+func findField(obj interface{}, path []string) (interface{}, bool) {
+	if v, ok := walkField(obj, path); ok {
+		return v, true
+	}
+
+	switch v := obj.(type) {
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+
+		for key := range v {
+			keys = append(keys, key)
+		}
+
+		sort.Strings(keys)
+
+		for _, key := range keys {
+			if found, ok := findField(v[key], path); ok {
+				return found, true
+			}
+		}
+	case []interface{}:
+		for _, child := range v {
+			if found, ok := findField(child, path); ok {
+				return found, true
+			}
+		}
+	}
+
+	return nil, false
+}
+
+//parseNumber parses a JSON number or numeric string with enough precision for hastings values
+func parseNumber(s string) (*big.Float, bool) {
+	f, ok := new(big.Float).SetPrec(256).SetString(s)
+	return f, ok
+}
+
+//compare applies the operator to the result of a three-way comparison
+func compare(operator string, c int) bool {
+	switch operator {
+	case "==":
+		return c == 0
+	case "!=":
+		return c != 0
+	case "<":
+		return c < 0
+	case "<=":
+		return c <= 0
+	case ">":
+		return c > 0
+	case ">=":
+		return c >= 0
+	}
+
+	return false
+}
+
+//Matches evaluates the condition against the field's value. Arrays and objects compared to
+//a number use their length
+func (cond failCondition) Matches(value interface{}) (bool, error) {
+	if v, ok := value.([]interface{}); ok {
+		value = json.Number(strconv.Itoa(len(v)))
+	} else if v, ok := value.(map[string]interface{}); ok {
+		value = json.Number(strconv.Itoa(len(v)))
+	}
+
+	switch v := value.(type) {
+	case nil:
+		if cond.Operator != "==" && cond.Operator != "!=" {
+			return false, fmt.Errorf("%s: cannot compare null with %s", cond.Expression, cond.Operator)
+		}
+
+		return compare(cond.Operator, strings.Compare("null", cond.Value)), nil
+	case bool:
+		expected, err := strconv.ParseBool(cond.Value)
+
+		if err != nil || (cond.Operator != "==" && cond.Operator != "!=") {
+			return false, fmt.Errorf("%s: %s is a boolean", cond.Expression, cond.Field)
+		}
+
+		return compare(cond.Operator, strings.Compare(strconv.FormatBool(v), strconv.FormatBool(expected))), nil
+	case json.Number, string:
+		str := fmt.Sprint(v)
+		actual, actualOK := parseNumber(str)
+		expected, expectedOK := parseNumber(cond.Value)
+
+		if actualOK && expectedOK {
+			return compare(cond.Operator, actual.Cmp(expected)), nil
+		}
+
+		if cond.Operator != "==" && cond.Operator != "!=" {
+			return false, fmt.Errorf("%s: %s is not a number", cond.Expression, cond.Field)
+		}
+
+		return compare(cond.Operator, strings.Compare(str, cond.Value)), nil
+	}
+
+	return false, fmt.Errorf("%s: unsupported value type for %s", cond.Expression, cond.Field)
+}
+
+//checkFailConditions evaluates the command's --fail-on expressions against the response body and
+//returns the first condition that matched
+func checkFailConditions(cmd Command, body []byte) (matched *failCondition, err error) {
+	var obj interface{}
+
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.UseNumber()
+
+	if err = dec.Decode(&obj); err != nil {
+		err = fmt.Errorf("unable to evaluate --fail-on, response is not JSON: %s", err)
+		return
+	}
+
+	for _, expr := range cmd.FailOn {
+		cond, err := parseFailCondition(expr)
+
+		if err != nil {
+			return nil, err
+		}
+
+		value, ok := findField(obj, strings.Split(cond.Field, "."))
+
+		if !ok {
+			return nil, fmt.Errorf("%s: %s", cond.Field, errFieldNotFound)
+		}
+
+		match, err := cond.Matches(value)
+
+		if err != nil {
+			return nil, err
+		}
+
+		if match {
+			return &cond, nil
+		}
+	}
+
+	return
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+type (
+	//FastestAddress the address --select-fastest chose among a profile's addresses
+	FastestAddress struct {
+		Address  string    `json:"address"`
+		Latency  string    `json:"latency"`
+		Selected time.Time `json:"selected"`
+	}
+
+	//addressProbe the result of probing an address
+	addressProbe struct {
+		address string
+		latency time.Duration
+		err     error
+	}
+)
+
+//fastestAddressTTL how long the address chosen by --select-fastest is reused before the
+//addresses are probed again
+const fastestAddressTTL = 5 * time.Minute
+
+//probeAddresses asks each address for /daemon/version at the same time, waiting at most the
+//connect timeout for each
+func probeAddresses(cmd Command, addresses []string) []addressProbe {
+	probes := make([]addressProbe, len(addresses))
+	var wg sync.WaitGroup
+
+	for i, address := range addresses {
+		wg.Add(1)
+
+		go func(i int, address string) {
+			defer wg.Done()
+
+			probeCmd := cmd
+			probeCmd.APIAddress = address
+			probeCmd.Timeout = cmd.ConnectTimeout
+
+			start := time.Now()
+			var version daemonVersionResponse
+			err := callAPI(probeCmd, "GET", "/daemon/version", nil, &version)
+			probes[i] = addressProbe{address: address, latency: time.Since(start), err: err}
+		}(i, address)
+	}
+
+	wg.Wait()
+
+	return probes
+}
+
+//selectFastest sends the command to the address of its profile's addresses whose daemon
+//answered its version the quickest: --select-fastest. The choice is cached in the state for a few
+//minutes so consecutive commands do not probe every address
+func selectFastest(cmd *Command) (err error) {
+	if !cmd.SelectFastest {
+		return
+	}
+
+	if len(cmd.Addresses) == 0 {
+		return fmt.Errorf("--select-fastest needs a profile or config with addresses")
+	}
+
+	key := strings.Join(cmd.Addresses, ",")
+	state, err := LoadState()
+
+	if err != nil {
+		return
+	}
+
+	if cached, ok := state.FastestAddresses[key]; ok && time.Since(cached.Selected) < fastestAddressTTL {
+		cmd.APIAddress = cached.Address
+		return
+	}
+
+	var fastest *addressProbe
+	probes := probeAddresses(*cmd, cmd.Addresses)
+	var failures []string
+
+	for i, probe := range probes {
+		if probe.err != nil {
+			failures = append(failures, fmt.Sprintf("%s: %s", probe.address, probe.err))
+			continue
+		}
+
+		if fastest == nil || probe.latency < fastest.latency {
+			fastest = &probes[i]
+		}
+	}
+
+	if fastest == nil {
+		return ExitCodeError{Code: ExitConnectionError, Err: fmt.Errorf("no address responded: %s", strings.Join(failures, "; "))}
+	}
+
+	for _, failure := range failures {
+		notify(*cmd, "warning: skipping %s", failure)
+	}
+
+	notify(*cmd, "selected %s, %s", fastest.address, fastest.latency.Round(time.Millisecond))
+	cmd.APIAddress = fastest.address
+
+	if state.FastestAddresses == nil {
+		state.FastestAddresses = make(map[string]FastestAddress)
+	}
+
+	state.FastestAddresses[key] = FastestAddress{
+		Address:  fastest.address,
+		Latency:  fastest.latency.Round(time.Millisecond).String(),
+		Selected: time.Now(),
+	}
+
+	return SaveState(state)
+}
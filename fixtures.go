@@ -0,0 +1,208 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/n8maninger/siac-json/endpoints"
+)
+
+type (
+	//fixture a captured response of an endpoint, one file per endpoint
+	fixture struct {
+		Method   string    `json:"method"`
+		Path     string    `json:"path"`
+		Captured time.Time `json:"captured"`
+		//Version the version of the daemon the response was captured from
+		Version string `json:"version,omitempty"`
+		//Redacted the fields that were redacted, as dotted paths
+		Redacted []string    `json:"redacted,omitempty"`
+		Response interface{} `json:"response"`
+	}
+
+	//fixturesReport the result of fixtures capture
+	fixturesReport struct {
+		Dir      string   `json:"dir"`
+		Files    []string `json:"files"`
+		Redacted int      `json:"redacted"`
+	}
+)
+
+//fixtureSensitiveFields fields of responses that are redacted from fixtures in addition to the
+//sensitive params: seeds and the addresses that identify the node and its wallet
+var fixtureSensitiveFields = map[string]bool{
+	"address":     true,
+	"addresses":   true,
+	"allseeds":    true,
+	"netaddress":  true,
+	"primaryseed": true,
+	"unlockhash":  true,
+}
+
+//fixtureHexRe matches hex values, which are redacted to zeros so they keep their length and
+//still parse as hashes
+var fixtureHexRe = regexp.MustCompile(`^[0-9a-f]+$`)
+
+//redactFixture returns a copy of the response with the values of sensitive fields redacted,
+//appending the path of each redacted field
+func redactFixture(v interface{}, path string, sensitive bool, redactedFields *[]string) interface{} {
+	switch t := v.(type) {
+	case map[string]interface{}:
+		c := make(map[string]interface{}, len(t))
+
+		for key, value := range t {
+			lower := strings.ToLower(key)
+			c[key] = redactFixture(value, strings.TrimPrefix(path+"."+key, "."), sensitive || sensitiveParams[lower] || fixtureSensitiveFields[lower], redactedFields)
+		}
+
+		return c
+	case []interface{}:
+		c := make([]interface{}, len(t))
+
+		for i, value := range t {
+			c[i] = redactFixture(value, strings.TrimPrefix(fmt.Sprintf("%s.%d", path, i), "."), sensitive, redactedFields)
+		}
+
+		return c
+	case string:
+		if !sensitive || len(t) == 0 {
+			return t
+		}
+
+		*redactedFields = append(*redactedFields, path)
+
+		if fixtureHexRe.MatchString(t) {
+			return strings.Repeat("0", len(t))
+		}
+
+		return redacted
+	}
+
+	return v
+}
+
+//fixtureName returns the file name of the fixture of the request, such as GET_renter_contracts.json
+func fixtureName(method, path string) string {
+	return method + "_" + strings.Replace(strings.Trim(path, "/"), "/", "_", -1) + ".json"
+}
+
+//writeFixture writes the redacted response of the GET endpoint to its fixture file in the dir
+func writeFixture(dir, path, version string, response interface{}) (name string, f fixture, err error) {
+	f = fixture{
+		Method:   "GET",
+		Path:     path,
+		Captured: time.Now().UTC().Truncate(time.Second),
+		Version:  version,
+	}
+	f.Response = redactFixture(response, "", false, &f.Redacted)
+	sort.Strings(f.Redacted)
+
+	buf, err := json.MarshalIndent(f, "", "\t")
+
+	if err != nil {
+		return
+	}
+
+	name = filepath.Join(dir, fixtureName(f.Method, path))
+
+	// fixtures are readable by test servers, the secrets have been redacted
+	err = ioutil.WriteFile(name, append(buf, '\n'), 0644)
+
+	return
+}
+
+//readFixture reads a fixture file
+func readFixture(name string) (f fixture, err error) {
+	buf, err := ioutil.ReadFile(name)
+
+	if err != nil {
+		return
+	}
+
+	if err = json.Unmarshal(buf, &f); err != nil {
+		return f, fmt.Errorf("unable to read fixture %s: %s", name, err)
+	}
+
+	return
+}
+
+//fixtureEndpoints returns the paths of the GET endpoints named by --endpoints, such as
+//--endpoints wallet,consensus
+func fixtureEndpoints(cmd Command) (paths []string, err error) {
+	for _, value := range cmd.Params["endpoints"] {
+		for _, name := range strings.Split(value, ",") {
+			if name = strings.Trim(strings.TrimSpace(name), "/"); len(name) > 0 {
+				paths = append(paths, "/"+name)
+			}
+		}
+	}
+
+	for _, path := range paths {
+		if len(endpoints.Match(path, "GET")) == 0 {
+			return nil, fmt.Errorf("%s is not a GET endpoint", path)
+		}
+	}
+
+	return
+}
+
+//fixturesCaptureCommand captures the responses of GET endpoints of a live daemon as fixtures in
+//the --output directory, fixtures by default: fixtures capture --endpoints wallet,consensus.
+//Seeds, passwords and addresses are redacted from the responses and each fixture lists the
+//fields that were redacted
+func fixturesCaptureCommand(cmd Command) (err error) {
+	paths, err := fixtureEndpoints(cmd)
+
+	if err != nil {
+		return
+	} else if len(paths) == 0 {
+		return fmt.Errorf("--endpoints is required, such as --endpoints wallet,consensus")
+	}
+
+	dir := cmd.OutputFile
+
+	if len(dir) == 0 {
+		dir = "fixtures"
+	}
+
+	if err = os.MkdirAll(dir, 0700); err != nil {
+		return
+	}
+
+	var version daemonVersionResponse
+
+	// the version is informational, daemons without /daemon/version can still be captured
+	callAPI(cmd, "GET", "/daemon/version", nil, &version)
+
+	report := fixturesReport{
+		Dir:   dir,
+		Files: []string{},
+	}
+
+	for _, path := range paths {
+		var response interface{}
+
+		if err = callAPI(cmd, "GET", path, nil, &response); err != nil {
+			return fmt.Errorf("unable to capture %s: %s", path, err)
+		}
+
+		name, f, err := writeFixture(dir, path, version.Version, response)
+
+		if err != nil {
+			return err
+		}
+
+		notify(cmd, "captured %s to %s, %d fields redacted", path, name, len(f.Redacted))
+		report.Files = append(report.Files, name)
+		report.Redacted += len(f.Redacted)
+	}
+
+	return writeJSON(report)
+}
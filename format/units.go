@@ -0,0 +1,234 @@
+//Package format parses and formats the units used by the Sia API and by sia-json: Siacoin
+//amounts, byte counts and durations
+package format
+
+import (
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//CurrencyUnits the Siacoin units in increasing order. Each unit is 1000x the previous, starting at 10^12 hastings
+var CurrencyUnits = []string{"pS", "nS", "uS", "mS", "SC", "KS", "MS", "GS", "TS"}
+
+//CurrencyUnitHastings returns the number of hastings in the currency unit at index i
+func CurrencyUnitHastings(i int) *big.Int {
+	return new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(12+3*i)), nil)
+}
+
+//ParseCurrency parses a Siacoin amount like "100SC", "1.5KS" or "1000H" into hastings
+func ParseCurrency(s string) (*big.Int, error) {
+	s = strings.TrimSpace(s)
+
+	if strings.HasSuffix(s, "H") {
+		h, ok := new(big.Int).SetString(strings.TrimSpace(strings.TrimSuffix(s, "H")), 10)
+
+		if !ok || h.Sign() < 0 {
+			return nil, fmt.Errorf("invalid currency %q", s)
+		}
+
+		return h, nil
+	}
+
+	for i, unit := range CurrencyUnits {
+		if !strings.HasSuffix(s, unit) {
+			continue
+		}
+
+		r, ok := new(big.Rat).SetString(strings.TrimSpace(strings.TrimSuffix(s, unit)))
+
+		if !ok || r.Sign() < 0 {
+			return nil, fmt.Errorf("invalid currency %q", s)
+		}
+
+		r.Mul(r, new(big.Rat).SetInt(CurrencyUnitHastings(i)))
+
+		if !r.IsInt() {
+			return nil, fmt.Errorf("invalid currency %q: smaller than 1 hasting", s)
+		}
+
+		return r.Num(), nil
+	}
+
+	return nil, fmt.Errorf("invalid currency %q: expected a unit such as SC or H", s)
+}
+
+//BlocksPerMonth the number of blocks in a month at 10 minutes per block, as used by siac
+const BlocksPerMonth = 4320
+
+//parsePerSize parses a price like "25SC/TB" into the currency and the bytes of the size unit.
+//A price without a size uses the default size
+func parsePerSize(s string, defaultSize uint64) (h *big.Int, size uint64, err error) {
+	size = defaultSize
+
+	if i := strings.Index(s, "/"); i >= 0 {
+		if size, err = ParseBytes("1" + s[i+1:]); err != nil || size == 0 {
+			return nil, 0, fmt.Errorf("invalid price %q: expected a size such as /TB", s)
+		}
+
+		s = s[:i]
+	}
+
+	h, err = ParseCurrency(s)
+
+	return
+}
+
+//ParsePrice parses a price like "100SC" into hastings. A price per size like "25SC/TB" is
+//converted to hastings per byte, as siac does for bandwidth prices
+func ParsePrice(s string) (*big.Int, error) {
+	h, size, err := parsePerSize(strings.TrimSpace(s), 1)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return h.Div(h, new(big.Int).SetUint64(size)), nil
+}
+
+//ParseMonthlyPrice parses a storage price per TB per month like "100SC" or "100SC/TB/month"
+//into hastings per byte per block, like siac
+func ParseMonthlyPrice(s string) (*big.Int, error) {
+	s = strings.TrimSpace(s)
+
+	if strings.HasSuffix(strings.ToLower(s), "/month") {
+		s = s[:len(s)-len("/month")]
+	}
+
+	h, size, err := parsePerSize(s, 1e12)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return h.Div(h, new(big.Int).Mul(new(big.Int).SetUint64(size), big.NewInt(BlocksPerMonth))), nil
+}
+
+//FormatCurrency formats hastings in the largest unit that keeps the value at least 1, like siac
+func FormatCurrency(h *big.Int) string {
+	if h.Cmp(CurrencyUnitHastings(0)) < 0 {
+		return h.String() + " H"
+	}
+
+	i := len(CurrencyUnits) - 1
+
+	for ; i > 0 && h.Cmp(CurrencyUnitHastings(i)) < 0; i-- {
+	}
+
+	value := new(big.Rat).SetFrac(h, CurrencyUnitHastings(i))
+
+	return strings.TrimRight(strings.TrimRight(value.FloatString(3), "0"), ".") + " " + CurrencyUnits[i]
+}
+
+//FormatBytes formats the byte count using binary units
+func FormatBytes(n int64) string {
+	units := []string{"B", "KiB", "MiB", "GiB", "TiB", "PiB"}
+	value := float64(n)
+	i := 0
+
+	for ; value >= 1024 && i < len(units)-1; i++ {
+		value /= 1024
+	}
+
+	if i == 0 {
+		return fmt.Sprintf("%d %s", n, units[0])
+	}
+
+	return fmt.Sprintf("%.1f %s", value, units[i])
+}
+
+//byteUnits the decimal and binary size units accepted by ParseBytes
+var byteUnits = map[string]uint64{
+	"B":   1,
+	"KB":  1e3,
+	"MB":  1e6,
+	"GB":  1e9,
+	"TB":  1e12,
+	"PB":  1e15,
+	"KIB": 1 << 10,
+	"MIB": 1 << 20,
+	"GIB": 1 << 30,
+	"TIB": 1 << 40,
+	"PIB": 1 << 50,
+}
+
+//ParseBytes parses a data size like "10TB", "500GiB" or "4096" into bytes. Units are case
+//insensitive, KB to PB are powers of 1000 like siac and KiB to PiB powers of 1024
+func ParseBytes(s string) (uint64, error) {
+	s = strings.TrimSpace(s)
+	n := s
+	i := strings.IndexFunc(s, func(r rune) bool { return !(r >= '0' && r <= '9' || r == '.') })
+	unit := uint64(1)
+
+	if i >= 0 {
+		var ok bool
+
+		if unit, ok = byteUnits[strings.ToUpper(strings.TrimSpace(s[i:]))]; !ok {
+			return 0, fmt.Errorf("invalid data size %q: expected a unit such as TB or GiB", s)
+		}
+
+		n = s[:i]
+	}
+
+	r, ok := new(big.Rat).SetString(n)
+
+	if !ok || r.Sign() < 0 {
+		return 0, fmt.Errorf("invalid data size %q", s)
+	}
+
+	r.Mul(r, new(big.Rat).SetInt(new(big.Int).SetUint64(unit)))
+
+	if !r.IsInt() {
+		return 0, fmt.Errorf("invalid data size %q: not a whole number of bytes", s)
+	} else if !r.Num().IsUint64() {
+		return 0, fmt.Errorf("invalid data size %q: too large", s)
+	}
+
+	return r.Num().Uint64(), nil
+}
+
+//ParseDuration parses a duration like time.ParseDuration with additional support for
+//days "7d" and weeks "2w"
+func ParseDuration(s string) (time.Duration, error) {
+	for suffix, unit := range map[string]time.Duration{"d": 24 * time.Hour, "w": 7 * 24 * time.Hour} {
+		if !strings.HasSuffix(s, suffix) {
+			continue
+		}
+
+		n, err := strconv.ParseFloat(strings.TrimSuffix(s, suffix), 64)
+
+		if err != nil {
+			return 0, fmt.Errorf("invalid duration %q", s)
+		}
+
+		return time.Duration(n * float64(unit)), nil
+	}
+
+	return time.ParseDuration(s)
+}
+
+//BlockTime the target time between blocks
+const BlockTime = 10 * time.Minute
+
+//ParseBlocks parses a block count like "4032", "4032b" or a duration like "12w", "3d" or "6h" into
+//blocks at 10 minutes per block, like siac. Durations of less than a block are an error
+func ParseBlocks(s string) (uint64, error) {
+	s = strings.TrimSpace(s)
+
+	if n, err := strconv.ParseUint(strings.TrimSuffix(s, "b"), 10, 64); err == nil {
+		return n, nil
+	}
+
+	d, err := ParseDuration(s)
+
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("invalid block count %q: expected blocks or a duration such as 12w", s)
+	} else if d > 0 && d < BlockTime {
+		// it would round down to no blocks at all
+		return 0, fmt.Errorf("invalid block count %q: durations shorter than one block (%s) are not supported", s, BlockTime)
+	}
+
+	return uint64(d / BlockTime), nil
+}
@@ -0,0 +1,372 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/n8maninger/siac-json/format"
+)
+
+type (
+	//gatewayPeer the fields of a /gateway peer used by sia-json
+	gatewayPeer struct {
+		NetAddress string `json:"netaddress"`
+		Version    string `json:"version"`
+		Inbound    bool   `json:"inbound"`
+	}
+
+	//peerCheck the result of checking a peer against the prune criteria
+	peerCheck struct {
+		NetAddress string   `json:"netaddress"`
+		Version    string   `json:"version"`
+		Latency    string   `json:"latency,omitempty"`
+		Reasons    []string `json:"reasons,omitempty"`
+	}
+
+	//gatewayPruneReport the result of gateway prune
+	gatewayPruneReport struct {
+		DryRun       bool        `json:"dryrun"`
+		Peers        int         `json:"peers"`
+		Failing      []peerCheck `json:"failing"`
+		Disconnected []string    `json:"disconnected"`
+	}
+
+	//gatewayResponse the fields of /gateway used by sia-json
+	gatewayResponse struct {
+		NetAddress string        `json:"netaddress"`
+		Peers      []gatewayPeer `json:"peers"`
+	}
+
+	//gatewayBandwidthResponse the response of /gateway/bandwidth. Older versions of siad do not
+	//have the endpoint
+	gatewayBandwidthResponse struct {
+		Download  uint64    `json:"download"`
+		Upload    uint64    `json:"upload"`
+		StartTime time.Time `json:"starttime"`
+	}
+
+	//gatewaySample a recorded sample of the gateway's connections
+	gatewaySample struct {
+		Time     time.Time `json:"time"`
+		Peers    int       `json:"peers"`
+		Inbound  int       `json:"inbound"`
+		Outbound int       `json:"outbound"`
+		Download *uint64   `json:"download,omitempty"`
+		Upload   *uint64   `json:"upload,omitempty"`
+	}
+
+	//gatewayOutage a period where every sample had no peers
+	gatewayOutage struct {
+		Start time.Time `json:"start"`
+		End   time.Time `json:"end"`
+	}
+
+	//gatewayStatsReport the result of gateway stats
+	gatewayStatsReport struct {
+		Samples       int             `json:"samples"`
+		First         *time.Time      `json:"first"`
+		Last          *time.Time      `json:"last"`
+		MinPeers      int             `json:"minpeers"`
+		MaxPeers      int             `json:"maxpeers"`
+		AveragePeers  float64         `json:"averagepeers"`
+		Outages       []gatewayOutage `json:"outages"`
+		Downloaded    *uint64         `json:"downloaded,omitempty"`
+		Uploaded      *uint64         `json:"uploaded,omitempty"`
+		DownloadSpeed *float64        `json:"downloadspeed,omitempty"`
+		UploadSpeed   *float64        `json:"uploadspeed,omitempty"`
+	}
+)
+
+//defaultGatewayInterval how often gateway stats record samples the gateway
+const defaultGatewayInterval = time.Minute
+
+//sampleGateway records the gateway's current peer counts and bandwidth counters
+func sampleGateway(cmd Command) (sample gatewaySample, err error) {
+	var gateway gatewayResponse
+
+	if err = callAPI(cmd, "GET", "/gateway", nil, &gateway); err != nil {
+		return
+	}
+
+	sample = gatewaySample{
+		Time:  time.Now().UTC(),
+		Peers: len(gateway.Peers),
+	}
+
+	for _, peer := range gateway.Peers {
+		if peer.Inbound {
+			sample.Inbound++
+		} else {
+			sample.Outbound++
+		}
+	}
+
+	var bandwidth gatewayBandwidthResponse
+
+	if callAPI(cmd, "GET", "/gateway/bandwidth", nil, &bandwidth) == nil {
+		sample.Download = &bandwidth.Download
+		sample.Upload = &bandwidth.Upload
+	}
+
+	return
+}
+
+//gatewayStatsRecordCommand samples the gateway every --interval until interrupted. With
+//--max-interval the gateway is sampled less often while its connections do not change
+func gatewayStatsRecordCommand(cmd Command) (err error) {
+	interval := defaultGatewayInterval
+
+	if values := cmd.Params["interval"]; len(values) > 0 {
+		if interval, err = format.ParseDuration(values[0]); err != nil {
+			return
+		}
+	}
+
+	scheduler, err := commandScheduler(cmd, interval)
+
+	if err != nil {
+		return
+	}
+
+	path := metricsPath("gateway", cmd.APIAddress)
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, os.Interrupt)
+	var prev gatewaySample
+
+	notify(cmd, "recording gateway stats to %s every %s", path, interval)
+
+	for {
+		sample, err := sampleGateway(cmd)
+
+		// an unreachable daemon is recorded as having no peers so the outage shows up in stats
+		if err != nil {
+			notify(cmd, "unable to sample gateway: %s", err)
+			sample = gatewaySample{Time: time.Now().UTC()}
+		}
+
+		if err := appendMetric(path, sample); err != nil {
+			return err
+		}
+
+		// the bandwidth counters always move so only connection changes count
+		changed := sample.Peers != prev.Peers || sample.Inbound != prev.Inbound
+		prev = sample
+
+		select {
+		case <-time.After(scheduler.Next(changed)):
+		case <-sigs:
+			return nil
+		}
+	}
+}
+
+//gatewayStatsCommand summarizes the gateway samples recorded within --since (default 24h)
+func gatewayStatsCommand(cmd Command) (err error) {
+	since := 24 * time.Hour
+
+	if values := cmd.Params["since"]; len(values) > 0 {
+		if since, err = format.ParseDuration(values[0]); err != nil {
+			return
+		}
+	}
+
+	cutoff := time.Now().Add(-since)
+	report := gatewayStatsReport{Outages: []gatewayOutage{}}
+	var first, last gatewaySample
+	var outage *gatewayOutage
+	totalPeers := 0
+
+	err = readMetrics(metricsPath("gateway", cmd.APIAddress), func(line []byte) {
+		var sample gatewaySample
+
+		// a partially written last line is skipped rather than failing the report
+		if json.Unmarshal(line, &sample) != nil || sample.Time.Before(cutoff) {
+			return
+		}
+
+		if report.Samples == 0 {
+			first = sample
+			report.MinPeers = sample.Peers
+		}
+
+		report.Samples++
+		totalPeers += sample.Peers
+		last = sample
+
+		if sample.Peers < report.MinPeers {
+			report.MinPeers = sample.Peers
+		}
+
+		if sample.Peers > report.MaxPeers {
+			report.MaxPeers = sample.Peers
+		}
+
+		if sample.Peers == 0 && outage == nil {
+			outage = &gatewayOutage{Start: sample.Time, End: sample.Time}
+		} else if sample.Peers == 0 {
+			outage.End = sample.Time
+		} else if outage != nil {
+			report.Outages = append(report.Outages, *outage)
+			outage = nil
+		}
+	})
+
+	if err != nil {
+		return
+	}
+
+	if outage != nil {
+		report.Outages = append(report.Outages, *outage)
+	}
+
+	if report.Samples == 0 {
+		notify(cmd, "no gateway stats recorded in the last %s. Run gateway stats record to start recording", since)
+		return writeJSON(report)
+	}
+
+	report.First, report.Last = &first.Time, &last.Time
+	report.AveragePeers = float64(totalPeers) / float64(report.Samples)
+
+	// the counters reset when siad restarts, so a decrease means they cannot be compared
+	if first.Download != nil && last.Download != nil && *last.Download >= *first.Download && *last.Upload >= *first.Upload {
+		downloaded, uploaded := *last.Download-*first.Download, *last.Upload-*first.Upload
+		report.Downloaded, report.Uploaded = &downloaded, &uploaded
+
+		if elapsed := last.Time.Sub(first.Time).Seconds(); elapsed > 0 {
+			downloadSpeed, uploadSpeed := float64(downloaded)/elapsed, float64(uploaded)/elapsed
+			report.DownloadSpeed, report.UploadSpeed = &downloadSpeed, &uploadSpeed
+		}
+	}
+
+	return writeJSON(report)
+}
+
+//compareVersions compares two dotted version strings numerically, returning -1, 0 or 1. Missing
+//components are treated as 0 so 1.5 == 1.5.0
+func compareVersions(a, b string) int {
+	as, bs := strings.Split(a, "."), strings.Split(b, ".")
+
+	for i := 0; i < len(as) || i < len(bs); i++ {
+		var x, y int
+
+		if i < len(as) {
+			x, _ = strconv.Atoi(as[i])
+		}
+
+		if i < len(bs) {
+			y, _ = strconv.Atoi(bs[i])
+		}
+
+		if x != y {
+			if x < y {
+				return -1
+			}
+
+			return 1
+		}
+	}
+
+	return 0
+}
+
+//checkPeers checks each peer's version and the time taken to open a connection to it
+func checkPeers(peers []gatewayPeer, minVersion string, maxLatency time.Duration) []peerCheck {
+	checks := make([]peerCheck, len(peers))
+	var wg sync.WaitGroup
+
+	for i, peer := range peers {
+		wg.Add(1)
+
+		go func(i int, peer gatewayPeer) {
+			defer wg.Done()
+
+			check := peerCheck{
+				NetAddress: peer.NetAddress,
+				Version:    peer.Version,
+			}
+
+			if len(minVersion) > 0 && compareVersions(peer.Version, minVersion) < 0 {
+				check.Reasons = append(check.Reasons, fmt.Sprintf("version %s is older than %s", peer.Version, minVersion))
+			}
+
+			if maxLatency > 0 {
+				start := time.Now()
+				conn, err := net.DialTimeout("tcp", peer.NetAddress, maxLatency)
+				latency := time.Since(start)
+
+				if err != nil {
+					check.Reasons = append(check.Reasons, fmt.Sprintf("unreachable within %s", maxLatency))
+				} else {
+					conn.Close()
+					check.Latency = latency.Round(time.Millisecond).String()
+				}
+			}
+
+			checks[i] = check
+		}(i, peer)
+	}
+
+	wg.Wait()
+
+	return checks
+}
+
+//gatewayPruneCommand disconnects peers older than --min-version or slower to connect to than
+//--max-latency. With --dry-run the failing peers are listed but not disconnected
+func gatewayPruneCommand(cmd Command) (err error) {
+	var maxLatency time.Duration
+	minVersion := ""
+
+	if values := cmd.Params["min-version"]; len(values) > 0 {
+		minVersion = values[0]
+	}
+
+	if values := cmd.Params["max-latency"]; len(values) > 0 {
+		if maxLatency, err = format.ParseDuration(values[0]); err != nil {
+			return
+		}
+	}
+
+	if len(minVersion) == 0 && maxLatency == 0 {
+		return fmt.Errorf("at least one of --min-version or --max-latency is required")
+	}
+
+	var gateway gatewayResponse
+
+	if err = callAPI(cmd, "GET", "/gateway", nil, &gateway); err != nil {
+		return
+	}
+
+	report := gatewayPruneReport{
+		DryRun:       cmd.DryRun,
+		Peers:        len(gateway.Peers),
+		Failing:      []peerCheck{},
+		Disconnected: []string{},
+	}
+
+	for _, check := range checkPeers(gateway.Peers, minVersion, maxLatency) {
+		if len(check.Reasons) > 0 {
+			report.Failing = append(report.Failing, check)
+		}
+	}
+
+	if !cmd.DryRun {
+		for _, check := range report.Failing {
+			if err := callAPI(cmd, "POST", "/gateway/disconnect/"+check.NetAddress, nil, nil); err != nil {
+				notify(cmd, "unable to disconnect %s: %s", check.NetAddress, err)
+				continue
+			}
+
+			report.Disconnected = append(report.Disconnected, check.NetAddress)
+		}
+	}
+
+	return writeJSON(report)
+}
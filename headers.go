@@ -0,0 +1,50 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/textproto"
+	"regexp"
+	"strings"
+)
+
+//headerNameRe matches the valid characters of a header name
+var headerNameRe = regexp.MustCompile("^[!#$%&'*+.^_`|~0-9A-Za-z-]+$")
+
+//parseHeader parses a "Name: value" header
+func parseHeader(s string) (name, value string, err error) {
+	parts := strings.SplitN(s, ":", 2)
+
+	if len(parts) != 2 || !headerNameRe.MatchString(strings.TrimSpace(parts[0])) {
+		return "", "", fmt.Errorf("invalid header %q, expected \"Name: value\"", s)
+	}
+
+	return textproto.CanonicalMIMEHeaderKey(strings.TrimSpace(parts[0])), strings.TrimSpace(parts[1]), nil
+}
+
+//applyHeaders adds the "Name: value" headers to those the command's requests are sent with. A
+//header given again replaces the value from an earlier source, so --header overrides a profile
+//and a profile the config file
+func applyHeaders(cmd *Command, headers []string) error {
+	parsed := make(http.Header)
+
+	for _, header := range headers {
+		name, value, err := parseHeader(header)
+
+		if err != nil {
+			return err
+		}
+
+		parsed.Add(name, value)
+	}
+
+	if len(parsed) > 0 && cmd.Header == nil {
+		cmd.Header = make(http.Header)
+	}
+
+	for name, values := range parsed {
+		cmd.Header[name] = values
+	}
+
+	return nil
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/n8maninger/siac-json/endpoints"
+)
+
+//the help commands are registered at init because they list BuiltinCommands themselves
+func init() {
+	BuiltinCommands = append(BuiltinCommands,
+		BuiltinCommand{
+			Path:     "/help",
+			HelpText: "lists the known endpoints and commands",
+			Run:      helpCommand,
+		},
+		BuiltinCommand{
+			Path:     "/help/*path",
+			HelpText: "lists the endpoints and commands matching the path with their params, their location, format and whether they are required",
+			Run:      helpCommand,
+		},
+	)
+}
+
+//helpPath returns the request path of the words after help. Paths may be typed with spaces or
+//slashes
+func helpPath(args []string) string {
+	var segments []string
+
+	for _, arg := range args {
+		for _, seg := range strings.Split(arg, "/") {
+			if len(seg) > 0 {
+				segments = append(segments, seg)
+			}
+		}
+	}
+
+	return "/" + strings.Join(segments, "/")
+}
+
+//matchesHelpPath returns true if the template matches the path or is below it, so
+//help renter lists every /renter endpoint
+func matchesHelpPath(path, template string) bool {
+	return endpoints.MatchPath(path, template) || strings.HasPrefix(template, path+"/")
+}
+
+//writeEndpointHelp writes the method, path, help text and params of the endpoint
+func writeEndpointHelp(w *tabwriter.Writer, endpoint endpoints.CommandEndpoint) {
+	fmt.Fprintf(w, "%s %s\n", endpoint.Method, endpoint.Path)
+
+	if len(endpoint.HelpText) > 0 {
+		fmt.Fprintf(w, "  %s\n", endpoint.HelpText)
+	}
+
+	if len(endpoint.AlternativeMatches) > 0 {
+		fmt.Fprintf(w, "  also matches %s\n", strings.Join(endpoint.AlternativeMatches, ", "))
+	}
+
+	if endpoint.Binary {
+		fmt.Fprintf(w, "  responds with raw file data\n")
+	}
+
+	if len(endpoint.AddedIn) > 0 {
+		fmt.Fprintf(w, "  added in siad %s\n", endpoint.AddedIn)
+	}
+
+	if len(endpoint.DeprecatedIn) > 0 {
+		fmt.Fprintf(w, "  deprecated since siad %s, use %s\n", endpoint.DeprecatedIn, endpoint.Replacement)
+	}
+
+	if len(endpoint.Params) > 0 {
+		fmt.Fprintf(w, "\n  PARAM\tLOCATION\tTYPE\tFORMAT\tREQUIRED\tDESCRIPTION\n")
+	}
+
+	for _, param := range endpoint.Params {
+		required := "no"
+
+		if param.Required {
+			required = "yes"
+		}
+
+		fmt.Fprintf(w, "  %s\t%s\t%s\t%s\t%s\t%s\n", param.Key, param.Location, endpoints.TypeOf(param), param.Formatter, required, param.HelpText)
+	}
+
+	fmt.Fprintln(w)
+}
+
+//helpCommand lists the endpoints and commands matching the path with their params: help [path]
+func helpCommand(cmd Command) (err error) {
+	path := helpPath(cmd.Args[1:])
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	found := false
+
+	// without a path every endpoint is listed on one line
+	if path == "/" {
+		for _, endpoint := range endpoints.All {
+			fmt.Fprintf(w, "%s\t%s\t%s\n", endpoint.Method, endpoint.Path, endpoint.HelpText)
+		}
+
+		for _, builtin := range BuiltinCommands {
+			fmt.Fprintf(w, "\t%s\t%s\n", builtin.Path, builtin.HelpText)
+		}
+
+		return w.Flush()
+	}
+
+	for _, endpoint := range endpoints.All {
+		matched := matchesHelpPath(path, endpoint.Path)
+
+		for _, alt := range endpoint.AlternativeMatches {
+			matched = matched || matchesHelpPath(path, alt)
+		}
+
+		if matched {
+			writeEndpointHelp(w, endpoint)
+			found = true
+		}
+	}
+
+	for _, builtin := range BuiltinCommands {
+		if matchesHelpPath(path, builtin.Path) {
+			fmt.Fprintf(w, "%s (sia-json command)\n  %s\n\n", builtin.Path, builtin.HelpText)
+			found = true
+		}
+	}
+
+	if !found {
+		return fmt.Errorf("no endpoints or commands match %s", path)
+	}
+
+	return w.Flush()
+}
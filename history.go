@@ -0,0 +1,251 @@
+package main
+
+import (
+	"fmt"
+	"math/big"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/n8maninger/siac-json/format"
+)
+
+type (
+	//historicalBlock the fields of a /consensus/blocks block used by sia-json
+	historicalBlock struct {
+		ID        string `json:"id"`
+		Height    uint64 `json:"height"`
+		Timestamp int64  `json:"timestamp"`
+	}
+
+	//processedInput the fields of a wallet transaction input used by sia-json
+	processedInput struct {
+		FundType      string `json:"fundtype"`
+		WalletAddress bool   `json:"walletaddress"`
+		Value         string `json:"value"`
+	}
+
+	//processedOutput the fields of a wallet transaction output used by sia-json
+	processedOutput struct {
+		FundType       string `json:"fundtype"`
+		MaturityHeight uint64 `json:"maturityheight"`
+		WalletAddress  bool   `json:"walletaddress"`
+		Value          string `json:"value"`
+	}
+
+	//processedTransaction the fields of a /wallet/transactions transaction used by sia-json
+	processedTransaction struct {
+		TransactionID      string            `json:"transactionid"`
+		ConfirmationHeight uint64            `json:"confirmationheight"`
+		Inputs             []processedInput  `json:"inputs"`
+		Outputs            []processedOutput `json:"outputs"`
+	}
+
+	//walletTransactionsResponse the confirmed transactions of /wallet/transactions
+	walletTransactionsResponse struct {
+		ConfirmedTransactions []processedTransaction `json:"confirmedtransactions"`
+	}
+
+	//balanceEstimate the result of at wallet balance-estimate
+	balanceEstimate struct {
+		Height           uint64    `json:"height"`
+		BlockID          string    `json:"blockid"`
+		Time             time.Time `json:"time"`
+		Transactions     int       `json:"transactions"`
+		SiacoinBalance   string    `json:"siacoinbalance"`
+		Siacoins         string    `json:"siacoins"`
+		SiafundBalance   string    `json:"siafundbalance"`
+		ImmatureSiacoins string    `json:"immaturesiacoins"`
+	}
+)
+
+//blockAtHeight returns the block at the height
+func blockAtHeight(cmd Command, height uint64) (block historicalBlock, err error) {
+	err = callAPI(cmd, "GET", "/consensus/blocks", url.Values{"height": {strconv.FormatUint(height, 10)}}, &block)
+	return
+}
+
+//parseHistoryTime parses a --date as RFC 3339 or a date. Dates are the end of the day in UTC so
+//a tax cutoff date includes the whole day
+func parseHistoryTime(s string) (t time.Time, err error) {
+	if t, err = time.Parse(time.RFC3339, s); err == nil {
+		return
+	}
+
+	if t, err = time.Parse("2006-01-02", s); err != nil {
+		return t, fmt.Errorf("invalid --date %q, expected 2006-01-02 or RFC 3339", s)
+	}
+
+	return t.Add(24*time.Hour - time.Second), nil
+}
+
+//historyBlock returns the block selected by --height, or the last block before --date. Block
+//timestamps are set by miners and are only roughly increasing, so the block found for a date is
+//approximate
+func historyBlock(cmd Command) (block historicalBlock, err error) {
+	heights, dates := cmd.Params["height"], cmd.Params["date"]
+
+	switch {
+	case len(heights) > 0 && len(dates) > 0:
+		return block, fmt.Errorf("only one of --height or --date can be used")
+	case len(heights) > 0:
+		height, err := strconv.ParseUint(heights[0], 10, 64)
+
+		if err != nil {
+			return block, fmt.Errorf("invalid --height %q", heights[0])
+		}
+
+		return blockAtHeight(cmd, height)
+	case len(dates) == 0:
+		return block, fmt.Errorf("--height or --date is required")
+	}
+
+	cutoff, err := parseHistoryTime(dates[0])
+
+	if err != nil {
+		return
+	}
+
+	var consensus consensusResponse
+
+	if err = callAPI(cmd, "GET", "/consensus", nil, &consensus); err != nil {
+		return
+	}
+
+	// binary search for the last block with a timestamp before the cutoff
+	low, high := uint64(0), consensus.Height
+
+	if block, err = blockAtHeight(cmd, low); err != nil {
+		return
+	} else if time.Unix(block.Timestamp, 0).After(cutoff) {
+		return block, fmt.Errorf("%s is before the genesis block", dates[0])
+	}
+
+	for low < high {
+		mid := low + (high-low+1)/2
+		midBlock, err := blockAtHeight(cmd, mid)
+
+		if err != nil {
+			return block, err
+		}
+
+		if time.Unix(midBlock.Timestamp, 0).After(cutoff) {
+			high = mid - 1
+		} else {
+			low, block = mid, midBlock
+		}
+	}
+
+	return
+}
+
+//atBlockCommand writes the block at --height or the last block before --date
+func atBlockCommand(cmd Command) (err error) {
+	block, err := historyBlock(cmd)
+
+	if err != nil {
+		return
+	}
+
+	var resp interface{}
+
+	if err = callAPI(cmd, "GET", "/consensus/blocks", url.Values{"id": {block.ID}}, &resp); err != nil {
+		return
+	}
+
+	return writeJSON(resp)
+}
+
+//addValue adds the value in hastings or siafunds to the total
+func addValue(total *big.Int, value string, sign int) error {
+	v, ok := new(big.Int).SetString(value, 10)
+
+	if !ok {
+		return fmt.Errorf("invalid value %q", value)
+	}
+
+	if sign < 0 {
+		v.Neg(v)
+	}
+
+	total.Add(total, v)
+
+	return nil
+}
+
+//atBalanceCommand estimates the wallet's balances at --height or --date from the wallet's
+//confirmed transactions up to that block. Outputs are counted when confirmed and miner payouts
+//once they mature, so the estimate can differ from the balance siad reported at the time
+func atBalanceCommand(cmd Command) (err error) {
+	block, err := historyBlock(cmd)
+
+	if err != nil {
+		return
+	}
+
+	var txns walletTransactionsResponse
+
+	params := url.Values{
+		"startheight": {"0"},
+		"endheight":   {strconv.FormatUint(block.Height, 10)},
+	}
+
+	if err = callAPI(cmd, "GET", "/wallet/transactions", params, &txns); err != nil {
+		return
+	}
+
+	siacoins, siafunds, immature := new(big.Int), new(big.Int), new(big.Int)
+
+	for _, txn := range txns.ConfirmedTransactions {
+		if txn.ConfirmationHeight > block.Height {
+			continue
+		}
+
+		for _, input := range txn.Inputs {
+			if !input.WalletAddress {
+				continue
+			}
+
+			switch input.FundType {
+			case "siacoin input":
+				err = addValue(siacoins, input.Value, -1)
+			case "siafund input":
+				err = addValue(siafunds, input.Value, -1)
+			}
+
+			if err != nil {
+				return fmt.Errorf("transaction %s: %s", txn.TransactionID, err)
+			}
+		}
+
+		for _, output := range txn.Outputs {
+			if !output.WalletAddress {
+				continue
+			}
+
+			switch {
+			case output.FundType == "siafund output":
+				err = addValue(siafunds, output.Value, 1)
+			case output.MaturityHeight > block.Height:
+				err = addValue(immature, output.Value, 1)
+			default:
+				err = addValue(siacoins, output.Value, 1)
+			}
+
+			if err != nil {
+				return fmt.Errorf("transaction %s: %s", txn.TransactionID, err)
+			}
+		}
+	}
+
+	return writeJSON(balanceEstimate{
+		Height:           block.Height,
+		BlockID:          block.ID,
+		Time:             time.Unix(block.Timestamp, 0).UTC(),
+		Transactions:     len(txns.ConfirmedTransactions),
+		SiacoinBalance:   siacoins.String(),
+		Siacoins:         format.FormatCurrency(siacoins),
+		SiafundBalance:   siafunds.String(),
+		ImmatureSiacoins: immature.String(),
+	})
+}
@@ -0,0 +1,349 @@
+package main
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+type (
+	//Hook a recipe hooks serve runs when its endpoint is called with the hook's token
+	Hook struct {
+		Recipe    string
+		Token     string
+		TokenFile string
+	}
+
+	//hookServer serves the configured hooks. Recipes are run one at a time so two calls cannot
+	//interleave their API calls
+	hookServer struct {
+		cmd    Command
+		hooks  map[string]Hook
+		tokens map[string]string
+
+		mu    sync.Mutex
+		audit io.Writer
+	}
+
+	//hookAuditEntry a line of the audit log written for every call of a hook
+	hookAuditEntry struct {
+		Time     time.Time         `json:"time"`
+		Hook     string            `json:"hook"`
+		Remote   string            `json:"remote"`
+		Args     map[string]string `json:"args,omitempty"`
+		Status   int               `json:"status"`
+		Duration string            `json:"duration"`
+		Error    string            `json:"error,omitempty"`
+	}
+)
+
+const (
+	//defaultHooksListen the address hooks serve listens on
+	defaultHooksListen = "127.0.0.1:9985"
+
+	//maxHookBody the largest request body a hook accepts
+	maxHookBody = 1 << 20
+)
+
+//hooksAuditPath the default audit log of hooks serve
+func hooksAuditPath() string {
+	return filepath.Join(DefaultConfigDir(), "hooks_audit.log")
+}
+
+//parseHooks reads the [hooks.<name>] tables of the config file
+func parseHooks(table map[string]interface{}) (hooks map[string]Hook, err error) {
+	hookTables, ok := table["hooks"].(map[string]interface{})
+
+	if !ok {
+		return
+	}
+
+	hooks = make(map[string]Hook)
+
+	for name, v := range hookTables {
+		hookTable, ok := v.(map[string]interface{})
+
+		if !ok {
+			return nil, fmt.Errorf("hook %s must be a table", name)
+		} else if !profileNameRe.MatchString(name) {
+			return nil, fmt.Errorf("hook names may only contain letters, digits, - and _: %q", name)
+		}
+
+		var hook Hook
+
+		if hook.Recipe, err = configString(hookTable, "recipe"); err != nil {
+			return nil, fmt.Errorf("hook %s: %s", name, err)
+		} else if len(hook.Recipe) == 0 {
+			return nil, fmt.Errorf("hook %s: recipe is required", name)
+		}
+
+		if hook.Token, err = configString(hookTable, "token"); err != nil {
+			return nil, fmt.Errorf("hook %s: %s", name, err)
+		}
+
+		if hook.TokenFile, err = configString(hookTable, "token_file"); err != nil {
+			return nil, fmt.Errorf("hook %s: %s", name, err)
+		}
+
+		hooks[name] = hook
+	}
+
+	return
+}
+
+//token returns the hook's token, configured directly or through a token file
+func (h Hook) token() (token string, err error) {
+	if len(h.Token) > 0 || len(h.TokenFile) == 0 {
+		return h.Token, nil
+	}
+
+	buf, err := ioutil.ReadFile(h.TokenFile)
+
+	if err != nil {
+		return
+	}
+
+	return strings.TrimSpace(string(buf)), nil
+}
+
+//hookArgs decodes the JSON object of the request body into the recipe's args. Every arg of the
+//recipe is required and no other fields are accepted
+func hookArgs(r *http.Request, recipe Recipe) (args map[string]string, err error) {
+	fields := make(map[string]interface{})
+	dec := json.NewDecoder(io.LimitReader(r.Body, maxHookBody))
+	dec.UseNumber()
+
+	if err = dec.Decode(&fields); err != nil && err != io.EOF {
+		return nil, fmt.Errorf("the body must be a JSON object of the recipe's args: %s", err)
+	}
+
+	args = make(map[string]string)
+	allowed := make(map[string]bool)
+
+	for _, arg := range recipe.Args {
+		allowed[arg] = true
+	}
+
+	for key, value := range fields {
+		if !allowed[key] {
+			return nil, fmt.Errorf("unknown arg %q", key)
+		}
+
+		switch v := value.(type) {
+		case string:
+			args[key] = v
+		case json.Number:
+			args[key] = v.String()
+		default:
+			return nil, fmt.Errorf("arg %s must be a string or a number", key)
+		}
+	}
+
+	for _, arg := range recipe.Args {
+		if _, ok := args[arg]; !ok {
+			return nil, fmt.Errorf("missing arg %q", arg)
+		}
+	}
+
+	return
+}
+
+//writeHookError writes an error response in the Sia API's error format
+func writeHookError(w http.ResponseWriter, status int, err error) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(APIError{Message: err.Error()})
+}
+
+//ServeHTTP runs the recipe of the hook named by the path, POST /hooks/<name>, if the request
+//carries the hook's token as a bearer token
+func (s *hookServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	entry := hookAuditEntry{
+		Time:   start.UTC(),
+		Hook:   strings.TrimPrefix(r.URL.Path, "/hooks/"),
+		Remote: r.RemoteAddr,
+	}
+
+	status, err := s.serveHook(w, r, &entry)
+
+	if err != nil {
+		writeHookError(w, status, err)
+		entry.Error = err.Error()
+	}
+
+	entry.Status = status
+	entry.Duration = time.Since(start).Round(time.Millisecond).String()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if buf, err := json.Marshal(entry); err == nil {
+		s.audit.Write(append(buf, '\n'))
+	}
+}
+
+//serveHook authenticates the request and runs the hook's recipe, returning the response status
+func (s *hookServer) serveHook(w http.ResponseWriter, r *http.Request, entry *hookAuditEntry) (status int, err error) {
+	hook, ok := s.hooks[entry.Hook]
+
+	if !ok || !strings.HasPrefix(r.URL.Path, "/hooks/") {
+		return http.StatusNotFound, fmt.Errorf("unknown hook")
+	}
+
+	if r.Method != "POST" {
+		return http.StatusMethodNotAllowed, fmt.Errorf("hooks must be called with POST")
+	}
+
+	auth := r.Header.Get("Authorization")
+
+	if !strings.HasPrefix(auth, "Bearer ") || subtle.ConstantTimeCompare([]byte(auth[len("Bearer "):]), []byte(s.tokens[entry.Hook])) != 1 {
+		return http.StatusUnauthorized, fmt.Errorf("invalid token")
+	}
+
+	recipe := DefaultConfig.Recipes[hook.Recipe]
+	args, err := hookArgs(r, recipe)
+
+	if err != nil {
+		return http.StatusBadRequest, err
+	}
+
+	entry.Args = args
+
+	s.mu.Lock()
+	output, err := runRecipe(s.cmd, recipe, args)
+	s.mu.Unlock()
+
+	if err != nil {
+		return http.StatusBadGateway, err
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(output)
+
+	return http.StatusOK, nil
+}
+
+//hooksServeCommand serves the hooks of the config file until interrupted. POST /hooks/<name>
+//with the hook's token as a bearer token runs its recipe with the args in the JSON body and
+//responds with the recipe's output. Every call is appended to the --audit-log, by default
+//hooks_audit.log in the config dir. --listen sets the address, 127.0.0.1:9985 by default, and
+//--tls-cert and --tls-key serve https
+func hooksServeCommand(cmd Command) (err error) {
+	if len(DefaultConfig.Hooks) == 0 {
+		return fmt.Errorf("no hooks are configured in %s", ConfigPath())
+	}
+
+	server := &hookServer{
+		cmd:    cmd,
+		hooks:  DefaultConfig.Hooks,
+		tokens: make(map[string]string),
+	}
+
+	names := make([]string, 0, len(server.hooks))
+
+	for name, hook := range server.hooks {
+		if _, ok := DefaultConfig.Recipes[hook.Recipe]; !ok {
+			return fmt.Errorf("hook %s runs unknown recipe %q", name, hook.Recipe)
+		}
+
+		token, err := hook.token()
+
+		if err != nil {
+			return fmt.Errorf("hook %s: %s", name, err)
+		} else if len(token) == 0 {
+			return fmt.Errorf("hook %s needs a token or token_file", name)
+		}
+
+		server.tokens[name] = token
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	auditPath := hooksAuditPath()
+
+	if values := cmd.Params["audit-log"]; len(values) > 0 {
+		auditPath = values[0]
+	}
+
+	if err = os.MkdirAll(filepath.Dir(auditPath), 0700); err != nil {
+		return
+	}
+
+	audit, err := os.OpenFile(auditPath, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0600)
+
+	if err != nil {
+		return
+	}
+
+	defer audit.Close()
+
+	server.audit = audit
+
+	listen := defaultHooksListen
+
+	if values := cmd.Params["listen"]; len(values) > 0 {
+		listen = values[0]
+	}
+
+	var certFile, keyFile string
+
+	if values := cmd.Params["tls-cert"]; len(values) > 0 {
+		certFile = values[0]
+	}
+
+	if values := cmd.Params["tls-key"]; len(values) > 0 {
+		keyFile = values[0]
+	}
+
+	if (len(certFile) == 0) != (len(keyFile) == 0) {
+		return fmt.Errorf("--tls-cert and --tls-key must be given together")
+	}
+
+	l, err := net.Listen("tcp", listen)
+
+	if err != nil {
+		return
+	}
+
+	httpServer := &http.Server{Handler: server}
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(sigs)
+
+	go func() {
+		<-sigs
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		httpServer.Shutdown(ctx)
+	}()
+
+	notify(cmd, "serving hooks %s on %s, auditing to %s", strings.Join(names, ", "), l.Addr(), auditPath)
+
+	if len(certFile) > 0 {
+		err = httpServer.ServeTLS(l, certFile, keyFile)
+	} else {
+		err = httpServer.Serve(l)
+	}
+
+	if err == http.ErrServerClosed {
+		err = nil
+	}
+
+	return
+}
@@ -0,0 +1,307 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/n8maninger/siac-json/format"
+)
+
+type (
+	//hostResponse the fields of /host used by sia-json
+	hostResponse struct {
+		FinancialMetrics struct {
+			ContractCount uint64 `json:"contractcount"`
+		} `json:"financialmetrics"`
+		InternalSettings struct {
+			AcceptingContracts bool `json:"acceptingcontracts"`
+		} `json:"internalsettings"`
+	}
+
+	//consensusResponse the fields of /consensus used by sia-json
+	consensusResponse struct {
+		Synced       bool   `json:"synced"`
+		Height       uint64 `json:"height"`
+		CurrentBlock string `json:"currentblock"`
+	}
+
+	//storageObligation the fields of a /host/contracts contract used by sia-json
+	storageObligation struct {
+		ObligationID        string `json:"obligationid"`
+		ExpirationHeight    uint64 `json:"expirationheight"`
+		ProofDeadline       uint64 `json:"proofdeadline"`
+		ObligationStatus    string `json:"obligationstatus"`
+		ProofConfirmed      bool   `json:"proofconfirmed"`
+		RevisionConstructed bool   `json:"revisionconstructed"`
+		RevisionConfirmed   bool   `json:"revisionconfirmed"`
+		LockedCollateral    string `json:"lockedcollateral"`
+		RiskedCollateral    string `json:"riskedcollateral"`
+		//ContractCost and the potential revenues are what the host loses if it misses the proof
+		ContractCost             string `json:"contractcost"`
+		PotentialStorageRevenue  string `json:"potentialstoragerevenue"`
+		PotentialDownloadRevenue string `json:"potentialdownloadrevenue"`
+		PotentialUploadRevenue   string `json:"potentialuploadrevenue"`
+	}
+
+	//hostContractsResponse the response of /host/contracts
+	hostContractsResponse struct {
+		Contracts []storageObligation `json:"contracts"`
+	}
+
+	//storageFolder the fields of a /host/storage folder used by sia-json
+	storageFolder struct {
+		Path              string `json:"path"`
+		Capacity          uint64 `json:"capacity"`
+		CapacityRemaining uint64 `json:"capacityremaining"`
+		FailedReads       uint64 `json:"failedreads"`
+		FailedWrites      uint64 `json:"failedwrites"`
+	}
+
+	//hostStorageResponse the response of /host/storage
+	hostStorageResponse struct {
+		Folders []storageFolder `json:"folders"`
+	}
+
+	//safeToStopReport the result of host safe-to-stop
+	safeToStopReport struct {
+		Safe             bool                `json:"safe"`
+		Height           uint64              `json:"height"`
+		Reasons          []string            `json:"reasons"`
+		ImminentProofs   []storageObligation `json:"imminentproofs"`
+		PendingRevisions []string            `json:"pendingrevisions"`
+		UnhealthyFolders []storageFolder     `json:"unhealthyfolders"`
+	}
+)
+
+//defaultProofMargin the number of blocks before a proof window opens that stopping is considered unsafe
+const defaultProofMargin = 144
+
+//waitForNegotiations waits until the host's contract count has not changed for the settle period,
+//giving negotiations already in progress a chance to finish
+func waitForNegotiations(cmd Command, settle, timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	lastChange := time.Now()
+	count := uint64(0)
+
+	for first := true; ; first = false {
+		var host hostResponse
+
+		if err := callAPI(cmd, "GET", "/host", nil, &host); err != nil {
+			return err
+		}
+
+		if first || host.FinancialMetrics.ContractCount != count {
+			count = host.FinancialMetrics.ContractCount
+			lastChange = time.Now()
+		}
+
+		if time.Since(lastChange) >= settle {
+			return nil
+		}
+
+		if time.Now().After(deadline) {
+			return fmt.Errorf("contract count still changing after %s", timeout)
+		}
+
+		time.Sleep(settle / 4)
+	}
+}
+
+//hostMaintenanceOn stops the host accepting contracts and records its previous setting
+func hostMaintenanceOn(cmd Command, state State) (err error) {
+	if _, ok := state.HostMaintenance[cmd.APIAddress]; ok {
+		return fmt.Errorf("host %s is already in maintenance mode", cmd.APIAddress)
+	}
+
+	var host hostResponse
+
+	if err = callAPI(cmd, "GET", "/host", nil, &host); err != nil {
+		return
+	}
+
+	if cmd.Plan {
+		return planParams(cmd, "POST", "/host", url.Values{"acceptingcontracts": {"false"}})
+	}
+
+	if state.HostMaintenance == nil {
+		state.HostMaintenance = make(map[string]HostMaintenance)
+	}
+
+	// record the previous state before changing it so off can always restore it, and remove it
+	// again if the settings were not changed
+	state.HostMaintenance[cmd.APIAddress] = HostMaintenance{
+		AcceptingContracts: host.InternalSettings.AcceptingContracts,
+		Started:            time.Now(),
+	}
+
+	if err = SaveState(state); err != nil {
+		return
+	}
+
+	if err = callAPI(cmd, "POST", "/host", url.Values{"acceptingcontracts": {"false"}}, nil); err != nil {
+		delete(state.HostMaintenance, cmd.APIAddress)
+
+		if saveErr := SaveState(state); saveErr != nil {
+			notify(cmd, "warning: unable to remove the maintenance state of %s: %s", cmd.APIAddress, saveErr)
+		}
+
+		return
+	}
+
+	fmt.Println("host maintenance mode on, no longer accepting contracts")
+
+	values := cmd.Params["wait"]
+
+	if len(values) == 0 {
+		return
+	}
+
+	timeout, err := format.ParseDuration(values[0])
+
+	if err != nil {
+		return
+	}
+
+	settle := time.Minute
+
+	if values := cmd.Params["settle"]; len(values) > 0 {
+		if settle, err = format.ParseDuration(values[0]); err != nil {
+			return
+		}
+	}
+
+	notify(cmd, "waiting %s for contract negotiations to settle", settle)
+
+	return waitForNegotiations(cmd, settle, timeout)
+}
+
+//hostMaintenanceOff restores the host's accepting contracts setting from before maintenance
+func hostMaintenanceOff(cmd Command, state State) (err error) {
+	previous, ok := state.HostMaintenance[cmd.APIAddress]
+
+	if !ok {
+		return fmt.Errorf("host %s is not in maintenance mode", cmd.APIAddress)
+	}
+
+	accepting := strconv.FormatBool(previous.AcceptingContracts)
+
+	if cmd.Plan {
+		return planParams(cmd, "POST", "/host", url.Values{"acceptingcontracts": {accepting}})
+	}
+
+	if err = callAPI(cmd, "POST", "/host", url.Values{"acceptingcontracts": {accepting}}, nil); err != nil {
+		return
+	}
+
+	delete(state.HostMaintenance, cmd.APIAddress)
+
+	if err = SaveState(state); err != nil {
+		return
+	}
+
+	fmt.Printf("host maintenance mode off after %s, acceptingcontracts restored to %s\n", time.Since(previous.Started).Round(time.Second), accepting)
+
+	return
+}
+
+//hostMaintenanceCommand toggles host maintenance mode: host maintenance on|off
+func hostMaintenanceCommand(cmd Command) (err error) {
+	state, err := LoadState()
+
+	if err != nil {
+		return
+	}
+
+	switch cmd.Args[2] {
+	case "on":
+		return hostMaintenanceOn(cmd, state)
+	case "off":
+		return hostMaintenanceOff(cmd, state)
+	}
+
+	return fmt.Errorf("unknown maintenance mode %q, expected on or off", cmd.Args[2])
+}
+
+//hostSafeToStopCommand checks whether the host can be stopped without missing storage proofs:
+//host safe-to-stop --margin 144
+func hostSafeToStopCommand(cmd Command) (err error) {
+	margin, err := paramUint(cmd, "margin", defaultProofMargin)
+
+	if err != nil {
+		return
+	}
+
+	var consensus consensusResponse
+	var contracts hostContractsResponse
+	var storage hostStorageResponse
+
+	if err = callAPI(cmd, "GET", "/consensus", nil, &consensus); err != nil {
+		return
+	}
+
+	if err = callAPI(cmd, "GET", "/host/contracts", nil, &contracts); err != nil {
+		return
+	}
+
+	if err = callAPI(cmd, "GET", "/host/storage", nil, &storage); err != nil {
+		return
+	}
+
+	report := safeToStopReport{
+		Height:           consensus.Height,
+		Reasons:          []string{},
+		ImminentProofs:   []storageObligation{},
+		PendingRevisions: []string{},
+		UnhealthyFolders: []storageFolder{},
+	}
+
+	if !consensus.Synced {
+		report.Reasons = append(report.Reasons, "consensus is not synced")
+	}
+
+	for _, contract := range contracts.Contracts {
+		if contract.ObligationStatus != "obligationUnresolved" || contract.ProofConfirmed {
+			continue
+		}
+
+		if consensus.Height+margin >= contract.ExpirationHeight && consensus.Height <= contract.ProofDeadline {
+			report.ImminentProofs = append(report.ImminentProofs, contract)
+		}
+
+		if contract.RevisionConstructed && !contract.RevisionConfirmed {
+			report.PendingRevisions = append(report.PendingRevisions, contract.ObligationID)
+		}
+	}
+
+	for _, folder := range storage.Folders {
+		if folder.FailedReads > 0 || folder.FailedWrites > 0 {
+			report.UnhealthyFolders = append(report.UnhealthyFolders, folder)
+		}
+	}
+
+	if n := len(report.ImminentProofs); n > 0 {
+		report.Reasons = append(report.Reasons, fmt.Sprintf("%d storage proof windows open within %d blocks", n, margin))
+	}
+
+	if n := len(report.PendingRevisions); n > 0 {
+		report.Reasons = append(report.Reasons, fmt.Sprintf("%d contract revisions are not confirmed", n))
+	}
+
+	if n := len(report.UnhealthyFolders); n > 0 {
+		report.Reasons = append(report.Reasons, fmt.Sprintf("%d storage folders have failed reads or writes", n))
+	}
+
+	report.Safe = len(report.Reasons) == 0
+
+	if err = writeJSON(report); err != nil {
+		return
+	}
+
+	if !report.Safe {
+		return ExitCodeError{Code: ExitAssertionFailed, Err: fmt.Errorf("host is not safe to stop")}
+	}
+
+	return
+}
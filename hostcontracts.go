@@ -0,0 +1,185 @@
+package main
+
+import (
+	"encoding/json"
+	"math/big"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/n8maninger/siac-json/format"
+)
+
+type (
+	//hostContractEvent a change of a storage obligation written by tail host-contracts
+	hostContractEvent struct {
+		Time time.Time `json:"time"`
+		//Event new, proof-succeeded, proof-missed, rejected or expired
+		Event            string `json:"event"`
+		ObligationID     string `json:"obligationid"`
+		Height           uint64 `json:"height"`
+		ExpirationHeight uint64 `json:"expirationheight"`
+		ProofDeadline    uint64 `json:"proofdeadline"`
+		Status           string `json:"status"`
+		//Revenue the contract cost and potential revenue of the obligation in hastings
+		Revenue          string `json:"revenue"`
+		RiskedCollateral string `json:"riskedcollateral"`
+		LockedCollateral string `json:"lockedcollateral"`
+	}
+)
+
+//defaultHostContractsInterval how often tail host-contracts polls /host/contracts
+const defaultHostContractsInterval = time.Minute
+
+//hostContractEvents the event of each obligation status
+var hostContractEvents = map[string]string{
+	"obligationSucceeded": "proof-succeeded",
+	"obligationFailed":    "proof-missed",
+	"obligationRejected":  "rejected",
+}
+
+//revenue returns the contract cost and potential revenue of the obligation, what the host loses
+//if it misses the storage proof
+func (so storageObligation) revenue() *big.Int {
+	total := new(big.Int)
+
+	for _, s := range []string{so.ContractCost, so.PotentialStorageRevenue, so.PotentialDownloadRevenue, so.PotentialUploadRevenue} {
+		if h, ok := new(big.Int).SetString(s, 10); ok {
+			total.Add(total, h)
+		}
+	}
+
+	return total
+}
+
+//hostContractEventsOf returns the events of the obligation between the previous poll and now.
+//An obligation expires when the height reaches its expiration height and its proof window opens
+func hostContractEventsOf(prev storageObligation, seen bool, cur storageObligation, prevHeight, height uint64) (events []string) {
+	if !seen {
+		events = append(events, "new")
+	}
+
+	if event, ok := hostContractEvents[cur.ObligationStatus]; ok && (!seen || prev.ObligationStatus != cur.ObligationStatus) {
+		events = append(events, event)
+	}
+
+	if height >= cur.ExpirationHeight && (!seen || prevHeight < cur.ExpirationHeight) && cur.ObligationStatus == "obligationUnresolved" {
+		events = append(events, "expired")
+	}
+
+	return
+}
+
+//tailHostContractsCommand polls /host/contracts every --interval and writes a JSON line for each
+//new obligation, successful or missed storage proof, rejected obligation and expired contract
+//until interrupted: tail host-contracts. Obligations that existed when tailing started are only
+//reported when they change. Every event is also recorded to the host-contracts metrics series,
+//and missed proofs are warned about on stderr
+func tailHostContractsCommand(cmd Command) (err error) {
+	interval := defaultHostContractsInterval
+
+	if values := cmd.Params["interval"]; len(values) > 0 {
+		if interval, err = format.ParseDuration(values[0]); err != nil {
+			return
+		}
+	}
+
+	var consensus consensusResponse
+	var contracts hostContractsResponse
+
+	if err = callAPI(cmd, "GET", "/consensus", nil, &consensus); err != nil {
+		return
+	}
+
+	if err = callAPI(cmd, "GET", "/host/contracts", nil, &contracts); err != nil {
+		return
+	}
+
+	obligations := make(map[string]storageObligation)
+
+	for _, contract := range contracts.Contracts {
+		obligations[contract.ObligationID] = contract
+	}
+
+	height := consensus.Height
+	path := metricsPath("host-contracts", cmd.APIAddress)
+	notify(cmd, "tailing %d obligations at height %d, recording events to %s", len(obligations), height, path)
+
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, os.Interrupt)
+	defer signal.Stop(sigs)
+
+	scheduler, err := commandScheduler(cmd, interval)
+
+	if err != nil {
+		return
+	}
+
+	enc := json.NewEncoder(os.Stdout)
+	changed := false
+
+	for {
+		select {
+		case <-time.After(scheduler.Next(changed)):
+		case <-sigs:
+			return nil
+		}
+
+		changed = false
+		consensus, contracts = consensusResponse{}, hostContractsResponse{}
+
+		if err := callAPI(cmd, "GET", "/consensus", nil, &consensus); err != nil {
+			notify(cmd, "unable to get consensus: %s", err)
+			continue
+		}
+
+		if err := callAPI(cmd, "GET", "/host/contracts", nil, &contracts); err != nil {
+			notify(cmd, "unable to get host contracts: %s", err)
+			continue
+		}
+
+		current := make(map[string]storageObligation)
+
+		for _, contract := range contracts.Contracts {
+			prev, seen := obligations[contract.ObligationID]
+			current[contract.ObligationID] = contract
+
+			for _, name := range hostContractEventsOf(prev, seen, contract, height, consensus.Height) {
+				event := hostContractEvent{
+					Time:             time.Now().UTC(),
+					Event:            name,
+					ObligationID:     contract.ObligationID,
+					Height:           consensus.Height,
+					ExpirationHeight: contract.ExpirationHeight,
+					ProofDeadline:    contract.ProofDeadline,
+					Status:           contract.ObligationStatus,
+					Revenue:          contract.revenue().String(),
+					RiskedCollateral: contract.RiskedCollateral,
+					LockedCollateral: contract.LockedCollateral,
+				}
+
+				if err = enc.Encode(event); err != nil {
+					return
+				}
+
+				if err = appendMetric(path, event); err != nil {
+					return
+				}
+
+				if name == "proof-missed" {
+					collateral, _ := new(big.Int).SetString(contract.RiskedCollateral, 10)
+
+					if collateral == nil {
+						collateral = new(big.Int)
+					}
+
+					notify(cmd, "warning: missed the storage proof of %s, losing %s of revenue and %s of collateral", contract.ObligationID, format.FormatCurrency(contract.revenue()), format.FormatCurrency(collateral))
+				}
+
+				changed = true
+			}
+		}
+
+		obligations, height = current, consensus.Height
+	}
+}
@@ -0,0 +1,187 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/n8maninger/siac-json/format"
+)
+
+type (
+	//hostScan a scan of a host recorded by the renter's hostdb
+	hostScan struct {
+		Timestamp time.Time `json:"timestamp"`
+		Success   bool      `json:"success"`
+	}
+
+	//hostDBEntry the fields of a /hostdb/all host used by sia-json
+	hostDBEntry struct {
+		PublicKey   string     `json:"publickeystring"`
+		NetAddress  string     `json:"netaddress"`
+		ScanHistory []hostScan `json:"scanhistory"`
+	}
+
+	//hostDBAllResponse the response of /hostdb/all
+	hostDBAllResponse struct {
+		Hosts []hostDBEntry `json:"hosts"`
+	}
+
+	//staleHost a host whose last hostdb scan is older than the threshold and the result of
+	//checking it
+	staleHost struct {
+		PublicKey  string     `json:"publickey"`
+		NetAddress string     `json:"netaddress"`
+		LastScan   *time.Time `json:"lastscan"`
+		WasOnline  bool       `json:"wasonline"`
+		Online     bool       `json:"online"`
+		Error      string     `json:"error,omitempty"`
+	}
+
+	//hostDBScanReport the result of hostdb scan
+	hostDBScanReport struct {
+		Hosts        int         `json:"hosts"`
+		Stale        int         `json:"stale"`
+		NewlyOnline  []staleHost `json:"newlyonline"`
+		NewlyOffline []staleHost `json:"newlyoffline"`
+		Unchanged    []staleHost `json:"unchanged"`
+	}
+)
+
+const (
+	defaultStaleThreshold = 7 * 24 * time.Hour
+	defaultScanWorkers    = 10
+	defaultDialTimeout    = 5 * time.Second
+)
+
+//lastScan returns the most recent scan of the host, nil if the host has never been scanned
+func (h hostDBEntry) lastScan() *hostScan {
+	if len(h.ScanHistory) == 0 {
+		return nil
+	}
+
+	return &h.ScanHistory[len(h.ScanHistory)-1]
+}
+
+//checkHosts dials each host's net address using a pool of workers and records whether it accepted
+//the connection
+func checkHosts(hosts []staleHost, workers int, timeout time.Duration) {
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			for j := range jobs {
+				conn, err := net.DialTimeout("tcp", hosts[j].NetAddress, timeout)
+
+				if err != nil {
+					hosts[j].Error = err.Error()
+					continue
+				}
+
+				conn.Close()
+				hosts[j].Online = true
+			}
+		}()
+	}
+
+	for i := range hosts {
+		jobs <- i
+	}
+
+	close(jobs)
+	wg.Wait()
+}
+
+//hostDBScanCommand finds hosts whose last hostdb scan is older than --stale and checks whether
+//they are reachable, reporting hosts that have come online or gone offline since their last
+//scan. The Sia API has no endpoint to trigger a scan, so the renter's hostdb picks up the
+//changes on its next scheduled scan
+func hostDBScanCommand(cmd Command) (err error) {
+	threshold := defaultStaleThreshold
+	timeout := defaultDialTimeout
+
+	if values := cmd.Params["stale"]; len(values) > 0 {
+		if threshold, err = format.ParseDuration(values[0]); err != nil {
+			return
+		}
+	}
+
+	if values := cmd.Params["dial-timeout"]; len(values) > 0 {
+		if timeout, err = format.ParseDuration(values[0]); err != nil {
+			return
+		}
+	}
+
+	workers, err := paramUint(cmd, "workers", defaultScanWorkers)
+
+	if err != nil {
+		return
+	}
+
+	if workers == 0 {
+		return fmt.Errorf("--workers must be at least 1")
+	}
+
+	var all hostDBAllResponse
+
+	if err = callAPI(cmd, "GET", "/hostdb/all", nil, &all); err != nil {
+		return
+	}
+
+	cutoff := time.Now().Add(-threshold)
+	var stale []staleHost
+
+	for _, host := range all.Hosts {
+		h := staleHost{
+			PublicKey:  host.PublicKey,
+			NetAddress: host.NetAddress,
+		}
+
+		if scan := host.lastScan(); scan != nil {
+			if scan.Timestamp.After(cutoff) {
+				continue
+			}
+
+			h.LastScan = &scan.Timestamp
+			h.WasOnline = scan.Success
+		}
+
+		stale = append(stale, h)
+	}
+
+	notify(cmd, "checking %d of %d hosts not scanned in %s", len(stale), len(all.Hosts), threshold)
+
+	checkHosts(stale, int(workers), timeout)
+
+	report := hostDBScanReport{
+		Hosts:        len(all.Hosts),
+		Stale:        len(stale),
+		NewlyOnline:  []staleHost{},
+		NewlyOffline: []staleHost{},
+		Unchanged:    []staleHost{},
+	}
+
+	sort.Slice(stale, func(i, j int) bool {
+		return stale[i].NetAddress < stale[j].NetAddress
+	})
+
+	for _, h := range stale {
+		switch {
+		case h.Online && !h.WasOnline:
+			report.NewlyOnline = append(report.NewlyOnline, h)
+		case !h.Online && h.WasOnline:
+			report.NewlyOffline = append(report.NewlyOffline, h)
+		default:
+			report.Unchanged = append(report.Unchanged, h)
+		}
+	}
+
+	return writeJSON(report)
+}
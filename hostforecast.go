@@ -0,0 +1,164 @@
+package main
+
+import (
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/n8maninger/siac-json/format"
+)
+
+type (
+	//forecastWeek the payouts expected in a week of host forecast. Amounts are in hastings
+	forecastWeek struct {
+		Week        int    `json:"week"`
+		Start       string `json:"start"`
+		StartHeight uint64 `json:"startheight"`
+		EndHeight   uint64 `json:"endheight"`
+		Obligations int    `json:"obligations"`
+		//Potential the revenue of the obligations if every proof succeeds
+		Potential string `json:"potential"`
+		//Expected the potential revenue less the missed proof rate
+		Expected string `json:"expected"`
+		//CollateralAtRisk the collateral the expected missed proofs would lose
+		CollateralAtRisk string `json:"collateralatrisk"`
+	}
+
+	//hostForecast the result of host forecast. Amounts are in hastings
+	hostForecast struct {
+		Height uint64 `json:"height"`
+		//MissRate the share of proofs expected to be missed, from the resolved obligations unless
+		//--miss-rate was given
+		MissRate float64 `json:"missrate"`
+		//Succeeded and Missed the resolved obligations the miss rate was calculated from
+		Succeeded int `json:"succeeded"`
+		Missed    int `json:"missed"`
+		//Confirmed the revenue of obligations whose proof succeeded
+		Confirmed string `json:"confirmed"`
+		//Lost the revenue of obligations whose proof was missed
+		Lost      string         `json:"lost"`
+		Potential string         `json:"potential"`
+		Expected  string         `json:"expected"`
+		Weeks     []forecastWeek `json:"weeks"`
+	}
+)
+
+//blocksPerWeek the number of blocks in a week at the target block time
+const blocksPerWeek = uint64(7 * 24 * time.Hour / format.BlockTime)
+
+//parseMissRate parses the --miss-rate percentage of proofs expected to be missed, such as 2%
+func parseMissRate(value string) (float64, error) {
+	rate, err := strconv.ParseFloat(strings.TrimSuffix(strings.TrimSpace(value), "%"), 64)
+
+	if err != nil || rate < 0 || rate > 100 {
+		return 0, fmt.Errorf("invalid --miss-rate value %q, use a percentage such as 2%%", value)
+	}
+
+	return rate / 100, nil
+}
+
+//scaleHastings returns the amount multiplied by the rate, rounded down
+func scaleHastings(amount *big.Int, rate float64) *big.Int {
+	scaled, _ := new(big.Float).Mul(new(big.Float).SetInt(amount), big.NewFloat(rate)).Int(nil)
+	return scaled
+}
+
+//hostForecastCommand projects the host's income week by week from the unresolved obligations
+//of /host/contracts: host forecast. Each obligation is paid in the week its proof window
+//closes, and its revenue is discounted by the rate of missed proofs among the resolved
+//obligations, or by --miss-rate such as 2%. Heights are converted to dates at 10 minutes a block
+func hostForecastCommand(cmd Command) (err error) {
+	var consensus consensusResponse
+	var contracts hostContractsResponse
+
+	if err = callAPI(cmd, "GET", "/consensus", nil, &consensus); err != nil {
+		return
+	}
+
+	if err = callAPI(cmd, "GET", "/host/contracts", nil, &contracts); err != nil {
+		return
+	}
+
+	forecast := hostForecast{
+		Height: consensus.Height,
+		Weeks:  []forecastWeek{},
+	}
+	confirmed, lost := new(big.Int), new(big.Int)
+	var unresolved []storageObligation
+
+	for _, contract := range contracts.Contracts {
+		switch contract.ObligationStatus {
+		case "obligationSucceeded":
+			forecast.Succeeded++
+			confirmed.Add(confirmed, contract.revenue())
+		case "obligationFailed":
+			forecast.Missed++
+			lost.Add(lost, contract.revenue())
+		case "obligationUnresolved":
+			unresolved = append(unresolved, contract)
+		}
+	}
+
+	if values := cmd.Params["miss-rate"]; len(values) > 0 {
+		if forecast.MissRate, err = parseMissRate(values[0]); err != nil {
+			return
+		}
+	} else if resolved := forecast.Succeeded + forecast.Missed; resolved > 0 {
+		forecast.MissRate = float64(forecast.Missed) / float64(resolved)
+	}
+
+	potential, expected, atRisk := []*big.Int{}, []*big.Int{}, []*big.Int{}
+	counts := []int{}
+
+	for _, contract := range unresolved {
+		week := 0
+
+		// proofs already due are still awaiting resolution and paid in the first week
+		if contract.ProofDeadline > consensus.Height {
+			week = int((contract.ProofDeadline - consensus.Height) / blocksPerWeek)
+		}
+
+		for len(potential) <= week {
+			potential, expected, atRisk = append(potential, new(big.Int)), append(expected, new(big.Int)), append(atRisk, new(big.Int))
+			counts = append(counts, 0)
+		}
+
+		collateral, ok := new(big.Int).SetString(contract.RiskedCollateral, 10)
+
+		if !ok {
+			collateral = new(big.Int)
+		}
+
+		revenue := contract.revenue()
+		counts[week]++
+		potential[week].Add(potential[week], revenue)
+		expected[week].Add(expected[week], scaleHastings(revenue, 1-forecast.MissRate))
+		atRisk[week].Add(atRisk[week], scaleHastings(collateral, forecast.MissRate))
+	}
+
+	totalPotential, totalExpected := new(big.Int), new(big.Int)
+	now := time.Now().UTC()
+
+	for i := range potential {
+		forecast.Weeks = append(forecast.Weeks, forecastWeek{
+			Week:             i + 1,
+			Start:            now.Add(time.Duration(i) * 7 * 24 * time.Hour).Format("2006-01-02"),
+			StartHeight:      consensus.Height + uint64(i)*blocksPerWeek,
+			EndHeight:        consensus.Height + uint64(i+1)*blocksPerWeek - 1,
+			Obligations:      counts[i],
+			Potential:        potential[i].String(),
+			Expected:         expected[i].String(),
+			CollateralAtRisk: atRisk[i].String(),
+		})
+		totalPotential.Add(totalPotential, potential[i])
+		totalExpected.Add(totalExpected, expected[i])
+	}
+
+	forecast.Confirmed, forecast.Lost = confirmed.String(), lost.String()
+	forecast.Potential, forecast.Expected = totalPotential.String(), totalExpected.String()
+	notify(cmd, "%d unresolved obligations expected to pay %s of %s over %d weeks, %.1f%% of proofs expected to be missed", len(unresolved), format.FormatCurrency(totalExpected), format.FormatCurrency(totalPotential), len(forecast.Weeks), forecast.MissRate*100)
+
+	return writeJSON(forecast)
+}
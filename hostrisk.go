@@ -0,0 +1,296 @@
+package main
+
+import (
+	"fmt"
+	"math/big"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/n8maninger/siac-json/format"
+)
+
+type (
+	//riskContract the collateral an unresolved obligation risks in its proof window
+	riskContract struct {
+		ObligationID     string `json:"obligationid"`
+		ExpirationHeight uint64 `json:"expirationheight"`
+		ProofDeadline    uint64 `json:"proofdeadline"`
+		RiskedCollateral string `json:"riskedcollateral"`
+		Revenue          string `json:"revenue"`
+	}
+
+	//riskDay the collateral risked by the proof windows opening on a day
+	riskDay struct {
+		Date             string `json:"date"`
+		StartHeight      uint64 `json:"startheight"`
+		EndHeight        uint64 `json:"endheight"`
+		Contracts        int    `json:"contracts"`
+		RiskedCollateral string `json:"riskedcollateral"`
+	}
+
+	//outageImpact the obligations an outage of the --downtime length starting between From and
+	//To would miss the proofs of, and what they would lose. The last impact has no end, To is its
+	//start
+	outageImpact struct {
+		FromHeight uint64    `json:"fromheight"`
+		ToHeight   uint64    `json:"toheight"`
+		From       time.Time `json:"from"`
+		To         time.Time `json:"to"`
+		Missed     []string  `json:"missed"`
+		Collateral string    `json:"collateral"`
+		Revenue    string    `json:"revenue"`
+
+		collateral, revenue *big.Int
+	}
+
+	//hostRiskReport the result of host risk. Amounts are in hastings
+	hostRiskReport struct {
+		Height           uint64         `json:"height"`
+		Downtime         string         `json:"downtime"`
+		DowntimeBlocks   uint64         `json:"downtimeblocks"`
+		RiskedCollateral string         `json:"riskedcollateral"`
+		Contracts        []riskContract `json:"contracts"`
+		Days             []riskDay      `json:"days"`
+		//Costliest the outage starts that would lose the most collateral, costliest first
+		Costliest []outageImpact `json:"costliest"`
+		//Safest the outage start losing the least collateral
+		Safest *outageImpact `json:"safest"`
+	}
+)
+
+const (
+	//defaultRiskDowntime the outage length host risk simulates
+	defaultRiskDowntime = 24 * time.Hour
+	//defaultRiskTop the number of costliest outages host risk reports
+	defaultRiskTop = 3
+	//blocksPerDay the number of blocks in a day at the target block time
+	blocksPerDay = uint64(24 * time.Hour / format.BlockTime)
+)
+
+//heightTime returns the estimated time the chain reaches the height
+func heightTime(now time.Time, current, height uint64) time.Time {
+	if height < current {
+		return now
+	}
+
+	return now.Add(time.Duration(height-current) * format.BlockTime).Truncate(time.Second)
+}
+
+//outageMisses reports whether an outage of the blocks starting at the height covers the
+//obligation's whole proof window, so its proof cannot be submitted
+func outageMisses(contract storageObligation, start, blocks uint64) bool {
+	return start <= contract.ExpirationHeight && start+blocks >= contract.ProofDeadline
+}
+
+//hostRiskCommand reports the collateral the host's unresolved obligations risk, by contract and
+//by the day their proof windows open, and simulates an outage of --downtime (default 24h)
+//starting at every height where its impact changes: host risk. Outages that would lose the
+//most collateral are flagged in costliest, --top of them (default 3), along with the safest
+//start, to help schedule maintenance
+func hostRiskCommand(cmd Command) (err error) {
+	downtime := defaultRiskDowntime
+
+	if values := cmd.Params["downtime"]; len(values) > 0 {
+		if downtime, err = format.ParseDuration(values[0]); err != nil {
+			return
+		}
+	}
+
+	if downtime <= 0 {
+		return fmt.Errorf("--downtime must be positive")
+	}
+
+	top, err := paramUint(cmd, "top", defaultRiskTop)
+
+	if err != nil {
+		return
+	}
+
+	var consensus consensusResponse
+	var contracts hostContractsResponse
+
+	if err = callAPI(cmd, "GET", "/consensus", nil, &consensus); err != nil {
+		return
+	}
+
+	if err = callAPI(cmd, "GET", "/host/contracts", nil, &contracts); err != nil {
+		return
+	}
+
+	height, now := consensus.Height, time.Now().UTC()
+	blocks := uint64((downtime + format.BlockTime - 1) / format.BlockTime)
+	report := hostRiskReport{
+		Height:         height,
+		Downtime:       downtime.String(),
+		DowntimeBlocks: blocks,
+		Contracts:      []riskContract{},
+		Days:           []riskDay{},
+		Costliest:      []outageImpact{},
+	}
+
+	var unresolved []storageObligation
+	collateral := make(map[string]*big.Int)
+	total := new(big.Int)
+	days := make(map[uint64]*riskDay)
+	dayTotals := make(map[uint64]*big.Int)
+
+	for _, contract := range contracts.Contracts {
+		if contract.ObligationStatus != "obligationUnresolved" {
+			continue
+		}
+
+		risked, ok := new(big.Int).SetString(contract.RiskedCollateral, 10)
+
+		if !ok {
+			risked = new(big.Int)
+		}
+
+		unresolved = append(unresolved, contract)
+		collateral[contract.ObligationID] = risked
+		total.Add(total, risked)
+		report.Contracts = append(report.Contracts, riskContract{
+			ObligationID:     contract.ObligationID,
+			ExpirationHeight: contract.ExpirationHeight,
+			ProofDeadline:    contract.ProofDeadline,
+			RiskedCollateral: risked.String(),
+			Revenue:          contract.revenue().String(),
+		})
+
+		// windows already open count toward today
+		day := uint64(0)
+
+		if contract.ExpirationHeight > height {
+			day = (contract.ExpirationHeight - height) / blocksPerDay
+		}
+
+		if _, ok := days[day]; !ok {
+			start := height + day*blocksPerDay
+			days[day] = &riskDay{
+				Date:        heightTime(now, height, start).Format("2006-01-02"),
+				StartHeight: start,
+				EndHeight:   start + blocksPerDay - 1,
+			}
+			dayTotals[day] = new(big.Int)
+		}
+
+		days[day].Contracts++
+		dayTotals[day].Add(dayTotals[day], risked)
+	}
+
+	sort.Slice(report.Contracts, func(i, j int) bool {
+		return report.Contracts[i].ExpirationHeight < report.Contracts[j].ExpirationHeight
+	})
+
+	dayKeys := make([]uint64, 0, len(days))
+
+	for day := range days {
+		dayKeys = append(dayKeys, day)
+	}
+
+	sort.Slice(dayKeys, func(i, j int) bool { return dayKeys[i] < dayKeys[j] })
+
+	for _, day := range dayKeys {
+		days[day].RiskedCollateral = dayTotals[day].String()
+		report.Days = append(report.Days, *days[day])
+	}
+
+	// the impact of an outage only changes where its start enters or leaves the range that
+	// covers a proof window, so only those starts are simulated
+	candidates := map[uint64]bool{height: true}
+
+	for _, contract := range unresolved {
+		starts := []uint64{contract.ExpirationHeight, contract.ExpirationHeight + 1}
+
+		if contract.ProofDeadline >= blocks {
+			starts = append(starts, contract.ProofDeadline-blocks)
+		}
+
+		for _, start := range starts {
+			if start >= height {
+				candidates[start] = true
+			}
+		}
+	}
+
+	starts := make([]uint64, 0, len(candidates))
+
+	for start := range candidates {
+		starts = append(starts, start)
+	}
+
+	sort.Slice(starts, func(i, j int) bool { return starts[i] < starts[j] })
+
+	// consecutive starts missing the same proofs are merged, each impact lasts until the next
+	// start, the last has no end
+	var ranked []*outageImpact
+	var prevKey string
+
+	for i, start := range starts {
+		impact := &outageImpact{
+			FromHeight: start,
+			ToHeight:   start,
+			Missed:     []string{},
+			collateral: new(big.Int),
+			revenue:    new(big.Int),
+		}
+
+		for _, contract := range unresolved {
+			if outageMisses(contract, start, blocks) {
+				impact.Missed = append(impact.Missed, contract.ObligationID)
+				impact.collateral.Add(impact.collateral, collateral[contract.ObligationID])
+				impact.revenue.Add(impact.revenue, contract.revenue())
+			}
+		}
+
+		key := strings.Join(impact.Missed, ",")
+
+		if len(ranked) == 0 || key != prevKey {
+			ranked = append(ranked, impact)
+			prevKey = key
+		}
+
+		if i+1 < len(starts) {
+			ranked[len(ranked)-1].ToHeight = starts[i+1] - 1
+		}
+	}
+
+	for _, impact := range ranked {
+		impact.From, impact.To = heightTime(now, height, impact.FromHeight), heightTime(now, height, impact.ToHeight)
+		impact.Collateral, impact.Revenue = impact.collateral.String(), impact.revenue.String()
+	}
+
+	// the safest start is the earliest with the least collateral lost
+	for _, impact := range ranked {
+		if report.Safest == nil || impact.collateral.Cmp(report.Safest.collateral) < 0 {
+			report.Safest = impact
+		}
+	}
+
+	sort.SliceStable(ranked, func(i, j int) bool {
+		if c := ranked[i].collateral.Cmp(ranked[j].collateral); c != 0 {
+			return c > 0
+		}
+
+		return ranked[i].revenue.Cmp(ranked[j].revenue) > 0
+	})
+
+	for _, impact := range ranked {
+		if uint64(len(report.Costliest)) == top || impact.collateral.Sign() == 0 {
+			break
+		}
+
+		report.Costliest = append(report.Costliest, *impact)
+	}
+
+	report.RiskedCollateral = total.String()
+
+	if len(report.Costliest) > 0 {
+		worst := report.Costliest[0]
+		notify(cmd, "%d obligations risk %s of collateral. A %s outage starting between %s and %s would miss %d proofs and lose %s", len(unresolved), format.FormatCurrency(total), downtime, worst.From.Format(time.RFC3339), worst.To.Format(time.RFC3339), len(worst.Missed), format.FormatCurrency(worst.collateral))
+	} else {
+		notify(cmd, "%d obligations risk %s of collateral. No %s outage would miss a proof", len(unresolved), format.FormatCurrency(total), downtime)
+	}
+
+	return writeJSON(report)
+}
@@ -0,0 +1,234 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"hash"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/n8maninger/siac-json/format"
+)
+
+type (
+	//journalChunk a chunk of the upload file that was streamed to siad
+	journalChunk struct {
+		Offset int64  `json:"offset"`
+		Length int64  `json:"length"`
+		Hash   string `json:"hash"`
+	}
+
+	//uploadJournal records the chunks of an upload file streamed to /renter/uploadstream so an
+	//interrupted upload can be resumed. It is removed once siad accepts the upload
+	uploadJournal struct {
+		Address   string         `json:"address"`
+		SiaPath   string         `json:"siapath"`
+		Source    string         `json:"source"`
+		Size      int64          `json:"size"`
+		ModTime   time.Time      `json:"modtime"`
+		ChunkSize int64          `json:"chunksize"`
+		Started   time.Time      `json:"started"`
+		Chunks    []journalChunk `json:"chunks"`
+
+		path string
+	}
+
+	//journalReader hashes the upload file as it is streamed and adds each chunk to the journal
+	journalReader struct {
+		r       io.Reader
+		journal *uploadJournal
+		hash    hash.Hash
+		offset  int64
+		n       int64
+	}
+)
+
+//journalChunkSize the size of the chunks recorded in upload journals
+const journalChunkSize = 64 << 20
+
+//uploadJournalPath returns the path of the journal of uploads to the siapath on the API address
+func uploadJournalPath(address, siapath string) string {
+	sum := sha256.Sum256([]byte(address + "\x00" + siapath))
+	return filepath.Join(DefaultConfigDir(), "uploads", hex.EncodeToString(sum[:8])+".json")
+}
+
+//save atomically writes the journal
+func (j *uploadJournal) save() (err error) {
+	if err = os.MkdirAll(filepath.Dir(j.path), 0700); err != nil {
+		return
+	}
+
+	buf, err := json.MarshalIndent(j, "", "\t")
+
+	if err != nil {
+		return
+	}
+
+	tmp := j.path + ".tmp"
+
+	if err = ioutil.WriteFile(tmp, buf, 0600); err != nil {
+		return
+	}
+
+	return os.Rename(tmp, j.path)
+}
+
+//remove removes the journal once the upload has finished
+func (j *uploadJournal) remove() error {
+	if err := os.Remove(j.path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	return nil
+}
+
+//verify returns true if the journal was written for the same file and its chunks still match
+//the file's contents
+func (j *uploadJournal) verify(f *os.File, info os.FileInfo) bool {
+	if j.Size != info.Size() || !j.ModTime.Equal(info.ModTime()) || j.ChunkSize != journalChunkSize {
+		return false
+	}
+
+	defer f.Seek(0, io.SeekStart)
+
+	for _, chunk := range j.Chunks {
+		h := sha256.New()
+
+		if _, err := f.Seek(chunk.Offset, io.SeekStart); err != nil {
+			return false
+		}
+
+		if n, err := io.CopyN(h, f, chunk.Length); err != nil || n != chunk.Length {
+			return false
+		}
+
+		if hex.EncodeToString(h.Sum(nil)) != chunk.Hash {
+			return false
+		}
+	}
+
+	return true
+}
+
+//Read hashes the data read from the file, adding a chunk to the journal each time one has been
+//read in full. The last chunk is added at the end of the file
+func (jr *journalReader) Read(p []byte) (n int, err error) {
+	n, err = jr.r.Read(p)
+
+	for written := 0; written < n; {
+		m := n - written
+
+		if left := int(jr.journal.ChunkSize - jr.n); m > left {
+			m = left
+		}
+
+		jr.hash.Write(p[written : written+m])
+		jr.n += int64(m)
+		written += m
+
+		if jr.n == jr.journal.ChunkSize {
+			if saveErr := jr.addChunk(); saveErr != nil {
+				return n, saveErr
+			}
+		}
+	}
+
+	if err == io.EOF && jr.n > 0 {
+		if saveErr := jr.addChunk(); saveErr != nil {
+			return n, saveErr
+		}
+	}
+
+	return
+}
+
+//addChunk adds the chunk read so far to the journal and saves it
+func (jr *journalReader) addChunk() error {
+	jr.journal.Chunks = append(jr.journal.Chunks, journalChunk{
+		Offset: jr.offset,
+		Length: jr.n,
+		Hash:   hex.EncodeToString(jr.hash.Sum(nil)),
+	})
+
+	jr.offset += jr.n
+	jr.n = 0
+	jr.hash.Reset()
+
+	return jr.journal.save()
+}
+
+//openUploadJournal starts the journal of the command's upload file. If an earlier upload of the
+//file to the same siapath was interrupted and the file has not changed since, the upload is
+//resumed. siad cannot continue a stream from an offset, so the file is streamed again with
+//repair=true and siad only uploads the chunks missing from the remote file
+func openUploadJournal(cmd *Command, f *os.File) (journal *uploadJournal, err error) {
+	info, err := f.Stat()
+
+	if err != nil {
+		return
+	}
+
+	_, siapath, ok := splitSiaPath(*cmd)
+
+	if !ok {
+		return
+	}
+
+	siapath = strings.TrimPrefix(siapath, "/")
+	path := uploadJournalPath(cmd.APIAddress, siapath)
+	source, _ := filepath.Abs(cmd.UploadFile)
+
+	var previous uploadJournal
+
+	if buf, readErr := ioutil.ReadFile(path); readErr == nil && json.Unmarshal(buf, &previous) == nil {
+		_, force := cmd.Params["force"]
+		_, repair := cmd.Params["repair"]
+
+		switch {
+		case force || repair:
+		case previous.Source != source || !previous.verify(f, info):
+			notify(*cmd, "%s changed since its upload to %s was interrupted, starting over", cmd.UploadFile, siapath)
+		case callAPI(*cmd, "GET", "/renter/file/"+siapath, nil, nil) != nil:
+			// the upload was interrupted before siad created the file
+		default:
+			var streamed int64
+
+			for _, chunk := range previous.Chunks {
+				streamed += chunk.Length
+			}
+
+			notify(*cmd, "resuming interrupted upload of %s, %s of %s were streamed before", siapath, format.FormatBytes(streamed), format.FormatBytes(info.Size()))
+			cmd.Params["repair"] = []string{"true"}
+		}
+	}
+
+	journal = &uploadJournal{
+		Address:   cmd.APIAddress,
+		SiaPath:   siapath,
+		Source:    source,
+		Size:      info.Size(),
+		ModTime:   info.ModTime(),
+		ChunkSize: journalChunkSize,
+		Started:   time.Now().UTC(),
+		Chunks:    []journalChunk{},
+		path:      path,
+	}
+
+	err = journal.save()
+
+	return
+}
+
+//newJournalReader returns a reader adding the chunks read from r to the journal
+func newJournalReader(r io.Reader, journal *uploadJournal) io.Reader {
+	return &journalReader{
+		r:       r,
+		journal: journal,
+		hash:    sha256.New(),
+	}
+}
@@ -0,0 +1,264 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"sync"
+	"time"
+)
+
+type (
+	//livenessStatus the availability of a daemon as last seen by its keepalive pinger
+	livenessStatus struct {
+		Address string `json:"address"`
+		Up      bool   `json:"up"`
+		//Since when the daemon became reachable or unreachable
+		Since   time.Time `json:"since"`
+		Checked time.Time `json:"checked"`
+		//Failures the number of checks that failed in a row
+		Failures int    `json:"failures,omitempty"`
+		Error    string `json:"error,omitempty"`
+	}
+
+	//livenessPinger checks that a daemon is reachable every interval while a long running mode
+	//is open. An unreachable daemon is checked again sooner, backing off up to the interval,
+	//so recovery is noticed quickly
+	livenessPinger struct {
+		interval time.Duration
+		probe    func() error
+		onChange func(livenessStatus)
+
+		mu     sync.Mutex
+		status livenessStatus
+
+		wake    chan struct{}
+		recheck chan struct{}
+		stop    chan struct{}
+		once    sync.Once
+	}
+)
+
+//keepaliveRetryDelay how long to wait before checking an unreachable daemon again the first
+//time. The delay doubles with each failure up to the keepalive interval
+const keepaliveRetryDelay = time.Second
+
+//newLivenessPinger starts checking the daemon with the probe every interval. The daemon is
+//assumed to be reachable until a check fails. onChange is called when its availability changes
+func newLivenessPinger(address string, interval time.Duration, probe func() error, onChange func(livenessStatus)) *livenessPinger {
+	p := &livenessPinger{
+		interval: interval,
+		probe:    probe,
+		onChange: onChange,
+		status: livenessStatus{
+			Address: address,
+			Up:      true,
+			Since:   time.Now(),
+		},
+		wake:    make(chan struct{}, 1),
+		recheck: make(chan struct{}, 1),
+		stop:    make(chan struct{}),
+	}
+
+	go p.run()
+
+	return p
+}
+
+//run checks the daemon until the pinger is stopped
+func (p *livenessPinger) run() {
+	for {
+		timer := time.NewTimer(p.delay())
+
+		select {
+		case <-p.stop:
+			timer.Stop()
+			return
+		case <-p.wake:
+			// a failed request changed the delay of the next check
+			timer.Stop()
+			continue
+		case <-p.recheck:
+			timer.Stop()
+		case <-timer.C:
+		}
+
+		p.record(p.probe())
+	}
+}
+
+//delay returns how long to wait before the next check
+func (p *livenessPinger) delay() time.Duration {
+	p.mu.Lock()
+	failures := p.status.Failures
+	p.mu.Unlock()
+
+	if failures == 0 {
+		return p.interval
+	}
+
+	delay := keepaliveRetryDelay
+
+	for i := 1; i < failures && delay < p.interval; i++ {
+		delay *= 2
+	}
+
+	if delay > p.interval {
+		delay = p.interval
+	}
+
+	return delay
+}
+
+//record updates the status with the result of a check, calling onChange if the daemon became
+//reachable or unreachable
+func (p *livenessPinger) record(err error) {
+	p.mu.Lock()
+	status := p.status
+	up := err == nil
+	changed := up != status.Up
+
+	status.Up, status.Checked, status.Error = up, time.Now(), ""
+
+	if changed {
+		status.Since = status.Checked
+	}
+
+	if up {
+		status.Failures = 0
+	} else {
+		status.Failures++
+		status.Error = err.Error()
+	}
+
+	p.status = status
+	p.mu.Unlock()
+
+	if changed && p.onChange != nil {
+		p.onChange(status)
+	}
+}
+
+//Status returns the daemon's availability as of the last check
+func (p *livenessPinger) Status() livenessStatus {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return p.status
+}
+
+//Fail records a failed request as a failed check, so the daemon is marked unreachable without
+//waiting for the next check
+func (p *livenessPinger) Fail(err error) {
+	p.record(err)
+
+	select {
+	case p.wake <- struct{}{}:
+	default:
+	}
+}
+
+//Recheck checks the daemon now instead of waiting for the next check, without blocking
+func (p *livenessPinger) Recheck() {
+	select {
+	case p.recheck <- struct{}{}:
+	default:
+	}
+}
+
+//Stop stops checking the daemon
+func (p *livenessPinger) Stop() {
+	p.once.Do(func() { close(p.stop) })
+}
+
+//commandPinger starts a pinger checking /daemon/version of the command's daemon every --keepalive
+//interval, or returns nil if --keepalive is 0. A daemon answering with an API error is reachable
+func commandPinger(cmd Command, onChange func(livenessStatus)) *livenessPinger {
+	if cmd.Keepalive <= 0 {
+		return nil
+	}
+
+	probeCmd := cmd
+	probeCmd.Timeout, probeCmd.Retries, probeCmd.Verbose = cmd.ConnectTimeout, 0, false
+
+	probe := func() error {
+		var version daemonVersionResponse
+		err := callAPI(probeCmd, "GET", "/daemon/version", nil, &version)
+
+		if _, ok := err.(APIError); ok {
+			return nil
+		} else if err != nil {
+			// connections to a daemon that went away are not reused once it is back
+			closeSharedTransports()
+		}
+
+		return err
+	}
+
+	return newLivenessPinger(cmd.APIAddress, cmd.Keepalive, probe, onChange)
+}
+
+//transportPinger starts a pinger checking /daemon/version of the target through the transport.
+//Idle connections of the transport are closed when the target is unreachable
+func transportPinger(target string, interval, timeout time.Duration, transport *http.Transport, onChange func(livenessStatus)) *livenessPinger {
+	probe := func() error {
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		defer cancel()
+
+		req, err := http.NewRequest("GET", target+"/daemon/version", nil)
+
+		if err != nil {
+			return err
+		}
+
+		req.Header.Set("User-Agent", "Sia-Agent")
+		resp, err := transport.RoundTrip(req.WithContext(ctx))
+
+		if err != nil {
+			transport.CloseIdleConnections()
+			return err
+		}
+
+		resp.Body.Close()
+
+		return nil
+	}
+
+	return newLivenessPinger(target, interval, probe, onChange)
+}
+
+//notifyLiveness writes a status line when the daemon becomes reachable or unreachable. Machine
+//mode writes the status as a JSON line to stderr instead
+func notifyLiveness(cmd Command, status livenessStatus) {
+	if cmd.Machine {
+		buf, _ := json.Marshal(map[string]livenessStatus{"liveness": status})
+		os.Stderr.Write(append(buf, '\n'))
+
+		return
+	}
+
+	if status.Up {
+		notify(cmd, "%s is reachable again", status.Address)
+		return
+	}
+
+	notify(cmd, "%s is unreachable, retrying: %s", status.Address, status.Error)
+}
+
+//isUnreachable reports whether the request failed to reach the daemon rather than getting an
+//error response
+func isUnreachable(err error) bool {
+	if _, ok := err.(*url.Error); ok {
+		return true
+	}
+
+	return exitCode(err) == ExitConnectionError
+}
+
+//livenessLabel returns a short description of an unreachable daemon for prompts and headers
+func livenessLabel(status livenessStatus) string {
+	return fmt.Sprintf("unreachable since %s", status.Since.Format("15:04:05"))
+}
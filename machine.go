@@ -0,0 +1,195 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+)
+
+type (
+	//machineEnvelope the stdout object written for each request in machine mode
+	machineEnvelope struct {
+		Endpoint string            `json:"endpoint"`
+		Method   string            `json:"method"`
+		Path     string            `json:"path"`
+		Status   int               `json:"status"`
+		Data     json.RawMessage   `json:"data"`
+		Output   string            `json:"output,omitempty"`
+		Error    string            `json:"error,omitempty"`
+		Code     int               `json:"code"`
+		Warnings []endpointWarning `json:"warnings,omitempty"`
+	}
+
+	//reportedError an error that has already been written to the user
+	reportedError struct {
+		error
+	}
+)
+
+//notify writes an informational message to stderr. Messages are suppressed in machine mode
+func notify(cmd Command, format string, args ...interface{}) {
+	if cmd.Machine {
+		return
+	}
+
+	os.Stderr.WriteString(fmt.Sprintf(format, args...) + "\n")
+}
+
+//writeMachineEnvelope writes the response wrapped in a machine envelope. Responses that are not
+//JSON are included as a JSON string. Binary responses are written to the output file and only
+//the file name is included. The response body is returned
+func writeMachineEnvelope(cmd Command, resp *http.Response) (body []byte, err error) {
+	envelope := machineEnvelope{
+		Endpoint: cmd.Endpoint.Path,
+		Method:   cmd.Method,
+		Path:     cmd.RequestPath,
+		Status:   resp.StatusCode,
+		Data:     json.RawMessage("null"),
+		Warnings: cmd.Warnings,
+	}
+
+	if cmd.Endpoint.Binary && resp.StatusCode < 300 {
+		if err = writeOutput(cmd, resp); err != nil {
+			return
+		}
+
+		envelope.Output = cmd.OutputFile
+	} else {
+		if body, err = ioutil.ReadAll(resp.Body); err != nil {
+			return
+		}
+
+		if json.Valid(body) {
+			envelope.Data = json.RawMessage(body)
+		} else if len(body) > 0 {
+			envelope.Data, _ = json.Marshal(string(body))
+		}
+	}
+
+	if resp.StatusCode >= 300 {
+		apiErr := APIError{StatusCode: resp.StatusCode}
+		json.Unmarshal(body, &apiErr)
+		envelope.Error = apiErr.Error()
+		envelope.Code = apiExitCode(apiErr)
+	}
+
+	err = json.NewEncoder(os.Stdout).Encode(envelope)
+
+	return
+}
+
+//machineError returns the envelope of a command that failed with the error. The status is set
+//for API errors
+func machineError(endpoint string, cmd Command, err error) machineEnvelope {
+	envelope := machineEnvelope{
+		Endpoint: endpoint,
+		Method:   cmd.Method,
+		Path:     cmd.RequestPath,
+		Data:     json.RawMessage("null"),
+		Error:    err.Error(),
+		Code:     exitCode(err),
+	}
+
+	if exitErr, ok := err.(ExitCodeError); ok {
+		err = exitErr.Err
+	}
+
+	if apiErr, ok := err.(APIError); ok {
+		envelope.Status = apiErr.StatusCode
+	}
+
+	return envelope
+}
+
+//writeMachineError writes an envelope for a command that failed before or without a response
+//from the API, so callers always parse the same object from stdout. Nothing is written if err is
+//nil
+func writeMachineError(cmd Command, err error) {
+	if err == nil {
+		return
+	}
+
+	json.NewEncoder(os.Stdout).Encode(machineError(cmd.Endpoint.Path, cmd, err))
+}
+
+//machineData returns the output of a builtin command as the data of an envelope: a JSON value,
+//an array of JSON lines, or a JSON string for text
+func machineData(output []byte) json.RawMessage {
+	output = bytes.TrimSpace(output)
+
+	if len(output) == 0 {
+		return json.RawMessage("null")
+	} else if json.Valid(output) {
+		return json.RawMessage(output)
+	}
+
+	var lines [][]byte
+	dec := json.NewDecoder(bytes.NewReader(output))
+
+	for {
+		var line json.RawMessage
+
+		if err := dec.Decode(&line); err == io.EOF {
+			return json.RawMessage("[" + string(bytes.Join(lines, []byte(","))) + "]")
+		} else if err != nil {
+			break
+		}
+
+		lines = append(lines, []byte(line))
+	}
+
+	data, _ := json.Marshal(string(output))
+
+	return data
+}
+
+//machineBuiltin returns the builtin's Run with its stdout captured and written as the data of
+//one envelope, with the error if it failed, like the responses of endpoints
+func machineBuiltin(builtin BuiltinCommand) func(Command) error {
+	return func(cmd Command) (err error) {
+		r, w, err := os.Pipe()
+
+		if err != nil {
+			return
+		}
+
+		var output bytes.Buffer
+		copied := make(chan struct{})
+
+		go func() {
+			io.Copy(&output, r)
+			close(copied)
+		}()
+
+		stdout := os.Stdout
+		os.Stdout = w
+		runErr := builtin.Run(cmd)
+		os.Stdout = stdout
+		w.Close()
+		<-copied
+		r.Close()
+
+		envelope := machineEnvelope{
+			Endpoint: builtin.Path,
+			Method:   cmd.Method,
+			Path:     cmd.RequestPath,
+		}
+
+		if runErr != nil {
+			envelope = machineError(builtin.Path, cmd, runErr)
+		}
+
+		envelope.Data = machineData(output.Bytes())
+
+		if err = json.NewEncoder(os.Stdout).Encode(envelope); err != nil || runErr == nil {
+			return
+		}
+
+		// the error is in the envelope and must not be written again
+		return ExitCodeError{Code: exitCode(runErr), Err: reportedError{runErr}}
+	}
+}
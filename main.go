@@ -1,458 +1,270 @@
 package main
 
 import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"io"
 	"io/ioutil"
+	"net"
 	"net/http"
 	"net/url"
 	"os"
+	"os/signal"
 	"path/filepath"
 	"runtime"
+	"strconv"
 	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/n8maninger/siac-json/client"
+	"github.com/n8maninger/siac-json/endpoints"
+	"github.com/n8maninger/siac-json/format"
 )
 
 type (
-
-	//ParamLocation the location of the param in the request
-	ParamLocation string
-
-	//ParamFormat the format of the param will be used to get the friendly strings from siac "10TB" "100SC"
-	ParamFormat string
-
-	//CommandParam a known parameter of a command. Used only if the parameter needs special formatting or needs to be part of the help text
-	CommandParam struct {
-		Key       string
-		HelpText  string
-		Location  ParamLocation
-		Formatter ParamFormat
+	//BuiltinCommand a command handled locally by sia-json instead of being sent to siad
+	BuiltinCommand struct {
+		Path     string
+		HelpText string
+		Run      func(cmd Command) error
+		//DryRun the command handles --dry-run itself by reporting what it would change
+		DryRun bool
+		//Plan the command handles --plan itself by diffing the state it would change
+		Plan bool
+		//Stream the command writes JSON lines until it ends or runs commands writing their own
+		//output, so in machine mode its output is not wrapped in one envelope
+		Stream bool
 	}
 
-	//CommandEndpoint a known Sia API endpoint. Describes how the endpoint should be accessed, any help text and any parameters that are required
-	CommandEndpoint struct {
-		Path               string
-		AlternativeMatches []string
-		Method             string
-		HelpText           string
-		Params             []CommandParam
+	//APIError an error response returned by the Sia API
+	APIError = client.APIError
+
+	//ExitCodeError an error that exits the process with a specific exit code
+	ExitCodeError struct {
+		Code int
+		Err  error
 	}
 
 	//Command the command parsed from the input
 	Command struct {
-		Endpoint    CommandEndpoint
+		Endpoint    endpoints.CommandEndpoint
+		Args        []string
 		RequestPath string
 		Method      string
 		UserAgent   string
 		APIAddress  string
 		APIPassword string
 		Params      map[string][]string
+		//Header the headers given with --header and headers in the config file, sent with every request
+		Header http.Header
+
+		//Addresses the addresses --select-fastest chooses APIAddress from
+		Addresses []string
+		//SelectFastest sends the request to the address of Addresses that responds the quickest
+		SelectFastest bool
+
+		//PasswordFile a file containing the wallet encryption password, sent as the encryptionpassword param
+		PasswordFile string
+		//RelockAfter locks the wallet again after a successful /wallet/unlock once the duration has passed
+		RelockAfter time.Duration
+		//OutputFile writes a successful response body to the file instead of stdout
+		OutputFile string
+		//FailOn expressions evaluated against the response. The command fails if any of them match
+		FailOn []string
+		//Compute "name = expression" fields added to the response
+		Compute []string
+		//Limit the maximum number of array elements written
+		Limit int
+		//Offset the number of array elements skipped
+		Offset int
+		//Sample the number of array elements chosen at random
+		Sample int
+		//Quiet suppresses progress output
+		Quiet bool
+		//UploadFile a local file streamed as the request body
+		UploadFile string
+		//Bundle uploads a directory as a single tar.gz with an index, or downloads and extracts one
+		Bundle bool
+		//BundlePaths the files and directories restored from a bundle, every file if empty
+		BundlePaths []string
+		//EncryptKey the name of the key an uploaded file is encrypted with, and a download decrypted with
+		EncryptKey string
+		//Compress the compression of an uploaded file, and of a download to decompress: gzip
+		Compress string
+		//EndpointFiles JSON files of endpoint definitions given with --endpoints
+		EndpointFiles []string
+		//UploadChecksums the checksums of the blocks of the upload body, saved for renter scrub
+		UploadChecksums *checksumRecord
+		//UploadJournal the journal of the chunks of the upload file streamed so far
+		UploadJournal *uploadJournal
+		//VersionOnConflict renames an existing remote file with a timestamp suffix before uploading over it
+		VersionOnConflict bool
+		//JSONBody a JSON object, or @file containing one, sent as the request body
+		JSONBody string
+		//RawBody the --data-raw or --data-binary value sent as the request body
+		RawBody string
+		//RawBodyFile the file --data-binary @file sends as the request body, - for stdin
+		RawBodyFile string
+		//RawBodySet a body was given with --data-raw or --data-binary, it can be empty
+		RawBodySet bool
+		//ContentType the content type of a raw request body
+		ContentType string
+		//BodyLength the length of a raw request body, 0 if unknown
+		BodyLength int64
+		//Format the output format of the response: raw or pretty
+		Format string
+		//Timeout the overall request timeout, 0 for no timeout
+		Timeout time.Duration
+		//TimeoutFlag the timeout was set with --timeout, which also applies it to streaming requests
+		TimeoutFlag bool
+		//ConnectTimeout how long to wait for the connection to the API, 0 for no limit
+		ConnectTimeout time.Duration
+		//Keepalive how often long running modes check that the daemon is reachable, 0 to not check
+		Keepalive time.Duration
+		//Cache how long GET responses are reused from the disk cache, 0 to not cache
+		Cache time.Duration
+		//NoCache skips cached responses, the fresh response is still cached
+		NoCache bool
+		//TLSCACert a PEM file of certificates trusted for https targets
+		TLSCACert string
+		//TLSInsecure skips certificate verification for https targets
+		TLSInsecure bool
+		//TLSServerName overrides the hostname used for SNI and certificate verification
+		TLSServerName string
+		//SSHTarget a user@host the request is tunneled to over SSH
+		SSHTarget string
+		//IncludeHeaders writes the response status line and headers before the body
+		IncludeHeaders bool
+		//Machine fixes output for programmatic callers: a JSON envelope on stdout for every result
+		//including errors, no prompts, no progress, and no informational messages
+		Machine bool
+		//Verbose logs each request and response to stderr with credentials redacted
+		Verbose bool
+		//DryRun writes the composed request to stdout instead of sending it
+		DryRun bool
+		//AsCurl writes an equivalent curl command to stdout instead of sending the request
+		AsCurl bool
+		//Plan writes a diff of the current and resulting state instead of sending the request
+		Plan bool
+		//Retries the number of times a request that could not connect is retried
+		Retries int
+		//RetryDelay the delay before the first retry. The delay doubles after each attempt
+		RetryDelay time.Duration
+		//RetryAll retries requests other than GET, which may not be safe to repeat
+		RetryAll bool
+		//Watch repeats the command at the interval until interrupted
+		Watch time.Duration
+		//MaxInterval lets watch mode and polling commands poll less often, up to the interval,
+		//while the response does not change
+		MaxInterval time.Duration
+		//Clear clears the terminal before each repeat in watch mode
+		Clear bool
+		//Changes only writes the changes from the previous response in watch mode
+		Changes bool
+		//Detect detects the daemon before matching the endpoint and uses the endpoints of its version
+		Detect bool
+		//NoDeprecated fails instead of warning when the endpoint is deprecated
+		NoDeprecated bool
+		//StrictParams fails instead of warning when a param is not known to the endpoint
+		StrictParams bool
+		//Warnings the warnings included in the machine envelope
+		Warnings []endpointWarning
+		//Flags the flags and values given on the command line, passed on to the commands run by
+		//shell and run <file>
+		Flags []string
 	}
 )
 
 const (
-	//URLParam the parameter should go in the url as part of the path
-	URLParam ParamLocation = "url"
+	//ExitSuccess the request completed successfully
+	ExitSuccess = 0
+
+	//ExitError the command could not be run because of a local error
+	ExitError = 1
+
+	//ExitConnectionError the Sia API could not be reached
+	ExitConnectionError = 2
 
-	//QueryParam the parameter should go in the query
-	QueryParam ParamLocation = "query"
+	//ExitAPIError the Sia API responded with an error status not covered by a more specific code
+	ExitAPIError = 3
 
-	//BodyParam the parameter should go in the body
-	BodyParam ParamLocation = "body"
+	//ExitAssertionFailed a --fail-on condition matched the response
+	ExitAssertionFailed = 4
 
-	//DefaultFormat an unformatted parameter
-	DefaultFormat ParamFormat = ""
+	//ExitUnauthorized the Sia API rejected the API password
+	ExitUnauthorized = 5
 
-	//DataFormat a parameter formatted in the friendly data size format "10TB"
-	DataFormat ParamFormat = "data"
+	//ExitNotFound the Sia API responded with not found
+	ExitNotFound = 6
 
-	//PriceFormat a parameter formatted in the Siacoin price format "100SC"
-	PriceFormat ParamFormat = "price"
+	//ExitServerError the Sia API responded with a 5xx status
+	ExitServerError = 7
 
-	//MonthlyPriceFormat a parameter formatted in the Siacoin monthly price format "100SC"
-	MonthlyPriceFormat ParamFormat = "monthlyprice"
+	//ExitWalletLocked the request needs an unlocked wallet
+	ExitWalletLocked = 10
 
-	//BlockTimeFormat a parameter formatted in the 10 minutes per block format "10w"
-	BlockTimeFormat ParamFormat = "monthlyprice"
+	//ExitModuleNotLoaded the request needs a module siad was started without
+	ExitModuleNotLoaded = 11
+
+	//ExitNotSynced the request needs a synced consensus
+	ExitNotSynced = 12
+
+	//ExitNoEndpoint the command did not match a single endpoint
+	ExitNoEndpoint = 127
+
+	//RawFormat writes the response exactly as it was received
+	RawFormat = "raw"
+
+	//PrettyFormat indents JSON responses
+	PrettyFormat = "pretty"
 )
 
+func (e ExitCodeError) Error() string {
+	return e.Err.Error()
+}
+
 var (
 	//DefaultAPIPassword the default Sia API Password
 	DefaultAPIPassword string
-)
 
-//SiaAPIEndpoints all current endpoints listed in https://sia.tech/docs as of v1.4.1
-var SiaAPIEndpoints = []CommandEndpoint{
-	CommandEndpoint{
-		Path:   "/consensus",
-		Method: "GET",
-	},
-	CommandEndpoint{
-		Path:   "/consensus/blocks",
-		Method: "GET",
-	},
-	CommandEndpoint{
-		Path:   "/consensus/validate/transactionset",
-		Method: "POST",
-	},
-	CommandEndpoint{
-		Path:   "/daemon/constants",
-		Method: "GET",
-	},
-	CommandEndpoint{
-		Path:   "/daemon/settings",
-		Method: "GET",
-	},
-	CommandEndpoint{
-		Path:   "/daemon/settings",
-		Method: "POST",
-	},
-	CommandEndpoint{
-		Path:   "/daemon/stop",
-		Method: "GET",
-	},
-	CommandEndpoint{
-		Path:   "/daemon/update",
-		Method: "GET",
-	},
-	CommandEndpoint{
-		Path:   "/daemon/update",
-		Method: "POST",
-	},
-	CommandEndpoint{
-		Path:   "/daemon/version",
-		Method: "GET",
-	},
-	CommandEndpoint{
-		Path:   "/gateway",
-		Method: "GET",
-	},
-	CommandEndpoint{
-		Path:   "/gateway",
-		Method: "POST",
-	},
-	CommandEndpoint{
-		Path:   "/gateway/connect/:netaddress",
-		Method: "POST",
-	},
-	CommandEndpoint{
-		Path:   "/gateway/disconnect/:netaddress",
-		Method: "POST",
-	},
-	CommandEndpoint{
-		Path:   "/host",
-		Method: "GET",
-	},
-	CommandEndpoint{
-		Path:   "/host",
-		Method: "POST",
-	},
-	CommandEndpoint{
-		Path:   "/host/announce",
-		Method: "POST",
-	},
-	CommandEndpoint{
-		Path:   "/host/contracts",
-		Method: "GET",
-	},
-	CommandEndpoint{
-		Path:   "/host/storage",
-		Method: "GET",
-		AlternativeMatches: []string{
-			"/host/folders",
-		},
-	},
-	CommandEndpoint{
-		Path:   "/host/storage/folders/add",
-		Method: "POST",
-	},
-	CommandEndpoint{
-		Path:   "/host/storage/folders/remove",
-		Method: "POST",
-	},
-	CommandEndpoint{
-		Path:   "/host/storage/folders/resize",
-		Method: "POST",
-	},
-	CommandEndpoint{
-		Path:   "/host/storage/sectors/delete/:merkleroot",
-		Method: "POST",
-	},
-	CommandEndpoint{
-		Path:   "/host/estimatescore",
-		Method: "GET",
-	},
-	CommandEndpoint{
-		Path:   "/hostdb",
-		Method: "GET",
-	},
-	CommandEndpoint{
-		Path:   "/hostdb/active",
-		Method: "GET",
-	},
-	CommandEndpoint{
-		Path:   "/hostdb/all",
-		Method: "GET",
-	},
-	CommandEndpoint{
-		Path:   "/hostdb/hosts/:pubkey",
-		Method: "GET",
-	},
-	CommandEndpoint{
-		Path:   "/hostdb/filtermode",
-		Method: "GET",
-	},
-	CommandEndpoint{
-		Path:   "/hostdb/filtermode",
-		Method: "POST",
-	},
-	CommandEndpoint{
-		Path:   "/miner",
-		Method: "GET",
-	},
-	CommandEndpoint{
-		Path:   "/miner/start",
-		Method: "GET",
-	},
-	CommandEndpoint{
-		Path:   "/miner/stop",
-		Method: "GET",
-	},
-	CommandEndpoint{
-		Path:   "/miner/header",
-		Method: "GET",
-	},
-	CommandEndpoint{
-		Path:   "/miner/header",
-		Method: "POST",
-	},
-	CommandEndpoint{
-		Path:   "/renter",
-		Method: "GET",
-	},
-	CommandEndpoint{
-		Path:   "/renter",
-		Method: "POST",
-	},
-	CommandEndpoint{
-		Path:   "/renter/contract/cancel",
-		Method: "POST",
-	},
-	CommandEndpoint{
-		Path:   "/renter/backup",
-		Method: "POST",
-	},
-	CommandEndpoint{
-		Path:   "/renter/recoverbackup",
-		Method: "POST",
-	},
-	CommandEndpoint{
-		Path:   "/renter/uploadedbackups",
-		Method: "POST",
-	},
-	CommandEndpoint{
-		Path:   "/renter/contracts",
-		Method: "GET",
-	},
-	CommandEndpoint{
-		Path:   "/renter/dir/*siapath",
-		Method: "GET",
-	},
-	CommandEndpoint{
-		Path:   "/renter/dir/*siapath",
-		Method: "POST",
-	},
-	CommandEndpoint{
-		Path:   "/renter/downloads",
-		Method: "GET",
-	},
-	CommandEndpoint{
-		Path:   "/renter/downloads/clear",
-		Method: "POST",
-	},
-	CommandEndpoint{
-		Path:   "/renter/prices",
-		Method: "GET",
-	},
-	CommandEndpoint{
-		Path:   "/renter/files",
-		Method: "GET",
-	},
-	CommandEndpoint{
-		Path:   "/renter/file/*siapath",
-		Method: "GET",
-	},
-	CommandEndpoint{
-		Path:   "/renter/file/*siapath",
-		Method: "POST",
-	},
-	CommandEndpoint{
-		Path:   "/renter/delete/s*iapath",
-		Method: "POST",
-	},
-	CommandEndpoint{
-		Path:   "/renter/download/*siapath",
-		Method: "GET",
-	},
-	CommandEndpoint{
-		Path:   "/renter/download/cancel",
-		Method: "POST",
-	},
-	CommandEndpoint{
-		Path:   "/renter/downloadsync/*siapath",
-		Method: "GET",
-	},
-	CommandEndpoint{
-		Path:   "/renter/recoveryscan",
-		Method: "POST",
-	},
-	CommandEndpoint{
-		Path:   "/renter/recoveryscan",
-		Method: "GET",
-	},
-	CommandEndpoint{
-		Path:   "/renter/rename/*siapath",
-		Method: "POST",
-	},
-	CommandEndpoint{
-		Path:   "/renter/stream/*siapath",
-		Method: "GET",
-	},
-	CommandEndpoint{
-		Path:   "/renter/upload/*siapath",
-		Method: "POST",
-	},
-	CommandEndpoint{
-		Path:   "/renter/uploadstream/*siapath",
-		Method: "POST",
-	},
-	CommandEndpoint{
-		Path:   "/renter/validate/*siapath",
-		Method: "POST",
-	},
-	CommandEndpoint{
-		Path:   "/tpool/confirmed/:id",
-		Method: "GET",
-	},
-	CommandEndpoint{
-		Path:   "/tpool/fee",
-		Method: "GET",
-	},
-	CommandEndpoint{
-		Path:   "/tpool/raw/:id",
-		Method: "GET",
-	},
-	CommandEndpoint{
-		Path:   "/tpool/raw",
-		Method: "POST",
-	},
-	CommandEndpoint{
-		Path:   "/tpool/confirmed/:id",
-		Method: "GET",
-	},
-	CommandEndpoint{
-		Path:   "/wallet",
-		Method: "GET",
-	},
-	CommandEndpoint{
-		Path:   "/wallet/033x",
-		Method: "POST",
-	},
-	CommandEndpoint{
-		Path:   "/wallet/address",
-		Method: "GET",
-	},
-	CommandEndpoint{
-		Path:   "/wallet/addresses",
-		Method: "GET",
-	},
-	CommandEndpoint{
-		Path:   "/wallet/seedaddrs",
-		Method: "GET",
-	},
-	CommandEndpoint{
-		Path:   "/wallet/backup",
-		Method: "GET",
-	},
-	CommandEndpoint{
-		Path:   "/wallet/changepassword",
-		Method: "POST",
-	},
-	CommandEndpoint{
-		Path:   "/wallet/init",
-		Method: "POST",
-	},
-	CommandEndpoint{
-		Path:   "/wallet/init/seed",
-		Method: "POST",
-	},
-	CommandEndpoint{
-		Path:   "/wallet/seed",
-		Method: "POST",
-	},
-	CommandEndpoint{
-		Path:   "/wallet/seeds",
-		Method: "GET",
-	},
-	CommandEndpoint{
-		Path:   "/wallet/siacoins",
-		Method: "POST",
-	},
-	CommandEndpoint{
-		Path:   "/wallet/siafunds",
-		Method: "POST",
-	},
-	CommandEndpoint{
-		Path:   "/wallet/siagkey",
-		Method: "POST",
-	},
-	CommandEndpoint{
-		Path:   "/wallet/sign",
-		Method: "POST",
-	},
-	CommandEndpoint{
-		Path:   "/wallet/sweep/seed",
-		Method: "POST",
-	},
-	CommandEndpoint{
-		Path:   "/wallet/lock",
-		Method: "POST",
-	},
-	CommandEndpoint{
-		Path:   "/wallet/transaction/:id",
-		Method: "GET",
-	},
-	CommandEndpoint{
-		Path:   "/wallet/transactions",
-		Method: "GET",
-	},
-	CommandEndpoint{
-		Path:   "/wallet/transactions/:addr",
-		Method: "GET",
-	},
-	CommandEndpoint{
-		Path:   "/wallet/unlock",
-		Method: "POST",
-	},
-	CommandEndpoint{
-		Path:   "/wallet/unlockconditions/:addr",
-		Method: "GET",
-	},
-	CommandEndpoint{
-		Path:   "/wallet/unspent",
-		Method: "GET",
-	},
-	CommandEndpoint{
-		Path:   "/wallet/verify/address/:addr",
-		Method: "GET",
-	},
-	CommandEndpoint{
-		Path:   "/wallet/watch",
-		Method: "GET",
-	},
-	CommandEndpoint{
-		Path:   "/wallet/watch",
-		Method: "POST",
-	},
-}
+	//DefaultConnectTimeout how long to wait for a connection to the Sia API
+	DefaultConnectTimeout = 10 * time.Second
+
+	//DefaultKeepalive how often the shell, agent and --watch check that the daemon is reachable
+	DefaultKeepalive = 30 * time.Second
+
+	//boolFlags tool flags that do not take a value
+	boolFlags = map[string]bool{
+		"as-curl":             true,
+		"bundle":              true,
+		"changes":             true,
+		"clear":               true,
+		"detect":              true,
+		"dry-run":             true,
+		"include":             true,
+		"insecure":            true,
+		"machine":             true,
+		"no-cache":            true,
+		"no-deprecated":       true,
+		"plan":                true,
+		"quiet":               true,
+		"retry-all":           true,
+		"select-fastest":      true,
+		"strict-params":       true,
+		"trash":               true,
+		"update":              true,
+		"verbose":             true,
+		"version-on-conflict": true,
+		"yes":                 true,
+	}
+)
 
 // DefaultSiaDir returns the default data directory of siad. The values for
 // supported operating systems are:
@@ -477,9 +289,16 @@ func LoadDefaultAPIPassword() (password string, err error) {
 		return
 	}
 
+	if password, err = DefaultConfig.Password(); err != nil || len(password) > 0 {
+		return
+	}
+
 	passBuf, err := ioutil.ReadFile(filepath.Join(DefaultSiaDir(), "apipassword"))
 
-	if err != nil {
+	// a remote node or profile may not need the local password
+	if os.IsNotExist(err) {
+		return "", nil
+	} else if err != nil {
 		return
 	}
 
@@ -488,186 +307,1107 @@ func LoadDefaultAPIPassword() (password string, err error) {
 	return
 }
 
-func matchPaths(path, template string) bool {
-	pathSegments := strings.Split(path, "/")
-	segments := strings.Split(template, "/")
+func matchBuiltin(cmd Command) (builtin BuiltinCommand, ok bool) {
+	for _, builtin = range BuiltinCommands {
+		if endpoints.MatchPath(cmd.RequestPath, builtin.Path) {
+			return builtin, true
+		}
+	}
 
-	if len(segments) == 0 || len(pathSegments) == 0 {
-		return false
+	return
+}
+
+func matchEndpoints(cmd Command) []endpoints.CommandEndpoint {
+	return endpoints.Match(cmd.RequestPath, cmd.Method)
+}
+
+//applyEnvironment applies connection settings from environment variables. They override the
+//config file and profiles but not flags
+func applyEnvironment(cmd *Command) (err error) {
+	if addr := os.Getenv("SIA_API_ADDR"); len(addr) > 0 {
+		cmd.APIAddress, cmd.Addresses = addr, nil
 	}
 
-	if len(pathSegments) < len(segments) {
-		return false
+	if userAgent := os.Getenv("SIA_USER_AGENT"); len(userAgent) > 0 {
+		cmd.UserAgent = userAgent
 	}
 
-	for i, pathSeg := range pathSegments {
-		if len(segments) <= i {
-			return false
+	if format := os.Getenv("SIA_JSON_FORMAT"); len(format) > 0 {
+		cmd.Format = strings.ToLower(format)
+	}
+
+	if timeout := os.Getenv("SIA_JSON_TIMEOUT"); len(timeout) > 0 {
+		if cmd.Timeout, err = format.ParseDuration(timeout); err != nil {
+			err = fmt.Errorf("invalid SIA_JSON_TIMEOUT %q: %s", timeout, err)
 		}
+	}
 
-		seg := segments[i]
+	return
+}
 
-		if strings.HasPrefix(seg, ":") {
-			continue
-		}
+func parseInputs(args []string) (apiCommand Command, err error) {
+	apiCommand = Command{
+		APIAddress:     "localhost:9980",
+		APIPassword:    DefaultAPIPassword,
+		UserAgent:      "Sia-Agent",
+		Params:         make(map[string][]string),
+		Format:         RawFormat,
+		Timeout:        DefaultConfig.Timeout,
+		Cache:          DefaultConfig.Cache,
+		ConnectTimeout: DefaultConnectTimeout,
+		Keepalive:      DefaultKeepalive,
+		RetryDelay:     time.Second,
+	}
 
-		if strings.HasPrefix(seg, "*") {
-			return true
-		}
+	apiCommand.Flags = flagArgs(args)
 
-		if seg != pathSeg {
-			return false
-		}
+	if len(DefaultConfig.Address) > 0 {
+		apiCommand.APIAddress = DefaultConfig.Address
 	}
 
-	return true
-}
+	if len(DefaultConfig.Addresses) > 0 {
+		apiCommand.Addresses = DefaultConfig.Addresses
 
-func matchEndpoints(cmd Command) (endpoints []CommandEndpoint) {
-	for _, endpoint := range SiaAPIEndpoints {
-		if !matchPaths(cmd.RequestPath, endpoint.Path) {
-			continue
+		if len(DefaultConfig.Address) == 0 {
+			apiCommand.APIAddress = DefaultConfig.Addresses[0]
 		}
+	}
 
-		if len(cmd.Method) > 0 && cmd.Method != endpoint.Method {
-			continue
+	if len(DefaultConfig.UserAgent) > 0 {
+		apiCommand.UserAgent = DefaultConfig.UserAgent
+	}
+
+	if len(DefaultConfig.Format) > 0 {
+		apiCommand.Format = DefaultConfig.Format
+	}
+
+	apiCommand.Detect = DefaultConfig.Detect
+
+	applyTLSConfig(&apiCommand, DefaultConfig)
+
+	if err = applyHeaders(&apiCommand, DefaultConfig.Headers); err != nil {
+		return
+	}
+
+	if profile := profileArg(args); len(profile) > 0 {
+		if err = applyProfile(&apiCommand, profile); err != nil {
+			return
 		}
+	}
 
-		endpoints = append(endpoints, endpoint)
+	if err = applyEnvironment(&apiCommand); err != nil {
+		return
 	}
 
-	return
-}
+	tokens, err := tokenizeArgs(args)
 
-func parseInputs(args []string) (apiCommand Command) {
-	apiCommand = Command{
-		APIAddress:  "localhost:9980",
-		APIPassword: DefaultAPIPassword,
-		UserAgent:   "Sia-Agent",
-		Params:      make(map[string][]string),
+	if err != nil {
+		return
 	}
 
-	for i := 0; i < len(args); i++ {
-		arg := args[i]
+	var headers []string
 
-		if len(arg) == 0 {
+	for _, token := range tokens {
+		arg := token.Value
+
+		if len(token.Flag) == 0 && len(arg) == 0 {
 			continue
 		}
 
-		if strings.HasPrefix(arg, "--") {
-			key := strings.ToLower(arg[2:])
-			value := ""
+		if token.Param {
+			apiCommand.Params[token.Flag] = append(apiCommand.Params[token.Flag], token.Value)
+			continue
+		}
 
-			if len(args) > i+1 && !strings.HasPrefix(args[i+1], "--") {
-				value = args[i+1]
-				i++
-			}
+		if len(token.Flag) > 0 {
+			key, value := token.Flag, token.Value
 
-			if key == "method" {
+			switch key {
+			case "method":
 				apiCommand.Method = strings.ToUpper(value)
-				continue
-			} else if key == "addr" {
-				apiCommand.APIAddress = value
-				continue
-			} else if key == "useragent" {
+			case "addr":
+				apiCommand.APIAddress, apiCommand.Addresses = value, nil
+			case "useragent":
 				apiCommand.UserAgent = value
-				continue
-			} else if key == "apipassword" {
+			case "header":
+				headers = append(headers, value)
+			case "apipassword":
 				apiCommand.APIPassword = value
-				continue
+			case "cacert":
+				apiCommand.TLSCACert = value
+			case "insecure":
+				apiCommand.TLSInsecure = true
+			case "servername":
+				apiCommand.TLSServerName = value
+			case "include":
+				apiCommand.IncludeHeaders = true
+			case "machine":
+				apiCommand.Machine = true
+			case "verbose":
+				apiCommand.Verbose = true
+			case "dry-run":
+				apiCommand.DryRun = true
+			case "as-curl":
+				apiCommand.AsCurl = true
+			case "plan":
+				apiCommand.Plan = true
+			case "retry":
+				if apiCommand.Retries, err = strconv.Atoi(value); err != nil || apiCommand.Retries < 0 {
+					err = fmt.Errorf("invalid --retry value %q", value)
+					return
+				}
+			case "retry-delay":
+				if apiCommand.RetryDelay, err = format.ParseDuration(value); err != nil {
+					err = fmt.Errorf("invalid --retry-delay value %q: %s", value, err)
+					return
+				}
+			case "retry-all":
+				apiCommand.RetryAll = true
+			case "watch":
+				if apiCommand.Watch, err = format.ParseDuration(value); err != nil || apiCommand.Watch <= 0 {
+					err = fmt.Errorf("invalid --watch value %q", value)
+					return
+				}
+			case "max-interval":
+				if apiCommand.MaxInterval, err = format.ParseDuration(value); err != nil || apiCommand.MaxInterval <= 0 {
+					err = fmt.Errorf("invalid --max-interval value %q", value)
+					return
+				}
+			case "clear":
+				apiCommand.Clear = true
+			case "changes":
+				apiCommand.Changes = true
+			case "no-deprecated":
+				apiCommand.NoDeprecated = true
+			case "detect":
+				apiCommand.Detect = true
+			case "select-fastest":
+				apiCommand.SelectFastest = true
+			case "strict-params":
+				apiCommand.StrictParams = true
+			case "ssh":
+				apiCommand.SSHTarget = value
+			case "profile":
+				// applied before the other flags by applyProfile
+			case "format":
+				apiCommand.Format = strings.ToLower(value)
+			case "timeout":
+				if apiCommand.Timeout, err = format.ParseDuration(value); err != nil {
+					err = fmt.Errorf("invalid --timeout value %q: %s", value, err)
+					return
+				}
+
+				apiCommand.TimeoutFlag = true
+			case "keepalive":
+				if apiCommand.Keepalive, err = format.ParseDuration(value); err != nil || apiCommand.Keepalive < 0 {
+					err = fmt.Errorf("invalid --keepalive value %q", value)
+					return
+				}
+			case "cache":
+				if apiCommand.Cache, err = format.ParseDuration(value); err != nil || apiCommand.Cache < 0 {
+					err = fmt.Errorf("invalid --cache value %q", value)
+					return
+				}
+			case "no-cache":
+				apiCommand.NoCache = true
+			case "connect-timeout":
+				if apiCommand.ConnectTimeout, err = format.ParseDuration(value); err != nil {
+					err = fmt.Errorf("invalid --connect-timeout value %q: %s", value, err)
+					return
+				}
+			case "password-file":
+				apiCommand.PasswordFile = value
+			case "quiet":
+				apiCommand.Quiet = true
+			case "fail-on":
+				apiCommand.FailOn = append(apiCommand.FailOn, value)
+			case "compute":
+				apiCommand.Compute = append(apiCommand.Compute, value)
+			case "version-on-conflict":
+				apiCommand.VersionOnConflict = true
+			case "file":
+				apiCommand.UploadFile = value
+			case "json":
+				apiCommand.JSONBody = value
+			case "data-raw":
+				apiCommand.RawBody, apiCommand.RawBodySet = value, true
+			case "data-binary":
+				// like curl, --data-binary @file sends the file and --data-raw never reads one
+				if strings.HasPrefix(value, "@") {
+					apiCommand.RawBodyFile = value[1:]
+				} else {
+					apiCommand.RawBody = value
+				}
+
+				apiCommand.RawBodySet = true
+			case "output":
+				apiCommand.OutputFile = value
+			case "bundle":
+				apiCommand.Bundle = true
+			case "bundle-path":
+				apiCommand.BundlePaths = append(apiCommand.BundlePaths, value)
+			case "encrypt-key":
+				apiCommand.EncryptKey = value
+			case "compress":
+				apiCommand.Compress = strings.ToLower(value)
+			case "endpoints":
+				apiCommand.EndpointFiles = append(apiCommand.EndpointFiles, value)
+			case "relock-after":
+				if apiCommand.RelockAfter, err = format.ParseDuration(value); err != nil {
+					err = fmt.Errorf("invalid --relock-after value %q: %s", value, err)
+					return
+				}
+			default:
+				apiCommand.Params[key] = append(apiCommand.Params[key], value)
 			}
 
-			apiCommand.Params[key] = append(apiCommand.Params[key], value)
 			continue
 		}
 
+		apiCommand.Args = append(apiCommand.Args, arg)
 		apiCommand.RequestPath += "/" + arg
 	}
 
+	if err = applyHeaders(&apiCommand, headers); err != nil {
+		return
+	}
+
+	// fixtures capture and conformance name GET endpoints with --endpoints rather than endpoint files
+	if apiCommand.RequestPath == "/fixtures/capture" || apiCommand.RequestPath == "/conformance" {
+		apiCommand.Params["endpoints"], apiCommand.EndpointFiles = apiCommand.EndpointFiles, nil
+	}
+
+	if err = composeJSONBody(&apiCommand); err != nil {
+		return
+	}
+
+	if err = loadEndpointFiles(append(append([]string{}, DefaultConfig.Endpoints...), apiCommand.EndpointFiles...)); err != nil {
+		return
+	}
+
+	if apiCommand.Machine {
+		apiCommand.Quiet = true
+		apiCommand.Format = RawFormat
+	}
+
+	if _, ok := formatPlugin(apiCommand.Format); !ok && apiCommand.Format != RawFormat && apiCommand.Format != PrettyFormat {
+		err = fmt.Errorf("unknown format %q, expected %s, %s or a format plugin", apiCommand.Format, RawFormat, PrettyFormat)
+	}
+
 	return
 }
 
-func makeRequest(cmd Command, body io.Reader) (req *http.Request, err error) {
-	urlStr := "http://" + cmd.APIAddress + cmd.RequestPath
+//loadPasswordFile reads the wallet encryption password from the command's password file
+func loadPasswordFile(cmd Command) (password string, err error) {
+	passBuf, err := ioutil.ReadFile(cmd.PasswordFile)
 
 	if err != nil {
 		return
 	}
 
-	if cmd.Method == "GET" && len(cmd.Params) > 0 {
-		urlStr += "?" + url.Values(cmd.Params).Encode()
-	} else if cmd.Method == "POST" && body == nil && len(cmd.Params) > 0 {
-		body = strings.NewReader(url.Values(cmd.Params).Encode())
+	password = strings.TrimSpace(string(passBuf))
+
+	if len(password) == 0 {
+		err = fmt.Errorf("password file %s is empty", cmd.PasswordFile)
+	}
+
+	return
+}
+
+//relockWallet waits for the command's relock window to pass and then locks the wallet. An
+//interrupt or SIGTERM during the wait locks the wallet immediately rather than leaving it unlocked
+func relockWallet(cmd Command) (err error) {
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(sigs)
+
+	select {
+	case <-time.After(cmd.RelockAfter):
+	case sig := <-sigs:
+		os.Stderr.WriteString(fmt.Sprintf("received %s, relocking now\n", sig))
+	}
+
+	if err = callAPI(cmd, "POST", "/wallet/lock", nil, nil); err != nil {
+		err = fmt.Errorf("unable to relock wallet: %s", err)
+	}
+
+	return
+}
+
+//isTerminal returns true if the file is attached to a terminal
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+
+	if err != nil {
+		return false
 	}
 
-	req, err = http.NewRequest(cmd.Method, urlStr, body)
+	return info.Mode()&os.ModeCharDevice != 0
+}
+
+//copyOutput copies the response body to w, indenting JSON responses if the pretty format was requested
+//or transforming them with the format plugin
+func copyOutput(cmd Command, w io.Writer, resp *http.Response) (err error) {
+	if path, ok := formatPlugin(cmd.Format); ok && !cmd.Endpoint.Binary {
+		return writeFormatted(cmd, path, w, resp.Body)
+	}
+
+	if cmd.Format != PrettyFormat || cmd.Endpoint.Binary {
+		_, err = io.Copy(w, resp.Body)
+		return
+	}
+
+	buf, err := ioutil.ReadAll(resp.Body)
 
 	if err != nil {
 		return
 	}
 
-	req.SetBasicAuth("", cmd.APIPassword)
-	req.Header.Add("User-Agent", cmd.UserAgent)
+	var indented bytes.Buffer
 
-	if cmd.Method == "POST" {
-		req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+	// responses that are not JSON are written unchanged
+	if err = json.Indent(&indented, bytes.TrimSpace(buf), "", "\t"); err != nil {
+		_, err = w.Write(buf)
+		return
 	}
 
+	indented.WriteByte('\n')
+	_, err = indented.WriteTo(w)
+
 	return
 }
 
-func main() {
-	var err error
+//writeOutput writes the response body to the command's output file, or stdout if no file was given
+func writeOutput(cmd Command, resp *http.Response) (err error) {
+	if len(cmd.OutputFile) == 0 || resp.StatusCode >= 300 {
+		return copyOutput(cmd, os.Stdout, resp)
+	}
 
-	DefaultAPIPassword, err = LoadDefaultAPIPassword()
+	f, err := os.Create(cmd.OutputFile)
 
 	if err != nil {
-		os.Stderr.WriteString("unable to load API password")
-		os.Exit(1)
+		return
+	}
+
+	defer f.Close()
+
+	if err = copyOutput(cmd, f, resp); err != nil {
+		return
+	}
+
+	return f.Close()
+}
+
+//writeHeaders writes the response status line and headers to stdout followed by a blank line
+func writeHeaders(resp *http.Response) (err error) {
+	if _, err = fmt.Fprintf(os.Stdout, "%s %s\r\n", resp.Proto, resp.Status); err != nil {
+		return
+	}
+
+	if err = resp.Header.Write(os.Stdout); err != nil {
+		return
+	}
+
+	_, err = os.Stdout.WriteString("\r\n")
+
+	return
+}
+
+//writeJSON writes the object to stdout as indented JSON
+func writeJSON(obj interface{}) error {
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "\t")
+
+	return enc.Encode(obj)
+}
+
+func makeRequest(cmd Command, body io.Reader) (req *http.Request, err error) {
+	return apiClient(cmd).NewRequest(client.Command{
+		Method:        cmd.Method,
+		Path:          cmd.RequestPath,
+		Params:        url.Values(cmd.Params),
+		Body:          body,
+		ContentType:   cmd.ContentType,
+		ContentLength: cmd.BodyLength,
+	})
+}
+
+//apiClient returns the client of the command's Sia API. Requests are sent with httpClient
+func apiClient(cmd Command) *client.Client {
+	return &client.Client{
+		Address:   cmd.APIAddress,
+		Password:  cmd.APIPassword,
+		UserAgent: cmd.UserAgent,
+		Header:    cmd.Header,
+	}
+}
+
+//baseURL returns the scheme and host of the Sia API. Addresses without a scheme use http
+func baseURL(cmd Command) string {
+	return apiClient(cmd).BaseURL()
+}
+
+//tlsConfig returns the TLS settings for https targets
+func tlsConfig(cmd Command) (config *tls.Config, err error) {
+	config = &tls.Config{
+		ServerName:         cmd.TLSServerName,
+		InsecureSkipVerify: cmd.TLSInsecure,
+	}
+
+	if len(cmd.TLSCACert) == 0 {
+		return
+	}
+
+	pem, err := ioutil.ReadFile(cmd.TLSCACert)
+
+	if err != nil {
+		return
+	}
+
+	config.RootCAs = x509.NewCertPool()
+
+	if !config.RootCAs.AppendCertsFromPEM(pem) {
+		err = fmt.Errorf("no certificates found in %s", cmd.TLSCACert)
+	}
+
+	return
+}
+
+//sharedTransports the transports reused between commands keyed by their connection settings. Set
+//by the shell so its commands keep their connections to siad open
+var sharedTransports map[string]*http.Transport
+
+//sharedTransportsMu guards sharedTransports, which keepalive checks use alongside commands
+var sharedTransportsMu sync.Mutex
+
+//closeSharedTransports closes the idle connections of the shared transports so the next
+//request reconnects
+func closeSharedTransports() {
+	sharedTransportsMu.Lock()
+	defer sharedTransportsMu.Unlock()
+
+	for _, transport := range sharedTransports {
+		transport.CloseIdleConnections()
+	}
+}
+
+//newTransport returns a transport with the settings of http.DefaultTransport. They are listed
+//because Transport.Clone needs go 1.13
+func newTransport() *http.Transport {
+	return &http.Transport{
+		Proxy: http.ProxyFromEnvironment,
+		DialContext: (&net.Dialer{
+			Timeout:   30 * time.Second,
+			KeepAlive: 30 * time.Second,
+		}).DialContext,
+		MaxIdleConns:          100,
+		IdleConnTimeout:       90 * time.Second,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ExpectContinueTimeout: time.Second,
+	}
+}
+
+//httpClient returns the HTTP client used to send the command's requests. Requests are delegated
+//to the agent when one is running
+func httpClient(cmd Command) (client *http.Client, err error) {
+	var transport http.RoundTripper
+
+	if useAgent(cmd) {
+		transport = newAgentTransport(cmd)
+	} else {
+		tls, err := tlsConfig(cmd)
+
+		if err != nil {
+			return nil, err
+		}
+
+		dialer := &net.Dialer{
+			Timeout:   cmd.ConnectTimeout,
+			KeepAlive: 30 * time.Second,
+		}
+
+		key := fmt.Sprintf("%s\x00%s\x00%s\x00%t\x00%s", cmd.APIAddress, cmd.TLSCACert, cmd.TLSServerName, cmd.TLSInsecure, cmd.ConnectTimeout)
+
+		sharedTransportsMu.Lock()
+
+		if shared, ok := sharedTransports[key]; ok {
+			transport = shared
+		} else {
+			direct := newTransport()
+			direct.DialContext = dialer.DialContext
+			direct.TLSClientConfig = tls
+			transport = direct
+
+			if sharedTransports != nil {
+				sharedTransports[key] = direct
+			}
+		}
+
+		sharedTransportsMu.Unlock()
+	}
+
+	if cmd.Retries > 0 {
+		transport = &retryTransport{
+			next:     transport,
+			retries:  cmd.Retries,
+			delay:    cmd.RetryDelay,
+			retryAll: cmd.RetryAll,
+			cmd:      cmd,
+		}
+	}
+
+	if cmd.Verbose {
+		transport = &verboseTransport{next: transport}
+	}
+
+	client = &http.Client{
+		Transport: transport,
+	}
+
+	return
+}
+
+//isStreaming returns true if the request streams a file to or from the API
+func isStreaming(cmd Command) bool {
+	return cmd.Endpoint.Binary || cmd.ContentType == "application/octet-stream"
+}
+
+//requestTimeout returns the overall deadline for the command's request. Streaming requests can
+//run for as long as the transfer takes, so the default timeout only applies to them if it was
+//set with --timeout
+func requestTimeout(cmd Command) time.Duration {
+	if isStreaming(cmd) && !cmd.TimeoutFlag {
+		return 0
+	}
+
+	return cmd.Timeout
+}
+
+//withTimeout attaches the command's overall deadline to the request. The returned function must
+//be called once the response has been read
+func withTimeout(cmd Command, req *http.Request) (*http.Request, context.CancelFunc) {
+	timeout := requestTimeout(cmd)
+
+	if timeout <= 0 {
+		return req, func() {}
+	}
+
+	ctx, cancel := context.WithTimeout(req.Context(), timeout)
+
+	return req.WithContext(ctx), cancel
+}
+
+//timeoutError replaces an error caused by the request's deadline with one that says so
+func timeoutError(cmd Command, req *http.Request, err error) error {
+	if err != nil && req.Context().Err() == context.DeadlineExceeded {
+		return fmt.Errorf("request timed out after %s", requestTimeout(cmd))
+	}
+
+	return err
+}
+
+//callAPI sends a request to the Sia API using the connection settings from cmd and decodes the
+//JSON response into obj. obj may be nil if the response should be discarded
+func callAPI(cmd Command, method, path string, params url.Values, obj interface{}) (err error) {
+	// the connection settings such as TLS, retries and --verbose are kept, only the request changes
+	callCmd := cmd
+	callCmd.Method, callCmd.RequestPath, callCmd.Params = method, path, params
+	callCmd.Endpoint, callCmd.ContentType, callCmd.BodyLength = endpoints.CommandEndpoint{}, "", 0
+
+	return streamAPI(callCmd, nil, func(r io.Reader) error {
+		if obj == nil {
+			return nil
+		}
+
+		return json.NewDecoder(r).Decode(obj)
+	})
+}
+
+//streamAPI sends the command's request with the raw body, which may be nil, and passes the
+//response body to read. read may be nil if the response should be discarded
+func streamAPI(cmd Command, body io.Reader, read func(io.Reader) error) (err error) {
+	req, err := makeRequest(cmd, body)
+
+	if err != nil {
+		return
+	}
+
+	c := apiClient(cmd)
+
+	if c.HTTPClient, err = httpClient(cmd); err != nil {
+		return
+	}
+
+	req, cancel := withTimeout(cmd, req)
+	defer cancel()
+
+	resp, err := c.Send(req)
+
+	if _, ok := err.(APIError); ok {
+		return
+	} else if err != nil {
+		return timeoutError(cmd, req, err)
+	}
+
+	defer resp.Body.Close()
+
+	if read == nil {
+		return
+	}
+
+	return timeoutError(cmd, req, read(resp.Body))
+}
+
+//apiErrorCodes exit codes for well known Sia error messages. The messages are matched
+//case-insensitively against the error returned by the API
+var apiErrorCodes = []struct {
+	Message string
+	Code    int
+}{
+	{"wallet must be unlocked", ExitWalletLocked},
+	{"wallet has not been unlocked", ExitWalletLocked},
+	{"wallet is locked", ExitWalletLocked},
+	{"module not loaded", ExitModuleNotLoaded},
+	{"is not loaded", ExitModuleNotLoaded},
+	{"not synced", ExitNotSynced},
+}
+
+//apiExitCode returns the exit code for an error response, using the message for well known Sia
+//errors and the status class otherwise
+func apiExitCode(apiErr APIError) int {
+	message := strings.ToLower(apiErr.Message)
+
+	for _, known := range apiErrorCodes {
+		if strings.Contains(message, known.Message) {
+			return known.Code
+		}
+	}
+
+	switch {
+	case apiErr.StatusCode == http.StatusUnauthorized:
+		return ExitUnauthorized
+	case apiErr.StatusCode == http.StatusNotFound:
+		return ExitNotFound
+	case apiErr.StatusCode >= 500:
+		return ExitServerError
+	}
+
+	return ExitAPIError
+}
+
+//reportError writes the error to stderr, or as an envelope on stdout in machine mode. Errors that
+//have already been reported are not written again
+func reportError(cmd Command, err error) {
+	if exitErr, ok := err.(ExitCodeError); ok {
+		if _, reported := exitErr.Err.(reportedError); reported {
+			return
+		}
+	}
+
+	if cmd.Machine {
+		writeMachineError(cmd, err)
+	} else if err != nil {
+		os.Stderr.WriteString(err.Error())
+	}
+}
+
+//exitCode returns the process exit code for the error
+func exitCode(err error) int {
+	if err == nil {
+		return ExitSuccess
 	}
 
-	command := parseInputs(os.Args[1:])
+	if exitErr, ok := err.(ExitCodeError); ok {
+		return exitErr.Code
+	}
 
-	endpoints := matchEndpoints(command)
+	if apiErr, ok := err.(APIError); ok {
+		return apiExitCode(apiErr)
+	}
+
+	return ExitError
+}
+
+//prepareCommand matches the command to an endpoint and applies and validates the flags that
+//change the request before it is sent
+func prepareCommand(command *Command) (err error) {
+	endpoints := matchEndpoints(*command)
+
+	// plugins are only asked for their endpoints when none of the built-in endpoints match
+	if len(endpoints) == 0 {
+		if err = loadPluginEndpoints(); err != nil {
+			return
+		}
+
+		endpoints = matchEndpoints(*command)
+	}
+
+	if info, ok := applyDetection(command); ok {
+		endpoints = selectEndpoints(endpoints, info)
+	}
 
 	if len(endpoints) == 0 && len(command.Method) == 0 {
-		os.Stderr.WriteString("No matching endpoints. Try specifying the request method or checking http://sia.tech/docs")
-		os.Exit(127)
+		return ExitCodeError{Code: ExitNoEndpoint, Err: fmt.Errorf("No matching endpoints. Try specifying the request method or checking http://sia.tech/docs")}
 	}
 
 	if len(endpoints) > 1 && len(command.Method) == 0 {
-		os.Stderr.WriteString("More than one matching endpoint. Try specifying the request method or checking http://sia.tech/docs")
-		os.Exit(127)
+		return ExitCodeError{Code: ExitNoEndpoint, Err: fmt.Errorf("More than one matching endpoint. Try specifying the request method or checking http://sia.tech/docs")}
 	}
 
 	if len(endpoints) > 0 {
 		command.Endpoint = endpoints[0]
+		command.RequestPath = command.Endpoint.CanonicalPath(command.RequestPath)
 
 		if len(command.Method) == 0 {
 			command.Method = command.Endpoint.Method
 		}
 	}
 
-	req, err := makeRequest(command, nil)
+	if command.RelockAfter > 0 && command.Endpoint.Path != "/wallet/unlock" {
+		return fmt.Errorf("--relock-after can only be used with wallet unlock")
+	}
+
+	if len(command.PasswordFile) > 0 {
+		password, err := loadPasswordFile(*command)
+
+		if err != nil {
+			return err
+		}
+
+		command.Params["encryptionpassword"] = []string{password}
+	}
+
+	if err = resolveSiaPaths(command); err != nil {
+		return
+	}
+
+	if err = parseSliceParams(command); err != nil {
+		return
+	}
+
+	if command.VersionOnConflict && (command.DryRun || command.AsCurl) {
+		return fmt.Errorf("--version-on-conflict cannot be used with --dry-run or --as-curl")
+	}
+
+	if command.VersionOnConflict {
+		if err = versionExistingFile(*command); err != nil {
+			return
+		}
+	}
+
+	if err = checkRequiredParams(*command); err != nil {
+		return
+	}
+
+	if err = checkUnknownParams(command); err != nil {
+		return
+	}
+
+	if err = convertParams(command); err != nil {
+		return
+	}
+
+	if err = validateParams(*command); err != nil {
+		return
+	}
+
+	if err = checkEncryptKey(*command); err != nil {
+		return
+	}
+
+	if err = checkCompress(command); err != nil {
+		return
+	}
+
+	if command.Endpoint.Binary && len(command.FailOn) > 0 {
+		return fmt.Errorf("--fail-on cannot be used with binary endpoints")
+	}
+
+	// the conditions are checked before the request is sent so a typo cannot follow a POST
+	for _, expr := range command.FailOn {
+		if _, err = parseFailCondition(expr); err != nil {
+			return
+		}
+	}
+
+	if command.Endpoint.Binary && len(command.Compute) > 0 {
+		return fmt.Errorf("--compute cannot be used with binary endpoints")
+	}
+
+	for _, expr := range command.Compute {
+		if _, err = parseComputedField(expr); err != nil {
+			return
+		}
+	}
+
+	if command.Endpoint.Binary && len(command.OutputFile) == 0 && isTerminal(os.Stdout) {
+		return fmt.Errorf("refusing to write binary data to a terminal. Use --output <file> or redirect stdout")
+	}
+
+	return
+}
+
+//sendCommand sends the command's request to the Sia API and writes the response
+func sendCommand(command Command) (err error) {
+	body, err := openRequestBody(&command)
 
 	if err != nil {
-		os.Stderr.WriteString(err.Error())
-		os.Exit(1)
+		return
+	}
+
+	if body != nil {
+		defer body.Close()
 	}
 
-	resp, err := http.DefaultClient.Do(req)
+	req, err := makeRequest(command, body)
 
 	if err != nil {
+		return
+	}
+
+	if command.DryRun {
+		writeRequestLog(os.Stdout, "", req)
+		return
+	}
+
+	if command.AsCurl {
+		return writeCurlCommand(command, req)
+	}
+
+	checkAvailable(&command)
+
+	if err = checkDeprecated(&command); err != nil {
+		return
+	}
+
+	client, err := httpClient(command)
+
+	if err != nil {
+		return
+	}
+
+	req, cancel := withTimeout(command, req)
+	defer cancel()
+
+	resp, cached := cachedResponse(command)
+
+	if !cached {
+		if resp, err = client.Do(req); err != nil {
+			return ExitCodeError{Code: ExitConnectionError, Err: timeoutError(command, req, err)}
+		}
+
+		if err = cacheResponse(command, resp); err != nil {
+			resp.Body.Close()
+			return ExitCodeError{Code: ExitConnectionError, Err: timeoutError(command, req, err)}
+		}
+	}
+
+	defer resp.Body.Close()
+
+	// the full response is kept for --fail-on when only part of it is written
+	var respBody, fullBody []byte
+	sliced := command.Limit > 0 || command.Offset > 0 || command.Sample > 0
+
+	if (len(command.FailOn) > 0 || len(command.Compute) > 0 || sliced) && resp.StatusCode < 300 {
+		if respBody, err = ioutil.ReadAll(resp.Body); err != nil {
+			return ExitCodeError{Code: ExitConnectionError, Err: err}
+		}
+
+		// computed fields are written with the response and can be used in --fail-on
+		if len(command.Compute) > 0 {
+			if respBody, err = computeFields(command, respBody); err != nil {
+				return
+			}
+		}
+
+		fullBody = respBody
+
+		if sliced {
+			if respBody, err = sliceResponse(command, respBody); err != nil {
+				return
+			}
+		}
+
+		resp.Body = ioutil.NopCloser(bytes.NewReader(respBody))
+	}
+
+	if command.Endpoint.Binary && resp.StatusCode < 300 {
+		resp.Body = ioutil.NopCloser(withProgress(command, resp.Body, resp.ContentLength, "downloading"))
+
+		if len(command.EncryptKey) > 0 {
+			if resp.Body, err = decryptedBody(command, resp.Body); err != nil {
+				return
+			}
+		}
+
+		if len(command.Compress) > 0 {
+			if resp.Body, err = decompressedBody(resp.Body); err != nil {
+				return
+			}
+		}
+	}
+
+	// error responses are kept to pick the exit code after they are written
+	if resp.StatusCode >= 300 {
+		if respBody, err = ioutil.ReadAll(resp.Body); err != nil {
+			return ExitCodeError{Code: ExitConnectionError, Err: err}
+		}
+
+		resp.Body = ioutil.NopCloser(bytes.NewReader(respBody))
+	}
+
+	if command.IncludeHeaders && !command.Machine {
+		if err = writeHeaders(resp); err != nil {
+			return
+		}
+	}
+
+	if command.Machine && (!command.Endpoint.Binary || len(command.OutputFile) > 0) {
+		respBody, err = writeMachineEnvelope(command, resp)
+	} else {
+		err = writeOutput(command, resp)
+	}
+
+	if err = timeoutError(command, req, err); err != nil {
+		return
+	}
+
+	if resp.StatusCode >= 300 {
+		apiErr := APIError{StatusCode: resp.StatusCode}
+
+		if json.Unmarshal(respBody, &apiErr) != nil {
+			apiErr.Message = string(bytes.TrimSpace(respBody))
+		}
+
+		if command.Machine {
+			return ExitCodeError{Code: apiExitCode(apiErr), Err: reportedError{apiErr}}
+		}
+
+		// the error response has already been written to stdout
+		return ExitCodeError{Code: apiExitCode(apiErr), Err: errors.New("")}
+	}
+
+	if command.UploadJournal != nil {
+		if err = command.UploadJournal.remove(); err != nil {
+			return
+		}
+	}
+
+	if err = command.UploadChecksums.save(); err != nil {
+		return
+	}
+
+	if len(command.FailOn) > 0 {
+		cond, err := checkFailConditions(command, fullBody)
+
+		if err != nil {
+			return err
+		}
+
+		if cond != nil {
+			return ExitCodeError{Code: ExitAssertionFailed, Err: fmt.Errorf("fail-on condition matched: %s", cond.Expression)}
+		}
+	}
+
+	if command.RelockAfter > 0 {
+		os.Stderr.WriteString(fmt.Sprintf("wallet unlocked, relocking in %s\n", command.RelockAfter))
+
+		err = relockWallet(command)
+	}
+
+	return
+}
+
+//run runs sia-json with the command line arguments and returns the exit code
+func run(args []string) int {
+	var err error
+
+	if len(args) > 0 && args[0] == completeCommandName {
+		return completeCommand(args[1:])
+	}
+
+	if DefaultConfig, err = LoadConfig(); err != nil {
 		os.Stderr.WriteString(err.Error())
-		os.Exit(1)
+		return ExitError
 	}
 
-	_, err = io.Copy(os.Stdout, resp.Body)
+	DefaultAPIPassword, err = LoadDefaultAPIPassword()
 
 	if err != nil {
+		os.Stderr.WriteString("unable to load API password")
+		return ExitError
+	}
+
+	command, err := parseInputs(args)
+
+	if err != nil && command.Machine {
+		writeMachineError(command, err)
+		return ExitError
+	} else if err != nil {
 		os.Stderr.WriteString(err.Error())
-		os.Exit(1)
+		return ExitError
+	}
+
+	started := time.Now()
+	err = execute(command)
+	reportError(command, err)
+	recordHistory(args, command, started, err)
+
+	return exitCode(err)
+}
+
+//execute runs the parsed command: a builtin command or a request to the Sia API, repeated if
+//--watch was given
+func execute(command Command) (err error) {
+	if err = resolveDiscovery(&command); err != nil {
+		return
+	}
+
+	if err = selectFastest(&command); err != nil {
+		return
+	}
+
+	if len(command.SSHTarget) > 0 {
+		closeTunnel, err := openSSHTunnel(&command)
+
+		if err != nil {
+			return ExitCodeError{Code: ExitConnectionError, Err: err}
+		}
+
+		defer closeTunnel()
+	}
+
+	var send func(Command) error
+
+	if builtin, ok := matchBuiltin(command); ok && (command.AsCurl || command.DryRun && !builtin.DryRun) {
+		err = fmt.Errorf("--dry-run and --as-curl cannot be used with %s", builtin.Path)
+	} else if ok && command.Plan && !builtin.Plan {
+		err = fmt.Errorf("--plan is not supported for %s", builtin.Path)
+	} else if ok && command.Machine && !builtin.Stream {
+		send = machineBuiltin(builtin)
+	} else if ok {
+		send = builtin.Run
+	} else if plugin, ok := pluginCommand(command); ok {
+		send = plugin
+	} else if err = prepareCommand(&command); err == nil && command.Plan {
+		send = planCommand
+	} else if err == nil && command.Bundle {
+		send, err = bundleSender(command)
+	} else if err == nil {
+		send = sendCommand
+	}
+
+	if err == nil && command.Changes && command.Watch == 0 {
+		err = fmt.Errorf("--changes can only be used with --watch")
+	} else if err == nil && command.Watch > 0 {
+		err = watch(command, send)
+	} else if err == nil {
+		err = send(command)
 	}
 
 	return
 }
+
+func main() {
+	os.Exit(run(os.Args[1:]))
+}
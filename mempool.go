@@ -0,0 +1,309 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/n8maninger/siac-json/format"
+)
+
+type (
+	//mempoolInput the fields of a wallet transaction input used by monitor mempool
+	mempoolInput struct {
+		ParentID string `json:"parentid"`
+		FundType string `json:"fundtype"`
+	}
+
+	//mempoolOutput the fields of a wallet transaction output used by monitor mempool
+	mempoolOutput struct {
+		FundType       string `json:"fundtype"`
+		RelatedAddress string `json:"relatedaddress"`
+		Value          string `json:"value"`
+	}
+
+	//mempoolTransaction the fields of a /wallet/transactions/:addr transaction used by monitor
+	//mempool
+	mempoolTransaction struct {
+		TransactionID      string          `json:"transactionid"`
+		ConfirmationHeight uint64          `json:"confirmationheight"`
+		Inputs             []mempoolInput  `json:"inputs"`
+		Outputs            []mempoolOutput `json:"outputs"`
+	}
+
+	//addressTransactionsResponse the response of /wallet/transactions/:addr
+	addressTransactionsResponse struct {
+		ConfirmedTransactions   []mempoolTransaction `json:"confirmedtransactions"`
+		UnconfirmedTransactions []mempoolTransaction `json:"unconfirmedtransactions"`
+	}
+
+	//mempoolEvent a change of a transaction touching a monitored address written by monitor
+	//mempool
+	mempoolEvent struct {
+		Time time.Time `json:"time"`
+		//Event unconfirmed, confirmed, double-spend or evicted
+		Event         string `json:"event"`
+		TransactionID string `json:"transactionid"`
+		Address       string `json:"address"`
+		//Value the siacoins the transaction sends to the address in hastings
+		Value  string `json:"value"`
+		Height uint64 `json:"height,omitempty"`
+		//ConflictsWith the transaction spending the same outputs, for double-spend events
+		ConflictsWith string `json:"conflictswith,omitempty"`
+		//Outputs the outputs spent by both transactions, for double-spend events
+		Outputs []string `json:"outputs,omitempty"`
+	}
+)
+
+//defaultMempoolInterval how often monitor mempool polls the addresses' transactions
+const defaultMempoolInterval = 10 * time.Second
+
+//received returns the siacoins the transaction sends to the address
+func (txn mempoolTransaction) received(address string) *big.Int {
+	total := new(big.Int)
+
+	for _, output := range txn.Outputs {
+		if output.FundType == "siacoin output" && output.RelatedAddress == address {
+			total.Add(total, parseHastings(output.Value))
+		}
+	}
+
+	return total
+}
+
+//mempoolAddresses returns the addresses of --address, which can be repeated or comma separated
+func mempoolAddresses(cmd Command) (addresses []string, err error) {
+	for _, value := range cmd.Params["address"] {
+		for _, address := range strings.Split(value, ",") {
+			if address = strings.TrimSpace(address); len(address) > 0 {
+				addresses = append(addresses, address)
+			}
+		}
+	}
+
+	if len(addresses) == 0 {
+		return nil, fmt.Errorf("--address is required, such as --address <addr>")
+	}
+
+	return
+}
+
+//checkWatched warns about addresses that are neither wallet addresses nor watched, siad does not
+//report their transactions
+func checkWatched(cmd Command, addresses []string) {
+	var watched, owned struct {
+		Addresses []string `json:"addresses"`
+	}
+
+	// the warning is informational, the addresses are polled either way
+	if callAPI(cmd, "GET", "/wallet/watch", nil, &watched) != nil || callAPI(cmd, "GET", "/wallet/addresses", nil, &owned) != nil {
+		return
+	}
+
+	known := make(map[string]bool)
+
+	for _, address := range append(watched.Addresses, owned.Addresses...) {
+		known[address] = true
+	}
+
+	for _, address := range addresses {
+		if !known[address] {
+			notify(cmd, "warning: %s is not a wallet or watched address and its transactions are not reported. Watch it with wallet watch --addresses", address)
+		}
+	}
+}
+
+//mempoolState the transactions monitor mempool has seen
+type mempoolState struct {
+	//pending the unconfirmed event of each transaction not yet confirmed or evicted
+	pending map[string]mempoolEvent
+	//spends the transaction spending each output
+	spends map[string]string
+	//conflicted the pairs of transactions already reported as double spends
+	conflicted map[string]bool
+}
+
+//spend records the outputs the transaction spends and returns the transactions already spending
+//any of them, with the outputs both spend
+func (s *mempoolState) spend(txn mempoolTransaction) (conflicts map[string][]string) {
+	for _, input := range txn.Inputs {
+		if input.FundType != "siacoin input" && input.FundType != "siafund input" {
+			continue
+		}
+
+		if other, ok := s.spends[input.ParentID]; ok && other != txn.TransactionID {
+			if conflicts == nil {
+				conflicts = make(map[string][]string)
+			}
+
+			conflicts[other] = append(conflicts[other], input.ParentID)
+			continue
+		}
+
+		s.spends[input.ParentID] = txn.TransactionID
+	}
+
+	return
+}
+
+//monitorMempoolCommand polls the transactions of the --address addresses every --interval
+//(default 10s) and writes a JSON line when an unconfirmed transaction touching one appears, when
+//it confirms or is evicted from the transaction pool, and when two transactions spend the same
+//outputs, until interrupted: monitor mempool --address <addr>. Double spends and evictions are
+//warned about on stderr. siad only reports the transactions of wallet and watched addresses, so a
+//conflicting spend paying elsewhere is only seen as the eviction of the payment
+func monitorMempoolCommand(cmd Command) (err error) {
+	addresses, err := mempoolAddresses(cmd)
+
+	if err != nil {
+		return
+	}
+
+	interval := defaultMempoolInterval
+
+	if values := cmd.Params["interval"]; len(values) > 0 {
+		if interval, err = format.ParseDuration(values[0]); err != nil {
+			return
+		}
+	}
+
+	checkWatched(cmd, addresses)
+
+	scheduler, err := commandScheduler(cmd, interval)
+
+	if err != nil {
+		return
+	}
+
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, os.Interrupt)
+	defer signal.Stop(sigs)
+
+	state := mempoolState{
+		pending:    make(map[string]mempoolEvent),
+		spends:     make(map[string]string),
+		conflicted: make(map[string]bool),
+	}
+	enc := json.NewEncoder(os.Stdout)
+	notify(cmd, "monitoring the transaction pool for %d addresses", len(addresses))
+
+	for first, changed := true, false; ; first = false {
+		if !first {
+			select {
+			case <-time.After(scheduler.Next(changed)):
+			case <-sigs:
+				return nil
+			}
+		}
+
+		changed = false
+		var events []mempoolEvent
+		seen := make(map[string]bool)
+
+		for _, address := range addresses {
+			var txns addressTransactionsResponse
+
+			if err := callAPI(cmd, "GET", "/wallet/transactions/"+address, nil, &txns); err != nil {
+				notify(cmd, "unable to get the transactions of %s: %s", address, err)
+				continue
+			}
+
+			// the lists tell confirmed transactions apart, the height of unconfirmed ones is unset
+			for i, list := range [][]mempoolTransaction{txns.ConfirmedTransactions, txns.UnconfirmedTransactions} {
+				for _, txn := range list {
+					confirmed := i == 0
+					seen[txn.TransactionID] = true
+					event := mempoolEvent{
+						TransactionID: txn.TransactionID,
+						Address:       address,
+						Value:         txn.received(address).String(),
+					}
+
+					if _, ok := state.pending[txn.TransactionID]; ok && confirmed {
+						event.Event, event.Height = "confirmed", txn.ConfirmationHeight
+						events = append(events, event)
+						delete(state.pending, txn.TransactionID)
+					} else if _, ok := state.pending[txn.TransactionID]; !ok && !confirmed {
+						event.Event = "unconfirmed"
+						events = append(events, event)
+						state.pending[txn.TransactionID] = event
+					}
+
+					conflicts := state.spend(txn)
+					others := make([]string, 0, len(conflicts))
+
+					for other := range conflicts {
+						others = append(others, other)
+					}
+
+					sort.Strings(others)
+
+					for _, other := range others {
+						if state.conflicted[other+txn.TransactionID] {
+							continue
+						}
+
+						state.conflicted[other+txn.TransactionID] = true
+						conflict := event
+						conflict.Event, conflict.Height, conflict.ConflictsWith, conflict.Outputs = "double-spend", 0, other, conflicts[other]
+						events = append(events, conflict)
+					}
+				}
+			}
+		}
+
+		// pending transactions no longer reported were evicted, unless they confirmed since
+		ids := make([]string, 0, len(state.pending))
+
+		for id := range state.pending {
+			if !seen[id] {
+				ids = append(ids, id)
+			}
+		}
+
+		sort.Strings(ids)
+
+		for _, id := range ids {
+			var confirmed struct {
+				Confirmed bool `json:"confirmed"`
+			}
+
+			if err := callAPI(cmd, "GET", "/tpool/confirmed/"+id, nil, &confirmed); err != nil {
+				notify(cmd, "unable to check transaction %s: %s", id, err)
+				continue
+			}
+
+			event := state.pending[id]
+			event.Event = "evicted"
+
+			if confirmed.Confirmed {
+				event.Event = "confirmed"
+			}
+
+			events = append(events, event)
+			delete(state.pending, id)
+		}
+
+		for _, event := range events {
+			event.Time = time.Now().UTC()
+
+			if err = enc.Encode(event); err != nil {
+				return
+			}
+
+			switch event.Event {
+			case "double-spend":
+				notify(cmd, "warning: %s and %s spend the same outputs %s, only one can confirm", event.ConflictsWith, event.TransactionID, strings.Join(event.Outputs, ", "))
+			case "evicted":
+				notify(cmd, "warning: %s was evicted from the transaction pool without confirming", event.TransactionID)
+			}
+
+			changed = true
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path/filepath"
+)
+
+//metricsDir returns the directory metric samples are recorded in. Each series is a file of JSON
+//lines with one sample per line
+func metricsDir() string {
+	return filepath.Join(DefaultConfigDir(), "metrics")
+}
+
+//metricsPath returns the file the samples of a series are recorded in. Series are recorded per
+//API address so samples from different nodes are not mixed
+func metricsPath(series, addr string) string {
+	return filepath.Join(metricsDir(), series+"-"+safeFileName(addr)+".jsonl")
+}
+
+//safeFileName replaces the characters of s that are not safe in a file name
+func safeFileName(s string) string {
+	buf := []byte(s)
+
+	for i, c := range buf {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '-' || c == '.') {
+			buf[i] = '_'
+		}
+	}
+
+	return string(buf)
+}
+
+//appendMetric appends the sample to the series
+func appendMetric(path string, sample interface{}) (err error) {
+	if err = os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return
+	}
+
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+
+	if err != nil {
+		return
+	}
+
+	defer f.Close()
+
+	return json.NewEncoder(f).Encode(sample)
+}
+
+//readMetrics calls fn with each sample line of the series. A missing series has no samples
+func readMetrics(path string, fn func(line []byte)) (err error) {
+	f, err := os.Open(path)
+
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return
+	}
+
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+
+	for scanner.Scan() {
+		fn(scanner.Bytes())
+	}
+
+	return scanner.Err()
+}
@@ -0,0 +1,283 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/n8maninger/siac-json/endpoints"
+)
+
+type (
+	//openAPIDoc an OpenAPI 3 document of the endpoint table
+	openAPIDoc struct {
+		OpenAPI    string                                 `json:"openapi"`
+		Info       openAPIInfo                            `json:"info"`
+		Servers    []openAPIServer                        `json:"servers"`
+		Paths      map[string]map[string]openAPIOperation `json:"paths"`
+		Components openAPIComponents                      `json:"components"`
+		Security   []map[string][]string                  `json:"security"`
+	}
+
+	//openAPIInfo the info object of the document
+	openAPIInfo struct {
+		Title       string `json:"title"`
+		Description string `json:"description"`
+		Version     string `json:"version"`
+	}
+
+	//openAPIServer a server the API is reached at
+	openAPIServer struct {
+		URL string `json:"url"`
+	}
+
+	//openAPIOperation a method of a path
+	openAPIOperation struct {
+		Summary     string                     `json:"summary,omitempty"`
+		OperationID string                     `json:"operationId"`
+		Deprecated  bool                       `json:"deprecated,omitempty"`
+		Parameters  []openAPIParameter         `json:"parameters,omitempty"`
+		RequestBody *openAPIRequestBody        `json:"requestBody,omitempty"`
+		Responses   map[string]openAPIResponse `json:"responses"`
+		//AlternativePaths the other paths the endpoint is matched by on the command line
+		AlternativePaths []string `json:"x-alternative-paths,omitempty"`
+	}
+
+	//openAPIParameter a path or query parameter
+	openAPIParameter struct {
+		Name        string        `json:"name"`
+		In          string        `json:"in"`
+		Description string        `json:"description,omitempty"`
+		Required    bool          `json:"required,omitempty"`
+		Schema      openAPISchema `json:"schema"`
+	}
+
+	//openAPISchema the schema of a parameter or body. SiaFormat is the format sia-json
+	//converts friendly values from, such as 10TB or 100SC
+	openAPISchema struct {
+		Type        string                   `json:"type"`
+		Format      string                   `json:"format,omitempty"`
+		Pattern     string                   `json:"pattern,omitempty"`
+		Description string                   `json:"description,omitempty"`
+		Properties  map[string]openAPISchema `json:"properties,omitempty"`
+		Required    []string                 `json:"required,omitempty"`
+		SiaFormat   string                   `json:"x-sia-format,omitempty"`
+	}
+
+	//openAPIRequestBody the form encoded body of a POST endpoint
+	openAPIRequestBody struct {
+		Required bool                        `json:"required,omitempty"`
+		Content  map[string]openAPIMediaType `json:"content"`
+	}
+
+	//openAPIMediaType the schema of a content type
+	openAPIMediaType struct {
+		Schema openAPISchema `json:"schema"`
+	}
+
+	//openAPIResponse a response of an operation
+	openAPIResponse struct {
+		Description string                      `json:"description"`
+		Content     map[string]openAPIMediaType `json:"content,omitempty"`
+	}
+
+	//openAPIComponents the security scheme of the Sia API
+	openAPIComponents struct {
+		SecuritySchemes map[string]openAPISecurityScheme `json:"securitySchemes"`
+	}
+
+	//openAPISecurityScheme a security scheme
+	openAPISecurityScheme struct {
+		Type        string `json:"type"`
+		Scheme      string `json:"scheme"`
+		Description string `json:"description"`
+	}
+)
+
+//openAPIPathParamRe matches the :name and *name segments of a path template
+var openAPIPathParamRe = regexp.MustCompile(`/[:*]([^/]+)`)
+
+//openAPIParamSchema returns the schema of a param in the format siad expects
+func openAPIParamSchema(param endpoints.CommandParam) (schema openAPISchema) {
+	schema = openAPISchema{
+		Type:      "string",
+		SiaFormat: string(param.Formatter),
+	}
+
+	switch param.Formatter {
+	case endpoints.DataFormat:
+		schema.Type, schema.Format, schema.Description = "integer", "uint64", "bytes"
+	case endpoints.PriceFormat:
+		schema.Pattern, schema.Description = "^[0-9]+$", "hastings"
+	case endpoints.MonthlyPriceFormat:
+		schema.Pattern, schema.Description = "^[0-9]+$", "hastings per byte per block"
+	case endpoints.BlockTimeFormat:
+		schema.Type, schema.Format, schema.Description = "integer", "uint64", "blocks"
+	case endpoints.TransactionIDFormat, endpoints.ContractIDFormat, endpoints.MerkleRootFormat, endpoints.BlockIDFormat:
+		schema.Pattern = "^[0-9a-f]{64}$"
+	case endpoints.DefaultFormat:
+		switch param.Type {
+		case endpoints.IntType:
+			schema.Type, schema.Format = "integer", "uint64"
+		case endpoints.BoolType:
+			schema.Type = "boolean"
+		case endpoints.CurrencyType:
+			schema.Pattern, schema.Description = "^[0-9]+$", "hastings"
+		case endpoints.HashType:
+			schema.Pattern = "^[0-9a-f]{64}$"
+		case endpoints.AddressType:
+			schema.Pattern = "^[0-9a-f]{76}$"
+		}
+	}
+
+	return
+}
+
+//openAPIOperationID returns a unique operation id of the endpoint, such as getRenterFileSiapath
+func openAPIOperationID(endpoint endpoints.CommandEndpoint) string {
+	id := strings.ToLower(endpoint.Method)
+
+	for _, part := range strings.FieldsFunc(endpoint.Path, func(r rune) bool { return !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9') }) {
+		id += strings.ToUpper(part[:1]) + part[1:]
+	}
+
+	return id
+}
+
+//openAPIEndpoint returns the operation of the endpoint
+func openAPIEndpoint(endpoint endpoints.CommandEndpoint) (operation openAPIOperation) {
+	operation = openAPIOperation{
+		Summary:          endpoint.HelpText,
+		OperationID:      openAPIOperationID(endpoint),
+		Deprecated:       len(endpoint.DeprecatedIn) > 0,
+		AlternativePaths: endpoint.AlternativeMatches,
+		Responses: map[string]openAPIResponse{
+			"200": openAPIResponse{
+				Description: "success",
+				Content:     map[string]openAPIMediaType{"application/json": openAPIMediaType{Schema: openAPISchema{Type: "object"}}},
+			},
+			"204": openAPIResponse{Description: "success without a response body"},
+			"default": openAPIResponse{
+				Description: "error",
+				Content: map[string]openAPIMediaType{"application/json": openAPIMediaType{Schema: openAPISchema{
+					Type:       "object",
+					Properties: map[string]openAPISchema{"message": openAPISchema{Type: "string"}},
+				}}},
+			},
+		},
+	}
+
+	if endpoint.Binary {
+		operation.Responses["200"] = openAPIResponse{
+			Description: "file data",
+			Content:     map[string]openAPIMediaType{"application/octet-stream": openAPIMediaType{Schema: openAPISchema{Type: "string", Format: "binary"}}},
+		}
+	}
+
+	if operation.Deprecated {
+		operation.Summary += fmt.Sprintf(". Deprecated in %s", endpoint.DeprecatedIn)
+
+		if len(endpoint.Replacement) > 0 {
+			operation.Summary += ", use " + openAPIPathParamRe.ReplaceAllString(endpoint.Replacement, "/{$1}")
+		}
+	}
+
+	body := openAPISchema{
+		Type:       "object",
+		Properties: make(map[string]openAPISchema),
+	}
+
+	for _, param := range endpoint.Params {
+		schema := openAPIParamSchema(param)
+
+		switch param.Location {
+		case endpoints.URLParam:
+			description := param.HelpText
+
+			// a *name segment matches the rest of the path, slashes included
+			if strings.Contains(endpoint.Path, "/*"+param.Key) {
+				description += ". May contain /"
+			}
+
+			// url params are always required to match the path
+			operation.Parameters = append(operation.Parameters, openAPIParameter{
+				Name:        param.Key,
+				In:          "path",
+				Description: description,
+				Required:    true,
+				Schema:      schema,
+			})
+		case endpoints.BodyParam:
+			if len(schema.Description) > 0 {
+				schema.Description = param.HelpText + ", in " + schema.Description
+			} else {
+				schema.Description = param.HelpText
+			}
+
+			body.Properties[param.Key] = schema
+
+			if param.Required {
+				body.Required = append(body.Required, param.Key)
+			}
+		default:
+			operation.Parameters = append(operation.Parameters, openAPIParameter{
+				Name:        param.Key,
+				In:          "query",
+				Description: param.HelpText,
+				Required:    param.Required,
+				Schema:      schema,
+			})
+		}
+	}
+
+	if len(body.Properties) > 0 {
+		operation.RequestBody = &openAPIRequestBody{
+			Required: len(body.Required) > 0,
+			Content:  map[string]openAPIMediaType{"application/x-www-form-urlencoded": openAPIMediaType{Schema: body}},
+		}
+	}
+
+	return
+}
+
+//openAPICommand writes an OpenAPI 3 document generated from the endpoint table, including
+//endpoints added by --endpoints files, so client generators and other tools can use the same
+//endpoint knowledge as sia-json. The server is the --addr of the command
+func openAPICommand(cmd Command) (err error) {
+	doc := openAPIDoc{
+		OpenAPI: "3.0.3",
+		Info: openAPIInfo{
+			Title:       "Sia API",
+			Description: "Generated by sia-json from its endpoint table. Every request must send a User-Agent header containing Sia-Agent.",
+			Version:     "1.5.4",
+		},
+		Servers: []openAPIServer{
+			openAPIServer{URL: baseURL(cmd)},
+		},
+		Paths: make(map[string]map[string]openAPIOperation),
+		Components: openAPIComponents{
+			SecuritySchemes: map[string]openAPISecurityScheme{
+				"apiPassword": openAPISecurityScheme{
+					Type:        "http",
+					Scheme:      "basic",
+					Description: "the API password with an empty username",
+				},
+			},
+		},
+		Security: []map[string][]string{
+			{"apiPassword": {}},
+		},
+	}
+
+	for _, endpoint := range endpoints.All {
+		path := openAPIPathParamRe.ReplaceAllString(endpoint.Path, "/{$1}")
+
+		if doc.Paths[path] == nil {
+			doc.Paths[path] = make(map[string]openAPIOperation)
+		}
+
+		doc.Paths[path][strings.ToLower(endpoint.Method)] = openAPIEndpoint(endpoint)
+	}
+
+	return writeJSON(doc)
+}
@@ -0,0 +1,257 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/n8maninger/siac-json/format"
+)
+
+//defaultParallelWorkers the number of commands run at a time by parallel
+const defaultParallelWorkers = 4
+
+//parallelTarget returns the daemon the command line is sent to: its --profile, --addr or --ssh
+//flag, or the target of parallel's own flags
+func parallelTarget(line string) string {
+	words, _ := splitShellLine(line)
+	tokens, _ := tokenizeArgs(words)
+
+	for i := len(tokens) - 1; i >= 0; i-- {
+		switch tokens[i].Flag {
+		case "profile", "addr", "ssh":
+			return "--" + tokens[i].Flag + " " + tokens[i].Value
+		}
+	}
+
+	return ""
+}
+
+//runParallelLine runs a command line in a child process with the flags. Children are used
+//instead of running the commands in this process since commands write to stdout directly. A
+//child still running when the context is done is killed
+func runParallelLine(ctx context.Context, exe string, base []string, line string) (result batchResult) {
+	result.Command = line
+
+	words, err := splitShellLine(line)
+
+	if err != nil {
+		result.Error = err.Error()
+		result.ExitCode = ExitError
+
+		return
+	}
+
+	var stdout, stderr bytes.Buffer
+
+	c := exec.CommandContext(ctx, exe, append(append([]string{}, base...), words...)...)
+	c.Stdout = &stdout
+	c.Stderr = &stderr
+
+	err = c.Run()
+
+	if out := bytes.TrimSpace(stdout.Bytes()); json.Valid(out) {
+		result.Output = json.RawMessage(out)
+	} else if len(out) > 0 {
+		result.Output = string(out)
+	}
+
+	if ctx.Err() == context.DeadlineExceeded {
+		result.ExitCode = ExitConnectionError
+		result.Error = "timed out"
+		result.TimedOut = true
+	} else if exitErr, ok := err.(*exec.ExitError); ok {
+		result.ExitCode = exitErr.ExitCode()
+		result.Error = string(bytes.TrimSpace(stderr.Bytes()))
+	} else if err != nil {
+		result.ExitCode = ExitError
+		result.Error = err.Error()
+	}
+
+	return
+}
+
+//parallelCommand runs several commands at once and writes their responses as one JSON object
+//keyed by command: parallel "consensus" "renter contracts" --workers 4. --from loads the
+//commands from a batch file instead. The flags given to parallel apply to every command and a
+//failed command's value is its exit code and error.
+//
+//Commands are queued by the daemon they are sent to, their --profile, --addr or --ssh, and the
+//queues take turns for the workers. Each daemon runs at most --per-target commands at a time,
+//by default an equal share of the workers, so a hung daemon cannot hold every worker. A command
+//running longer than --target-timeout is killed and the daemon's remaining commands are
+//skipped, so the responses of the other daemons are still written
+func parallelCommand(cmd Command) (err error) {
+	lines := cmd.Args[1:]
+
+	if values := cmd.Params["from"]; len(values) > 0 {
+		batch, err := loadBatchFile(values[0])
+
+		if err != nil {
+			return err
+		}
+
+		lines = append(lines, batch.Commands...)
+	}
+
+	if len(lines) == 0 {
+		return fmt.Errorf("usage: parallel <command>... or parallel --from <file>")
+	}
+
+	workers := defaultParallelWorkers
+
+	if values := cmd.Params["workers"]; len(values) > 0 {
+		if workers, err = strconv.Atoi(values[0]); err != nil || workers < 1 {
+			return fmt.Errorf("invalid --workers value %q", values[0])
+		}
+	}
+
+	seen := make(map[string]bool)
+	queues := make(map[string][]int)
+	var targets []string
+
+	for i, line := range lines {
+		if seen[line] {
+			return fmt.Errorf("%q is given more than once", line)
+		}
+
+		seen[line] = true
+		target := parallelTarget(line)
+
+		if _, ok := queues[target]; !ok {
+			targets = append(targets, target)
+		}
+
+		queues[target] = append(queues[target], i)
+	}
+
+	perTarget := workers / len(targets)
+
+	if perTarget < 1 {
+		perTarget = 1
+	}
+
+	if values := cmd.Params["per-target"]; len(values) > 0 {
+		if perTarget, err = strconv.Atoi(values[0]); err != nil || perTarget < 1 {
+			return fmt.Errorf("invalid --per-target value %q", values[0])
+		}
+	}
+
+	var timeout time.Duration
+
+	if values := cmd.Params["target-timeout"]; len(values) > 0 {
+		if timeout, err = format.ParseDuration(values[0]); err != nil || timeout <= 0 {
+			return fmt.Errorf("invalid --target-timeout value %q", values[0])
+		}
+	}
+
+	exe, err := os.Executable()
+
+	if err != nil {
+		return
+	}
+
+	base := flagArgs(cmd.Flags, "workers", "from", "per-target", "target-timeout")
+	results := make([]batchResult, len(lines))
+	// goroutines blocked sending to a channel are woken in order, so the targets take turns
+	tokens := make(chan struct{}, workers)
+	var wg sync.WaitGroup
+
+	for _, target := range targets {
+		queue := make(chan int, len(queues[target]))
+
+		for _, i := range queues[target] {
+			queue <- i
+		}
+
+		close(queue)
+
+		var mu sync.Mutex
+		timedOut := false
+
+		for i := 0; i < perTarget && i < len(queues[target]); i++ {
+			wg.Add(1)
+
+			go func(target string) {
+				defer wg.Done()
+
+				for j := range queue {
+					mu.Lock()
+					skip := timedOut
+					mu.Unlock()
+
+					if skip {
+						results[j] = batchResult{Command: lines[j], ExitCode: ExitConnectionError, Error: "skipped after an earlier command to the daemon timed out", TimedOut: true}
+						continue
+					}
+
+					tokens <- struct{}{}
+
+					ctx, cancel := context.Background(), func() {}
+
+					if timeout > 0 {
+						ctx, cancel = context.WithTimeout(ctx, timeout)
+					}
+
+					results[j] = runParallelLine(ctx, exe, base, lines[j])
+					cancel()
+					<-tokens
+
+					if results[j].TimedOut {
+						mu.Lock()
+						timedOut = true
+						mu.Unlock()
+
+						notify(cmd, "%q timed out after %s, skipping the remaining commands to %s", lines[j], timeout, parallelTargetName(target))
+					}
+				}
+			}(target)
+		}
+	}
+
+	wg.Wait()
+
+	merged := make(map[string]interface{})
+	failed := 0
+
+	for _, result := range results {
+		if result.ExitCode == ExitSuccess {
+			merged[result.Command] = result.Output
+			continue
+		}
+
+		failed++
+		merged[result.Command] = batchResult{
+			ExitCode: result.ExitCode,
+			Output:   result.Output,
+			Error:    result.Error,
+			TimedOut: result.TimedOut,
+		}
+	}
+
+	if err = writeJSON(merged); err != nil {
+		return
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d of %d commands failed", failed, len(lines))
+	}
+
+	return
+}
+
+//parallelTargetName returns the target for messages
+func parallelTargetName(target string) string {
+	if len(target) == 0 {
+		return "the default daemon"
+	}
+
+	return strings.TrimPrefix(target, "--")
+}
@@ -0,0 +1,210 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+)
+
+type (
+	//pathPattern a gitignore-style pattern matched against slash separated paths relative to a
+	//local directory
+	pathPattern struct {
+		//Negate re-includes paths matched by an earlier pattern, set by a leading !
+		Negate bool
+		//DirOnly only matches directories, set by a trailing /
+		DirOnly bool
+		//Segments the glob of each path segment. ** matches any number of directories
+		Segments []string
+	}
+
+	//pathFilter the --include, --exclude and --exclude-from patterns of a command that walks a
+	//local directory
+	pathFilter struct {
+		Include []pathPattern
+		Exclude []pathPattern
+	}
+)
+
+//parsePathPattern parses a gitignore-style pattern. A pattern without a slash, other than a
+//trailing one, matches the name of a file or directory at any depth, otherwise it is matched
+//against the path relative to the directory
+func parsePathPattern(line string) (pattern pathPattern, err error) {
+	original := line
+
+	if strings.HasPrefix(line, "!") {
+		pattern.Negate = true
+		line = line[1:]
+	} else if strings.HasPrefix(line, `\!`) || strings.HasPrefix(line, `\#`) {
+		line = line[1:]
+	}
+
+	if strings.HasSuffix(line, "/") {
+		pattern.DirOnly = true
+		line = strings.TrimRight(line, "/")
+	}
+
+	if len(strings.Trim(line, "/")) == 0 {
+		return pattern, fmt.Errorf("invalid pattern %q", original)
+	}
+
+	if !strings.Contains(line, "/") {
+		line = "**/" + line
+	}
+
+	line = strings.TrimPrefix(line, "/")
+
+	pattern.Segments = strings.Split(line, "/")
+
+	for _, segment := range pattern.Segments {
+		if _, err = path.Match(segment, ""); err != nil {
+			return pattern, fmt.Errorf("invalid pattern %q: %s", original, err)
+		}
+	}
+
+	return
+}
+
+//readPatternFile reads the patterns of an --exclude-from file, one per line. Blank lines and
+//lines starting with # are skipped
+func readPatternFile(name string) (patterns []pathPattern, err error) {
+	f, err := os.Open(name)
+
+	if err != nil {
+		return
+	}
+
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), " \r")
+
+		if len(line) == 0 || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		pattern, err := parsePathPattern(line)
+
+		if err != nil {
+			return nil, fmt.Errorf("%s: %s", name, err)
+		}
+
+		patterns = append(patterns, pattern)
+	}
+
+	err = scanner.Err()
+
+	return
+}
+
+//newPathFilter parses the --include, --exclude and --exclude-from params. The patterns of
+//--exclude-from files come before the --exclude patterns, so the command line has the last word
+func newPathFilter(params map[string][]string) (filter pathFilter, err error) {
+	for _, line := range params["include"] {
+		pattern, err := parsePathPattern(line)
+
+		if err != nil {
+			return filter, err
+		}
+
+		filter.Include = append(filter.Include, pattern)
+	}
+
+	for _, name := range params["exclude-from"] {
+		patterns, err := readPatternFile(name)
+
+		if err != nil {
+			return filter, err
+		}
+
+		filter.Exclude = append(filter.Exclude, patterns...)
+	}
+
+	for _, line := range params["exclude"] {
+		pattern, err := parsePathPattern(line)
+
+		if err != nil {
+			return filter, err
+		}
+
+		filter.Exclude = append(filter.Exclude, pattern)
+	}
+
+	return
+}
+
+//matchSegments returns true if the globs match the path segments. ** matches zero or more
+//segments, or one or more at the end of a pattern so dir/** matches what is inside dir
+func matchSegments(globs, segments []string) bool {
+	for len(globs) > 0 {
+		if globs[0] == "**" {
+			if len(globs) == 1 {
+				return len(segments) > 0
+			}
+
+			for i := range segments {
+				if matchSegments(globs[1:], segments[i:]) {
+					return true
+				}
+			}
+
+			return false
+		}
+
+		if len(segments) == 0 {
+			return false
+		}
+
+		if ok, _ := path.Match(globs[0], segments[0]); !ok {
+			return false
+		}
+
+		globs, segments = globs[1:], segments[1:]
+	}
+
+	return len(segments) == 0
+}
+
+//matchPatterns returns true if the last pattern matching the path is not negated
+func matchPatterns(patterns []pathPattern, segments []string, dir bool) (matched bool) {
+	for _, pattern := range patterns {
+		if pattern.DirOnly && !dir {
+			continue
+		}
+
+		if matchSegments(pattern.Segments, segments) {
+			matched = !pattern.Negate
+		}
+	}
+
+	return
+}
+
+//matchPath returns true if the patterns match the path or one of its parent directories. As
+//with gitignore, a path below a matched directory cannot be negated
+func matchPath(patterns []pathPattern, rel string, dir bool) bool {
+	segments := strings.Split(rel, "/")
+
+	for i := 1; i < len(segments); i++ {
+		if matchPatterns(patterns, segments[:i], true) {
+			return true
+		}
+	}
+
+	return matchPatterns(patterns, segments, dir)
+}
+
+//Excluded returns true if the slash separated path relative to the walked directory is skipped.
+//Excluded directories are not walked. Files are skipped if they are excluded, or do not match
+//an --include pattern when there are any
+func (filter pathFilter) Excluded(rel string, dir bool) bool {
+	if matchPath(filter.Exclude, rel, dir) {
+		return true
+	}
+
+	return !dir && len(filter.Include) > 0 && !matchPath(filter.Include, rel, false)
+}
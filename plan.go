@@ -0,0 +1,313 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+)
+
+type (
+	//planTarget the state a mutating endpoint changes: the GET endpoint returning it and where
+	//each param is found in its response
+	planTarget struct {
+		State string
+		//Prefix the field params are found below, unless they are listed in Fields
+		Prefix string
+		Fields map[string]string
+	}
+
+	//planChange a field --plan reports would be changed
+	planChange struct {
+		Action  string      `json:"action"`
+		Field   string      `json:"field"`
+		Current interface{} `json:"current,omitempty"`
+		Desired interface{} `json:"desired,omitempty"`
+	}
+
+	//planReport the --plan output of machine mode
+	planReport struct {
+		Request string       `json:"request"`
+		Changes []planChange `json:"changes"`
+	}
+)
+
+//planTargets the endpoints --plan can diff, by method and path
+var planTargets = map[string]planTarget{
+	"POST /renter": planTarget{
+		State:  "/renter",
+		Prefix: "settings.allowance",
+		Fields: map[string]string{
+			"maxdownloadspeed":    "settings.maxdownloadspeed",
+			"maxuploadspeed":      "settings.maxuploadspeed",
+			"checkforipviolation": "settings.ipviolationcheck",
+		},
+	},
+	"POST /host": planTarget{
+		State:  "/host",
+		Prefix: "internalsettings",
+	},
+	"POST /hostdb/filtermode": planTarget{
+		State: "/hostdb/filtermode",
+	},
+	"POST /daemon/settings": planTarget{
+		State: "/daemon/settings",
+	},
+	"POST /gateway": planTarget{
+		State: "/gateway",
+	},
+}
+
+//copyJSON returns a deep copy of a decoded JSON value
+func copyJSON(v interface{}) interface{} {
+	switch t := v.(type) {
+	case map[string]interface{}:
+		c := make(map[string]interface{}, len(t))
+
+		for key, value := range t {
+			c[key] = copyJSON(value)
+		}
+
+		return c
+	case []interface{}:
+		c := make([]interface{}, len(t))
+
+		for i, value := range t {
+			c[i] = copyJSON(value)
+		}
+
+		return c
+	}
+
+	return v
+}
+
+//planValue converts a param to the JSON type of the current value, so "true" and true or "50"
+//and 50 compare equal
+func planValue(values []string, current interface{}) interface{} {
+	if _, ok := current.([]interface{}); ok {
+		arr := make([]interface{}, len(values))
+
+		for i, value := range values {
+			arr[i] = value
+		}
+
+		return arr
+	}
+
+	value := values[0]
+
+	switch current.(type) {
+	case bool:
+		if b, err := strconv.ParseBool(value); err == nil {
+			return b
+		}
+	case float64:
+		if f, err := strconv.ParseFloat(value, 64); err == nil {
+			return f
+		}
+	}
+
+	return value
+}
+
+//setField sets the dotted field of the object, creating the objects above it
+func setField(obj map[string]interface{}, field string, value interface{}) {
+	parts := strings.Split(field, ".")
+
+	for _, part := range parts[:len(parts)-1] {
+		next, ok := obj[part].(map[string]interface{})
+
+		if !ok {
+			next = make(map[string]interface{})
+			obj[part] = next
+		}
+
+		obj = next
+	}
+
+	obj[parts[len(parts)-1]] = value
+}
+
+//planChanges returns the changes between the current and desired state using the same diff as
+//--watch --changes, with the current value of each changed field
+func planChanges(current, desired interface{}) (changes []planChange) {
+	changes = []planChange{}
+
+	for _, op := range diffJSON(current, desired, "") {
+		var path []string
+
+		for _, seg := range strings.Split(strings.TrimPrefix(op.Path, "/"), "/") {
+			path = append(path, strings.NewReplacer("~1", "/", "~0", "~").Replace(seg))
+		}
+
+		change := planChange{
+			Field:   strings.Join(path, "."),
+			Desired: op.Value,
+		}
+
+		switch op.Op {
+		case "add":
+			change.Action = "add"
+		case "remove":
+			change.Action = "remove"
+			change.Current, _ = walkField(current, path)
+		default:
+			change.Action = "change"
+			change.Current, _ = walkField(current, path)
+		}
+
+		changes = append(changes, change)
+	}
+
+	return
+}
+
+//planJSON returns the value as compact JSON
+func planJSON(v interface{}) string {
+	buf, _ := json.Marshal(v)
+	return string(buf)
+}
+
+//writePlan writes the changes the request would make, as a diff colored when stdout is a
+//terminal, followed by a summary. Machine mode writes a planReport instead
+func writePlan(cmd Command, request string, current, desired interface{}) error {
+	changes := planChanges(current, desired)
+
+	if cmd.Machine {
+		return writeJSON(planReport{Request: request, Changes: changes})
+	}
+
+	color := isTerminal(os.Stdout) && len(os.Getenv("NO_COLOR")) == 0
+	counts := make(map[string]int)
+	var sb strings.Builder
+
+	fmt.Fprintf(&sb, "%s on %s\n", request, cmd.APIAddress)
+
+	for _, change := range changes {
+		var line, code string
+
+		switch change.Action {
+		case "add":
+			line, code = fmt.Sprintf("+ %s: %s", change.Field, planJSON(change.Desired)), "32"
+		case "remove":
+			line, code = fmt.Sprintf("- %s: %s", change.Field, planJSON(change.Current)), "31"
+		default:
+			line, code = fmt.Sprintf("~ %s: %s -> %s", change.Field, planJSON(change.Current), planJSON(change.Desired)), "33"
+		}
+
+		if color {
+			line = "\033[" + code + "m" + line + "\033[0m"
+		}
+
+		counts[change.Action]++
+		sb.WriteString("  " + line + "\n")
+	}
+
+	if len(changes) == 0 {
+		sb.WriteString("No changes.\n")
+	} else {
+		fmt.Fprintf(&sb, "Plan: %d to change, %d to add, %d to remove. Nothing was changed.\n", counts["change"], counts["add"], counts["remove"])
+	}
+
+	_, err := os.Stdout.WriteString(sb.String())
+
+	return err
+}
+
+//planParams writes the plan of sending the params to a mutating endpoint with a plan target
+func planParams(cmd Command, method, path string, params url.Values) (err error) {
+	request := method + " " + path
+	target, ok := planTargets[request]
+
+	if !ok {
+		return fmt.Errorf("--plan is not supported for %s", request)
+	}
+
+	var current interface{}
+
+	if err = callAPI(cmd, "GET", target.State, nil, &current); err != nil {
+		return
+	}
+
+	state, ok := current.(map[string]interface{})
+
+	if !ok {
+		return fmt.Errorf("unexpected response from %s", target.State)
+	}
+
+	desired := copyJSON(state).(map[string]interface{})
+	for key, values := range params {
+		if len(values) == 0 {
+			continue
+		}
+
+		field, ok := target.Fields[key]
+
+		if !ok && len(target.Prefix) > 0 {
+			field = target.Prefix + "." + key
+		} else if !ok {
+			field = key
+		}
+
+		value, _ := walkField(state, strings.Split(field, "."))
+		setField(desired, field, planValue(values, value))
+	}
+
+	return writePlan(cmd, request, state, desired)
+}
+
+//planFolders writes the plan of adding, removing or resizing a host storage folder
+func planFolders(cmd Command) (err error) {
+	var storage struct {
+		Folders []struct {
+			Path     string `json:"path"`
+			Capacity uint64 `json:"capacity"`
+		} `json:"folders"`
+	}
+
+	if err = callAPI(cmd, "GET", "/host/storage", nil, &storage); err != nil {
+		return
+	}
+
+	current := make(map[string]interface{})
+
+	for _, folder := range storage.Folders {
+		current[folder.Path] = map[string]interface{}{"capacity": float64(folder.Capacity)}
+	}
+
+	desired := copyJSON(current).(map[string]interface{})
+	params := url.Values(cmd.Params)
+	folder := params.Get("path")
+
+	switch cmd.Endpoint.Path {
+	case "/host/storage/folders/add":
+		desired[folder] = map[string]interface{}{"capacity": planValue([]string{params.Get("size")}, float64(0))}
+	case "/host/storage/folders/remove":
+		if _, ok := desired[folder]; !ok {
+			return fmt.Errorf("%s is not a storage folder of the host", folder)
+		}
+
+		delete(desired, folder)
+	case "/host/storage/folders/resize":
+		if _, ok := desired[folder]; !ok {
+			return fmt.Errorf("%s is not a storage folder of the host", folder)
+		}
+
+		desired[folder] = map[string]interface{}{"capacity": planValue([]string{params.Get("newsize")}, float64(0))}
+	}
+
+	return writePlan(cmd, cmd.Method+" "+cmd.Endpoint.Path, map[string]interface{}{"folders": current}, map[string]interface{}{"folders": desired})
+}
+
+//planCommand writes the changes the command's request would make to the state of siad instead
+//of sending it: --plan
+func planCommand(cmd Command) error {
+	if strings.HasPrefix(cmd.Endpoint.Path, "/host/storage/folders/") && cmd.Method == "POST" {
+		return planFolders(cmd)
+	}
+
+	return planParams(cmd, cmd.Method, cmd.Endpoint.Path, cmd.Params)
+}
@@ -0,0 +1,262 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/n8maninger/siac-json/endpoints"
+)
+
+type (
+	//pluginInfo a plugin listed by plugins
+	pluginInfo struct {
+		Name      string   `json:"name"`
+		Kind      string   `json:"kind"`
+		Path      string   `json:"path"`
+		Endpoints []string `json:"endpoints,omitempty"`
+	}
+)
+
+const (
+	//pluginPrefix the prefix of command plugin executables: sia-json-<name>
+	pluginPrefix = "sia-json-"
+
+	//formatPluginPrefix the prefix of output format plugin executables: sia-json-format-<name>
+	formatPluginPrefix = "sia-json-format-"
+
+	//pluginEndpointsFlag the flag a command plugin is run with to describe the siad endpoints it
+	//adds
+	pluginEndpointsFlag = "--sia-json-endpoints"
+
+	//pluginEndpointsTimeout how long a plugin has to describe its endpoints
+	pluginEndpointsTimeout = 5 * time.Second
+)
+
+//pluginEndpointsLoaded set once the endpoints of the plugins have been added to endpoints.All
+var pluginEndpointsLoaded bool
+
+//findPlugins returns the plugin executables on PATH by name. Format plugins are named
+//format-<name>. The first executable on PATH with a name is used, like a shell would
+func findPlugins() (plugins map[string]string) {
+	plugins = make(map[string]string)
+
+	for _, dir := range filepath.SplitList(os.Getenv("PATH")) {
+		entries, err := ioutil.ReadDir(dir)
+
+		if err != nil {
+			continue
+		}
+
+		for _, entry := range entries {
+			name := strings.TrimSuffix(entry.Name(), filepath.Ext(entry.Name()))
+
+			if !strings.HasPrefix(name, pluginPrefix) || entry.IsDir() || entry.Mode()&0111 == 0 {
+				continue
+			}
+
+			if name = strings.TrimPrefix(name, pluginPrefix); len(name) == 0 {
+				continue
+			}
+
+			if _, exists := plugins[name]; !exists {
+				plugins[name] = filepath.Join(dir, entry.Name())
+			}
+		}
+	}
+
+	return
+}
+
+//lookupPlugin returns the path of the plugin executable with the prefix and name
+func lookupPlugin(prefix, name string) (string, bool) {
+	if !profileNameRe.MatchString(name) {
+		return "", false
+	}
+
+	path, err := exec.LookPath(prefix + name)
+
+	return path, err == nil
+}
+
+//describePluginEndpoints runs the plugin with --sia-json-endpoints and returns the endpoints it
+//prints as a JSON array. Plugins that do not add endpoints print nothing or exit with an error
+func describePluginEndpoints(path string) (added []endpoints.CommandEndpoint, err error) {
+	ctx, cancel := context.WithTimeout(context.Background(), pluginEndpointsTimeout)
+	defer cancel()
+
+	out, err := exec.CommandContext(ctx, path, pluginEndpointsFlag).Output()
+
+	if err != nil || len(bytes.TrimSpace(out)) == 0 {
+		return nil, nil
+	}
+
+	if added, err = decodeEndpoints(out); err != nil {
+		return nil, fmt.Errorf("%s: invalid endpoints: %s", filepath.Base(path), err)
+	}
+
+	return
+}
+
+//loadPluginEndpoints adds the endpoints described by the command plugins to endpoints.All.
+//Plugins are only run once a request does not match a built-in endpoint
+func loadPluginEndpoints() (err error) {
+	if pluginEndpointsLoaded {
+		return
+	}
+
+	pluginEndpointsLoaded = true
+	plugins := findPlugins()
+	names := make([]string, 0, len(plugins))
+
+	for name := range plugins {
+		if !strings.HasPrefix(name, "format-") {
+			names = append(names, name)
+		}
+	}
+
+	sort.Strings(names)
+
+	for _, name := range names {
+		added, err := describePluginEndpoints(plugins[name])
+
+		if err != nil {
+			return err
+		}
+
+		mergeEndpoints(added)
+	}
+
+	return
+}
+
+//pluginCommand returns the function running the command plugin named by the first word of the
+//command, if the command does not match an endpoint: sia-json <name> [args] runs
+//sia-json-<name> [args]
+func pluginCommand(cmd Command) (send func(Command) error, ok bool) {
+	if len(cmd.Args) == 0 || len(matchEndpoints(cmd)) > 0 {
+		return
+	}
+
+	name := strings.ToLower(cmd.Args[0])
+
+	if strings.HasPrefix(name, "format-") {
+		return
+	}
+
+	path, ok := lookupPlugin(pluginPrefix, name)
+
+	if !ok {
+		return
+	}
+
+	// an endpoint added by a plugin takes precedence over running it
+	if loadPluginEndpoints() == nil && len(matchEndpoints(cmd)) > 0 {
+		return nil, false
+	}
+
+	return func(cmd Command) error {
+		return runPlugin(cmd, path)
+	}, true
+}
+
+//pluginEnv returns the environment of a plugin, with the connection settings of the command
+func pluginEnv(cmd Command) []string {
+	return append(os.Environ(),
+		"SIA_API_ADDR="+cmd.APIAddress,
+		"SIA_API_PASSWORD="+cmd.APIPassword,
+		"SIA_JSON_USER_AGENT="+cmd.UserAgent,
+		"SIA_JSON_FORMAT="+cmd.Format,
+	)
+}
+
+//runPlugin runs the command plugin with the command's remaining words and flags. The plugin's
+//exit code is the command's
+func runPlugin(cmd Command, path string) error {
+	c := exec.Command(path, append(append([]string{}, cmd.Args[1:]...), cmd.Flags...)...)
+	c.Stdin = os.Stdin
+	c.Stdout = os.Stdout
+	c.Stderr = os.Stderr
+	c.Env = pluginEnv(cmd)
+
+	err := c.Run()
+
+	if exitErr, ok := err.(*exec.ExitError); ok {
+		return ExitCodeError{Code: exitErr.ExitCode(), Err: reportedError{err}}
+	}
+
+	return err
+}
+
+//formatPlugin returns the path of the output format plugin for --format
+func formatPlugin(format string) (string, bool) {
+	if format == RawFormat || format == PrettyFormat {
+		return "", false
+	}
+
+	return lookupPlugin(formatPluginPrefix, format)
+}
+
+//writeFormatted writes the response body transformed by the format plugin, which reads the
+//body from stdin. SIA_JSON_ENDPOINT tells it which endpoint the response is from
+func writeFormatted(cmd Command, path string, w io.Writer, body io.Reader) error {
+	c := exec.Command(path)
+	c.Stdin = body
+	c.Stdout = w
+	c.Stderr = os.Stderr
+	c.Env = append(pluginEnv(cmd), "SIA_JSON_ENDPOINT="+cmd.Endpoint.Path)
+
+	if err := c.Run(); err != nil {
+		return fmt.Errorf("format %s: %s", cmd.Format, err)
+	}
+
+	return nil
+}
+
+//pluginsCommand lists the command and format plugins found on PATH and the endpoints they add
+func pluginsCommand(cmd Command) (err error) {
+	plugins := findPlugins()
+	names := make([]string, 0, len(plugins))
+
+	for name := range plugins {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	infos := []pluginInfo{}
+
+	for _, name := range names {
+		info := pluginInfo{
+			Name: name,
+			Kind: "command",
+			Path: plugins[name],
+		}
+
+		if strings.HasPrefix(name, "format-") {
+			info.Name, info.Kind = strings.TrimPrefix(name, "format-"), "format"
+		} else {
+			added, err := describePluginEndpoints(info.Path)
+
+			if err != nil {
+				return err
+			}
+
+			for _, endpoint := range added {
+				info.Endpoints = append(info.Endpoints, endpoint.Method+" "+endpoint.Path)
+			}
+		}
+
+		infos = append(infos, info)
+	}
+
+	return writeJSON(infos)
+}
@@ -0,0 +1,260 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/n8maninger/siac-json/client"
+)
+
+type (
+	//profileSummary a profile as listed by profiles list. Passwords are never printed
+	profileSummary struct {
+		Name            string   `json:"name"`
+		Address         string   `json:"address,omitempty"`
+		Addresses       []string `json:"addresses,omitempty"`
+		UserAgent       string   `json:"useragent,omitempty"`
+		APIPasswordFile string   `json:"apipasswordfile,omitempty"`
+		HasPassword     bool     `json:"haspassword"`
+	}
+)
+
+var (
+	//profileNameRe profile names must be valid bare TOML keys
+	profileNameRe = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+	//profileKeys the params accepted by profiles add and the config key each is written to
+	profileKeys = []struct {
+		Param string
+		Key   string
+	}{
+		{"address", "address"},
+		{"user-agent", "user_agent"},
+		{"api-password", "api_password"},
+		{"api-password-file", "api_password_file"},
+	}
+)
+
+//profileArg returns the value of the --profile flag, if any. Profiles are applied before other
+//flags so that flags always take precedence regardless of order
+func profileArg(args []string) string {
+	// invalid args are reported when the command is parsed
+	tokens, _ := tokenizeArgs(args)
+
+	for _, token := range tokens {
+		if token.Flag == "profile" {
+			return token.Value
+		}
+	}
+
+	return ""
+}
+
+//applyProfile applies the named profile's settings to the command
+func applyProfile(cmd *Command, name string) error {
+	profile, ok := DefaultConfig.Profiles[name]
+
+	if !ok {
+		return fmt.Errorf("unknown profile %q", name)
+	}
+
+	if len(profile.Address) > 0 {
+		cmd.APIAddress, cmd.Addresses = profile.Address, nil
+	}
+
+	if len(profile.Addresses) > 0 {
+		cmd.Addresses = profile.Addresses
+
+		if len(profile.Address) == 0 {
+			cmd.APIAddress = profile.Addresses[0]
+		}
+	}
+
+	if len(profile.UserAgent) > 0 {
+		cmd.UserAgent = profile.UserAgent
+	}
+
+	if err := applyHeaders(cmd, profile.Headers); err != nil {
+		return err
+	}
+
+	if len(profile.Format) > 0 {
+		cmd.Format = profile.Format
+	}
+
+	if profile.Timeout > 0 {
+		cmd.Timeout = profile.Timeout
+	}
+
+	if profile.Cache > 0 {
+		cmd.Cache = profile.Cache
+	}
+
+	applyTLSConfig(cmd, profile)
+
+	// SIA_API_PASSWORD still overrides the profile's password
+	if len(os.Getenv("SIA_API_PASSWORD")) > 0 {
+		return nil
+	}
+
+	password, err := profile.Password()
+
+	if err != nil {
+		return fmt.Errorf("profile %s: %s", name, err)
+	}
+
+	if len(password) > 0 {
+		cmd.APIPassword = password
+	}
+
+	return nil
+}
+
+//readConfigLines reads the config file as lines. A missing file returns no lines
+func readConfigLines() (lines []string, err error) {
+	buf, err := ioutil.ReadFile(ConfigPath())
+
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return
+	}
+
+	return strings.Split(strings.TrimRight(string(buf), "\n"), "\n"), nil
+}
+
+//writeConfigLines replaces the config file with the lines
+func writeConfigLines(lines []string) error {
+	if err := os.MkdirAll(filepath.Dir(ConfigPath()), 0700); err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(ConfigPath(), []byte(strings.Join(lines, "\n")+"\n"), 0600)
+}
+
+//removeConfigTable removes the table's header and keys from the config lines. Comments and
+//other tables are left untouched
+func removeConfigTable(lines []string, header string) (kept []string, found bool) {
+	inTable := false
+
+	for _, line := range lines {
+		trimmed := strings.TrimSpace(line)
+
+		if strings.HasPrefix(trimmed, "[") {
+			inTable = trimmed == header
+			found = found || inTable
+		}
+
+		if !inTable {
+			kept = append(kept, line)
+		}
+	}
+
+	for len(kept) > 0 && len(strings.TrimSpace(kept[len(kept)-1])) == 0 {
+		kept = kept[:len(kept)-1]
+	}
+
+	return
+}
+
+//profilesListCommand lists the configured profiles
+func profilesListCommand(cmd Command) error {
+	names := make([]string, 0, len(DefaultConfig.Profiles))
+
+	for name := range DefaultConfig.Profiles {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	summaries := []profileSummary{}
+
+	for _, name := range names {
+		profile := DefaultConfig.Profiles[name]
+		summaries = append(summaries, profileSummary{
+			Name:            name,
+			Address:         profile.Address,
+			Addresses:       profile.Addresses,
+			UserAgent:       profile.UserAgent,
+			APIPasswordFile: profile.APIPasswordFile,
+			HasPassword:     len(profile.APIPassword) > 0,
+		})
+	}
+
+	return writeJSON(summaries)
+}
+
+//profilesAddCommand adds a profile to the config file: profiles add <name> --address host:port
+func profilesAddCommand(cmd Command) (err error) {
+	name := cmd.Args[2]
+
+	if !profileNameRe.MatchString(name) {
+		return fmt.Errorf("invalid profile name %q, use letters, numbers, - and _", name)
+	}
+
+	if _, exists := DefaultConfig.Profiles[name]; exists {
+		return fmt.Errorf("profile %s already exists", name)
+	}
+
+	if values := cmd.Params["address"]; len(values) > 0 && !isDiscoveryAddress(values[0]) {
+		if _, err = client.ParseAddress(values[0]); err != nil {
+			return
+		}
+	}
+
+	lines, err := readConfigLines()
+
+	if err != nil {
+		return
+	}
+
+	if len(lines) > 0 {
+		lines = append(lines, "")
+	}
+
+	lines = append(lines, "[profiles."+name+"]")
+
+	for _, key := range profileKeys {
+		if values := cmd.Params[key.Param]; len(values) > 0 {
+			lines = append(lines, key.Key+" = "+strconv.Quote(values[0]))
+		}
+	}
+
+	if err = writeConfigLines(lines); err != nil {
+		return
+	}
+
+	fmt.Printf("added profile %s\n", name)
+
+	return
+}
+
+//profilesRemoveCommand removes a profile from the config file: profiles remove <name>
+func profilesRemoveCommand(cmd Command) (err error) {
+	name := cmd.Args[2]
+	lines, err := readConfigLines()
+
+	if err != nil {
+		return
+	}
+
+	lines, found := removeConfigTable(lines, "[profiles."+name+"]")
+
+	if !found {
+		return fmt.Errorf("unknown profile %q", name)
+	}
+
+	if err = writeConfigLines(lines); err != nil {
+		return
+	}
+
+	fmt.Printf("removed profile %s\n", name)
+
+	return
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/n8maninger/siac-json/format"
+)
+
+type (
+	//progressReader writes a progress bar to stderr as the underlying reader is consumed
+	progressReader struct {
+		reader  io.Reader
+		label   string
+		total   int64
+		read    int64
+		start   time.Time
+		lastOut time.Time
+	}
+)
+
+//progressInterval how often the progress bar is redrawn
+const progressInterval = 250 * time.Millisecond
+
+//withProgress wraps the reader with a progress bar unless the command is quiet or stderr is not a terminal.
+//total may be -1 if the size is unknown
+func withProgress(cmd Command, r io.Reader, total int64, label string) io.Reader {
+	if cmd.Quiet || !isTerminal(os.Stderr) {
+		return r
+	}
+
+	return &progressReader{
+		reader: r,
+		label:  label,
+		total:  total,
+		start:  time.Now(),
+	}
+}
+
+func (p *progressReader) Read(b []byte) (n int, err error) {
+	n, err = p.reader.Read(b)
+	p.read += int64(n)
+
+	if err == io.EOF {
+		p.render()
+		os.Stderr.WriteString("\n")
+	} else if time.Since(p.lastOut) >= progressInterval {
+		p.render()
+	}
+
+	return
+}
+
+func (p *progressReader) render() {
+	p.lastOut = time.Now()
+	elapsed := time.Since(p.start).Seconds()
+	rate := float64(0)
+
+	if elapsed > 0 {
+		rate = float64(p.read) / elapsed
+	}
+
+	line := fmt.Sprintf("%s %s %s/s", p.label, format.FormatBytes(p.read), format.FormatBytes(int64(rate)))
+
+	if p.total > 0 {
+		const width = 30
+		done := int(float64(width) * float64(p.read) / float64(p.total))
+
+		if done > width {
+			done = width
+		}
+
+		eta := "--"
+
+		if rate > 0 && p.read < p.total {
+			eta = (time.Duration(float64(p.total-p.read)/rate) * time.Second).String()
+		} else if p.read >= p.total {
+			eta = "0s"
+		}
+
+		line = fmt.Sprintf("%s [%s%s] %3d%% %s/%s %s/s ETA %s", p.label, strings.Repeat("#", done), strings.Repeat(".", width-done),
+			p.read*100/p.total, format.FormatBytes(p.read), format.FormatBytes(p.total), format.FormatBytes(int64(rate)), eta)
+	}
+
+	// pad to clear any leftover characters from a longer previous line
+	os.Stderr.WriteString("\r" + line + "   ")
+}
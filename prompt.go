@@ -0,0 +1,113 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+//stdinReader a shared reader so buffered input is not lost between prompts
+var stdinReader = bufio.NewReader(os.Stdin)
+
+//readLine prints the prompt to stderr and reads a line from stdin
+func readLine(prompt string) (string, error) {
+	os.Stderr.WriteString(prompt)
+
+	line, err := stdinReader.ReadString('\n')
+
+	if err != nil && len(line) == 0 {
+		return "", err
+	}
+
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+//readPassword prints the prompt to stderr and reads a line from stdin with terminal echo turned
+//off so the password is not shown
+func readPassword(prompt string) (string, error) {
+	if runtime.GOOS != "windows" && isTerminal(os.Stdin) {
+		stty := exec.Command("stty", "-echo")
+		stty.Stdin = os.Stdin
+
+		if err := stty.Run(); err == nil {
+			defer func() {
+				restore := exec.Command("stty", "echo")
+				restore.Stdin = os.Stdin
+				restore.Run()
+				os.Stderr.WriteString("\n")
+			}()
+		}
+	}
+
+	return readLine(prompt)
+}
+
+//promptPassword prompts for a password. Without a terminal to prompt on --password-file must be used
+func promptPassword(cmd Command, prompt string) (string, error) {
+	if cmd.Machine {
+		return "", fmt.Errorf("%s: prompts are disabled in machine mode, use --password-file", prompt)
+	}
+
+	if !isTerminal(os.Stdin) {
+		return "", fmt.Errorf("%s: stdin is not a terminal, use --password-file", prompt)
+	}
+
+	return readPassword(prompt + ": ")
+}
+
+//promptNewPassword prompts for a new password twice and checks the entries match
+func promptNewPassword(cmd Command, prompt string) (string, error) {
+	for {
+		password, err := promptPassword(cmd, prompt)
+
+		if err != nil {
+			return "", err
+		}
+
+		if len(password) == 0 {
+			os.Stderr.WriteString("the password cannot be empty\n")
+			continue
+		}
+
+		again, err := readPassword("Confirm " + strings.ToLower(prompt[:1]) + prompt[1:] + ": ")
+
+		if err != nil {
+			return "", err
+		}
+
+		if password == again {
+			return password, nil
+		}
+
+		os.Stderr.WriteString("the passwords do not match\n")
+	}
+}
+
+//confirm asks the user to confirm an action. --yes skips the prompt. Without a terminal to
+//prompt on the action is refused
+func confirm(cmd Command, prompt string) (bool, error) {
+	if _, ok := cmd.Params["yes"]; ok {
+		return true, nil
+	}
+
+	if cmd.Machine {
+		return false, fmt.Errorf("%s: prompts are disabled in machine mode, use --yes to confirm", prompt)
+	}
+
+	if !isTerminal(os.Stdin) {
+		return false, fmt.Errorf("%s: stdin is not a terminal, use --yes to confirm", prompt)
+	}
+
+	answer, err := readLine(prompt + " [y/N] ")
+
+	if err != nil {
+		return false, err
+	}
+
+	answer = strings.ToLower(strings.TrimSpace(answer))
+
+	return answer == "y" || answer == "yes", nil
+}
@@ -0,0 +1,384 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+type (
+	//Recipe a named sequence of API calls defined in the config file and run with sia-json run
+	Recipe struct {
+		Description string
+		//Args the names of the positional arguments the recipe takes
+		Args []string
+		//Steps the calls made in order
+		Steps []RecipeStep
+		//Output maps output field names to templates. If empty the last step's response is written
+		Output map[string]string
+	}
+
+	//RecipeStep one API call in a recipe. The path, method and params may reference the recipe's
+	//args and earlier steps' responses as {{name}} or {{step.field}}
+	RecipeStep struct {
+		Name   string
+		Path   string
+		Method string
+		//Params key=value pairs sent with the request
+		Params []string
+	}
+)
+
+//run is registered at init because batch files run their commands through BuiltinCommands
+func init() {
+	BuiltinCommands = append(BuiltinCommands, BuiltinCommand{
+		Path:     "/run/*recipe",
+		HelpText: "runs a recipe of API calls defined in the config file with the remaining args, or the commands of a batch file: run <file>",
+		Run:      runRecipeCommand,
+	})
+}
+
+//recipeTemplateRe matches a {{reference}} in a recipe template
+var recipeTemplateRe = regexp.MustCompile(`\{\{\s*([\w.-]+)\s*\}\}`)
+
+//configStrings returns the string array value of the key or an error if it is not an array of
+//strings
+func configStrings(table map[string]interface{}, key string) (values []string, err error) {
+	v, ok := table[key]
+
+	if !ok {
+		return
+	}
+
+	arr, ok := v.([]interface{})
+
+	if !ok {
+		return nil, fmt.Errorf("%s must be an array of strings", key)
+	}
+
+	for _, elem := range arr {
+		str, ok := elem.(string)
+
+		if !ok {
+			return nil, fmt.Errorf("%s must be an array of strings", key)
+		}
+
+		values = append(values, str)
+	}
+
+	return
+}
+
+//parseRecipes reads the [recipes.<name>] tables of the config file
+func parseRecipes(table map[string]interface{}) (recipes map[string]Recipe, err error) {
+	recipeTables, ok := table["recipes"].(map[string]interface{})
+
+	if !ok {
+		return
+	}
+
+	recipes = make(map[string]Recipe)
+
+	for name, v := range recipeTables {
+		recipeTable, ok := v.(map[string]interface{})
+
+		if !ok {
+			return nil, fmt.Errorf("recipe %s must be a table", name)
+		}
+
+		var recipe Recipe
+
+		if recipe.Description, err = configString(recipeTable, "description"); err != nil {
+			return nil, fmt.Errorf("recipe %s: %s", name, err)
+		}
+
+		if recipe.Args, err = configStrings(recipeTable, "args"); err != nil {
+			return nil, fmt.Errorf("recipe %s: %s", name, err)
+		}
+
+		steps, _ := recipeTable["steps"].([]map[string]interface{})
+
+		if len(steps) == 0 {
+			return nil, fmt.Errorf("recipe %s has no [[recipes.%s.steps]]", name, name)
+		}
+
+		for i, stepTable := range steps {
+			step := RecipeStep{Name: fmt.Sprintf("step%d", i+1)}
+
+			if stepName, err := configString(stepTable, "name"); err != nil {
+				return nil, fmt.Errorf("recipe %s step %d: %s", name, i+1, err)
+			} else if len(stepName) > 0 {
+				step.Name = stepName
+			}
+
+			if step.Path, err = configString(stepTable, "path"); err != nil {
+				return nil, fmt.Errorf("recipe %s step %d: %s", name, i+1, err)
+			} else if len(step.Path) == 0 {
+				return nil, fmt.Errorf("recipe %s step %d: path is required", name, i+1)
+			}
+
+			if step.Method, err = configString(stepTable, "method"); err != nil {
+				return nil, fmt.Errorf("recipe %s step %d: %s", name, i+1, err)
+			}
+
+			if step.Params, err = configStrings(stepTable, "params"); err != nil {
+				return nil, fmt.Errorf("recipe %s step %d: %s", name, i+1, err)
+			}
+
+			recipe.Steps = append(recipe.Steps, step)
+		}
+
+		if output, ok := recipeTable["output"].(map[string]interface{}); ok {
+			recipe.Output = make(map[string]string)
+
+			for key := range output {
+				if recipe.Output[key], err = configString(output, key); err != nil {
+					return nil, fmt.Errorf("recipe %s output: %s", name, err)
+				}
+			}
+		}
+
+		recipes[name] = recipe
+	}
+
+	return
+}
+
+//lookupRecipeValue returns the value of a template reference: a recipe arg or a field of an
+//earlier step's response
+func lookupRecipeValue(ref string, args map[string]string, results map[string]interface{}) (interface{}, error) {
+	if v, ok := args[ref]; ok {
+		return v, nil
+	}
+
+	path := strings.Split(ref, ".")
+	result, ok := results[path[0]]
+
+	if !ok {
+		return nil, fmt.Errorf("unknown reference {{%s}}", ref)
+	}
+
+	v, ok := walkField(result, path[1:])
+
+	if !ok {
+		return nil, fmt.Errorf("%s not found in the response of step %s", strings.Join(path[1:], "."), path[0])
+	}
+
+	return v, nil
+}
+
+//renderRecipeTemplate replaces the references in the template. A template that is a single
+//reference returns the referenced value unchanged so output fields keep their JSON type
+func renderRecipeTemplate(template string, args map[string]string, results map[string]interface{}) (interface{}, error) {
+	if m := recipeTemplateRe.FindStringSubmatch(template); m != nil && m[0] == strings.TrimSpace(template) {
+		return lookupRecipeValue(m[1], args, results)
+	}
+
+	var err error
+
+	rendered := recipeTemplateRe.ReplaceAllStringFunc(template, func(match string) string {
+		v, lookupErr := lookupRecipeValue(recipeTemplateRe.FindStringSubmatch(match)[1], args, results)
+
+		if lookupErr != nil {
+			err = lookupErr
+			return ""
+		}
+
+		if str, ok := v.(string); ok {
+			return str
+		}
+
+		buf, _ := json.Marshal(v)
+
+		return string(buf)
+	})
+
+	return rendered, err
+}
+
+//renderRecipeString renders the template as a string
+func renderRecipeString(template string, args map[string]string, results map[string]interface{}) (string, error) {
+	v, err := renderRecipeTemplate(template, args, results)
+
+	if err != nil {
+		return "", err
+	}
+
+	if str, ok := v.(string); ok {
+		return str, nil
+	}
+
+	buf, err := json.Marshal(v)
+
+	return string(buf), err
+}
+
+//isPathSeparator returns true for the runes separating the segments of a recipe step's path,
+//which is written the same way as on the command line
+func isPathSeparator(r rune) bool {
+	return r == ' ' || r == '/'
+}
+
+//checkPathArgs returns an error if an arg referenced in the path template could change more than
+//the path of the request: a ? or # starting a query or fragment, a % escape or a .. segment. Args
+//may come from hooks serve callers, so they are kept to the segments the recipe expects
+func checkPathArgs(template string, args map[string]string) error {
+	for _, m := range recipeTemplateRe.FindAllStringSubmatch(template, -1) {
+		value, ok := args[m[1]]
+
+		if !ok {
+			continue
+		}
+
+		if strings.ContainsAny(value, "?#%") {
+			return fmt.Errorf("arg %s is used in a path and cannot contain ?, # or %%: %q", m[1], value)
+		}
+
+		for _, seg := range strings.FieldsFunc(value, isPathSeparator) {
+			if seg == ".." {
+				return fmt.Errorf("arg %s is used in a path and cannot contain .. segments: %q", m[1], value)
+			}
+		}
+	}
+
+	return nil
+}
+
+//runRecipeStep renders the step's templates and sends its request, returning the decoded response
+func runRecipeStep(cmd Command, step RecipeStep, args map[string]string, results map[string]interface{}) (result interface{}, err error) {
+	if err = checkPathArgs(step.Path, args); err != nil {
+		return
+	}
+
+	path, err := renderRecipeString(step.Path, args, results)
+
+	if err != nil {
+		return
+	}
+
+	// paths are written the same way as on the command line, with spaces or slashes
+	path = "/" + strings.Join(strings.FieldsFunc(path, isPathSeparator), "/")
+	method := strings.ToUpper(step.Method)
+
+	if len(method) == 0 {
+		endpoints := matchEndpoints(Command{RequestPath: path})
+
+		if len(endpoints) != 1 {
+			return nil, fmt.Errorf("%s matches %d endpoints, set the step's method", path, len(endpoints))
+		}
+
+		method = endpoints[0].Method
+	}
+
+	params := url.Values{}
+
+	for _, param := range step.Params {
+		kv := strings.SplitN(param, "=", 2)
+
+		if len(kv) != 2 {
+			return nil, fmt.Errorf("param %q must be key=value", param)
+		}
+
+		value, err := renderRecipeString(kv[1], args, results)
+
+		if err != nil {
+			return nil, err
+		}
+
+		params.Add(kv[0], value)
+	}
+
+	notify(cmd, "%s: %s %s", step.Name, method, path)
+
+	// empty responses decode to null
+	if err = callAPI(cmd, method, path, params, &result); err == io.EOF {
+		err = nil
+	}
+
+	return
+}
+
+//runRecipeCommand runs a recipe from the config file with the remaining args bound to the
+//recipe's args in order
+func runRecipeCommand(cmd Command) (err error) {
+	if len(cmd.Args) < 2 {
+		return fmt.Errorf("usage: run <recipe> [args]")
+	}
+
+	name := cmd.Args[1]
+	recipe, ok := DefaultConfig.Recipes[name]
+
+	// run <file> runs a batch file instead
+	if info, err := os.Stat(name); !ok && err == nil && info.Mode().IsRegular() {
+		if len(cmd.Args) > 2 {
+			return fmt.Errorf("usage: run <file>")
+		}
+
+		return runBatchCommand(cmd, name)
+	}
+
+	if !ok {
+		var names []string
+
+		for recipeName := range DefaultConfig.Recipes {
+			names = append(names, recipeName)
+		}
+
+		sort.Strings(names)
+
+		return fmt.Errorf("unknown recipe %q. Recipes in %s: %s", name, ConfigPath(), strings.Join(names, ", "))
+	}
+
+	values := cmd.Args[2:]
+
+	if len(values) != len(recipe.Args) {
+		return fmt.Errorf("usage: run %s %s", name, strings.ToUpper(strings.Join(recipe.Args, " ")))
+	}
+
+	args := make(map[string]string)
+
+	for i, arg := range recipe.Args {
+		args[arg] = values[i]
+	}
+
+	output, err := runRecipe(cmd, recipe, args)
+
+	if err != nil {
+		return
+	}
+
+	return writeJSON(output)
+}
+
+//runRecipe runs the recipe's steps with the args and returns its output
+func runRecipe(cmd Command, recipe Recipe, args map[string]string) (output interface{}, err error) {
+	results := make(map[string]interface{})
+	var last interface{}
+
+	for _, step := range recipe.Steps {
+		if last, err = runRecipeStep(cmd, step, args, results); err != nil {
+			return nil, fmt.Errorf("%s: %s", step.Name, err)
+		}
+
+		results[step.Name] = last
+	}
+
+	if len(recipe.Output) == 0 {
+		return last, nil
+	}
+
+	fields := make(map[string]interface{})
+
+	for key, template := range recipe.Output {
+		if fields[key], err = renderRecipeTemplate(template, args, results); err != nil {
+			return nil, fmt.Errorf("output %s: %s", key, err)
+		}
+	}
+
+	return fields, nil
+}
@@ -26,7 +26,7 @@ for os in darwin linux windows; do
 
 		rm -f dist/$bin
 
-		GOOS=${os} GOARCH=${arch} go build -ldflags "-extldflags '-static'" -o dist/$bin main.go
+		GOOS=${os} GOARCH=${arch} go build -ldflags "-extldflags '-static'" -o dist/$bin .
 
 		cd dist
 		name="siajson-${os}-${arch}.zip"
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"net"
+	"net/http"
+	"time"
+)
+
+type (
+	//retryTransport retries requests that could not connect to the Sia API
+	retryTransport struct {
+		next     http.RoundTripper
+		retries  int
+		delay    time.Duration
+		retryAll bool
+		cmd      Command
+	}
+)
+
+//maxRetryDelay the longest the backoff waits between attempts
+const maxRetryDelay = 30 * time.Second
+
+//isConnectionError returns true if the request failed before a connection to the API was made,
+//for example because siad is restarting
+func isConnectionError(err error) bool {
+	opErr, ok := err.(*net.OpError)
+
+	return ok && opErr.Op == "dial"
+}
+
+//RoundTrip sends the request, retrying connection errors with exponential backoff. Only GET and
+//HEAD requests are retried unless retryAll is set, and bodies are replayed only if they can be
+//read again
+func (t *retryTransport) RoundTrip(req *http.Request) (resp *http.Response, err error) {
+	idempotent := req.Method == "GET" || req.Method == "HEAD"
+	replayable := req.Body == nil || req.Body == http.NoBody || req.GetBody != nil
+	delay := t.delay
+
+	for attempt := 0; ; attempt++ {
+		resp, err = t.next.RoundTrip(req)
+
+		if err == nil || !isConnectionError(err) || attempt >= t.retries || !replayable || !(idempotent || t.retryAll) {
+			return
+		}
+
+		notify(t.cmd, "%s, retrying in %s (%d/%d)", err, delay, attempt+1, t.retries)
+
+		select {
+		case <-time.After(delay):
+		case <-req.Context().Done():
+			return nil, req.Context().Err()
+		}
+
+		if delay *= 2; delay > maxRetryDelay {
+			delay = maxRetryDelay
+		}
+
+		if req.GetBody != nil {
+			body, err := req.GetBody()
+
+			if err != nil {
+				return nil, err
+			}
+
+			req = req.WithContext(req.Context())
+			req.Body = body
+		}
+	}
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+//generateAPIPassword generates a random password in the same format siad uses
+func generateAPIPassword() (string, error) {
+	buf := make([]byte, 16)
+
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(buf), nil
+}
+
+//replaceConfigPasswords replaces every api_password in the config that matches the old password.
+//It returns the number of values replaced
+func replaceConfigPasswords(old, new string) (replaced int, err error) {
+	lines, err := readConfigLines()
+
+	if err != nil || len(old) == 0 {
+		return
+	}
+
+	for i, line := range lines {
+		stripped := stripTOMLComment(line)
+		kv := strings.SplitN(stripped, "=", 2)
+
+		if len(kv) != 2 || strings.TrimSpace(kv[0]) != "api_password" {
+			continue
+		}
+
+		if value, err := parseTOMLValue(strings.TrimSpace(kv[1])); err != nil || value != old {
+			continue
+		}
+
+		comment := line[len(stripped):]
+
+		if len(comment) > 0 {
+			comment = " " + comment
+		}
+
+		lines[i] = kv[0] + "= " + strconv.Quote(new) + comment
+		replaced++
+	}
+
+	if replaced > 0 {
+		err = writeConfigLines(lines)
+	}
+
+	return
+}
+
+//runHook runs a shell command, connecting its output to stderr
+func runHook(hook string) error {
+	shell, flag := "sh", "-c"
+
+	if runtime.GOOS == "windows" {
+		shell, flag = "cmd", "/C"
+	}
+
+	c := exec.Command(shell, flag, hook)
+	c.Stdout = os.Stderr
+	c.Stderr = os.Stderr
+
+	return c.Run()
+}
+
+//rotateAPIPasswordCommand writes a new apipassword file, updates profiles using the old password,
+//and runs the restart hook if one is configured: rotate-apipassword [--apipassword-file path] [--restart-hook cmd]
+func rotateAPIPasswordCommand(cmd Command) (err error) {
+	path := filepath.Join(DefaultSiaDir(), "apipassword")
+
+	if values := cmd.Params["apipassword-file"]; len(values) > 0 {
+		path = values[0]
+	}
+
+	old := ""
+
+	if buf, err := ioutil.ReadFile(path); err == nil {
+		old = strings.TrimSpace(string(buf))
+	} else if !os.IsNotExist(err) {
+		return err
+	}
+
+	password, err := generateAPIPassword()
+
+	if err != nil {
+		return
+	}
+
+	if err = os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return
+	}
+
+	if err = ioutil.WriteFile(path, []byte(password+"\n"), 0600); err != nil {
+		return
+	}
+
+	// WriteFile only sets the mode when the file is created
+	if err = os.Chmod(path, 0600); err != nil {
+		return
+	}
+
+	fmt.Printf("wrote a new API password to %s\n", path)
+
+	replaced, err := replaceConfigPasswords(old, password)
+
+	if err != nil {
+		return fmt.Errorf("unable to update %s: %s", ConfigPath(), err)
+	} else if replaced > 0 {
+		fmt.Printf("updated %d api_password values in %s\n", replaced, ConfigPath())
+	}
+
+	hook := DefaultConfig.RestartHook
+
+	if values := cmd.Params["restart-hook"]; len(values) > 0 {
+		hook = values[0]
+	}
+
+	if len(hook) == 0 {
+		fmt.Println("restart siad for the new password to take effect")
+
+		if len(os.Getenv("SIA_API_PASSWORD")) > 0 {
+			fmt.Println("SIA_API_PASSWORD is set and must also be updated")
+		}
+
+		return
+	}
+
+	if err = runHook(hook); err != nil {
+		return fmt.Errorf("restart hook failed: %s", err)
+	}
+
+	fmt.Println("ran restart hook")
+
+	return
+}
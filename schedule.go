@@ -0,0 +1,82 @@
+package main
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+//pollScheduler picks the interval between polls. With a maximum interval the interval doubles
+//each time a poll sees no change, up to the maximum, and drops back to the minimum as soon as
+//one does, reducing the load on siad while values are static
+type pollScheduler struct {
+	min, max, interval time.Duration
+}
+
+//newPollScheduler returns a scheduler polling every min, or adaptively between min and max if
+//max is greater than min
+func newPollScheduler(min, max time.Duration) *pollScheduler {
+	return &pollScheduler{min: min, max: max, interval: min}
+}
+
+//commandScheduler returns the command's poll scheduler starting at the interval. --max-interval
+//makes the scheduler adaptive
+func commandScheduler(cmd Command, interval time.Duration) (*pollScheduler, error) {
+	if cmd.MaxInterval > 0 && cmd.MaxInterval < interval {
+		return nil, fmt.Errorf("--max-interval %s is shorter than the interval %s", cmd.MaxInterval, interval)
+	}
+
+	return newPollScheduler(interval, cmd.MaxInterval), nil
+}
+
+//Next returns how long to wait before the next poll given whether the last poll saw a change
+func (s *pollScheduler) Next(changed bool) time.Duration {
+	switch {
+	case s.max <= s.min:
+	case changed:
+		s.interval = s.min
+	default:
+		if s.interval *= 2; s.interval > s.max {
+			s.interval = s.max
+		}
+	}
+
+	return s.interval
+}
+
+//redirectStdout runs fn, writing what it writes to stdout to dst instead
+func redirectStdout(dst io.Writer, fn func() error) (err error) {
+	r, w, err := os.Pipe()
+
+	if err != nil {
+		return
+	}
+
+	stdout := os.Stdout
+	done := make(chan struct{})
+
+	go func() {
+		io.Copy(dst, r)
+		r.Close()
+		close(done)
+	}()
+
+	os.Stdout = w
+	err = fn()
+	os.Stdout = stdout
+	w.Close()
+	<-done
+
+	return
+}
+
+//captureStdout runs fn, passing what it writes to stdout through while hashing it, so callers
+//can tell if the output changed between runs
+func captureStdout(fn func() error) (digest []byte, err error) {
+	h := sha256.New()
+	err = redirectStdout(io.MultiWriter(os.Stdout, h), fn)
+
+	return h.Sum(nil), err
+}
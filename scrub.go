@@ -0,0 +1,399 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"hash"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type (
+	//checksumRecord the SHA-256 of every block of an uploaded file, as it was sent to siad, so
+	//renter scrub can verify the stored data later
+	checksumRecord struct {
+		Address   string    `json:"address"`
+		SiaPath   string    `json:"siapath"`
+		Size      int64     `json:"size"`
+		BlockSize int64     `json:"blocksize"`
+		Blocks    []string  `json:"blocks"`
+		Recorded  time.Time `json:"recorded"`
+
+		path     string
+		complete bool
+	}
+
+	//checksumReader records the checksums of the blocks read from the wrapped reader
+	checksumReader struct {
+		r      io.Reader
+		record *checksumRecord
+		hash   hash.Hash
+		n      int64
+	}
+
+	//scrubResult the result of scrubbing a file
+	scrubResult struct {
+		SiaPath string  `json:"siapath"`
+		Status  string  `json:"status"`
+		Checked int     `json:"checked"`
+		Bad     []int64 `json:"badoffsets,omitempty"`
+		Error   string  `json:"error,omitempty"`
+	}
+
+	//scrubReport the results of renter scrub
+	scrubReport struct {
+		Time        time.Time     `json:"time"`
+		Files       int           `json:"files"`
+		Blocks      int           `json:"blocks"`
+		Bytes       int64         `json:"bytes"`
+		Corrupted   int           `json:"corrupted"`
+		Unavailable int           `json:"unavailable"`
+		Results     []scrubResult `json:"results"`
+	}
+)
+
+const (
+	//checksumBlockSize the size of the blocks checksums are recorded for
+	checksumBlockSize = 1 << 20
+
+	//defaultScrubSample the percentage of each file's blocks renter scrub downloads
+	defaultScrubSample = 5
+)
+
+//checksumsDir the directory checksum records are stored in
+func checksumsDir() string {
+	return filepath.Join(DefaultConfigDir(), "checksums")
+}
+
+//newChecksumReader returns a reader recording the checksums of the blocks of r uploaded to the
+//command's siapath
+func newChecksumReader(cmd Command, r io.Reader) (*checksumReader, bool) {
+	_, siapath, ok := splitSiaPath(cmd)
+
+	if !ok {
+		return nil, false
+	}
+
+	siapath = strings.TrimPrefix(siapath, "/")
+	sum := sha256.Sum256([]byte(cmd.APIAddress + "\x00" + siapath))
+
+	return &checksumReader{
+		r: r,
+		record: &checksumRecord{
+			Address:   cmd.APIAddress,
+			SiaPath:   siapath,
+			BlockSize: checksumBlockSize,
+			Blocks:    []string{},
+			path:      filepath.Join(checksumsDir(), hex.EncodeToString(sum[:8])+".json"),
+		},
+		hash: sha256.New(),
+	}, true
+}
+
+//Read reads from the wrapped reader and hashes the blocks read
+func (cr *checksumReader) Read(p []byte) (n int, err error) {
+	n, err = cr.r.Read(p)
+
+	for buf := p[:n]; len(buf) > 0; {
+		part := buf
+
+		if left := checksumBlockSize - cr.n; int64(len(part)) > left {
+			part = part[:left]
+		}
+
+		cr.hash.Write(part)
+		cr.n += int64(len(part))
+		cr.record.Size += int64(len(part))
+		buf = buf[len(part):]
+
+		if cr.n == checksumBlockSize {
+			cr.endBlock()
+		}
+	}
+
+	if err == io.EOF && !cr.record.complete {
+		if cr.n > 0 {
+			cr.endBlock()
+		}
+
+		cr.record.complete = true
+	}
+
+	return
+}
+
+//endBlock records the checksum of the current block
+func (cr *checksumReader) endBlock() {
+	cr.record.Blocks = append(cr.record.Blocks, hex.EncodeToString(cr.hash.Sum(nil)))
+	cr.hash.Reset()
+	cr.n = 0
+}
+
+//save atomically writes the record once the whole upload was read
+func (c *checksumRecord) save() (err error) {
+	if c == nil || !c.complete {
+		return
+	}
+
+	c.Recorded = time.Now().UTC()
+
+	if err = os.MkdirAll(filepath.Dir(c.path), 0700); err != nil {
+		return
+	}
+
+	buf, err := json.MarshalIndent(c, "", "\t")
+
+	if err != nil {
+		return
+	}
+
+	tmp := c.path + ".tmp"
+
+	if err = ioutil.WriteFile(tmp, buf, 0600); err != nil {
+		return
+	}
+
+	return os.Rename(tmp, c.path)
+}
+
+//loadChecksumRecords loads the records of the files uploaded to the address below the siapath
+func loadChecksumRecords(address, prefix string) (records []checksumRecord, err error) {
+	entries, err := ioutil.ReadDir(checksumsDir())
+
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return
+	}
+
+	prefix = strings.Trim(prefix, "/")
+
+	for _, entry := range entries {
+		if !strings.HasSuffix(entry.Name(), ".json") {
+			continue
+		}
+
+		buf, err := ioutil.ReadFile(filepath.Join(checksumsDir(), entry.Name()))
+
+		if err != nil {
+			return nil, err
+		}
+
+		var record checksumRecord
+
+		if err = json.Unmarshal(buf, &record); err != nil {
+			return nil, fmt.Errorf("%s: %s", entry.Name(), err)
+		}
+
+		if record.Address != address || record.BlockSize <= 0 {
+			continue
+		}
+
+		if len(prefix) > 0 && record.SiaPath != prefix && !strings.HasPrefix(record.SiaPath, prefix+"/") {
+			continue
+		}
+
+		records = append(records, record)
+	}
+
+	sort.Slice(records, func(i, j int) bool {
+		return records[i].SiaPath < records[j].SiaPath
+	})
+
+	return
+}
+
+//parseSample parses the --sample percentage of blocks to check, such as 5%
+func parseSample(value string) (float64, error) {
+	sample, err := strconv.ParseFloat(strings.TrimSuffix(strings.TrimSpace(value), "%"), 64)
+
+	if err != nil || sample <= 0 || sample > 100 {
+		return 0, fmt.Errorf("invalid --sample value %q, use a percentage such as 5%%", value)
+	}
+
+	return sample, nil
+}
+
+//streamRange downloads a byte range of the file from /renter/stream
+func streamRange(cmd Command, siapath string, offset, length int64) (buf []byte, err error) {
+	stream := cmd
+	stream.Method = "GET"
+	stream.RequestPath = "/renter/stream/" + siapath
+	stream.Params = nil
+
+	req, err := makeRequest(stream, nil)
+
+	if err != nil {
+		return
+	}
+
+	req.Header.Set("Range", fmt.Sprintf("bytes=%d-%d", offset, offset+length-1))
+
+	client, err := httpClient(stream)
+
+	if err != nil {
+		return
+	}
+
+	req, cancel := withTimeout(stream, req)
+	defer cancel()
+
+	resp, err := client.Do(req)
+
+	if err != nil {
+		return nil, ExitCodeError{Code: ExitConnectionError, Err: timeoutError(stream, req, err)}
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		buf, _ = ioutil.ReadAll(resp.Body)
+		apiErr := APIError{StatusCode: resp.StatusCode}
+
+		if json.Unmarshal(buf, &apiErr) != nil {
+			apiErr.Message = strings.TrimSpace(string(buf))
+		}
+
+		return nil, apiErr
+	}
+
+	// a server ignoring the range sends the whole file
+	if resp.StatusCode != http.StatusPartialContent && offset > 0 {
+		return nil, fmt.Errorf("siad did not return the requested range")
+	}
+
+	return ioutil.ReadAll(io.LimitReader(resp.Body, length))
+}
+
+//scrubFile downloads the sampled blocks of the file and compares them with its record
+func scrubFile(cmd Command, record checksumRecord, sample float64, rng *rand.Rand) (result scrubResult, downloaded int64, err error) {
+	result = scrubResult{
+		SiaPath: record.SiaPath,
+		Status:  "ok",
+	}
+
+	count := int(float64(len(record.Blocks))*sample/100 + 0.5)
+
+	if count < 1 && len(record.Blocks) > 0 {
+		count = 1
+	}
+
+	blocks := rng.Perm(len(record.Blocks))[:count]
+	sort.Ints(blocks)
+
+	for _, block := range blocks {
+		offset := int64(block) * record.BlockSize
+		length := record.BlockSize
+
+		if offset+length > record.Size {
+			length = record.Size - offset
+		}
+
+		buf, err := streamRange(cmd, record.SiaPath, offset, length)
+
+		if _, ok := err.(ExitCodeError); ok {
+			return result, downloaded, err
+		} else if err != nil {
+			result.Status, result.Error = "unavailable", err.Error()
+			return result, downloaded, nil
+		}
+
+		result.Checked++
+		downloaded += int64(len(buf))
+
+		if sum := sha256.Sum256(buf); int64(len(buf)) != length || hex.EncodeToString(sum[:]) != record.Blocks[block] {
+			result.Status = "corrupted"
+			result.Bad = append(result.Bad, offset)
+		}
+	}
+
+	return
+}
+
+//renterScrubCommand verifies files uploaded with --file by downloading a random --sample of
+//their blocks, 5% by default, from /renter/stream and comparing them with the checksums
+//recorded when they were uploaded: renter scrub [siapath]. Use --watch to scrub periodically.
+//The command fails if a file is corrupted or unavailable
+func renterScrubCommand(cmd Command) (err error) {
+	sample := float64(defaultScrubSample)
+
+	if values := cmd.Params["sample"]; len(values) > 0 {
+		if sample, err = parseSample(values[0]); err != nil {
+			return
+		}
+	}
+
+	var prefix string
+
+	if len(cmd.Args) > 2 {
+		workspace, err := LoadState()
+
+		if err != nil {
+			return err
+		}
+
+		prefix = resolveSiaPath(workspace.Cwd, strings.Join(cmd.Args[2:], "/"))
+	}
+
+	records, err := loadChecksumRecords(cmd.APIAddress, prefix)
+
+	if err != nil {
+		return
+	}
+
+	if len(records) == 0 && len(prefix) > 0 {
+		return fmt.Errorf("no checksums are recorded for files below %s", prefix)
+	} else if len(records) == 0 {
+		return fmt.Errorf("no checksums are recorded for files uploaded to %s. Upload files with renter uploadstream --file to record them", cmd.APIAddress)
+	}
+
+	rng := rand.New(rand.NewSource(time.Now().UnixNano()))
+	report := scrubReport{
+		Results: []scrubResult{},
+	}
+
+	for _, record := range records {
+		result, n, err := scrubFile(cmd, record, sample, rng)
+
+		if err != nil {
+			return err
+		}
+
+		notify(cmd, "%s: %s, %d blocks checked", result.SiaPath, result.Status, result.Checked)
+
+		report.Files++
+		report.Blocks += result.Checked
+		report.Bytes += n
+
+		switch result.Status {
+		case "corrupted":
+			report.Corrupted++
+		case "unavailable":
+			report.Unavailable++
+		}
+
+		report.Results = append(report.Results, result)
+	}
+
+	report.Time = time.Now().UTC()
+
+	if err = writeJSON(report); err != nil {
+		return
+	}
+
+	if report.Corrupted > 0 || report.Unavailable > 0 {
+		return ExitCodeError{Code: ExitAssertionFailed, Err: fmt.Errorf("%d corrupted and %d unavailable of %d files", report.Corrupted, report.Unavailable, report.Files)}
+	}
+
+	return
+}
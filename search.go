@@ -0,0 +1,222 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+type (
+	//searchSource an API response searched by sia-json search
+	searchSource struct {
+		Type   string
+		Path   string
+		Params url.Values
+		//Arrays the fields of the response holding the searched objects
+		Arrays []string
+		//Fields returns the identifier of an object and the named values matched against the term
+		Fields func(obj map[string]interface{}) (id string, values map[string][]string)
+	}
+
+	//searchHit an object matching the search term
+	searchHit struct {
+		Type  string `json:"type"`
+		ID    string `json:"id"`
+		Field string `json:"field"`
+		Value string `json:"value"`
+	}
+
+	//searchReport the result of search
+	searchReport struct {
+		Term   string            `json:"term"`
+		Hits   []searchHit       `json:"hits"`
+		Errors map[string]string `json:"errors,omitempty"`
+	}
+)
+
+//stringField returns the string value of the dotted field, or "" if it is missing or not a string
+func stringField(obj interface{}, path string) string {
+	v, _ := walkField(obj, strings.Split(path, "."))
+	str, _ := v.(string)
+
+	return str
+}
+
+//stringFields returns the string values of the field in each object of the array field
+func stringFields(obj map[string]interface{}, array, path string) (values []string) {
+	elems, _ := obj[array].([]interface{})
+
+	for _, elem := range elems {
+		if str := stringField(elem, path); len(str) > 0 {
+			values = append(values, str)
+		}
+	}
+
+	return
+}
+
+//contractFields the fields of a /renter/contracts contract. The host key is base64 encoded, so it
+//is also matched in the ed25519:<hex> format used by the hostdb
+func contractFields(obj map[string]interface{}) (string, map[string][]string) {
+	values := map[string][]string{
+		"id":         {stringField(obj, "id")},
+		"netaddress": {stringField(obj, "netaddress")},
+	}
+
+	if key, err := base64.StdEncoding.DecodeString(stringField(obj, "hostpublickey.key")); err == nil && len(key) > 0 {
+		values["hostpublickey"] = []string{stringField(obj, "hostpublickey.algorithm") + ":" + hex.EncodeToString(key)}
+	}
+
+	return stringField(obj, "id"), values
+}
+
+//searchSources the responses searched. The transaction source's end height is set to the current
+//block height
+func searchSources(height uint64) []searchSource {
+	return []searchSource{
+		{
+			Type:   "file",
+			Path:   "/renter/files",
+			Arrays: []string{"files"},
+			Fields: func(obj map[string]interface{}) (string, map[string][]string) {
+				return stringField(obj, "siapath"), map[string][]string{
+					"siapath":   {stringField(obj, "siapath")},
+					"localpath": {stringField(obj, "localpath")},
+				}
+			},
+		},
+		{
+			Type:   "contract",
+			Path:   "/renter/contracts",
+			Params: url.Values{"disabled": {"true"}, "expired": {"true"}},
+			Arrays: []string{"activecontracts", "passivecontracts", "refreshedcontracts", "disabledcontracts", "expiredcontracts", "expiredrefreshedcontracts"},
+			Fields: contractFields,
+		},
+		{
+			Type:   "transaction",
+			Path:   "/wallet/transactions",
+			Params: url.Values{"startheight": {"0"}, "endheight": {strconv.FormatUint(height, 10)}},
+			Arrays: []string{"confirmedtransactions", "unconfirmedtransactions"},
+			Fields: func(obj map[string]interface{}) (string, map[string][]string) {
+				return stringField(obj, "transactionid"), map[string][]string{
+					"transactionid": {stringField(obj, "transactionid")},
+					"inputs":        append(stringFields(obj, "inputs", "relatedaddress"), stringFields(obj, "inputs", "parentid")...),
+					"outputs":       append(stringFields(obj, "outputs", "relatedaddress"), stringFields(obj, "outputs", "id")...),
+				}
+			},
+		},
+		{
+			Type:   "host",
+			Path:   "/hostdb/all",
+			Arrays: []string{"hosts"},
+			Fields: func(obj map[string]interface{}) (string, map[string][]string) {
+				return stringField(obj, "publickeystring"), map[string][]string{
+					"publickey":  {stringField(obj, "publickeystring")},
+					"netaddress": {stringField(obj, "netaddress")},
+				}
+			},
+		},
+	}
+}
+
+//search returns the objects of the source with a field containing the term
+func (source searchSource) search(cmd Command, term string) (hits []searchHit, err error) {
+	var resp map[string]interface{}
+
+	if err = callAPI(cmd, "GET", source.Path, source.Params, &resp); err != nil {
+		return
+	}
+
+	for _, array := range source.Arrays {
+		elems, _ := resp[array].([]interface{})
+
+		for _, elem := range elems {
+			obj, ok := elem.(map[string]interface{})
+
+			if !ok {
+				continue
+			}
+
+			id, values := source.Fields(obj)
+			fields := make([]string, 0, len(values))
+
+			for field := range values {
+				fields = append(fields, field)
+			}
+
+			sort.Strings(fields)
+
+			// each object is reported once, for the first field that matched
+		match:
+			for _, field := range fields {
+				for _, value := range values[field] {
+					if len(value) > 0 && strings.Contains(strings.ToLower(value), term) {
+						hits = append(hits, searchHit{Type: source.Type, ID: id, Field: field, Value: value})
+						break match
+					}
+				}
+			}
+		}
+	}
+
+	return
+}
+
+//searchCommand searches renter files, contracts, wallet transactions and hosts concurrently for
+//siapaths, IDs, addresses and public keys containing the term. Sources that fail, for example
+//because the module is not loaded, are reported in errors
+func searchCommand(cmd Command) (err error) {
+	term := strings.TrimSpace(strings.Join(cmd.Args[1:], " "))
+
+	if len(term) == 0 {
+		return fmt.Errorf("usage: search <term>")
+	}
+
+	report := searchReport{
+		Term: term,
+		Hits: []searchHit{},
+	}
+
+	var consensus consensusResponse
+
+	if err = callAPI(cmd, "GET", "/consensus", nil, &consensus); err != nil {
+		return
+	}
+
+	sources := searchSources(consensus.Height)
+	results := make([][]searchHit, len(sources))
+	errs := make([]error, len(sources))
+	var wg sync.WaitGroup
+
+	for i, source := range sources {
+		wg.Add(1)
+
+		go func(i int, source searchSource) {
+			defer wg.Done()
+
+			results[i], errs[i] = source.search(cmd, strings.ToLower(term))
+		}(i, source)
+	}
+
+	wg.Wait()
+
+	for i, source := range sources {
+		if errs[i] != nil {
+			if report.Errors == nil {
+				report.Errors = make(map[string]string)
+			}
+
+			report.Errors[source.Type] = errs[i].Error()
+			continue
+		}
+
+		report.Hits = append(report.Hits, results[i]...)
+	}
+
+	return writeJSON(report)
+}
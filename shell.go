@@ -0,0 +1,446 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"unicode"
+)
+
+//maxShellHistory the number of lines kept in the shell history file
+const maxShellHistory = 1000
+
+//the shell is registered at init because the commands it runs look up BuiltinCommands
+func init() {
+	BuiltinCommands = append(BuiltinCommands, BuiltinCommand{
+		Path:     "/shell",
+		HelpText: "opens an interactive prompt with completion and history. The flags given to shell apply to every command",
+		Run:      shellCommand,
+		Stream:   true,
+	})
+}
+
+//shellEditor reads the shell's lines from a terminal in raw mode with completion and history
+type shellEditor struct {
+	prompt  string
+	history []string
+	buf     []rune
+	pos     int
+}
+
+//shellHistoryPath returns the path of the shell history file
+func shellHistoryPath() string {
+	return filepath.Join(DefaultConfigDir(), "shell_history")
+}
+
+//loadShellHistory loads the lines entered in previous shells
+func loadShellHistory() (history []string) {
+	buf, err := ioutil.ReadFile(shellHistoryPath())
+
+	if err != nil {
+		return
+	}
+
+	for _, line := range strings.Split(string(buf), "\n") {
+		if len(line) > 0 {
+			history = append(history, line)
+		}
+	}
+
+	return
+}
+
+//saveShellHistory writes the most recent lines to the history file
+func saveShellHistory(history []string) (err error) {
+	if len(history) > maxShellHistory {
+		history = history[len(history)-maxShellHistory:]
+	}
+
+	if err = os.MkdirAll(DefaultConfigDir(), 0700); err != nil {
+		return
+	}
+
+	return ioutil.WriteFile(shellHistoryPath(), []byte(strings.Join(history, "\n")+"\n"), 0600)
+}
+
+//splitShellLine splits the line into words. Words can be quoted with single or double quotes and
+//characters escaped with a backslash. If the line ends with a space an empty word is added so the
+//next word can be completed
+func splitShellLine(line string) (words []string, err error) {
+	var word strings.Builder
+	var quote rune
+	inWord, escaped := false, false
+
+	for _, r := range line {
+		switch {
+		case escaped:
+			word.WriteRune(r)
+			escaped = false
+		case r == '\\' && quote != '\'':
+			escaped, inWord = true, true
+		case quote != 0 && r == quote:
+			quote = 0
+		case quote != 0:
+			word.WriteRune(r)
+		case r == '"' || r == '\'':
+			quote, inWord = r, true
+		case unicode.IsSpace(r):
+			if inWord {
+				words = append(words, word.String())
+				word.Reset()
+				inWord = false
+			}
+		default:
+			word.WriteRune(r)
+			inWord = true
+		}
+	}
+
+	if quote != 0 || escaped {
+		return nil, fmt.Errorf("unterminated quote or escape")
+	}
+
+	if inWord {
+		words = append(words, word.String())
+	} else if len(line) > 0 {
+		words = append(words, "")
+	}
+
+	return
+}
+
+//flagArgs returns the flags and their values in the args, leaving out the path words and the
+//flags in skip
+func flagArgs(args []string, skip ...string) (flags []string) {
+	// invalid args are reported when the command is parsed
+	tokens, _ := tokenizeArgs(args)
+
+	for _, token := range tokens {
+		skipped := len(token.Flag) == 0
+
+		for _, flag := range skip {
+			skipped = skipped || token.Flag == flag
+		}
+
+		if !skipped {
+			flags = append(flags, token.Raw...)
+		}
+	}
+
+	return
+}
+
+//shellBaseArgs returns the flags of the shell command. They are passed to every command run in
+//the shell so connection settings such as --addr and --profile persist
+func shellBaseArgs(cmd Command) (base []string) {
+	base = append(base, cmd.Flags...)
+
+	// responses are pretty printed unless another format was chosen
+	tokens, _ := tokenizeArgs(base)
+
+	for _, token := range tokens {
+		if token.Flag == "format" {
+			return
+		}
+	}
+
+	if len(DefaultConfig.Format) == 0 {
+		base = append(base, "--format", PrettyFormat)
+	}
+
+	return
+}
+
+//commonPrefix returns the longest prefix shared by the strings
+func commonPrefix(strs []string) string {
+	if len(strs) == 0 {
+		return ""
+	}
+
+	prefix := strs[0]
+
+	for _, str := range strs[1:] {
+		for !strings.HasPrefix(str, prefix) {
+			prefix = prefix[:len(prefix)-1]
+		}
+	}
+
+	return prefix
+}
+
+//setRawMode puts the terminal in raw mode and returns a function restoring the previous mode
+func setRawMode() (restore func(), err error) {
+	save := exec.Command("stty", "-g")
+	save.Stdin = os.Stdin
+	state, err := save.Output()
+
+	if err != nil {
+		return
+	}
+
+	raw := exec.Command("stty", "raw", "-echo")
+	raw.Stdin = os.Stdin
+
+	if err = raw.Run(); err != nil {
+		return
+	}
+
+	return func() {
+		stty := exec.Command("stty", strings.TrimSpace(string(state)))
+		stty.Stdin = os.Stdin
+		stty.Run()
+	}, nil
+}
+
+//redraw rewrites the prompt and line and moves the cursor to its position
+func (e *shellEditor) redraw() {
+	os.Stderr.WriteString("\r\033[K" + e.prompt + string(e.buf))
+
+	if back := len(e.buf) - e.pos; back > 0 {
+		fmt.Fprintf(os.Stderr, "\033[%dD", back)
+	}
+}
+
+//insert inserts the string at the cursor
+func (e *shellEditor) insert(s string) {
+	runes := []rune(s)
+	e.buf = append(e.buf[:e.pos], append(runes, e.buf[e.pos:]...)...)
+	e.pos += len(runes)
+}
+
+//complete completes the word before the cursor. A single completion replaces the word, several
+//complete their common prefix and are listed if the word cannot be extended
+func (e *shellEditor) complete() {
+	text := string(e.buf[:e.pos])
+	words, err := splitShellLine(text)
+
+	if err != nil {
+		return
+	}
+
+	current := ""
+
+	if len(words) > 0 {
+		current = words[len(words)-1]
+	}
+
+	// words with quotes or escapes are not completed
+	if !strings.HasSuffix(text, current) {
+		return
+	}
+
+	completions := completeWords(words)
+
+	switch {
+	case len(completions) == 0:
+		return
+	case len(completions) == 1:
+		e.insert(completions[0][len(current):] + " ")
+	case len(commonPrefix(completions)) > len(current):
+		e.insert(commonPrefix(completions)[len(current):])
+	default:
+		os.Stderr.WriteString("\r\n" + strings.Join(completions, "  ") + "\r\n")
+	}
+}
+
+//readLine reads a line from the terminal. Returns io.EOF if ctrl-d is pressed on an empty line
+func (e *shellEditor) readLine() (line string, err error) {
+	restore, err := setRawMode()
+
+	if err != nil {
+		return
+	}
+
+	defer restore()
+
+	e.buf, e.pos = nil, 0
+	index := len(e.history)
+	e.redraw()
+
+	for {
+		r, _, err := stdinReader.ReadRune()
+
+		if err != nil {
+			return "", err
+		}
+
+		switch r {
+		case '\r', '\n':
+			os.Stderr.WriteString("\r\n")
+			return string(e.buf), nil
+		case 3: // ctrl-c discards the line
+			os.Stderr.WriteString("^C\r\n")
+			e.buf, e.pos = nil, 0
+		case 4: // ctrl-d
+			if len(e.buf) == 0 {
+				os.Stderr.WriteString("\r\n")
+				return "", io.EOF
+			}
+		case 1: // ctrl-a
+			e.pos = 0
+		case 5: // ctrl-e
+			e.pos = len(e.buf)
+		case 21: // ctrl-u
+			e.buf, e.pos = e.buf[e.pos:], 0
+		case 127, 8:
+			if e.pos > 0 {
+				e.buf = append(e.buf[:e.pos-1], e.buf[e.pos:]...)
+				e.pos--
+			}
+		case '\t':
+			e.complete()
+		case 27:
+			// arrow keys are sent as ESC [ A-D
+			if next, _, _ := stdinReader.ReadRune(); next != '[' {
+				break
+			}
+
+			key, _, _ := stdinReader.ReadRune()
+
+			switch {
+			case key == 'A' && index > 0:
+				index--
+				e.buf = []rune(e.history[index])
+				e.pos = len(e.buf)
+			case key == 'B' && index < len(e.history):
+				index++
+				e.buf = nil
+
+				if index < len(e.history) {
+					e.buf = []rune(e.history[index])
+				}
+
+				e.pos = len(e.buf)
+			case key == 'C' && e.pos < len(e.buf):
+				e.pos++
+			case key == 'D' && e.pos > 0:
+				e.pos--
+			}
+		default:
+			if unicode.IsPrint(r) {
+				e.insert(string(r))
+			}
+		}
+
+		e.redraw()
+	}
+}
+
+//shellPrompt returns the prompt of the shell, marking a daemon the keepalive pinger could not
+//reach
+func shellPrompt(cmd Command, status livenessStatus) string {
+	if !status.Up {
+		return fmt.Sprintf("sia-json %s (%s)> ", cmd.APIAddress, livenessLabel(status))
+	}
+
+	return fmt.Sprintf("sia-json %s> ", cmd.APIAddress)
+}
+
+//shellCommand opens an interactive prompt running sia-json commands against the same siad. The
+//flags given to shell, such as --addr and --profile, apply to every command and connections
+//are kept open between commands. Responses are pretty printed. Lines are read from stdin
+//without editing if it is not a terminal, so scripts can be piped in
+func shellCommand(cmd Command) (err error) {
+	base := shellBaseArgs(cmd)
+	interactive := isTerminal(os.Stdin) && runtime.GOOS != "windows"
+	editor := &shellEditor{
+		prompt: shellPrompt(cmd, livenessStatus{Up: true}),
+	}
+
+	if interactive {
+		editor.history = loadShellHistory()
+	}
+
+	interactiveStdin = true
+	sharedTransports = make(map[string]*http.Transport)
+
+	// ctrl-c stops the running command instead of the shell
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, os.Interrupt)
+	defer signal.Stop(sigs)
+
+	// changes are reported before the next prompt rather than while a line is being edited
+	pinger := commandPinger(cmd, nil)
+	reported := livenessStatus{Up: true}
+
+	if pinger != nil {
+		defer pinger.Stop()
+	}
+
+	for {
+		var line string
+
+		if pinger != nil {
+			status := pinger.Status()
+
+			if status.Up != reported.Up {
+				notifyLiveness(cmd, status)
+			}
+
+			reported = status
+			editor.prompt = shellPrompt(cmd, status)
+		}
+
+		if interactive {
+			line, err = editor.readLine()
+		} else {
+			line, err = readLine("")
+		}
+
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return
+		}
+
+		line = strings.TrimSpace(line)
+
+		if len(line) == 0 {
+			continue
+		}
+
+		if interactive && (len(editor.history) == 0 || editor.history[len(editor.history)-1] != line) {
+			editor.history = append(editor.history, line)
+
+			if err := saveShellHistory(editor.history); err != nil {
+				notify(cmd, "unable to save shell history: %s", err)
+			}
+		}
+
+		words, err := splitShellLine(line)
+
+		if err != nil {
+			os.Stderr.WriteString(err.Error() + "\n")
+			continue
+		}
+
+		switch words[0] {
+		case "exit", "quit":
+			return nil
+		case "shell":
+			os.Stderr.WriteString("already in a shell\n")
+			continue
+		}
+
+		if code := run(append(append([]string{}, base...), words...)); code != ExitSuccess {
+			os.Stderr.WriteString("\n")
+
+			if code == ExitConnectionError && pinger != nil {
+				pinger.Recheck()
+			}
+		}
+
+		// drain interrupts received while the command was running
+		select {
+		case <-sigs:
+		default:
+		}
+	}
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"sort"
+	"strconv"
+	"time"
+)
+
+//sliceParams the params that page through array responses client-side
+var sliceParams = []string{"limit", "offset", "sample"}
+
+//parseSliceParams moves --limit, --offset and --sample from the request params to the command.
+//Binary endpoints such as /renter/download have their own offset param so they are left as is
+func parseSliceParams(cmd *Command) (err error) {
+	if cmd.Endpoint.Binary {
+		return
+	}
+
+	fields := map[string]*int{
+		"limit":  &cmd.Limit,
+		"offset": &cmd.Offset,
+		"sample": &cmd.Sample,
+	}
+
+	for _, key := range sliceParams {
+		values, ok := cmd.Params[key]
+
+		if !ok {
+			continue
+		}
+
+		delete(cmd.Params, key)
+
+		if len(values) == 0 {
+			return fmt.Errorf("--%s requires a value", key)
+		}
+
+		n, err := strconv.Atoi(values[len(values)-1])
+
+		if err != nil || n < 0 || n == 0 && key != "offset" {
+			return fmt.Errorf("invalid --%s value %q", key, values[len(values)-1])
+		}
+
+		*fields[key] = n
+	}
+
+	return
+}
+
+//sliceArray applies the command's offset, limit and sample to the array
+func sliceArray(cmd Command, arr []interface{}) []interface{} {
+	if cmd.Offset >= len(arr) {
+		arr = arr[:0]
+	} else {
+		arr = arr[cmd.Offset:]
+	}
+
+	if cmd.Limit > 0 && cmd.Limit < len(arr) {
+		arr = arr[:cmd.Limit]
+	}
+
+	if cmd.Sample > 0 && cmd.Sample < len(arr) {
+		// sampled elements are written in their original order
+		indexes := rand.New(rand.NewSource(time.Now().UnixNano())).Perm(len(arr))[:cmd.Sample]
+		sort.Ints(indexes)
+		sample := make([]interface{}, len(indexes))
+
+		for i, index := range indexes {
+			sample[i] = arr[index]
+		}
+
+		arr = sample
+	}
+
+	return arr
+}
+
+//sliceResponse applies --limit, --offset and --sample to an array response, or to each array
+//field of an object response such as the hosts of /hostdb/all. A note is written to stderr for
+//each truncated array
+func sliceResponse(cmd Command, body []byte) (sliced []byte, err error) {
+	var obj interface{}
+
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.UseNumber()
+
+	if err = dec.Decode(&obj); err != nil {
+		return nil, fmt.Errorf("unable to apply --limit, --offset or --sample, response is not JSON: %s", err)
+	}
+
+	truncate := func(name string, arr []interface{}) []interface{} {
+		result := sliceArray(cmd, arr)
+
+		if len(result) < len(arr) {
+			notify(cmd, "showing %d of %d %s", len(result), len(arr), name)
+		}
+
+		return result
+	}
+
+	switch v := obj.(type) {
+	case []interface{}:
+		obj = truncate("elements", v)
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+
+		for key := range v {
+			keys = append(keys, key)
+		}
+
+		sort.Strings(keys)
+
+		for _, key := range keys {
+			if arr, ok := v[key].([]interface{}); ok {
+				v[key] = truncate(key, arr)
+			}
+		}
+	}
+
+	return json.Marshal(obj)
+}
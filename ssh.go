@@ -0,0 +1,103 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"strconv"
+	"time"
+
+	"github.com/n8maninger/siac-json/client"
+)
+
+//sshTunnelTimeout how long to wait for the SSH tunnel to start accepting connections
+const sshTunnelTimeout = 30 * time.Second
+
+//freeLocalPort returns a TCP port on the loopback interface that is not in use
+func freeLocalPort() (port int, err error) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+
+	if err != nil {
+		return
+	}
+
+	defer l.Close()
+
+	return l.Addr().(*net.TCPAddr).Port, nil
+}
+
+//openSSHTunnel starts ssh forwarding a local port to the API address on the remote machine and
+//points the command at the local end of the tunnel. The API address is resolved on the remote
+//machine, so the default localhost:9980 is the remote siad. The scheme of a URL address is kept,
+//https is verified against the remote host name. The returned function stops ssh
+func openSSHTunnel(cmd *Command) (closeTunnel func(), err error) {
+	base, err := client.ParseAddress(cmd.APIAddress)
+
+	if err != nil {
+		return
+	}
+
+	remote, err := client.HostPort(cmd.APIAddress)
+
+	if err != nil {
+		return
+	}
+
+	port, err := freeLocalPort()
+
+	if err != nil {
+		return
+	}
+
+	// ssh expects IPv6 hosts of the forward in brackets, the same as JoinHostPort
+	local := net.JoinHostPort("127.0.0.1", strconv.Itoa(port))
+	c := exec.Command("ssh", "-N", "-o", "ExitOnForwardFailure=yes", "-L", local+":"+remote, cmd.SSHTarget)
+	c.Stderr = os.Stderr
+
+	if err = c.Start(); err != nil {
+		return nil, fmt.Errorf("unable to start ssh: %s", err)
+	}
+
+	exited := make(chan error, 1)
+
+	go func() {
+		exited <- c.Wait()
+	}()
+
+	closeTunnel = func() {
+		c.Process.Kill()
+		<-exited
+	}
+
+	deadline := time.Now().Add(sshTunnelTimeout)
+
+	for {
+		select {
+		case err = <-exited:
+			return nil, fmt.Errorf("ssh exited before the tunnel was ready: %v", err)
+		default:
+		}
+
+		if conn, dialErr := net.DialTimeout("tcp", local, time.Second); dialErr == nil {
+			conn.Close()
+			break
+		}
+
+		if time.Now().After(deadline) {
+			closeTunnel()
+			return nil, fmt.Errorf("timed out waiting for the ssh tunnel to %s", cmd.SSHTarget)
+		}
+
+		time.Sleep(100 * time.Millisecond)
+	}
+
+	if base.Scheme == "https" && len(cmd.TLSServerName) == 0 {
+		cmd.TLSServerName = base.Hostname()
+	}
+
+	base.Host = local
+	cmd.APIAddress = base.String()
+
+	return
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"time"
+)
+
+type (
+	//TrashEntry a renter file that was moved to the trash instead of being deleted
+	TrashEntry struct {
+		SiaPath   string    `json:"siapath"`
+		TrashPath string    `json:"trashpath"`
+		Deleted   time.Time `json:"deleted"`
+		//Expires when the entry is purged, set from the --retention given when it was trashed
+		Expires time.Time `json:"expires,omitempty"`
+	}
+
+	//HostMaintenance the host settings recorded when maintenance mode was turned on
+	HostMaintenance struct {
+		AcceptingContracts bool      `json:"acceptingcontracts"`
+		Started            time.Time `json:"started"`
+	}
+
+	//State persistent state shared between invocations of sia-json
+	State struct {
+		//Cwd the current remote working directory relative siapaths are resolved against
+		Cwd string `json:"cwd,omitempty"`
+		//Trash renter files moved to the trash before the trash was kept per address. They are moved
+		//to the address of the next trash command
+		Trash []TrashEntry `json:"trash,omitempty"`
+		//Trashes renter files moved to the trash by renter rm --trash keyed by API address
+		Trashes map[string][]TrashEntry `json:"trashes,omitempty"`
+		//HostMaintenance hosts in maintenance mode keyed by API address
+		HostMaintenance map[string]HostMaintenance `json:"hostmaintenance,omitempty"`
+		//StorageSnapshots module directory sizes recorded by status storage
+		StorageSnapshots []StorageSnapshot `json:"storagesnapshots,omitempty"`
+		//FastestAddresses the addresses chosen by --select-fastest keyed by the addresses probed
+		FastestAddresses map[string]FastestAddress `json:"fastestaddresses,omitempty"`
+	}
+)
+
+// DefaultConfigDir returns the directory sia-json stores its config and state
+// in. The values for supported operating systems are:
+//
+// Linux:   $XDG_CONFIG_HOME/sia-json or $HOME/.config/sia-json
+// MacOS:   $HOME/Library/Application Support/sia-json
+// Windows: %APPDATA%\sia-json
+func DefaultConfigDir() string {
+	switch runtime.GOOS {
+	case "windows":
+		return filepath.Join(os.Getenv("APPDATA"), "sia-json")
+	case "darwin":
+		return filepath.Join(os.Getenv("HOME"), "Library", "Application Support", "sia-json")
+	default:
+		if dir := os.Getenv("XDG_CONFIG_HOME"); len(dir) > 0 {
+			return filepath.Join(dir, "sia-json")
+		}
+
+		return filepath.Join(os.Getenv("HOME"), ".config", "sia-json")
+	}
+}
+
+//LoadState loads the persistent state. A missing state file returns an empty state
+func LoadState() (state State, err error) {
+	buf, err := ioutil.ReadFile(filepath.Join(DefaultConfigDir(), "state.json"))
+
+	if os.IsNotExist(err) {
+		err = nil
+		return
+	} else if err != nil {
+		return
+	}
+
+	err = json.Unmarshal(buf, &state)
+
+	return
+}
+
+//SaveState atomically writes the persistent state
+func SaveState(state State) (err error) {
+	dir := DefaultConfigDir()
+
+	if err = os.MkdirAll(dir, 0700); err != nil {
+		return
+	}
+
+	buf, err := json.MarshalIndent(state, "", "\t")
+
+	if err != nil {
+		return
+	}
+
+	tmp := filepath.Join(dir, "state.json.tmp")
+
+	if err = ioutil.WriteFile(tmp, buf, 0600); err != nil {
+		return
+	}
+
+	return os.Rename(tmp, filepath.Join(dir, "state.json"))
+}
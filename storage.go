@@ -0,0 +1,223 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/n8maninger/siac-json/format"
+)
+
+type (
+	//StorageSnapshot the size of each module directory under a Sia dir at a point in time
+	StorageSnapshot struct {
+		Dir     string           `json:"dir"`
+		Time    time.Time        `json:"time"`
+		Modules map[string]int64 `json:"modules"`
+		Total   int64            `json:"total"`
+	}
+
+	//moduleStorage the size of a module directory in the storage report
+	moduleStorage struct {
+		Module string `json:"module"`
+		Size   int64  `json:"size"`
+		Human  string `json:"human"`
+	}
+
+	//storageReport the result of status storage
+	storageReport struct {
+		Dir            string          `json:"dir"`
+		Modules        []moduleStorage `json:"modules"`
+		Total          int64           `json:"total"`
+		Free           *uint64         `json:"free,omitempty"`
+		GrowthPerDay   *int64          `json:"growthperday,omitempty"`
+		GrowthSince    *time.Time      `json:"growthsince,omitempty"`
+		ExhaustionDate *time.Time      `json:"exhaustiondate,omitempty"`
+	}
+)
+
+const (
+	//storageSnapshotInterval the minimum time between recorded storage snapshots
+	storageSnapshotInterval = time.Hour
+	//storageGrowthWindow how far back status storage looks for a snapshot to measure growth from
+	storageGrowthWindow = 30 * 24 * time.Hour
+	//maxStorageSnapshots the number of snapshots kept in the state store
+	maxStorageSnapshots = 1000
+)
+
+//dirSize returns the total size of the regular files under dir
+func dirSize(dir string) (size int64, err error) {
+	err = filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			// files removed by siad while walking are skipped
+			if os.IsNotExist(err) {
+				return nil
+			}
+
+			return err
+		}
+
+		if info.Mode().IsRegular() {
+			size += info.Size()
+		}
+
+		return nil
+	})
+
+	return
+}
+
+//measureStorage returns the size of each module directory under the Sia dir
+func measureStorage(dir string) (snapshot StorageSnapshot, err error) {
+	entries, err := ioutil.ReadDir(dir)
+
+	if err != nil {
+		return
+	}
+
+	snapshot = StorageSnapshot{
+		Dir:     dir,
+		Time:    time.Now().UTC(),
+		Modules: make(map[string]int64),
+	}
+
+	for _, entry := range entries {
+		var size int64
+
+		if entry.IsDir() {
+			if size, err = dirSize(filepath.Join(dir, entry.Name())); err != nil {
+				return
+			}
+
+			snapshot.Modules[entry.Name()] = size
+		} else {
+			size = entry.Size()
+		}
+
+		snapshot.Total += size
+	}
+
+	return
+}
+
+//recordSnapshot adds the snapshot to the state if the last snapshot of the directory is older
+//than the snapshot interval, dropping the oldest snapshots past the limit
+func recordSnapshot(state *State, snapshot StorageSnapshot) bool {
+	for i := len(state.StorageSnapshots) - 1; i >= 0; i-- {
+		if prev := state.StorageSnapshots[i]; prev.Dir == snapshot.Dir {
+			if snapshot.Time.Sub(prev.Time) < storageSnapshotInterval {
+				return false
+			}
+
+			break
+		}
+	}
+
+	state.StorageSnapshots = append(state.StorageSnapshots, snapshot)
+
+	if len(state.StorageSnapshots) > maxStorageSnapshots {
+		state.StorageSnapshots = state.StorageSnapshots[len(state.StorageSnapshots)-maxStorageSnapshots:]
+	}
+
+	return true
+}
+
+//statusStorageCommand reports the size of each module directory under the Sia dir, how quickly
+//it is growing based on previous runs, and when the disk is projected to fill up
+func statusStorageCommand(cmd Command) (err error) {
+	dir := DefaultSiaDir()
+
+	if env := os.Getenv("SIA_DATA_DIR"); len(env) > 0 {
+		dir = env
+	}
+
+	if values := cmd.Params["sia-dir"]; len(values) > 0 {
+		dir = values[0]
+	}
+
+	if dir, err = filepath.Abs(dir); err != nil {
+		return
+	}
+
+	snapshot, err := measureStorage(dir)
+
+	if err != nil {
+		return
+	}
+
+	report := storageReport{
+		Dir:     dir,
+		Modules: []moduleStorage{},
+		Total:   snapshot.Total,
+	}
+
+	for module, size := range snapshot.Modules {
+		report.Modules = append(report.Modules, moduleStorage{
+			Module: module,
+			Size:   size,
+			Human:  format.FormatBytes(size),
+		})
+	}
+
+	sort.Slice(report.Modules, func(i, j int) bool {
+		return report.Modules[i].Size > report.Modules[j].Size
+	})
+
+	if free, err := diskFree(dir); err == nil {
+		report.Free = &free
+	} else {
+		notify(cmd, "unable to get free disk space: %s", err)
+	}
+
+	state, err := LoadState()
+
+	if err != nil {
+		return
+	}
+
+	// growth is measured from the oldest snapshot within the window
+	cutoff := snapshot.Time.Add(-storageGrowthWindow)
+
+	for _, prev := range state.StorageSnapshots {
+		if prev.Dir != dir || prev.Time.Before(cutoff) {
+			continue
+		}
+
+		elapsed := snapshot.Time.Sub(prev.Time)
+
+		if elapsed < storageSnapshotInterval {
+			break
+		}
+
+		growth := int64(float64(snapshot.Total-prev.Total) / elapsed.Hours() * 24)
+		since := prev.Time
+		report.GrowthPerDay, report.GrowthSince = &growth, &since
+
+		if growth > 0 && report.Free != nil {
+			days := float64(*report.Free) / float64(growth)
+
+			// projections past a century would overflow time.Duration and are meaningless anyway
+			if days < 36500 {
+				exhaustion := snapshot.Time.Add(time.Duration(days * 24 * float64(time.Hour)))
+				report.ExhaustionDate = &exhaustion
+			}
+		}
+
+		break
+	}
+
+	if report.GrowthPerDay == nil {
+		notify(cmd, "no earlier snapshot of %s to measure growth from. Run status storage again later", dir)
+	}
+
+	if recordSnapshot(&state, snapshot) {
+		if err = SaveState(state); err != nil {
+			return fmt.Errorf("unable to record storage snapshot: %s", err)
+		}
+	}
+
+	return writeJSON(report)
+}
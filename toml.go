@@ -0,0 +1,214 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+//decodeTOML decodes the subset of TOML used by the config file: tables, arrays of tables, and
+//key/value pairs holding strings, integers, floats, booleans, and single line arrays
+func decodeTOML(r io.Reader) (doc map[string]interface{}, err error) {
+	doc = make(map[string]interface{})
+	table := doc
+	scanner := bufio.NewScanner(r)
+	lineNum := 0
+
+	for scanner.Scan() {
+		lineNum++
+		line := strings.TrimSpace(stripTOMLComment(scanner.Text()))
+
+		if len(line) == 0 {
+			continue
+		}
+
+		switch {
+		case strings.HasPrefix(line, "[["):
+			if !strings.HasSuffix(line, "]]") {
+				return nil, fmt.Errorf("line %d: unterminated array of tables", lineNum)
+			}
+
+			if table, err = tomlArrayTable(doc, splitTOMLKey(line[2:len(line)-2])); err != nil {
+				return nil, fmt.Errorf("line %d: %s", lineNum, err)
+			}
+		case strings.HasPrefix(line, "["):
+			if !strings.HasSuffix(line, "]") {
+				return nil, fmt.Errorf("line %d: unterminated table", lineNum)
+			}
+
+			if table, err = tomlTable(doc, splitTOMLKey(line[1:len(line)-1])); err != nil {
+				return nil, fmt.Errorf("line %d: %s", lineNum, err)
+			}
+		default:
+			i := strings.Index(line, "=")
+
+			if i == -1 {
+				return nil, fmt.Errorf("line %d: expected key = value", lineNum)
+			}
+
+			keys := splitTOMLKey(line[:i])
+			value, err := parseTOMLValue(strings.TrimSpace(line[i+1:]))
+
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %s", lineNum, err)
+			}
+
+			parent, err := tomlTable(table, keys[:len(keys)-1])
+
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %s", lineNum, err)
+			}
+
+			parent[keys[len(keys)-1]] = value
+		}
+	}
+
+	err = scanner.Err()
+
+	return
+}
+
+//stripTOMLComment removes a trailing comment that is not inside a string
+func stripTOMLComment(line string) string {
+	var quote rune
+
+	for i, c := range line {
+		switch {
+		case quote != 0 && c == quote:
+			quote = 0
+		case quote == 0 && (c == '"' || c == '\''):
+			quote = c
+		case quote == 0 && c == '#':
+			return line[:i]
+		}
+	}
+
+	return line
+}
+
+//splitTOMLKey splits a dotted key into its parts, removing quotes
+func splitTOMLKey(key string) (parts []string) {
+	for _, part := range strings.Split(key, ".") {
+		parts = append(parts, strings.Trim(strings.TrimSpace(part), `"'`))
+	}
+
+	return
+}
+
+//tomlTable returns the nested table at the key path, creating tables as needed. If the path ends
+//at an array of tables the last table in the array is returned
+func tomlTable(root map[string]interface{}, keys []string) (table map[string]interface{}, err error) {
+	table = root
+
+	for _, key := range keys {
+		switch next := table[key].(type) {
+		case nil:
+			child := make(map[string]interface{})
+			table[key] = child
+			table = child
+		case map[string]interface{}:
+			table = next
+		case []map[string]interface{}:
+			table = next[len(next)-1]
+		default:
+			return nil, fmt.Errorf("%s is not a table", key)
+		}
+	}
+
+	return
+}
+
+//tomlArrayTable appends a new table to the array of tables at the key path
+func tomlArrayTable(root map[string]interface{}, keys []string) (table map[string]interface{}, err error) {
+	parent, err := tomlTable(root, keys[:len(keys)-1])
+
+	if err != nil {
+		return
+	}
+
+	key := keys[len(keys)-1]
+	table = make(map[string]interface{})
+
+	switch existing := parent[key].(type) {
+	case nil:
+		parent[key] = []map[string]interface{}{table}
+	case []map[string]interface{}:
+		parent[key] = append(existing, table)
+	default:
+		return nil, fmt.Errorf("%s is not an array of tables", key)
+	}
+
+	return
+}
+
+//parseTOMLValue parses a single value
+func parseTOMLValue(s string) (interface{}, error) {
+	switch {
+	case len(s) == 0:
+		return nil, fmt.Errorf("missing value")
+	case strings.HasPrefix(s, `"`):
+		return strconv.Unquote(s)
+	case strings.HasPrefix(s, "'"):
+		if len(s) < 2 || !strings.HasSuffix(s, "'") {
+			return nil, fmt.Errorf("unterminated string %s", s)
+		}
+
+		return s[1 : len(s)-1], nil
+	case strings.HasPrefix(s, "["):
+		return parseTOMLArray(s)
+	case s == "true" || s == "false":
+		return s == "true", nil
+	}
+
+	num := strings.Replace(s, "_", "", -1)
+
+	if i, err := strconv.ParseInt(num, 10, 64); err == nil {
+		return i, nil
+	}
+
+	if f, err := strconv.ParseFloat(num, 64); err == nil {
+		return f, nil
+	}
+
+	return nil, fmt.Errorf("invalid value %s", s)
+}
+
+//parseTOMLArray parses a single line array of values
+func parseTOMLArray(s string) (values []interface{}, err error) {
+	if !strings.HasSuffix(s, "]") {
+		return nil, fmt.Errorf("unterminated array %s", s)
+	}
+
+	var quote rune
+	inner := s[1 : len(s)-1]
+	start := 0
+	values = []interface{}{}
+
+	for i, c := range inner + "," {
+		switch {
+		case quote != 0 && c == quote:
+			quote = 0
+		case quote == 0 && (c == '"' || c == '\''):
+			quote = c
+		case quote == 0 && c == ',':
+			elem := strings.TrimSpace((inner + ",")[start:i])
+			start = i + 1
+
+			if len(elem) == 0 {
+				continue
+			}
+
+			value, err := parseTOMLValue(elem)
+
+			if err != nil {
+				return nil, err
+			}
+
+			values = append(values, value)
+		}
+	}
+
+	return
+}
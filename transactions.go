@@ -0,0 +1,135 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strconv"
+)
+
+//defaultTransactionsWindow the number of blocks requested at a time by wallet transactions all
+const defaultTransactionsWindow = 10000
+
+//transactionsWindow a /wallet/transactions response with the transactions left undecoded so
+//they are written unchanged
+type transactionsWindow struct {
+	ConfirmedTransactions   []json.RawMessage `json:"confirmedtransactions"`
+	UnconfirmedTransactions []json.RawMessage `json:"unconfirmedtransactions"`
+}
+
+//heightParam returns the height param, or the default if it was not given
+func heightParam(cmd Command, key string, def uint64) (uint64, error) {
+	values := cmd.Params[key]
+
+	if len(values) == 0 {
+		return def, nil
+	}
+
+	height, err := strconv.ParseUint(values[0], 10, 64)
+
+	if err != nil {
+		return 0, fmt.Errorf("invalid --%s %q", key, values[0])
+	}
+
+	return height, nil
+}
+
+//appendTransactions appends the transactions that have not been seen yet
+func appendTransactions(merged []json.RawMessage, txns []json.RawMessage, seen map[string]bool) ([]json.RawMessage, error) {
+	for _, txn := range txns {
+		var id struct {
+			TransactionID string `json:"transactionid"`
+		}
+
+		if err := json.Unmarshal(txn, &id); err != nil {
+			return merged, err
+		}
+
+		if seen[id.TransactionID] {
+			continue
+		}
+
+		seen[id.TransactionID] = true
+		merged = append(merged, txn)
+	}
+
+	return merged, nil
+}
+
+//walletTransactionsAllCommand requests /wallet/transactions in windows of --window blocks from
+//--startheight, default 0, to --endheight, default the current height, and writes the
+//transactions of every window as one JSON array. Unconfirmed transactions are written last
+func walletTransactionsAllCommand(cmd Command) (err error) {
+	var consensus consensusResponse
+
+	if err = callAPI(cmd, "GET", "/consensus", nil, &consensus); err != nil {
+		return
+	}
+
+	start, err := heightParam(cmd, "startheight", 0)
+
+	if err != nil {
+		return
+	}
+
+	end, err := heightParam(cmd, "endheight", consensus.Height)
+
+	if err != nil {
+		return
+	}
+
+	window, err := heightParam(cmd, "window", defaultTransactionsWindow)
+
+	if err != nil {
+		return
+	} else if window == 0 {
+		return fmt.Errorf("--window must be greater than 0")
+	}
+
+	if start > end {
+		return fmt.Errorf("--startheight %d is after --endheight %d", start, end)
+	}
+
+	merged := []json.RawMessage{}
+	seen := make(map[string]bool)
+	var unconfirmed []json.RawMessage
+
+	for low := start; low <= end; low += window {
+		high := low + window - 1
+
+		if high > end || high < low {
+			high = end
+		}
+
+		var resp transactionsWindow
+
+		params := url.Values{
+			"startheight": {strconv.FormatUint(low, 10)},
+			"endheight":   {strconv.FormatUint(high, 10)},
+		}
+
+		if err = callAPI(cmd, "GET", "/wallet/transactions", params, &resp); err != nil {
+			return fmt.Errorf("heights %d-%d: %s", low, high, err)
+		}
+
+		notify(cmd, "heights %d-%d: %d transactions", low, high, len(resp.ConfirmedTransactions))
+
+		// a transaction is only in one block, but the windows are deduplicated in case siad
+		// includes the blocks at the edges of a window twice
+		if merged, err = appendTransactions(merged, resp.ConfirmedTransactions, seen); err != nil {
+			return
+		}
+
+		unconfirmed = resp.UnconfirmedTransactions
+
+		if high == end {
+			break
+		}
+	}
+
+	if merged, err = appendTransactions(merged, unconfirmed, seen); err != nil {
+		return
+	}
+
+	return writeJSON(merged)
+}
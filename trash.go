@@ -0,0 +1,242 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/n8maninger/siac-json/format"
+)
+
+const (
+	//trashDir the renter directory files are moved to by renter rm --trash
+	trashDir = ".trash"
+
+	//defaultTrashRetention how long files stay in the trash before they are purged
+	defaultTrashRetention = 7 * 24 * time.Hour
+)
+
+//argSiaPath joins the command's positional args starting at i into a siapath resolved against the working directory
+func argSiaPath(cmd Command, state State, i int) (string, error) {
+	if len(cmd.Args) <= i {
+		return "", fmt.Errorf("missing siapath")
+	}
+
+	return resolveSiaPath(state.Cwd, strings.Join(cmd.Args[i:], "/")), nil
+}
+
+//trashRetention returns the retention window from --retention or the default
+func trashRetention(cmd Command) (time.Duration, error) {
+	if values := cmd.Params["retention"]; len(values) > 0 {
+		return format.ParseDuration(values[0])
+	}
+
+	return defaultTrashRetention, nil
+}
+
+//expired returns true if the entry's retention window has passed. Entries trashed before expiry
+//times were recorded use the default window
+func (entry TrashEntry) expired(now time.Time) bool {
+	if entry.Expires.IsZero() {
+		return now.After(entry.Deleted.Add(defaultTrashRetention))
+	}
+
+	return now.After(entry.Expires)
+}
+
+//trash returns the trash of the command's API address
+func trash(cmd Command, state *State) []TrashEntry {
+	if len(state.Trash) > 0 {
+		if state.Trashes == nil {
+			state.Trashes = make(map[string][]TrashEntry)
+		}
+
+		state.Trashes[cmd.APIAddress] = append(state.Trash, state.Trashes[cmd.APIAddress]...)
+		state.Trash = nil
+	}
+
+	return state.Trashes[cmd.APIAddress]
+}
+
+//setTrash replaces the trash of the command's API address
+func setTrash(cmd Command, state *State, entries []TrashEntry) {
+	if len(entries) == 0 {
+		delete(state.Trashes, cmd.APIAddress)
+		return
+	}
+
+	if state.Trashes == nil {
+		state.Trashes = make(map[string][]TrashEntry)
+	}
+
+	state.Trashes[cmd.APIAddress] = entries
+}
+
+//purgeTrash permanently deletes the entries of the command's trash that purge returns true for.
+//Entries that fail to delete are kept
+func purgeTrash(cmd Command, state *State, purge func(TrashEntry) bool) (purged int, err error) {
+	var kept []TrashEntry
+
+	for _, entry := range trash(cmd, state) {
+		if !purge(entry) {
+			kept = append(kept, entry)
+			continue
+		}
+
+		if delErr := callAPI(cmd, "POST", "/renter/delete/"+entry.TrashPath, nil, nil); delErr != nil {
+			notify(cmd, "unable to purge %s: %s", entry.SiaPath, delErr)
+			kept = append(kept, entry)
+			continue
+		}
+
+		purged++
+	}
+
+	setTrash(cmd, state, kept)
+	err = SaveState(*state)
+
+	return
+}
+
+//renterRmCommand deletes a renter file, or moves it to the trash with --trash: renter rm <siapath>.
+//Trashed files are purged once the --retention they were trashed with has passed
+func renterRmCommand(cmd Command) (err error) {
+	state, err := LoadState()
+
+	if err != nil {
+		return
+	}
+
+	siapath, err := argSiaPath(cmd, state, 2)
+
+	if err != nil {
+		return
+	}
+
+	if _, ok := cmd.Params["trash"]; !ok {
+		return callAPI(cmd, "POST", "/renter/delete/"+siapath, nil, nil)
+	}
+
+	retention, err := trashRetention(cmd)
+
+	if err != nil {
+		return
+	}
+
+	now := time.Now()
+	entry := TrashEntry{
+		SiaPath:   siapath,
+		TrashPath: path.Join(trashDir, strconv.FormatInt(now.Unix(), 10), siapath),
+		Deleted:   now,
+		Expires:   now.Add(retention),
+	}
+
+	if err = callAPI(cmd, "POST", "/renter/rename/"+siapath, url.Values{"newsiapath": {entry.TrashPath}}, nil); err != nil {
+		return
+	}
+
+	setTrash(cmd, &state, append(trash(cmd, &state), entry))
+
+	if err = SaveState(state); err != nil {
+		return
+	}
+
+	fmt.Printf("moved %s to the trash\n", siapath)
+
+	// each entry keeps the retention it was trashed with
+	_, err = purgeTrash(cmd, &state, func(entry TrashEntry) bool { return entry.expired(now) })
+
+	return
+}
+
+//trashListCommand lists the files in the trash
+func trashListCommand(cmd Command) (err error) {
+	state, err := LoadState()
+
+	if err != nil {
+		return
+	}
+
+	entries := trash(cmd, &state)
+
+	if entries == nil {
+		entries = []TrashEntry{}
+	}
+
+	return writeJSON(entries)
+}
+
+//trashRestoreCommand moves the most recently deleted copy of a file out of the trash: renter trash restore <siapath>
+func trashRestoreCommand(cmd Command) (err error) {
+	state, err := LoadState()
+
+	if err != nil {
+		return
+	}
+
+	siapath, err := argSiaPath(cmd, state, 3)
+
+	if err != nil {
+		return
+	}
+
+	entries := trash(cmd, &state)
+
+	for i := len(entries) - 1; i >= 0; i-- {
+		entry := entries[i]
+
+		if entry.SiaPath != siapath {
+			continue
+		}
+
+		if err = callAPI(cmd, "POST", "/renter/rename/"+entry.TrashPath, url.Values{"newsiapath": {entry.SiaPath}}, nil); err != nil {
+			return
+		}
+
+		setTrash(cmd, &state, append(entries[:i], entries[i+1:]...))
+
+		if err = SaveState(state); err != nil {
+			return
+		}
+
+		fmt.Printf("restored %s\n", siapath)
+
+		return
+	}
+
+	return fmt.Errorf("%s is not in the trash", siapath)
+}
+
+//trashPurgeCommand permanently deletes files in the trash, optionally only those older than --older-than
+func trashPurgeCommand(cmd Command) (err error) {
+	state, err := LoadState()
+
+	if err != nil {
+		return
+	}
+
+	cutoff := time.Now()
+
+	if values := cmd.Params["older-than"]; len(values) > 0 {
+		olderThan, err := format.ParseDuration(values[0])
+
+		if err != nil {
+			return err
+		}
+
+		cutoff = cutoff.Add(-olderThan)
+	}
+
+	purged, err := purgeTrash(cmd, &state, func(entry TrashEntry) bool { return !entry.Deleted.After(cutoff) })
+
+	if err != nil {
+		return
+	}
+
+	fmt.Printf("purged %d files from the trash\n", purged)
+
+	return
+}
@@ -0,0 +1,168 @@
+package main
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/n8maninger/siac-json/endpoints"
+)
+
+//idFormats the parameter formats that describe a 64 character hex identifier
+var idFormats = map[endpoints.ParamFormat]string{
+	endpoints.TransactionIDFormat: "transaction ID",
+	endpoints.ContractIDFormat:    "contract ID",
+	endpoints.MerkleRootFormat:    "merkle root",
+	endpoints.BlockIDFormat:       "block ID",
+}
+
+//validateID checks that the value is a correctly formatted identifier of the given format
+func validateID(value string, format endpoints.ParamFormat) error {
+	name, ok := idFormats[format]
+
+	if !ok {
+		return fmt.Errorf("unknown identifier type %q", format)
+	}
+
+	if len(value) != 64 {
+		return fmt.Errorf("invalid %s %q: expected 64 hex characters, got %d", name, value, len(value))
+	}
+
+	if _, err := hex.DecodeString(value); err != nil {
+		return fmt.Errorf("invalid %s %q: not hex encoded", name, value)
+	}
+
+	return nil
+}
+
+//paramValues returns the values supplied for the endpoint param, either from the request path or the command's params
+func paramValues(cmd Command, param endpoints.CommandParam) []string {
+	if param.Location != endpoints.URLParam {
+		return cmd.Params[param.Key]
+	}
+
+	pathSegments := strings.Split(cmd.RequestPath, "/")
+
+	for i, seg := range strings.Split(cmd.Endpoint.Path, "/") {
+		if seg == ":"+param.Key && i < len(pathSegments) {
+			return []string{pathSegments[i]}
+		}
+
+		// wildcards match the rest of the path
+		if seg == "*"+param.Key && i < len(pathSegments) && len(strings.Join(pathSegments[i:], "")) > 0 {
+			return []string{strings.Join(pathSegments[i:], "/")}
+		}
+	}
+
+	return nil
+}
+
+//validateType checks that the value of the param is of the param's type. Formatted params have
+//already been converted to siad's format. Empty values are left to siad
+func validateType(param endpoints.CommandParam, value string) error {
+	if len(value) == 0 {
+		return nil
+	}
+
+	name := "--" + param.Key + " value"
+
+	if param.Location == endpoints.URLParam {
+		name = param.Key + " in the request path"
+	}
+
+	switch endpoints.TypeOf(param) {
+	case endpoints.IntType:
+		if _, err := strconv.ParseUint(value, 10, 64); err != nil {
+			return fmt.Errorf("invalid %s %q: expected a whole number", name, value)
+		}
+	case endpoints.BoolType:
+		if _, err := strconv.ParseBool(value); err != nil {
+			return fmt.Errorf("invalid %s %q: expected true or false", name, value)
+		}
+	case endpoints.CurrencyType:
+		if !hastingsRe.MatchString(value) {
+			return fmt.Errorf("invalid %s %q: expected hastings or an amount such as 100SC", name, value)
+		}
+	case endpoints.HashType:
+		if _, err := hex.DecodeString(value); err != nil || len(value) != 64 {
+			return fmt.Errorf("invalid %s %q: expected 64 hex characters", name, value)
+		}
+	case endpoints.AddressType:
+		if _, err := hex.DecodeString(value); err != nil || len(value) != 76 {
+			return fmt.Errorf("invalid %s %q: expected an address of 76 hex characters", name, value)
+		}
+	}
+
+	return nil
+}
+
+//validateParams validates the format and type of the command's params before the request is
+//sent, so a mistyped value fails with the param named instead of a generic error from siad
+func validateParams(cmd Command) error {
+	for _, param := range cmd.Endpoint.Params {
+		_, isID := idFormats[param.Formatter]
+
+		for _, value := range paramValues(cmd, param) {
+			if isID {
+				if err := validateID(value, param.Formatter); err != nil {
+					return err
+				}
+
+				continue
+			}
+
+			if err := validateType(param, value); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+//checkRequiredParams returns an error naming the first required param of the endpoint that is
+//missing. Body params are not checked when the body is sent from a file, --json, --data-raw,
+//--data-binary or stdin
+func checkRequiredParams(cmd Command) error {
+	rawBody := len(cmd.UploadFile) > 0 || len(cmd.JSONBody) > 0 || cmd.RawBodySet || cmd.Method == "POST" && len(cmd.Params) == 0 && stdinPiped()
+
+	for _, param := range cmd.Endpoint.Params {
+		if !param.Required || param.Location == endpoints.BodyParam && rawBody {
+			continue
+		}
+
+		if values := paramValues(cmd, param); len(values) > 0 && len(values[0]) > 0 {
+			continue
+		}
+
+		// the help path stops before the first path param
+		usage := strings.Fields(strings.Replace(strings.Split(strings.Split(cmd.Endpoint.Path, ":")[0], "*")[0], "/", " ", -1))
+
+		if param.Location == endpoints.URLParam {
+			return fmt.Errorf("missing %s in the request path of %s %s: %s. See help %s", param.Key, cmd.Endpoint.Method, cmd.Endpoint.Path, param.HelpText, strings.Join(usage, " "))
+		}
+
+		return fmt.Errorf("missing required param --%s for %s %s: %s. See help %s", param.Key, cmd.Endpoint.Method, cmd.Endpoint.Path, param.HelpText, strings.Join(usage, " "))
+	}
+
+	return nil
+}
+
+//validateIDCommand validates an identifier passed on the command line: validate id <value> --type txid
+func validateIDCommand(cmd Command) error {
+	value := cmd.Args[2]
+	format := endpoints.TransactionIDFormat
+
+	if types := cmd.Params["type"]; len(types) > 0 {
+		format = endpoints.ParamFormat(strings.ToLower(types[0]))
+	}
+
+	if err := validateID(value, format); err != nil {
+		return err
+	}
+
+	fmt.Printf("%s is a valid %s\n", value, idFormats[format])
+
+	return nil
+}
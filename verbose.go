@@ -0,0 +1,214 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/n8maninger/siac-json/format"
+)
+
+type (
+	//verboseTransport logs each request and response to stderr
+	verboseTransport struct {
+		next http.RoundTripper
+	}
+)
+
+const (
+	//redacted replaces secrets in verbose output
+	redacted = "REDACTED"
+
+	//maxVerboseBody request bodies larger than this are logged by size only
+	maxVerboseBody = 64 << 10
+)
+
+//sensitiveParams params that are redacted from logged URLs, form bodies and JSON bodies
+var sensitiveParams = map[string]bool{
+	"apipassword":        true,
+	"encryptionpassword": true,
+	"newpassword":        true,
+	"password":           true,
+	"seed":               true,
+}
+
+//redactValues returns a copy of the values with sensitive params redacted
+func redactValues(values url.Values) url.Values {
+	redactedValues := make(url.Values, len(values))
+
+	for key, v := range values {
+		if sensitiveParams[strings.ToLower(key)] {
+			v = []string{redacted}
+		}
+
+		redactedValues[key] = v
+	}
+
+	return redactedValues
+}
+
+//redactURL returns the URL with sensitive query params redacted
+func redactURL(u *url.URL) string {
+	if len(u.RawQuery) == 0 {
+		return u.String()
+	}
+
+	values, err := url.ParseQuery(u.RawQuery)
+
+	if err != nil {
+		return u.String()
+	}
+
+	redactedURL := *u
+	redactedURL.RawQuery = redactValues(values).Encode()
+
+	return redactedURL.String()
+}
+
+//writeVerboseHeaders writes the headers in sorted order, redacting credentials
+func writeVerboseHeaders(w io.Writer, prefix string, header http.Header) {
+	keys := make([]string, 0, len(header))
+
+	for key := range header {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		for _, value := range header[key] {
+			switch key {
+			case "Authorization", "Proxy-Authorization":
+				value = strings.SplitN(value, " ", 2)[0] + " " + redacted
+			case "Cookie", "X-Api-Key":
+				value = redacted
+			}
+
+			fmt.Fprintf(w, "%s%s: %s\n", prefix, key, value)
+		}
+	}
+}
+
+//redactJSON returns a copy of the decoded JSON value with the values of sensitive keys redacted
+//in every object it contains
+func redactJSON(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		redactedObject := make(map[string]interface{}, len(v))
+
+		for key, value := range v {
+			if sensitiveParams[strings.ToLower(key)] {
+				value = redacted
+			} else {
+				value = redactJSON(value)
+			}
+
+			redactedObject[key] = value
+		}
+
+		return redactedObject
+	case []interface{}:
+		redactedArray := make([]interface{}, len(v))
+
+		for i, value := range v {
+			redactedArray[i] = redactJSON(value)
+		}
+
+		return redactedArray
+	}
+
+	return v
+}
+
+//redactJSONBody returns the JSON body with sensitive keys redacted. Bodies that cannot be
+//decoded are not logged, they could still contain secrets
+func redactJSONBody(buf []byte) string {
+	var v interface{}
+
+	dec := json.NewDecoder(bytes.NewReader(buf))
+	dec.UseNumber()
+
+	if err := dec.Decode(&v); err != nil {
+		return fmt.Sprintf("<%s>", redacted)
+	}
+
+	redactedBody, err := json.Marshal(redactJSON(v))
+
+	if err != nil {
+		return fmt.Sprintf("<%s>", redacted)
+	}
+
+	return string(redactedBody)
+}
+
+//verboseBody returns the request body to log. Only bodies that can be read again are logged in
+//full, streamed uploads are logged by size
+func verboseBody(req *http.Request) string {
+	if req.GetBody == nil || req.ContentLength > maxVerboseBody {
+		return fmt.Sprintf("<%s streamed>", format.FormatBytes(req.ContentLength))
+	}
+
+	body, err := req.GetBody()
+
+	if err != nil {
+		return fmt.Sprintf("<unreadable body: %s>", err)
+	}
+
+	defer body.Close()
+
+	buf, err := ioutil.ReadAll(body)
+
+	if err != nil {
+		return fmt.Sprintf("<unreadable body: %s>", err)
+	}
+
+	if req.Header.Get("Content-Type") == "application/x-www-form-urlencoded" {
+		if values, err := url.ParseQuery(string(buf)); err == nil {
+			return redactValues(values).Encode()
+		}
+	}
+
+	if trimmed := bytes.TrimSpace(buf); strings.HasPrefix(req.Header.Get("Content-Type"), "application/json") ||
+		bytes.HasPrefix(trimmed, []byte("{")) || bytes.HasPrefix(trimmed, []byte("[")) {
+		return redactJSONBody(buf)
+	}
+
+	return string(buf)
+}
+
+//writeRequestLog writes the request line, headers and body with each line prefixed
+func writeRequestLog(w io.Writer, prefix string, req *http.Request) {
+	fmt.Fprintf(w, "%s%s %s\n", prefix, req.Method, redactURL(req.URL))
+	writeVerboseHeaders(w, prefix, req.Header)
+
+	if req.Body != nil && req.Body != http.NoBody {
+		fmt.Fprintf(w, "%s\n%s%s\n", strings.TrimSpace(prefix), prefix, verboseBody(req))
+	}
+}
+
+//RoundTrip logs the request, sends it and logs the response status and timing
+func (t *verboseTransport) RoundTrip(req *http.Request) (resp *http.Response, err error) {
+	writeRequestLog(os.Stderr, "> ", req)
+
+	start := time.Now()
+	resp, err = t.next.RoundTrip(req)
+	elapsed := time.Since(start).Round(time.Millisecond)
+
+	if err != nil {
+		os.Stderr.WriteString(fmt.Sprintf("< request failed after %s: %s\n", elapsed, err))
+		return
+	}
+
+	os.Stderr.WriteString(fmt.Sprintf("< %s %s in %s\n", resp.Proto, resp.Status, elapsed))
+	writeVerboseHeaders(os.Stderr, "< ", resp.Header)
+
+	return
+}
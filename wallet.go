@@ -0,0 +1,283 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/n8maninger/siac-json/format"
+)
+
+type (
+	//walletInitResponse the response of /wallet/init
+	walletInitResponse struct {
+		PrimarySeed string `json:"primaryseed"`
+	}
+
+	//walletBalances the wallet balances before and after an import
+	walletBalances struct {
+		Siacoins string `json:"siacoins"`
+		Siafunds string `json:"siafunds"`
+	}
+
+	//walletImportReport the result of wallet import legacy
+	walletImportReport struct {
+		Endpoint string         `json:"endpoint"`
+		Files    []string       `json:"files"`
+		Before   walletBalances `json:"before"`
+		After    walletBalances `json:"after"`
+		Changed  bool           `json:"changed"`
+	}
+)
+
+//legacyFile checks the path of a legacy wallet file and returns true if it is a siag key file,
+//named *.siakey by siag. Anything else is assumed to be a v0.3.3.x wallet file. siad reads the
+//files, so they are not opened here and siad reports missing files
+func legacyFile(path string) (siag bool, err error) {
+	// the files are read by siad, so relative paths would be resolved against siad's directory
+	if !filepath.IsAbs(path) {
+		return false, fmt.Errorf("%s must be an absolute path", path)
+	}
+
+	return strings.HasSuffix(path, ".siakey"), nil
+}
+
+//balances returns the wallet's confirmed balances
+func balances(cmd Command) (b walletBalances, rescanning bool, err error) {
+	var wallet walletResponse
+
+	if err = callAPI(cmd, "GET", "/wallet", nil, &wallet); err != nil {
+		return
+	}
+
+	return walletBalances{
+		Siacoins: wallet.ConfirmedSiacoinBalance,
+		Siafunds: wallet.SiafundBalance,
+	}, wallet.Rescanning, nil
+}
+
+//walletImportLegacyCommand imports a v0.3.3.x wallet file or siag key files into the wallet and
+//waits for the balance to update. Files must exist on the machine running siad
+func walletImportLegacyCommand(cmd Command) (err error) {
+	var files []string
+
+	for _, file := range strings.Split(cmd.UploadFile, ",") {
+		if file = strings.TrimSpace(file); len(file) > 0 {
+			files = append(files, file)
+		}
+	}
+
+	if len(files) == 0 {
+		return fmt.Errorf("--file is required")
+	}
+
+	siagFiles := 0
+
+	for _, file := range files {
+		siag, err := legacyFile(file)
+
+		if err != nil {
+			return err
+		}
+
+		if siag {
+			siagFiles++
+		}
+	}
+
+	report := walletImportReport{Files: files}
+	params := url.Values{}
+
+	switch {
+	case siagFiles == len(files):
+		report.Endpoint = "/wallet/siagkey"
+		params.Set("keyfiles", strings.Join(files, ","))
+	case len(files) == 1:
+		report.Endpoint = "/wallet/033x"
+		params.Set("source", files[0])
+	default:
+		return fmt.Errorf("only siag key files can be imported together")
+	}
+
+	interval, timeout := 10*time.Second, 10*time.Minute
+
+	if values := cmd.Params["poll-interval"]; len(values) > 0 {
+		if interval, err = format.ParseDuration(values[0]); err != nil {
+			return
+		}
+	}
+
+	if values := cmd.Params["wait-timeout"]; len(values) > 0 {
+		if timeout, err = format.ParseDuration(values[0]); err != nil {
+			return
+		}
+	}
+
+	var password string
+
+	if len(cmd.PasswordFile) > 0 {
+		password, err = loadPasswordFile(cmd)
+	} else {
+		password, err = promptPassword(cmd, "Wallet encryption password")
+	}
+
+	if err != nil {
+		return
+	}
+
+	params.Set("encryptionpassword", password)
+
+	if report.Before, _, err = balances(cmd); err != nil {
+		return
+	}
+
+	if err = callAPI(cmd, "POST", report.Endpoint, params, nil); err != nil {
+		return
+	}
+
+	notify(cmd, "imported %s, waiting for the balance to update", strings.Join(files, ", "))
+
+	deadline := time.Now().Add(timeout)
+
+	for {
+		var rescanning bool
+
+		if report.After, rescanning, err = balances(cmd); err != nil {
+			return
+		}
+
+		report.Changed = report.After != report.Before
+
+		if report.Changed && !rescanning {
+			break
+		}
+
+		if time.Now().Add(interval).After(deadline) {
+			notify(cmd, "balance unchanged after %s. The wallet may still be rescanning", timeout)
+			break
+		}
+
+		time.Sleep(interval)
+	}
+
+	return writeJSON(report)
+}
+
+//normalizeSeed lowercases the seed and collapses whitespace so re-entered seeds compare equal
+func normalizeSeed(seed string) string {
+	return strings.Join(strings.Fields(strings.ToLower(seed)), " ")
+}
+
+//verifySeed clears the seed from the terminal and asks the user to type it back, showing it again
+//until the entry matches
+func verifySeed(seed string) error {
+	for {
+		if _, err := readLine("Write the seed down and press enter to continue "); err != nil {
+			return err
+		}
+
+		if isTerminal(os.Stderr) {
+			// clear the screen and scrollback so the seed is not left on screen
+			os.Stderr.WriteString("\033[H\033[2J\033[3J")
+		}
+
+		entry, err := readLine("Re-enter the seed to confirm it was recorded: ")
+
+		if err != nil {
+			return err
+		}
+
+		if normalizeSeed(entry) == normalizeSeed(seed) {
+			return nil
+		}
+
+		os.Stderr.WriteString(fmt.Sprintf("\nthe seed does not match. Your seed is:\n\n%s\n\n", seed))
+	}
+}
+
+//walletSetupCommand initializes a new wallet, makes the user confirm the seed was recorded,
+//optionally backs the wallet up and checks the wallet unlocks with the new password
+func walletSetupCommand(cmd Command) (err error) {
+	if cmd.Machine || !isTerminal(os.Stdin) {
+		return fmt.Errorf("wallet setup is interactive and needs a terminal")
+	}
+
+	backup := ""
+
+	if values := cmd.Params["backup"]; len(values) > 0 {
+		// the backup is written by siad, so a relative path would be relative to siad's directory
+		if backup = values[0]; !filepath.IsAbs(backup) {
+			return fmt.Errorf("--backup must be an absolute path on the siad machine")
+		}
+	}
+
+	var wallet walletResponse
+
+	if err = callAPI(cmd, "GET", "/wallet", nil, &wallet); err != nil {
+		return
+	}
+
+	if wallet.Encrypted {
+		return fmt.Errorf("the wallet is already initialized")
+	}
+
+	var password string
+
+	if len(cmd.PasswordFile) > 0 {
+		password, err = loadPasswordFile(cmd)
+	} else {
+		password, err = promptNewPassword(cmd, "Wallet encryption password")
+	}
+
+	if err != nil {
+		return
+	}
+
+	params := url.Values{"encryptionpassword": {password}}
+
+	if values := cmd.Params["dictionary"]; len(values) > 0 {
+		params.Set("dictionary", values[0])
+	}
+
+	var init walletInitResponse
+
+	if err = callAPI(cmd, "POST", "/wallet/init", params, &init); err != nil {
+		return
+	}
+
+	os.Stderr.WriteString(fmt.Sprintf("\nYour wallet seed is:\n\n%s\n\n", init.PrimarySeed))
+	os.Stderr.WriteString("The seed is the only way to recover the wallet's funds. Store it somewhere safe and offline.\n\n")
+
+	if err = verifySeed(init.PrimarySeed); err != nil {
+		return
+	}
+
+	fmt.Println("seed confirmed")
+
+	if len(backup) > 0 {
+		if err = callAPI(cmd, "GET", "/wallet/backup", url.Values{"destination": {backup}}, nil); err != nil {
+			return fmt.Errorf("unable to back up the wallet: %s", err)
+		}
+
+		fmt.Printf("encrypted wallet backup written to %s\n", backup)
+	}
+
+	if err = callAPI(cmd, "POST", "/wallet/unlock", url.Values{"encryptionpassword": {password}}, nil); err != nil {
+		return fmt.Errorf("the wallet was initialized but could not be unlocked: %s", err)
+	}
+
+	if err = callAPI(cmd, "GET", "/wallet", nil, &wallet); err != nil {
+		return
+	}
+
+	if !wallet.Unlocked {
+		return fmt.Errorf("the wallet was initialized but is still locked")
+	}
+
+	fmt.Println("wallet initialized and unlocked")
+
+	return
+}
@@ -0,0 +1,197 @@
+package main
+
+import (
+	"fmt"
+	"math/big"
+	"sort"
+
+	"github.com/n8maninger/siac-json/format"
+)
+
+type (
+	//unspentOutput the fields of a /wallet/unspent output used by sia-json
+	unspentOutput struct {
+		ID          string `json:"id"`
+		FundType    string `json:"fundtype"`
+		Value       string `json:"value"`
+		IsWatchOnly bool   `json:"iswatchonly"`
+	}
+
+	//walletUnspentResponse the response of /wallet/unspent
+	walletUnspentResponse struct {
+		Outputs []unspentOutput `json:"outputs"`
+	}
+
+	//tpoolFeeResponse the response of /tpool/fee, in hastings per byte
+	tpoolFeeResponse struct {
+		Minimum string `json:"minimum"`
+		Maximum string `json:"maximum"`
+	}
+
+	//outputBucket the outputs with a value in a range. Amounts are in hastings
+	outputBucket struct {
+		Range   string `json:"range"`
+		Outputs int    `json:"outputs"`
+		Total   string `json:"total"`
+	}
+
+	//walletAnalysis the result of wallet analyze. Amounts are in hastings
+	walletAnalysis struct {
+		Outputs int `json:"outputs"`
+		//WatchOnly outputs of watched addresses, which the wallet cannot spend and are not analyzed
+		WatchOnly int    `json:"watchonly"`
+		Total     string `json:"total"`
+		//InputFee the estimated fee of spending one output at the maximum /tpool/fee
+		InputFee     string         `json:"inputfee"`
+		Distribution []outputBucket `json:"distribution"`
+		//DustOutputs and DustTotal the outputs worth less than the fee of spending them
+		DustOutputs int    `json:"dustoutputs"`
+		DustTotal   string `json:"dusttotal"`
+		//MaxInputs the number of outputs that fit in a transaction
+		MaxInputs int `json:"maxinputs"`
+		//MaxSpendable the most one transaction can send: the largest outputs that fit, less fees
+		MaxSpendable    string   `json:"maxspendable"`
+		Consolidate     bool     `json:"consolidate"`
+		Recommendations []string `json:"recommendations"`
+	}
+)
+
+const (
+	//siacoinInputSize the estimated size in bytes of a siacoin input with a standard unlock
+	//condition and its signature
+	siacoinInputSize = 290
+	//transactionSizeLimit the largest transaction siad accepts into the transaction pool, less
+	//room for outputs and the miner fee
+	transactionSizeLimit = 32000 - 1000
+	//maxDustShare the share of outputs that may be dust before consolidation is recommended
+	maxDustShare = 0.2
+)
+
+//outputBuckets the upper bounds of the distribution buckets, from 1 SC in powers of ten
+var outputBuckets = func() (bounds []*big.Int) {
+	bound := format.CurrencyUnitHastings(4)
+
+	for i := 0; i < 6; i++ {
+		bounds = append(bounds, bound)
+		bound = new(big.Int).Mul(bound, big.NewInt(10))
+	}
+
+	return
+}()
+
+//outputBucketRange returns the label of the bucket with the index
+func outputBucketRange(i int) string {
+	switch {
+	case i == 0:
+		return "below " + format.FormatCurrency(outputBuckets[0])
+	case i == len(outputBuckets):
+		return format.FormatCurrency(outputBuckets[i-1]) + " and above"
+	}
+
+	return format.FormatCurrency(outputBuckets[i-1]) + " - " + format.FormatCurrency(outputBuckets[i])
+}
+
+//walletAnalyzeCommand reports how the wallet's balance is spread over its unspent outputs: the
+//distribution of output values, the dust worth less than the fee of spending it and the most one
+//transaction can send: wallet analyze. Consolidation is recommended when the balance cannot be
+//sent in one transaction or much of the wallet is dust
+func walletAnalyzeCommand(cmd Command) (err error) {
+	var unspent walletUnspentResponse
+	var fee tpoolFeeResponse
+
+	if err = callAPI(cmd, "GET", "/wallet/unspent", nil, &unspent); err != nil {
+		return
+	}
+
+	if err = callAPI(cmd, "GET", "/tpool/fee", nil, &fee); err != nil {
+		return
+	}
+
+	perByte, ok := new(big.Int).SetString(fee.Maximum, 10)
+
+	if !ok {
+		return fmt.Errorf("unexpected fee %q from /tpool/fee", fee.Maximum)
+	}
+
+	inputFee := new(big.Int).Mul(perByte, big.NewInt(siacoinInputSize))
+	analysis := walletAnalysis{
+		InputFee:        inputFee.String(),
+		MaxInputs:       transactionSizeLimit / siacoinInputSize,
+		Recommendations: []string{},
+	}
+	counts := make([]int, len(outputBuckets)+1)
+	totals := make([]*big.Int, len(outputBuckets)+1)
+
+	for i := range totals {
+		totals[i] = new(big.Int)
+	}
+
+	total, dust := new(big.Int), new(big.Int)
+	var spendable []*big.Int
+
+	for _, output := range unspent.Outputs {
+		if output.FundType != "siacoin output" {
+			continue
+		} else if output.IsWatchOnly {
+			analysis.WatchOnly++
+			continue
+		}
+
+		value, ok := new(big.Int).SetString(output.Value, 10)
+
+		if !ok {
+			return fmt.Errorf("unexpected value %q of output %s", output.Value, output.ID)
+		}
+
+		bucket := sort.Search(len(outputBuckets), func(i int) bool { return value.Cmp(outputBuckets[i]) < 0 })
+		counts[bucket]++
+		totals[bucket].Add(totals[bucket], value)
+		total.Add(total, value)
+		analysis.Outputs++
+
+		if value.Cmp(inputFee) <= 0 {
+			analysis.DustOutputs++
+			dust.Add(dust, value)
+		} else {
+			spendable = append(spendable, value)
+		}
+	}
+
+	for i := range counts {
+		analysis.Distribution = append(analysis.Distribution, outputBucket{
+			Range:   outputBucketRange(i),
+			Outputs: counts[i],
+			Total:   totals[i].String(),
+		})
+	}
+
+	sort.Slice(spendable, func(i, j int) bool { return spendable[i].Cmp(spendable[j]) > 0 })
+
+	maxSpendable := new(big.Int)
+
+	for i, value := range spendable {
+		if i == analysis.MaxInputs {
+			break
+		}
+
+		maxSpendable.Add(maxSpendable, new(big.Int).Sub(value, inputFee))
+	}
+
+	analysis.Total, analysis.DustTotal, analysis.MaxSpendable = total.String(), dust.String(), maxSpendable.String()
+
+	if len(spendable) > analysis.MaxInputs {
+		analysis.Consolidate = true
+		analysis.Recommendations = append(analysis.Recommendations, fmt.Sprintf("%d outputs do not fit in one transaction of at most %d inputs, one transaction can send at most %s of %s. Send the balance to the wallet's own address to consolidate it", len(spendable), analysis.MaxInputs, format.FormatCurrency(maxSpendable), format.FormatCurrency(total)))
+	}
+
+	if analysis.Outputs > 0 && float64(analysis.DustOutputs)/float64(analysis.Outputs) > maxDustShare {
+		analysis.Consolidate = true
+		analysis.Recommendations = append(analysis.Recommendations, fmt.Sprintf("%d of %d outputs are dust worth %s in total, less than the %s fee of spending each. Consolidate them when the /tpool/fee maximum drops", analysis.DustOutputs, analysis.Outputs, format.FormatCurrency(dust), format.FormatCurrency(inputFee)))
+	}
+
+	for _, recommendation := range analysis.Recommendations {
+		notify(cmd, "%s", recommendation)
+	}
+
+	return writeJSON(analysis)
+}
@@ -0,0 +1,338 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"net/url"
+	"strconv"
+
+	"github.com/n8maninger/siac-json/endpoints"
+	"github.com/n8maninger/siac-json/format"
+)
+
+type (
+	//bumpOutput an output of a /wallet/transaction transaction
+	bumpOutput struct {
+		ID             string `json:"id"`
+		FundType       string `json:"fundtype"`
+		WalletAddress  bool   `json:"walletaddress"`
+		RelatedAddress string `json:"relatedaddress"`
+		Value          string `json:"value"`
+	}
+
+	//walletTransactionResponse the fields of /wallet/transaction/:id used by wallet bump
+	walletTransactionResponse struct {
+		Transaction struct {
+			Transaction        json.RawMessage `json:"transaction"`
+			TransactionID      string          `json:"transactionid"`
+			ConfirmationHeight uint64          `json:"confirmationheight"`
+			Outputs            []bumpOutput    `json:"outputs"`
+		} `json:"transaction"`
+	}
+
+	//tpoolRawResponse the response of /tpool/raw/:id, base64 encoded. Parents are the unconfirmed
+	//transactions the transaction depends on
+	tpoolRawResponse struct {
+		ID          string `json:"id"`
+		Parents     string `json:"parents"`
+		Transaction string `json:"transaction"`
+	}
+
+	//bumpInput a siacoin input of a child transaction
+	bumpInput struct {
+		ParentID         string          `json:"parentid"`
+		UnlockConditions json.RawMessage `json:"unlockconditions"`
+	}
+
+	//bumpSiacoinOutput a siacoin output of a child transaction
+	bumpSiacoinOutput struct {
+		Value      string `json:"value"`
+		UnlockHash string `json:"unlockhash"`
+	}
+
+	//bumpCoveredFields the fields of a transaction a signature covers
+	bumpCoveredFields struct {
+		WholeTransaction bool `json:"wholetransaction"`
+	}
+
+	//bumpSignature a signature of a child transaction. /wallet/sign only fills in the signatures
+	//already present for the inputs it is asked to sign
+	bumpSignature struct {
+		ParentID       string            `json:"parentid"`
+		PublicKeyIndex uint64            `json:"publickeyindex"`
+		CoveredFields  bumpCoveredFields `json:"coveredfields"`
+	}
+
+	//bumpTransaction the child transaction respending the change output of a stuck transaction
+	bumpTransaction struct {
+		SiacoinInputs         []bumpInput         `json:"siacoininputs"`
+		SiacoinOutputs        []bumpSiacoinOutput `json:"siacoinoutputs"`
+		MinerFees             []string            `json:"minerfees"`
+		TransactionSignatures []bumpSignature     `json:"transactionsignatures"`
+	}
+
+	//walletBumpReport the result of wallet bump. Amounts are in hastings
+	walletBumpReport struct {
+		Parent string `json:"parent"`
+		//Output the change output of the parent the child spends
+		Output string `json:"output"`
+		//ParentFee the fees the parent already pays
+		ParentFee string `json:"parentfee"`
+		//Fee the fee of the child, paying for both transactions at FeeRate per byte
+		Fee     string `json:"fee"`
+		FeeRate string `json:"feerate"`
+		//Child the id of the broadcast child transaction, if the wallet reports it
+		Child       string          `json:"child,omitempty"`
+		Transaction bumpTransaction `json:"transaction"`
+		Broadcast   bool            `json:"broadcast"`
+	}
+)
+
+//bumpChildSize the estimated size in bytes of a child transaction with one input and output
+const bumpChildSize = siacoinInputSize + 150
+
+//postJSON sends a POST request to the path with the value as a JSON body and decodes the
+//response into obj, which may be nil
+func postJSON(cmd Command, path string, v, obj interface{}) (err error) {
+	buf, err := json.Marshal(v)
+
+	if err != nil {
+		return
+	}
+
+	post := cmd
+	post.Method, post.RequestPath, post.Params = "POST", path, nil
+	post.ContentType, post.BodyLength = "application/json", int64(len(buf))
+
+	return streamAPI(post, bytes.NewReader(buf), func(r io.Reader) error {
+		if obj == nil {
+			return nil
+		}
+
+		return json.NewDecoder(r).Decode(obj)
+	})
+}
+
+//bumpParents returns the base64 encoded parents of a child of the transaction: the unconfirmed
+//ancestors of the transaction from /tpool/raw followed by the transaction itself, so the pool
+//can accept the child even if the ancestors are not confirmed either. Both are in siad's binary
+//encoding, where a list of transactions is its length as a little endian uint64 followed by the
+//transactions
+func bumpParents(ancestors string, parent []byte) (parents string, err error) {
+	buf, err := base64.StdEncoding.DecodeString(ancestors)
+
+	if err != nil {
+		return
+	}
+
+	var count uint64
+
+	if len(buf) >= 8 {
+		count, buf = binary.LittleEndian.Uint64(buf[:8]), buf[8:]
+	} else if len(buf) > 0 {
+		return "", fmt.Errorf("truncated encoding")
+	}
+
+	encoded := make([]byte, 8, 8+len(buf)+len(parent))
+	binary.LittleEndian.PutUint64(encoded, count+1)
+	encoded = append(append(encoded, buf...), parent...)
+
+	return base64.StdEncoding.EncodeToString(encoded), nil
+}
+
+//walletBumpCommand speeds up an unconfirmed transaction lingering in the transaction pool with
+//a child-pays-for-parent respend of its largest output to the wallet: wallet bump <txid>. The
+//child sends the output to a new wallet address, paying a fee that raises the fee rate of both
+//transactions to the /tpool/fee maximum, or --fee. The child is signed and broadcast with its
+//parent after confirmation, --yes skips it and --dry-run only writes the child
+func walletBumpCommand(cmd Command) (err error) {
+	id := cmd.Args[2]
+
+	if err = validateID(id, endpoints.TransactionIDFormat); err != nil {
+		return
+	}
+
+	var confirmed struct {
+		Confirmed bool `json:"confirmed"`
+	}
+
+	if err = callAPI(cmd, "GET", "/tpool/confirmed/"+id, nil, &confirmed); err != nil {
+		return
+	} else if confirmed.Confirmed {
+		return fmt.Errorf("transaction %s is already confirmed", id)
+	}
+
+	var raw tpoolRawResponse
+
+	if err = callAPI(cmd, "GET", "/tpool/raw/"+id, nil, &raw); err != nil {
+		return fmt.Errorf("transaction %s is not in the transaction pool: %s", id, err)
+	}
+
+	parent, err := base64.StdEncoding.DecodeString(raw.Transaction)
+
+	if err != nil {
+		return fmt.Errorf("unable to decode transaction %s: %s", id, err)
+	}
+
+	parents, err := bumpParents(raw.Parents, parent)
+
+	if err != nil {
+		return fmt.Errorf("unable to decode the parents of transaction %s: %s", id, err)
+	}
+
+	var txn walletTransactionResponse
+
+	if err = callAPI(cmd, "GET", "/wallet/transaction/"+id, nil, &txn); err != nil {
+		return
+	}
+
+	var change *bumpOutput
+
+	for i, output := range txn.Transaction.Outputs {
+		if output.FundType != "siacoin output" || !output.WalletAddress {
+			continue
+		}
+
+		if change == nil || parseHastings(output.Value).Cmp(parseHastings(change.Value)) > 0 {
+			change = &txn.Transaction.Outputs[i]
+		}
+	}
+
+	if change == nil {
+		return fmt.Errorf("transaction %s has no output to the wallet to respend", id)
+	}
+
+	var parentTxn struct {
+		MinerFees []string `json:"minerfees"`
+	}
+
+	if err = json.Unmarshal(txn.Transaction.Transaction, &parentTxn); err != nil {
+		return
+	}
+
+	parentFee := new(big.Int)
+
+	for _, fee := range parentTxn.MinerFees {
+		parentFee.Add(parentFee, parseHastings(fee))
+	}
+
+	var fees tpoolFeeResponse
+
+	if err = callAPI(cmd, "GET", "/tpool/fee", nil, &fees); err != nil {
+		return
+	}
+
+	rate := parseHastings(fees.Maximum)
+	size := big.NewInt(int64(len(parent) + bumpChildSize))
+	fee := new(big.Int).Sub(new(big.Int).Mul(rate, size), parentFee)
+
+	// the child pays at least the rate for itself even if the parent already pays enough
+	if least := new(big.Int).Mul(rate, big.NewInt(bumpChildSize)); fee.Cmp(least) < 0 {
+		fee = least
+	}
+
+	if values := cmd.Params["fee"]; len(values) > 0 {
+		if fee, err = format.ParseCurrency(values[0]); err != nil {
+			return
+		}
+	}
+
+	value := parseHastings(change.Value)
+
+	if fee.Cmp(value) >= 0 {
+		return fmt.Errorf("the %s fee is more than the %s output %s", format.FormatCurrency(fee), format.FormatCurrency(value), change.ID)
+	}
+
+	var conditions struct {
+		UnlockConditions json.RawMessage `json:"unlockconditions"`
+	}
+
+	if err = callAPI(cmd, "GET", "/wallet/unlockconditions/"+change.RelatedAddress, nil, &conditions); err != nil {
+		return
+	}
+
+	var address struct {
+		Address string `json:"address"`
+	}
+
+	if err = callAPI(cmd, "GET", "/wallet/address", nil, &address); err != nil {
+		return
+	}
+
+	report := walletBumpReport{
+		Parent:    id,
+		Output:    change.ID,
+		ParentFee: parentFee.String(),
+		Fee:       fee.String(),
+		FeeRate:   rate.String(),
+		Transaction: bumpTransaction{
+			SiacoinInputs:  []bumpInput{{ParentID: change.ID, UnlockConditions: conditions.UnlockConditions}},
+			SiacoinOutputs: []bumpSiacoinOutput{{Value: new(big.Int).Sub(value, fee).String(), UnlockHash: address.Address}},
+			MinerFees:      []string{fee.String()},
+			//the wallet's standard unlock conditions have a single key
+			TransactionSignatures: []bumpSignature{{ParentID: change.ID, CoveredFields: bumpCoveredFields{WholeTransaction: true}}},
+		},
+	}
+
+	notify(cmd, "respending %s of %s with a %s fee", format.FormatCurrency(value), change.ID, format.FormatCurrency(fee))
+
+	if cmd.DryRun {
+		return writeJSON(report)
+	}
+
+	if ok, err := confirm(cmd, fmt.Sprintf("Broadcast a child of %s paying %s?", id, format.FormatCurrency(fee))); err != nil || !ok {
+		if err == nil {
+			err = fmt.Errorf("transaction not bumped")
+		}
+
+		return err
+	}
+
+	var signed struct {
+		Transaction json.RawMessage `json:"transaction"`
+	}
+
+	if err = postJSON(cmd, "/wallet/sign", map[string]interface{}{"transaction": report.Transaction, "tosign": []string{change.ID}}, &signed); err != nil {
+		return
+	}
+
+	// siad decodes each of parents and transaction as JSON or base64, so the binary parents from
+	// /tpool/raw can be sent with the JSON child
+	if err = callAPI(cmd, "POST", "/tpool/raw", url.Values{"parents": {parents}, "transaction": {string(signed.Transaction)}}, nil); err != nil {
+		return
+	}
+
+	report.Broadcast = true
+
+	var consensus consensusResponse
+	var transactions struct {
+		UnconfirmedTransactions []struct {
+			TransactionID string `json:"transactionid"`
+			Inputs        []struct {
+				ParentID string `json:"parentid"`
+			} `json:"inputs"`
+		} `json:"unconfirmedtransactions"`
+	}
+
+	// the child's id is only known to siad, it is found by the output it spends
+	if err := callAPI(cmd, "GET", "/consensus", nil, &consensus); err == nil {
+		height := strconv.FormatUint(consensus.Height, 10)
+
+		if err := callAPI(cmd, "GET", "/wallet/transactions", url.Values{"startheight": {height}, "endheight": {height}}, &transactions); err == nil {
+			for _, unconfirmed := range transactions.UnconfirmedTransactions {
+				for _, input := range unconfirmed.Inputs {
+					if input.ParentID == change.ID {
+						report.Child = unconfirmed.TransactionID
+					}
+				}
+			}
+		}
+	}
+
+	return writeJSON(report)
+}
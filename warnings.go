@@ -0,0 +1,164 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+)
+
+type (
+	//endpointWarning a warning about the request written to stderr, or included in the envelope
+	//in machine mode. Type is deprecated, unavailable, daemon or unknownparam
+	endpointWarning struct {
+		Type        string `json:"type"`
+		Endpoint    string `json:"endpoint"`
+		Method      string `json:"method"`
+		Param       string `json:"param,omitempty"`
+		Since       string `json:"since,omitempty"`
+		Replacement string `json:"replacement,omitempty"`
+		Message     string `json:"message"`
+	}
+
+	//daemonVersionResponse the fields of /daemon/version used by sia-json
+	daemonVersionResponse struct {
+		Version string `json:"version"`
+	}
+)
+
+//checkDeprecated warns when the endpoint is deprecated in the connected daemon's version, or
+//fails with --no-deprecated. The daemon is only asked for its version for deprecated endpoints
+func checkDeprecated(cmd *Command) (err error) {
+	endpoint := cmd.Endpoint
+
+	if len(endpoint.DeprecatedIn) == 0 {
+		return
+	}
+
+	info, detectErr := detectDaemon(*cmd)
+
+	// without a version the warning could be wrong, the request itself will report the error
+	if detectErr != nil || info.Kind != daemonSiad || compareVersions(info.Version, endpoint.DeprecatedIn) < 0 {
+		return
+	}
+
+	warning := endpointWarning{
+		Type:        "deprecated",
+		Endpoint:    endpoint.Path,
+		Method:      endpoint.Method,
+		Since:       endpoint.DeprecatedIn,
+		Replacement: endpoint.Replacement,
+		Message:     fmt.Sprintf("%s %s is deprecated since siad %s", endpoint.Method, endpoint.Path, endpoint.DeprecatedIn),
+	}
+
+	if len(endpoint.Replacement) > 0 {
+		warning.Message += ", use " + endpoint.Replacement + " instead"
+	}
+
+	if cmd.NoDeprecated {
+		return fmt.Errorf("%s. Remove --no-deprecated to send it anyway", warning.Message)
+	}
+
+	if cmd.Machine {
+		cmd.Warnings = append(cmd.Warnings, warning)
+	} else {
+		notify(*cmd, "warning: %s", warning.Message)
+	}
+
+	return
+}
+
+//editDistance returns the Levenshtein distance between a and b
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(a); i++ {
+		cur := make([]int, len(b)+1)
+		cur[0] = i
+
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+
+			cur[j] = prev[j-1] + cost
+
+			if prev[j]+1 < cur[j] {
+				cur[j] = prev[j] + 1
+			}
+
+			if cur[j-1]+1 < cur[j] {
+				cur[j] = cur[j-1] + 1
+			}
+		}
+
+		prev = cur
+	}
+
+	return prev[len(b)]
+}
+
+//checkUnknownParams warns about params that are not known params of the endpoint, suggesting
+//the closest known param, or fails with --strict-params. siad ignores params it does not know so
+//a typo silently has no effect
+func checkUnknownParams(cmd *Command) error {
+	// requests to paths that are not in the endpoint table have no known params
+	if len(cmd.Endpoint.Path) == 0 {
+		return nil
+	}
+
+	known := make(map[string]bool)
+
+	for _, param := range cmd.Endpoint.Params {
+		known[param.Key] = true
+	}
+
+	keys := make([]string, 0, len(cmd.Params))
+
+	for key := range cmd.Params {
+		if !known[key] {
+			keys = append(keys, key)
+		}
+	}
+
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		warning := endpointWarning{
+			Type:     "unknownparam",
+			Endpoint: cmd.Endpoint.Path,
+			Method:   cmd.Endpoint.Method,
+			Param:    key,
+			Message:  fmt.Sprintf("--%s is not a known param of %s %s", key, cmd.Endpoint.Method, cmd.Endpoint.Path),
+		}
+
+		// suggestions are limited to close matches so unrelated params are not suggested
+		best := 3
+
+		for _, param := range cmd.Endpoint.Params {
+			if d := editDistance(key, param.Key); d < best {
+				best, warning.Replacement = d, "--"+param.Key
+			}
+		}
+
+		if len(warning.Replacement) > 0 {
+			warning.Message += ", did you mean " + warning.Replacement + "?"
+		}
+
+		if cmd.StrictParams {
+			return fmt.Errorf("%s", warning.Message)
+		}
+
+		if cmd.Machine {
+			cmd.Warnings = append(cmd.Warnings, warning)
+		} else {
+			notify(*cmd, "warning: %s", warning.Message)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"os/signal"
+	"strings"
+	"time"
+)
+
+//clearScreen moves the cursor home and clears the terminal
+const clearScreen = "\033[H\033[2J"
+
+//changesSender returns a send func that writes the first response in full and then only the
+//changes from the previous response as JSON patch operations. Nothing is written if the response
+//did not change
+func changesSender() func(Command) error {
+	var prev interface{}
+	first := true
+
+	return func(cmd Command) (err error) {
+		var raw json.RawMessage
+
+		// empty responses are compared as null
+		if err = callAPI(cmd, cmd.Method, cmd.RequestPath, url.Values(cmd.Params), &raw); err != nil && err != io.EOF {
+			return
+		}
+
+		var cur interface{}
+
+		if len(raw) > 0 {
+			dec := json.NewDecoder(bytes.NewReader(raw))
+			// numbers are kept as written so large values are compared exactly
+			dec.UseNumber()
+
+			if err = dec.Decode(&cur); err != nil {
+				return
+			}
+		}
+
+		var out interface{} = cur
+
+		if !first {
+			ops := diffJSON(prev, cur, "")
+
+			if len(ops) == 0 {
+				return nil
+			}
+
+			out = ops
+		}
+
+		first, prev = false, cur
+		enc := json.NewEncoder(os.Stdout)
+
+		if cmd.Format == PrettyFormat {
+			enc.SetIndent("", "\t")
+		}
+
+		return enc.Encode(out)
+	}
+}
+
+//watch runs the command every --watch interval until interrupted. Failed runs are reported and
+//the command is run again at the next interval. While the daemon is unreachable the command is
+//not run and a status line is written instead of each connection error. The error of the last
+//run is returned
+func watch(cmd Command, send func(Command) error) (err error) {
+	if len(cmd.Endpoint.Path) > 0 && cmd.Method != "GET" {
+		return fmt.Errorf("--watch can only repeat GET requests")
+	}
+
+	if cmd.Changes {
+		if len(cmd.Endpoint.Path) == 0 {
+			return fmt.Errorf("--changes can only be used with API requests")
+		}
+
+		send = changesSender()
+	}
+
+	scheduler, err := commandScheduler(cmd, cmd.Watch)
+
+	if err != nil {
+		return
+	}
+
+	clear := cmd.Clear && isTerminal(os.Stdout) && !cmd.Machine
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, os.Interrupt)
+	defer signal.Stop(sigs)
+
+	pinger := commandPinger(cmd, func(status livenessStatus) {
+		notifyLiveness(cmd, status)
+	})
+
+	if pinger != nil {
+		defer pinger.Stop()
+	}
+
+	var last []byte
+
+	for {
+		var status livenessStatus
+
+		if pinger != nil {
+			status = pinger.Status()
+		}
+
+		if clear {
+			os.Stdout.WriteString(clearScreen)
+			fmt.Printf("Every %s: %s\t%s\n\n", scheduler.interval, strings.Join(cmd.Args, " "), time.Now().Format(time.RFC1123))
+
+			if pinger != nil && !status.Up {
+				fmt.Printf("%s %s, retrying\n\n", status.Address, livenessLabel(status))
+			}
+		}
+
+		// requests are not sent while the pinger reports the daemon unreachable, it reports
+		// when the daemon is back
+		changed := true
+
+		if pinger == nil || status.Up {
+			digest, sendErr := captureStdout(func() error { return send(cmd) })
+			// failures reset an adaptive interval so recovery is noticed quickly
+			changed = sendErr != nil || !bytes.Equal(digest, last)
+			last, err = digest, sendErr
+
+			if err != nil && pinger != nil && isUnreachable(err) {
+				pinger.Fail(err)
+			} else if err != nil {
+				reportError(cmd, err)
+
+				if !cmd.Machine {
+					os.Stderr.WriteString("\n")
+				}
+			}
+		}
+
+		select {
+		case <-time.After(scheduler.Next(changed)):
+		case <-sigs:
+			// errors were reported as they happened
+			if err != nil {
+				err = ExitCodeError{Code: exitCode(err), Err: reportedError{err}}
+			}
+
+			return
+		}
+	}
+}
@@ -0,0 +1,331 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/n8maninger/siac-json/endpoints"
+	"github.com/n8maninger/siac-json/format"
+)
+
+type (
+	//watchedFile the size and modification time of a file in a watched directory
+	watchedFile struct {
+		Size    int64     `json:"size"`
+		ModTime time.Time `json:"modtime"`
+	}
+
+	//watchDirState the files of a watched directory that have been uploaded, so files are not
+	//uploaded again when the watch is restarted
+	watchDirState struct {
+		Local    string                 `json:"local"`
+		SiaPath  string                 `json:"siapath"`
+		Uploaded map[string]watchedFile `json:"uploaded"`
+
+		path string
+	}
+
+	//watchDirUpdate a line written by renter watch-dir for each upload
+	watchDirUpdate struct {
+		Time    time.Time `json:"time"`
+		Path    string    `json:"path"`
+		SiaPath string    `json:"siapath"`
+		Status  string    `json:"status"`
+		Size    int64     `json:"size"`
+		Error   string    `json:"error,omitempty"`
+	}
+)
+
+const (
+	//defaultWatchDirInterval how often renter watch-dir scans the directory
+	defaultWatchDirInterval = 2 * time.Second
+
+	//defaultWatchDirDebounce how long a file must be unchanged before it is uploaded
+	defaultWatchDirDebounce = 5 * time.Second
+
+	//watchDirRetryDelay how long renter watch-dir waits before retrying a failed upload
+	watchDirRetryDelay = time.Minute
+)
+
+//loadWatchDirState loads the upload state of the local directory watched into the siapath
+func loadWatchDirState(cmd Command, local, siapath string) (state watchDirState, err error) {
+	sum := sha256.Sum256([]byte(cmd.APIAddress + "\x00" + local + "\x00" + siapath))
+	state = watchDirState{
+		Local:    local,
+		SiaPath:  siapath,
+		Uploaded: make(map[string]watchedFile),
+		path:     filepath.Join(DefaultConfigDir(), "watch", hex.EncodeToString(sum[:8])+".json"),
+	}
+
+	buf, err := ioutil.ReadFile(state.path)
+
+	if os.IsNotExist(err) {
+		return state, nil
+	} else if err != nil {
+		return
+	}
+
+	if err = json.Unmarshal(buf, &state); err == nil && state.Uploaded == nil {
+		state.Uploaded = make(map[string]watchedFile)
+	}
+
+	return
+}
+
+//save atomically writes the upload state
+func (s watchDirState) save() (err error) {
+	if err = os.MkdirAll(filepath.Dir(s.path), 0700); err != nil {
+		return
+	}
+
+	buf, err := json.MarshalIndent(s, "", "\t")
+
+	if err != nil {
+		return
+	}
+
+	tmp := s.path + ".tmp"
+
+	if err = ioutil.WriteFile(tmp, buf, 0600); err != nil {
+		return
+	}
+
+	return os.Rename(tmp, s.path)
+}
+
+//scanWatchedDir returns the regular files below the directory keyed by their slash separated
+//path relative to it, skipping the files and directories excluded by the filter
+func scanWatchedDir(dir string, filter pathFilter) (files map[string]watchedFile, err error) {
+	files = make(map[string]watchedFile)
+
+	err = filepath.Walk(dir, func(name string, info os.FileInfo, err error) error {
+		if err != nil {
+			// files removed while scanning are picked up by the next scan
+			if os.IsNotExist(err) {
+				return nil
+			}
+
+			return err
+		}
+
+		rel, err := filepath.Rel(dir, name)
+
+		if err != nil || rel == "." {
+			return err
+		}
+
+		rel = filepath.ToSlash(rel)
+
+		switch {
+		case filter.Excluded(rel, info.IsDir()) && info.IsDir():
+			return filepath.SkipDir
+		case filter.Excluded(rel, info.IsDir()) || !info.Mode().IsRegular():
+			return nil
+		}
+
+		files[rel] = watchedFile{
+			Size:    info.Size(),
+			ModTime: info.ModTime(),
+		}
+
+		return nil
+	})
+
+	return
+}
+
+//uploadWatchedFile streams the file to /renter/uploadstream with the upload journal, so an
+//upload interrupted by stopping the watch is resumed when it is restarted
+func uploadWatchedFile(cmd Command, local, siapath string, replace bool) (err error) {
+	upload := cmd
+	upload.Method = "POST"
+	upload.RequestPath = "/renter/uploadstream/" + siapath
+
+	if len(upload.Compress) > 0 {
+		upload.RequestPath = compressedPath(upload.RequestPath)
+	}
+	upload.UploadFile = local
+	upload.Params = make(map[string][]string)
+
+	upload.Endpoint, _ = endpoints.Find("/renter/uploadstream/*siapath", "POST")
+
+	if replace {
+		upload.Params["force"] = []string{"true"}
+	}
+
+	body, err := openRequestBody(&upload)
+
+	if err != nil {
+		return
+	}
+
+	defer body.Close()
+
+	if err = streamAPI(upload, body, nil); err != nil {
+		return
+	}
+
+	if err = upload.UploadChecksums.save(); err != nil {
+		return
+	}
+
+	// encrypted uploads are not journaled
+	if upload.UploadJournal == nil {
+		return
+	}
+
+	return upload.UploadJournal.remove()
+}
+
+//watchDirCommand watches a local directory and uploads new and modified files to the remote
+//directory until interrupted: renter watch-dir <local dir> <remote dir>. The directory is
+//scanned every --interval and a file is uploaded once it has not changed for --debounce.
+//Uploaded files are recorded so only files changed since are uploaded when the watch is
+//restarted. Files deleted locally are not deleted from the renter
+func watchDirCommand(cmd Command) (err error) {
+	if len(cmd.Args) != 4 {
+		return fmt.Errorf("usage: renter watch-dir <local dir> <remote dir>")
+	}
+
+	interval, debounce := defaultWatchDirInterval, defaultWatchDirDebounce
+
+	if values := cmd.Params["interval"]; len(values) > 0 {
+		if interval, err = format.ParseDuration(values[0]); err != nil {
+			return
+		}
+	}
+
+	if values := cmd.Params["debounce"]; len(values) > 0 {
+		if debounce, err = format.ParseDuration(values[0]); err != nil {
+			return
+		}
+	}
+
+	if len(cmd.Compress) > 0 && cmd.Compress != "gzip" {
+		return fmt.Errorf("invalid --compress value %q, use gzip", cmd.Compress)
+	}
+
+	filter, err := newPathFilter(cmd.Params)
+
+	if err != nil {
+		return
+	}
+
+	local, err := filepath.Abs(cmd.Args[2])
+
+	if err != nil {
+		return
+	}
+
+	if info, err := os.Stat(local); err != nil {
+		return err
+	} else if !info.IsDir() {
+		return fmt.Errorf("%s is not a directory", cmd.Args[2])
+	}
+
+	workspace, err := LoadState()
+
+	if err != nil {
+		return
+	}
+
+	remote := resolveSiaPath(workspace.Cwd, cmd.Args[3])
+
+	state, err := loadWatchDirState(cmd, local, remote)
+
+	if err != nil {
+		return
+	}
+
+	scheduler, err := commandScheduler(cmd, interval)
+
+	if err != nil {
+		return
+	}
+
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, os.Interrupt)
+	defer signal.Stop(sigs)
+
+	notify(cmd, "watching %s, uploading to %s", local, remote)
+
+	enc := json.NewEncoder(os.Stdout)
+	seen := make(map[string]watchedFile)
+	due := make(map[string]time.Time)
+	var changed bool
+
+	for {
+		files, err := scanWatchedDir(local, filter)
+
+		if err != nil {
+			return err
+		}
+
+		var ready []string
+		now := time.Now()
+		changed = false
+
+		for rel, file := range files {
+			if uploaded, ok := state.Uploaded[rel]; ok && uploaded.Size == file.Size && uploaded.ModTime.Equal(file.ModTime) {
+				continue
+			}
+
+			// the debounce restarts each time the file changes
+			if last, ok := seen[rel]; !ok || last.Size != file.Size || !last.ModTime.Equal(file.ModTime) {
+				due[rel] = now.Add(debounce)
+				changed = true
+			}
+
+			if !now.Before(due[rel]) {
+				ready = append(ready, rel)
+			}
+		}
+
+		seen = files
+		sort.Strings(ready)
+
+		for _, rel := range ready {
+			file := files[rel]
+			_, replace := state.Uploaded[rel]
+			update := watchDirUpdate{
+				Path:    filepath.Join(local, filepath.FromSlash(rel)),
+				SiaPath: strings.TrimPrefix(remote+"/"+rel, "/"),
+				Status:  "uploaded",
+				Size:    file.Size,
+			}
+
+			if err := uploadWatchedFile(cmd, update.Path, update.SiaPath, replace); err != nil {
+				update.Status, update.Error = "failed", err.Error()
+				due[rel] = time.Now().Add(watchDirRetryDelay)
+			} else {
+				state.Uploaded[rel] = file
+				delete(due, rel)
+
+				if err = state.save(); err != nil {
+					return err
+				}
+			}
+
+			update.Time = time.Now().UTC()
+
+			if err = enc.Encode(update); err != nil {
+				return err
+			}
+		}
+
+		select {
+		case <-time.After(scheduler.Next(changed || len(ready) > 0)):
+		case <-sigs:
+			return nil
+		}
+	}
+}
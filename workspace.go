@@ -0,0 +1,112 @@
+package main
+
+import (
+	"fmt"
+	"path"
+	"strings"
+)
+
+//siapathParams params that hold a siapath and are resolved against the working directory
+var siapathParams = []string{"newsiapath"}
+
+//resolveSiaPath resolves the siapath against the working directory. Absolute siapaths start with "/"
+func resolveSiaPath(cwd, siapath string) string {
+	if !strings.HasPrefix(siapath, "/") {
+		siapath = path.Join("/", cwd, siapath)
+	}
+
+	return strings.TrimPrefix(path.Clean(siapath), "/")
+}
+
+//splitSiaPath splits the command's request path into the endpoint prefix and the siapath matched
+//by the endpoint's wildcard segment
+func splitSiaPath(cmd Command) (prefix, siapath string, ok bool) {
+	wildcard := strings.Index(cmd.Endpoint.Path, "/*")
+
+	if wildcard == -1 {
+		return
+	}
+
+	prefixLen := len(strings.Split(cmd.Endpoint.Path[:wildcard], "/"))
+	segments := strings.Split(cmd.RequestPath, "/")
+
+	if len(segments) <= prefixLen {
+		return
+	}
+
+	return strings.Join(segments[:prefixLen], "/"), strings.Join(segments[prefixLen:], "/"), true
+}
+
+//resolveSiaPaths rewrites relative siapaths in the command's path and params using the working directory
+func resolveSiaPaths(cmd *Command) (err error) {
+	prefix, siapath, ok := splitSiaPath(*cmd)
+
+	if !ok {
+		return
+	}
+
+	state, err := LoadState()
+
+	if err != nil || len(state.Cwd) == 0 {
+		return
+	}
+
+	// "/" + an absolute siapath leaves an empty segment at the start of the siapath
+	cmd.RequestPath = prefix + "/" + resolveSiaPath(state.Cwd, siapath)
+
+	for _, key := range siapathParams {
+		for i, value := range cmd.Params[key] {
+			cmd.Params[key][i] = resolveSiaPath(state.Cwd, value)
+		}
+	}
+
+	return
+}
+
+//cdCommand changes the remote working directory: cd [siapath]
+func cdCommand(cmd Command) (err error) {
+	state, err := LoadState()
+
+	if err != nil {
+		return
+	}
+
+	dir := "/"
+
+	if len(cmd.Args) > 1 {
+		dir = "/" + resolveSiaPath(state.Cwd, strings.Join(cmd.Args[1:], "/"))
+	}
+
+	if dir != "/" {
+		if err = callAPI(cmd, "GET", "/renter/dir"+dir, nil, nil); err != nil {
+			return fmt.Errorf("unable to change directory to %s: %s", dir, err)
+		}
+	}
+
+	state.Cwd = dir
+
+	if err = SaveState(state); err != nil {
+		return
+	}
+
+	fmt.Println(dir)
+
+	return
+}
+
+//pwdCommand prints the remote working directory
+func pwdCommand(cmd Command) (err error) {
+	state, err := LoadState()
+
+	if err != nil {
+		return
+	}
+
+	if len(state.Cwd) == 0 {
+		state.Cwd = "/"
+	}
+
+	fmt.Println(state.Cwd)
+
+	return
+}